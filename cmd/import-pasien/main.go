@@ -0,0 +1,96 @@
+// Command import-pasien mengimpor data pasien dari file CSV, memakai validasi dan penomoran
+// Rekam_Medis yang sama dengan endpoint POST /api/management/pasien/import.
+//
+// Tanpa -commit hanya dilakukan dry-run (laporan validasi per baris, tidak ada yang disimpan):
+//
+//	go run ./cmd/import-pasien -file pasien.csv
+//	go run ./cmd/import-pasien -file pasien.csv -commit
+//
+// Exit code 1 jika ada baris yang gagal / konflik atau terjadi error, 2 jika argumen salah.
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/c14220110/poliklinik-backend/internal/administrasi/models"
+	"github.com/c14220110/poliklinik-backend/internal/administrasi/services"
+	"github.com/c14220110/poliklinik-backend/internal/common/antrian"
+	"github.com/c14220110/poliklinik-backend/pkg/storage/mariadb"
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	file := flag.String("file", "", "path file CSV pasien (wajib)")
+	commit := flag.Bool("commit", false, "simpan ke database; tanpa flag ini hanya dry-run")
+	envFile := flag.String("env", ".env", "file .env berisi konfigurasi database")
+	asJSON := flag.Bool("json", false, "cetak laporan lengkap dalam format JSON")
+	flag.Parse()
+
+	if *file == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err := godotenv.Load(*envFile); err != nil {
+		log.Fatalf("Gagal memuat %s: %v", *envFile, err)
+	}
+
+	f, err := os.Open(*file)
+	if err != nil {
+		log.Fatalf("Gagal membuka %s: %v", *file, err)
+	}
+	defer f.Close()
+
+	db := mariadb.Connect()
+	defer mariadb.Close()
+
+	svc := services.NewImportPasienService(db)
+	laporan, err := svc.Import(f, !*commit, antrian.Actor{Role: "Sistem"})
+	if laporan != nil {
+		if *asJSON {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			if err := enc.Encode(laporan); err != nil {
+				log.Fatalf("Gagal menulis laporan: %v", err)
+			}
+		} else {
+			cetakLaporan(laporan)
+		}
+	}
+	if err != nil {
+		if errors.Is(err, services.ErrImportDitolak) {
+			fmt.Fprintln(os.Stderr, "Import dibatalkan: perbaiki baris di atas lalu jalankan ulang.")
+			os.Exit(1)
+		}
+		log.Fatalf("Import gagal: %v", err)
+	}
+	if laporan.Gagal > 0 || laporan.Konflik > 0 {
+		os.Exit(1)
+	}
+}
+
+// cetakLaporan menampilkan baris yang bermasalah (dan yang diimpor) serta ringkasannya.
+func cetakLaporan(l *models.LaporanImportPasien) {
+	for _, b := range l.Baris {
+		switch b.Status {
+		case models.ImportGagal, models.ImportKonflik:
+			fmt.Printf("baris %d [%s] %s %s: %s\n", b.Baris, b.Status, b.NIK, b.Nama, strings.Join(b.Error, "; "))
+		case models.ImportDiimpor:
+			fmt.Printf("baris %d [%s] %s %s -> id_pasien %d, %s\n", b.Baris, b.Status, b.NIK, b.Nama, *b.IDPasien, b.IDRM)
+		}
+		for _, p := range b.Peringatan {
+			fmt.Printf("baris %d [peringatan] %s\n", b.Baris, p)
+		}
+	}
+	mode := "commit"
+	if l.DryRun {
+		mode = "dry-run"
+	}
+	fmt.Printf("\n%s: total %d, valid %d, konflik %d, gagal %d, diimpor %d\n",
+		mode, l.Total, l.Valid, l.Konflik, l.Gagal, l.Diimpor)
+}
@@ -1,9 +1,13 @@
 package config
 
 import (
+	"encoding/base64"
 	"log"
 	"os"
+	"strconv"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -17,6 +21,33 @@ type Config struct {
 	DBPort    string
 	DBName    string
 	JWTSecret string // <-- Tambahkan variabel ini
+
+	AccessTokenTTL  time.Duration // masa berlaku access token (ACCESS_TOKEN_TTL, default 15m)
+	RefreshTokenTTL time.Duration // masa berlaku sesi/refresh token (REFRESH_TOKEN_TTL, default 720h)
+
+	JanjiTemuCutoff time.Duration // batas check-in janji temu dihitung dari jam 00:00 (JANJI_TEMU_CUTOFF, default 10h = 10:00)
+
+	// PenutupanHarianJam: jam penutupan antrian hari ini dihitung dari jam 00:00
+	// (PENUTUPAN_HARIAN_JAM, default 23h = 23:00). Hari sebelumnya selalu ditutup.
+	PenutupanHarianJam time.Duration
+
+	// TriaseMaksTungguUmum: target tunggu maksimal pasien tanpa kategori triase
+	// (TRIASE_MAKS_TUNGGU_UMUM, default 2h). Dipakai untuk aturan keadilan panggilan.
+	TriaseMaksTungguUmum time.Duration
+
+	// NIKStrict: NIK_VALIDATION_MODE=strict menolak NIK yang tanggal lahir / jenis kelaminnya
+	// tidak cocok dengan data; lenient (default) hanya mengembalikan peringatan.
+	NIKStrict bool
+
+	// Lampiran dokumen pasien disimpan di luar folder uploads (yang publik) dan hanya bisa
+	// diunduh lewat API. LAMPIRAN_DIR default "storage/lampiran", LAMPIRAN_MAX_SIZE dalam byte
+	// (default 5 MiB).
+	LampiranDir     string
+	LampiranMaxSize int64
+
+	// VaultKey (VAULT_KEY, base64 32 byte) mengenkripsi data identitas pasien yang
+	// dipseudonimisasi dengan mode Vault. Kosong berarti mode Vault tidak tersedia.
+	VaultKey []byte
 }
 
 var (
@@ -38,7 +69,87 @@ func LoadConfig() *Config {
 			DBPort:     os.Getenv("DB_PORT"),
 			DBName:     os.Getenv("DB_NAME"),
 			JWTSecret:  os.Getenv("JWT_SECRET"), // Ambil JWT_SECRET dari .env
+
+			AccessTokenTTL:  durationEnv("ACCESS_TOKEN_TTL", 15*time.Minute),
+			RefreshTokenTTL: durationEnv("REFRESH_TOKEN_TTL", 720*time.Hour),
+
+			JanjiTemuCutoff: durationEnv("JANJI_TEMU_CUTOFF", 10*time.Hour),
+
+			PenutupanHarianJam: durationEnv("PENUTUPAN_HARIAN_JAM", 23*time.Hour),
+
+			TriaseMaksTungguUmum: durationEnv("TRIASE_MAKS_TUNGGU_UMUM", 2*time.Hour),
+
+			NIKStrict: nikStrictEnv(),
+
+			LampiranDir:     stringEnv("LAMPIRAN_DIR", "storage/lampiran"),
+			LampiranMaxSize: bytesEnv("LAMPIRAN_MAX_SIZE", 5<<20),
+
+			VaultKey: vaultKeyEnv(),
 		}
 	})
 	return cfg
 }
+
+// durationEnv membaca env berformat time.ParseDuration (misal "15m"), atau fallback jika kosong/tidak valid.
+func durationEnv(key string, fallback time.Duration) time.Duration {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d <= 0 {
+		log.Printf("Warning: %s tidak valid (%q), memakai default %s", key, v, fallback)
+		return fallback
+	}
+	return d
+}
+
+// stringEnv membaca env, atau fallback jika kosong.
+func stringEnv(key, fallback string) string {
+	if v := strings.TrimSpace(os.Getenv(key)); v != "" {
+		return v
+	}
+	return fallback
+}
+
+// bytesEnv membaca ukuran dalam byte (bilangan bulat positif), atau fallback jika kosong/tidak valid.
+func bytesEnv(key string, fallback int64) int64 {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback
+	}
+	n, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+	if err != nil || n <= 0 {
+		log.Printf("Warning: %s tidak valid (%q), memakai default %d", key, v, fallback)
+		return fallback
+	}
+	return n
+}
+
+// vaultKeyEnv membaca VAULT_KEY (base64 standar, 32 byte untuk AES-256). Kunci yang tidak
+// valid diabaikan dengan peringatan agar server tetap jalan tanpa mode Vault.
+func vaultKeyEnv() []byte {
+	v := strings.TrimSpace(os.Getenv("VAULT_KEY"))
+	if v == "" {
+		return nil
+	}
+	key, err := base64.StdEncoding.DecodeString(v)
+	if err != nil || len(key) != 32 {
+		log.Printf("Warning: VAULT_KEY harus base64 dari 32 byte, mode Vault dinonaktifkan")
+		return nil
+	}
+	return key
+}
+
+// nikStrictEnv membaca NIK_VALIDATION_MODE ("strict" atau "lenient", default lenient).
+func nikStrictEnv() bool {
+	switch v := strings.ToLower(strings.TrimSpace(os.Getenv("NIK_VALIDATION_MODE"))); v {
+	case "strict":
+		return true
+	case "", "lenient":
+		return false
+	default:
+		log.Printf("Warning: NIK_VALIDATION_MODE tidak valid (%q), memakai default lenient", v)
+		return false
+	}
+}
@@ -0,0 +1,41 @@
+-- File: db/migrations/002_privilege_policy.sql
+-- Seed privilege yang dipakai oleh tabel kebijakan route (internal/routes/policy.go).
+-- ID harus tetap sama dengan konstanta Priv* di policy.go. Privilege yang sudah ada tidak diubah;
+-- migrasi dihentikan bila id atau nama di bawah sudah dipakai privilege lain, karena route akan
+-- memeriksa privilege yang salah.
+
+CREATE TEMPORARY TABLE Seed_Privilege (
+  id_privilege   INT(11)      NOT NULL PRIMARY KEY,
+  nama_privilege VARCHAR(100) NOT NULL,
+  deskripsi      VARCHAR(255) NOT NULL
+);
+
+INSERT INTO Seed_Privilege (id_privilege, nama_privilege, deskripsi) VALUES
+  (1,  'Pendaftaran',        'Pendaftaran pasien dan kunjungan'),
+  (2,  'Kelola Antrian',     'Menunda, menjadwalkan ulang, dan membatalkan antrian'),
+  (3,  'Billing',            'Melihat dan membayar tagihan'),
+  (4,  'Screening',          'Input screening dan memanggil pasien ke screening'),
+  (5,  'Konsultasi',         'Memanggil pasien ke dokter, assessment, resep, pulangkan'),
+  (6,  'Lihat Rekam Medis',  'Melihat detail antrian, screening, assessment, dan resep'),
+  (7,  'Kelola Karyawan',    'Menambah, mengubah, dan menonaktifkan karyawan'),
+  (8,  'Kelola Poliklinik',  'Menambah, mengubah, dan menonaktifkan poliklinik'),
+  (9,  'Kelola Akses',       'Mengelola role, privilege, dan audit kebijakan route'),
+  (10, 'Kelola Shift',       'Mengatur shift karyawan'),
+  (11, 'Kelola CMS',         'Mengelola form assessment (CMS) per poli'),
+  (12, 'Dashboard',          'Melihat dashboard manajemen');
+
+DELIMITER //
+IF EXISTS (
+  SELECT 1
+  FROM Privilege p
+  JOIN Seed_Privilege s ON s.id_privilege = p.id_privilege OR s.nama_privilege = p.nama_privilege
+  WHERE p.id_privilege <> s.id_privilege OR p.nama_privilege <> s.nama_privilege
+) THEN
+  SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'Privilege: id / nama tidak sesuai konstanta Priv* di policy.go';
+END IF //
+DELIMITER ;
+
+INSERT IGNORE INTO Privilege (id_privilege, nama_privilege, deskripsi, created_at, updated_at)
+SELECT id_privilege, nama_privilege, deskripsi, NOW(), NOW() FROM Seed_Privilege;
+
+DROP TEMPORARY TABLE Seed_Privilege;
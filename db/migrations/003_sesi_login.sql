@@ -0,0 +1,23 @@
+-- File: db/migrations/003_sesi_login.sql
+-- Sesi login server-side: access token (jti = id_sesi) hanya berlaku selama sesi belum dicabut.
+-- Refresh token disimpan sebagai hash SHA-256 dan dirotasi setiap kali dipakai.
+
+CREATE TABLE IF NOT EXISTS Sesi_Login (
+  id_sesi             CHAR(32)     NOT NULL,
+  id_karyawan         INT(11)      NOT NULL, -- id_management untuk role Manajemen
+  role                VARCHAR(20)  NOT NULL,
+  id_role             INT(11)      NOT NULL DEFAULT 0,
+  id_poli             INT(11)      NOT NULL DEFAULT 0,
+  privileges          TEXT         NOT NULL, -- snapshot JSON privilege saat login
+  username            VARCHAR(100) NOT NULL,
+  nama                VARCHAR(255) NOT NULL,
+  refresh_token_hash  CHAR(64)     NOT NULL,
+  previous_token_hash CHAR(64)     DEFAULT NULL,
+  expires_at          DATETIME     NOT NULL,
+  created_at          DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP(),
+  last_used_at        DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP(),
+  revoked_at          DATETIME     DEFAULT NULL,
+  revoke_reason       VARCHAR(100) DEFAULT NULL,
+  PRIMARY KEY (id_sesi),
+  KEY idx_sesi_karyawan (id_karyawan, role, revoked_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
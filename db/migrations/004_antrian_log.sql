@@ -0,0 +1,27 @@
+-- File: db/migrations/004_antrian_log.sql
+-- Riwayat setiap perubahan status Antrian (lihat internal/common/antrian).
+-- status_dari NULL berarti antrian baru dibuat.
+
+CREATE TABLE IF NOT EXISTS Antrian_Log (
+  id_log      BIGINT(20)   NOT NULL AUTO_INCREMENT,
+  id_antrian  INT(11)      NOT NULL,
+  status_dari INT(11)      DEFAULT NULL,
+  status_ke   INT(11)      NOT NULL,
+  id_karyawan INT(11)      DEFAULT NULL, -- id_management untuk role Manajemen
+  role        VARCHAR(20)  NOT NULL,
+  alasan      VARCHAR(255) DEFAULT NULL,
+  created_at  DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP(),
+  PRIMARY KEY (id_log),
+  KEY idx_antrian_log_antrian (id_antrian, created_at),
+  CONSTRAINT fk_antrian_log_antrian FOREIGN KEY (id_antrian) REFERENCES Antrian (id_antrian)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+-- Kode memakai konstanta antrian.StatusDitunda = 2. Hentikan migrasi bila id 2 sudah dipakai
+-- status lain atau 'Ditunda' tercatat dengan id lain, daripada diam-diam salah status.
+DELIMITER //
+IF EXISTS (SELECT 1 FROM Status_Antrian WHERE (id_status = 2) <> (status = 'Ditunda')) THEN
+  SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'Status_Antrian: id 2 harus Ditunda (antrian.StatusDitunda)';
+END IF //
+DELIMITER ;
+
+INSERT IGNORE INTO Status_Antrian (id_status, status) VALUES (2, 'Ditunda');
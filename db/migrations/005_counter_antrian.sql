@@ -0,0 +1,23 @@
+-- File: db/migrations/005_counter_antrian.sql
+-- Nomor antrian per poli per hari dialokasikan lewat Counter_Antrian (SELECT ... FOR UPDATE),
+-- lihat antrian.AllocateNomor. Unique key di Antrian mencegah nomor ganda.
+
+CREATE TABLE IF NOT EXISTS Counter_Antrian (
+  id_poli INT(11) NOT NULL,
+  tanggal DATE    NOT NULL,
+  count   INT(11) NOT NULL DEFAULT 0,
+  PRIMARY KEY (id_poli, tanggal)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+-- Isi counter untuk data yang sudah ada.
+INSERT IGNORE INTO Counter_Antrian (id_poli, tanggal, count)
+SELECT id_poli, DATE(created_at), MAX(nomor_antrian)
+FROM Antrian
+GROUP BY id_poli, DATE(created_at);
+
+-- Periksa duplikat lama sebelum menambahkan unique key:
+--   SELECT id_poli, DATE(created_at), nomor_antrian, COUNT(*)
+--   FROM Antrian GROUP BY 1, 2, 3 HAVING COUNT(*) > 1;
+ALTER TABLE Antrian
+  ADD COLUMN IF NOT EXISTS tanggal_antrian DATE AS (DATE(created_at)) PERSISTENT,
+  ADD UNIQUE KEY uq_antrian_poli_tanggal_nomor (id_poli, tanggal_antrian, nomor_antrian);
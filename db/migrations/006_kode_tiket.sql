@@ -0,0 +1,7 @@
+-- File: db/migrations/006_kode_tiket.sql
+-- Kode tiket antrian untuk cek status & check-in ulang mandiri oleh pasien
+-- (lihat antrian.AssignKodeTiket). Antrian lama tidak memiliki kode (NULL).
+
+ALTER TABLE Antrian
+  ADD COLUMN IF NOT EXISTS kode_tiket VARCHAR(8) NULL AFTER nomor_antrian,
+  ADD UNIQUE KEY uq_antrian_kode_tiket (kode_tiket);
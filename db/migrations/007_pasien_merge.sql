@@ -0,0 +1,59 @@
+-- File: db/migrations/007_pasien_merge.sql
+-- Penggabungan data pasien ganda. Pasien duplikat tidak dihapus; ditandai merged_into
+-- dan setiap baris yang dipindahkan dicatat di Pasien_Merge_Detail agar bisa dikembalikan.
+
+ALTER TABLE Pasien
+  ADD COLUMN IF NOT EXISTS merged_into INT(11) NULL DEFAULT NULL,
+  ADD KEY idx_pasien_merged_into (merged_into);
+
+-- Rekam_Medis pasien duplikat ikut dipindah ke pasien utama agar nomor RM lama tetap menunjuk
+-- pasien yang aktif. digabung_dari mencatat id_pasien asal baris tersebut; NULL berarti nomor RM
+-- milik pasien itu sendiri, dan hanya baris inilah yang dipakai sebagai nomor RM aktif pasien.
+ALTER TABLE Rekam_Medis
+  ADD COLUMN IF NOT EXISTS digabung_dari INT(11) NULL DEFAULT NULL, -- id_pasien asal (merge)
+  ADD KEY IF NOT EXISTS idx_rekam_medis_digabung (id_pasien, digabung_dari);
+
+CREATE TABLE IF NOT EXISTS Pasien_Merge (
+  id_merge           INT(11)      NOT NULL AUTO_INCREMENT,
+  id_pasien_utama    INT(11)      NOT NULL,
+  id_pasien_duplikat INT(11)      NOT NULL,
+  skor               DECIMAL(4,3) DEFAULT NULL,
+  alasan             VARCHAR(255) DEFAULT NULL,
+  status             ENUM('Diajukan','Digabung','Ditolak','Dibatalkan') NOT NULL DEFAULT 'Diajukan',
+  diajukan_oleh      INT(11)      DEFAULT NULL, -- id_karyawan administrasi
+  diputuskan_oleh    INT(11)      DEFAULT NULL, -- id_management yang menyetujui / menolak
+  diputuskan_at      DATETIME     DEFAULT NULL,
+  dibatalkan_oleh    INT(11)      DEFAULT NULL, -- id_management
+  dibatalkan_at      DATETIME     DEFAULT NULL,
+  alasan_batal       VARCHAR(255) DEFAULT NULL,
+  created_at         DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP(),
+  PRIMARY KEY (id_merge),
+  KEY idx_pasien_merge_utama (id_pasien_utama),
+  KEY idx_pasien_merge_duplikat (id_pasien_duplikat),
+  CONSTRAINT fk_pasien_merge_utama FOREIGN KEY (id_pasien_utama) REFERENCES Pasien (id_pasien),
+  CONSTRAINT fk_pasien_merge_duplikat FOREIGN KEY (id_pasien_duplikat) REFERENCES Pasien (id_pasien)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+-- Satu baris per record yang dipindahkan: nilai kolom sebelum merge.
+CREATE TABLE IF NOT EXISTS Pasien_Merge_Detail (
+  id_detail  BIGINT(20)  NOT NULL AUTO_INCREMENT,
+  id_merge   INT(11)     NOT NULL,
+  tabel      VARCHAR(50) NOT NULL,
+  id_baris   VARCHAR(50) NOT NULL,
+  kolom      VARCHAR(50) NOT NULL,
+  nilai_lama VARCHAR(50) NOT NULL,
+  PRIMARY KEY (id_detail),
+  KEY idx_pasien_merge_detail_merge (id_merge),
+  CONSTRAINT fk_pasien_merge_detail_merge FOREIGN KEY (id_merge) REFERENCES Pasien_Merge (id_merge)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+-- Privilege 13 (routes.PrivKelolaDataPasien) untuk tindakan berisiko pada data induk pasien.
+-- Seperti migrasi 002: privilege yang sudah ada tidak diubah, id / nama yang bentrok menghentikan migrasi.
+DELIMITER //
+IF EXISTS (SELECT 1 FROM Privilege WHERE (id_privilege = 13) <> (nama_privilege = 'Kelola Data Pasien')) THEN
+  SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'Privilege: id 13 harus Kelola Data Pasien (PrivKelolaDataPasien)';
+END IF //
+DELIMITER ;
+
+INSERT IGNORE INTO Privilege (id_privilege, nama_privilege, deskripsi, created_at, updated_at) VALUES
+  (13, 'Kelola Data Pasien', 'Merge pasien ganda, restore versi, import, dan hapus data (pseudonimisasi) pasien', NOW(), NOW());
@@ -0,0 +1,52 @@
+-- File: db/migrations/008_janji_temu.sql
+-- Janji temu (booking) untuk tanggal mendatang. Saat pasien check-in pada hari H,
+-- janji temu berubah menjadi baris Antrian biasa dengan priority_order = nomor_slot,
+-- sehingga slot yang dipesan lebih awal tetap dipanggil lebih dulu daripada pasien walk-in
+-- yang datang belakangan. Janji temu yang tidak di-check-in sampai jam cutoff
+-- (JANJI_TEMU_CUTOFF) ditandai Kedaluwarsa.
+
+-- Kuota default per poli per hari; bisa dioverride per tanggal di Kuota_Janji_Temu.
+ALTER TABLE Poliklinik
+  ADD COLUMN IF NOT EXISTS kuota_janji_temu INT(11) NOT NULL DEFAULT 20;
+
+CREATE TABLE IF NOT EXISTS Kuota_Janji_Temu (
+  id_poli    INT(11)  NOT NULL,
+  tanggal    DATE     NOT NULL,
+  kuota      INT(11)  NOT NULL,
+  updated_by INT(11)  DEFAULT NULL, -- id_management
+  updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP() ON UPDATE CURRENT_TIMESTAMP(),
+  PRIMARY KEY (id_poli, tanggal),
+  CONSTRAINT fk_kuota_janji_temu_poli FOREIGN KEY (id_poli) REFERENCES Poliklinik (id_poli)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+-- Nomor slot per poli per tanggal, dialokasikan dengan SELECT ... FOR UPDATE seperti Counter_Antrian.
+-- Slot yang dibatalkan tidak dipakai ulang; kuota dihitung dari janji temu yang masih aktif.
+CREATE TABLE IF NOT EXISTS Counter_Janji_Temu (
+  id_poli INT(11) NOT NULL,
+  tanggal DATE    NOT NULL,
+  count   INT(11) NOT NULL DEFAULT 0,
+  PRIMARY KEY (id_poli, tanggal)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+CREATE TABLE IF NOT EXISTS Janji_Temu (
+  id_janji_temu INT(11)      NOT NULL AUTO_INCREMENT,
+  id_pasien     INT(11)      NOT NULL,
+  id_poli       INT(11)      NOT NULL,
+  id_dokter     INT(11)      DEFAULT NULL, -- id_karyawan dokter (opsional)
+  tanggal       DATE         NOT NULL,
+  nomor_slot    INT(11)      NOT NULL,
+  keluhan_utama VARCHAR(255) DEFAULT NULL,
+  status        ENUM('Dipesan','Hadir','Dibatalkan','Kedaluwarsa') NOT NULL DEFAULT 'Dipesan',
+  id_antrian    INT(11)      DEFAULT NULL, -- terisi saat check-in
+  alasan_batal  VARCHAR(255) DEFAULT NULL,
+  created_by    INT(11)      DEFAULT NULL, -- id_karyawan administrasi
+  created_at    DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP(),
+  updated_at    DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP() ON UPDATE CURRENT_TIMESTAMP(),
+  PRIMARY KEY (id_janji_temu),
+  UNIQUE KEY uq_janji_temu_slot (id_poli, tanggal, nomor_slot),
+  KEY idx_janji_temu_tanggal_status (tanggal, status),
+  KEY idx_janji_temu_pasien (id_pasien),
+  CONSTRAINT fk_janji_temu_pasien FOREIGN KEY (id_pasien) REFERENCES Pasien (id_pasien),
+  CONSTRAINT fk_janji_temu_poli FOREIGN KEY (id_poli) REFERENCES Poliklinik (id_poli),
+  CONSTRAINT fk_janji_temu_antrian FOREIGN KEY (id_antrian) REFERENCES Antrian (id_antrian)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
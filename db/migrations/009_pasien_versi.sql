@@ -0,0 +1,19 @@
+-- File: db/migrations/009_pasien_versi.sql
+-- Riwayat perubahan data demografis pasien. Setiap versi menyimpan snapshot lengkap kolom
+-- demografis (JSON) setelah perubahan, beserta operator yang mengubahnya. Pasien lama belum
+-- punya versi; snapshot "awal" dibuat otomatis sebelum perubahan pertamanya.
+
+CREATE TABLE IF NOT EXISTS Pasien_Versi (
+  id_versi    BIGINT(20)  NOT NULL AUTO_INCREMENT,
+  id_pasien   INT(11)     NOT NULL,
+  versi       INT(11)     NOT NULL,
+  data        LONGTEXT    NOT NULL CHECK (JSON_VALID(data)),
+  sumber      VARCHAR(30) NOT NULL, -- awal, pendaftaran, kunjungan, restore
+  diubah_oleh INT(11)     DEFAULT NULL, -- id_karyawan / id_management sesuai role
+  role        VARCHAR(30) DEFAULT NULL,
+  keterangan  VARCHAR(255) DEFAULT NULL,
+  created_at  DATETIME    NOT NULL DEFAULT CURRENT_TIMESTAMP(),
+  PRIMARY KEY (id_versi),
+  UNIQUE KEY uq_pasien_versi (id_pasien, versi),
+  CONSTRAINT fk_pasien_versi_pasien FOREIGN KEY (id_pasien) REFERENCES Pasien (id_pasien)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
@@ -0,0 +1,81 @@
+-- File: db/migrations/010_penjamin.sql
+-- Penjamin (BPJS, asuransi swasta, perusahaan) dan kepesertaan pasien. Penjamin dipilih saat
+-- pendaftaran dan dicatat di Riwayat_Kunjungan; saat tagihan dibayar, total dibagi antara
+-- bagian yang ditanggung penjamin dan bagian yang dibayar pasien sesuai Aturan_Penjamin.
+-- Kunjungan tanpa kepesertaan dianggap pasien umum (dibayar penuh oleh pasien).
+
+CREATE TABLE IF NOT EXISTS Penjamin (
+  id_penjamin INT(11)      NOT NULL AUTO_INCREMENT,
+  nama        VARCHAR(100) NOT NULL,
+  jenis       ENUM('BPJS','Asuransi','Perusahaan') NOT NULL,
+  aktif       TINYINT(1)   NOT NULL DEFAULT 1,
+  created_at  DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP(),
+  updated_at  DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP() ON UPDATE CURRENT_TIMESTAMP(),
+  PRIMARY KEY (id_penjamin),
+  UNIQUE KEY uq_penjamin_nama (nama)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+-- Aturan tanggungan per komponen tagihan. Untuk tindakan, aturan dengan id_icd9_cm berlaku
+-- untuk kode itu saja dan mengalahkan aturan umum (id_icd9_cm NULL). Komponen tanpa aturan
+-- tidak ditanggung. plafon = batas nominal yang ditanggung per kunjungan untuk aturan itu.
+-- Unique key memakai id_icd9_cm_kunci (NULL menjadi '') karena NULL tidak pernah sama dengan
+-- NULL, sehingga aturan umum per komponen tetap unik.
+CREATE TABLE IF NOT EXISTS Aturan_Penjamin (
+  id_aturan         INT(11)       NOT NULL AUTO_INCREMENT,
+  id_penjamin       INT(11)       NOT NULL,
+  komponen          ENUM('dokter','obat','tindakan') NOT NULL,
+  id_icd9_cm        VARCHAR(20)   DEFAULT NULL,
+  persen_tanggungan DECIMAL(5,2)  NOT NULL,
+  plafon            DECIMAL(15,2) DEFAULT NULL,
+  id_icd9_cm_kunci  VARCHAR(20)   AS (IFNULL(id_icd9_cm, '')) PERSISTENT,
+  PRIMARY KEY (id_aturan),
+  UNIQUE KEY uq_aturan_penjamin_kunci (id_penjamin, komponen, id_icd9_cm_kunci),
+  CONSTRAINT fk_aturan_penjamin_penjamin FOREIGN KEY (id_penjamin) REFERENCES Penjamin (id_penjamin),
+  CONSTRAINT chk_aturan_penjamin_persen CHECK (persen_tanggungan BETWEEN 0 AND 100)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+-- Kepesertaan yang tercipta otomatis saat pendaftaran (nomor_peserta belum terdaftar) belum
+-- terverifikasi: kunjungan tetap mencatat penjamin yang dipilih, tetapi pembagian tagihan
+-- menganggapnya pasien umum sampai manajemen mengonfirmasi kepesertaan tersebut.
+-- Kepesertaan yang didaftarkan lewat endpoint kepesertaan (dengan masa berlaku) langsung
+-- terverifikasi.
+CREATE TABLE IF NOT EXISTS Kepesertaan_Penjamin (
+  id_kepesertaan INT(11)     NOT NULL AUTO_INCREMENT,
+  id_pasien      INT(11)     NOT NULL,
+  id_penjamin    INT(11)     NOT NULL,
+  nomor_peserta  VARCHAR(50) NOT NULL,
+  berlaku_mulai  DATE        NOT NULL,
+  berlaku_sampai DATE        DEFAULT NULL, -- NULL = tanpa batas
+  terverifikasi  TINYINT(1)  NOT NULL DEFAULT 1,
+  verified_by    INT(11)     DEFAULT NULL, -- id_management
+  verified_at    DATETIME    DEFAULT NULL,
+  created_by     INT(11)     DEFAULT NULL, -- id_karyawan administrasi
+  created_at     DATETIME    NOT NULL DEFAULT CURRENT_TIMESTAMP(),
+  updated_at     DATETIME    NOT NULL DEFAULT CURRENT_TIMESTAMP() ON UPDATE CURRENT_TIMESTAMP(),
+  PRIMARY KEY (id_kepesertaan),
+  UNIQUE KEY uq_kepesertaan_nomor (id_penjamin, nomor_peserta),
+  KEY idx_kepesertaan_pasien (id_pasien),
+  KEY idx_kepesertaan_terverifikasi (terverifikasi),
+  CONSTRAINT fk_kepesertaan_pasien FOREIGN KEY (id_pasien) REFERENCES Pasien (id_pasien),
+  CONSTRAINT fk_kepesertaan_penjamin FOREIGN KEY (id_penjamin) REFERENCES Penjamin (id_penjamin)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+ALTER TABLE Riwayat_Kunjungan
+  ADD COLUMN IF NOT EXISTS id_kepesertaan INT(11) NULL DEFAULT NULL,
+  ADD KEY idx_riwayat_kunjungan_kepesertaan (id_kepesertaan);
+
+-- Pembagian total saat tagihan dibayar. tipe_pembayaran tetap cara bayar bagian pasien.
+ALTER TABLE Billing
+  ADD COLUMN IF NOT EXISTS id_penjamin         INT(11)       NULL DEFAULT NULL,
+  ADD COLUMN IF NOT EXISTS ditanggung_penjamin DECIMAL(15,2) NULL DEFAULT NULL,
+  ADD COLUMN IF NOT EXISTS dibayar_pasien      DECIMAL(15,2) NULL DEFAULT NULL;
+
+-- Privilege 14 (routes.PrivKelolaPenjamin); id / nama yang bentrok menghentikan migrasi (lihat 002).
+DELIMITER //
+IF EXISTS (SELECT 1 FROM Privilege WHERE (id_privilege = 14) <> (nama_privilege = 'Kelola Penjamin')) THEN
+  SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'Privilege: id 14 harus Kelola Penjamin (PrivKelolaPenjamin)';
+END IF //
+DELIMITER ;
+
+INSERT IGNORE INTO Privilege (id_privilege, nama_privilege, deskripsi, created_at, updated_at) VALUES
+  (14, 'Kelola Penjamin', 'Mengelola penjamin (BPJS, asuransi, perusahaan) dan aturan tanggungannya', NOW(), NOW());
@@ -0,0 +1,48 @@
+-- File: db/migrations/011_persetujuan.sql
+-- Persetujuan (informed consent) pasien. Template dikelola manajemen: persetujuan umum dan
+-- berbagi data per poli (id_poli NULL = semua poli), persetujuan tindakan per kode ICD9_CM.
+-- Persetujuan yang ditandatangani dicatat per kunjungan beserta isi template saat itu, sehingga
+-- perubahan template tidak mengubah dokumen yang sudah ditandatangani.
+-- SaveBillingAssessment menolak tindakan yang punya template wajib tetapi belum disetujui.
+
+CREATE TABLE IF NOT EXISTS Template_Persetujuan (
+  id_template INT(11)      NOT NULL AUTO_INCREMENT,
+  judul       VARCHAR(150) NOT NULL,
+  jenis       ENUM('Umum','Berbagi Data','Tindakan') NOT NULL,
+  isi         TEXT         NOT NULL,
+  id_poli     INT(11)      DEFAULT NULL, -- hanya untuk jenis Umum / Berbagi Data
+  id_icd9_cm  VARCHAR(20)  DEFAULT NULL, -- wajib untuk jenis Tindakan
+  wajib       TINYINT(1)   NOT NULL DEFAULT 1,
+  aktif       TINYINT(1)   NOT NULL DEFAULT 1,
+  created_by  INT(11)      DEFAULT NULL, -- id_management
+  updated_by  INT(11)      DEFAULT NULL, -- id_management
+  created_at  DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP(),
+  updated_at  DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP() ON UPDATE CURRENT_TIMESTAMP(),
+  PRIMARY KEY (id_template),
+  KEY idx_template_persetujuan_poli (id_poli),
+  KEY idx_template_persetujuan_icd9 (id_icd9_cm),
+  CONSTRAINT fk_template_persetujuan_poli FOREIGN KEY (id_poli) REFERENCES Poliklinik (id_poli)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+CREATE TABLE IF NOT EXISTS Persetujuan_Pasien (
+  id_persetujuan      INT(11)      NOT NULL AUTO_INCREMENT,
+  id_kunjungan        INT(11)      NOT NULL,
+  id_template         INT(11)      NOT NULL,
+  judul               VARCHAR(150) NOT NULL, -- salinan template saat ditandatangani
+  isi                 TEXT         NOT NULL,
+  setuju              TINYINT(1)   NOT NULL, -- 0 = pasien menolak
+  penanda_tangan      ENUM('Pasien','Penanggung Jawab') NOT NULL,
+  nama_penanda_tangan VARCHAR(100) NOT NULL,
+  hubungan            VARCHAR(50)  NOT NULL, -- 'Pasien' atau hubungan penanggung jawab dengan pasien
+  ditandatangani_at   DATETIME     NOT NULL,
+  dicatat_oleh        INT(11)      DEFAULT NULL, -- id_karyawan
+  role                VARCHAR(30)  DEFAULT NULL,
+  dicabut_at          DATETIME     DEFAULT NULL,
+  dicabut_oleh        INT(11)      DEFAULT NULL,
+  alasan_cabut        VARCHAR(255) DEFAULT NULL,
+  created_at          DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP(),
+  PRIMARY KEY (id_persetujuan),
+  KEY idx_persetujuan_kunjungan (id_kunjungan),
+  CONSTRAINT fk_persetujuan_kunjungan FOREIGN KEY (id_kunjungan) REFERENCES Riwayat_Kunjungan (id_kunjungan),
+  CONSTRAINT fk_persetujuan_template FOREIGN KEY (id_template) REFERENCES Template_Persetujuan (id_template)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
@@ -0,0 +1,30 @@
+-- File: db/migrations/012_lampiran.sql
+-- Lampiran dokumen pasien (scan KTP, kartu BPJS/asuransi, surat rujukan eksternal).
+-- File disimpan di LAMPIRAN_DIR (bukan folder uploads yang publik) dengan nama acak; kolom path
+-- relatif terhadap LAMPIRAN_DIR. sha256 dipakai untuk mendeteksi lampiran ganda dan memeriksa
+-- keutuhan file saat diunduh. Lampiran yang dihapus hanya ditandai deleted_at.
+
+CREATE TABLE IF NOT EXISTS Lampiran (
+  id_lampiran   INT(11)      NOT NULL AUTO_INCREMENT,
+  id_pasien     INT(11)      NOT NULL,
+  id_kunjungan  INT(11)      DEFAULT NULL,
+  jenis         ENUM('KTP','Kartu BPJS','Kartu Asuransi','Surat Rujukan','Lainnya') NOT NULL,
+  nama_file     VARCHAR(255) NOT NULL, -- nama asli dari pengunggah
+  path          VARCHAR(255) NOT NULL,
+  mime_type     VARCHAR(100) NOT NULL,
+  ukuran        BIGINT(20)   NOT NULL,
+  sha256        CHAR(64)     NOT NULL,
+  keterangan    VARCHAR(255) DEFAULT NULL,
+  diunggah_oleh INT(11)      DEFAULT NULL, -- id_karyawan
+  role          VARCHAR(30)  DEFAULT NULL,
+  created_at    DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP(),
+  deleted_at    DATETIME     DEFAULT NULL,
+  dihapus_oleh  INT(11)      DEFAULT NULL,
+  PRIMARY KEY (id_lampiran),
+  UNIQUE KEY uq_lampiran_path (path),
+  KEY idx_lampiran_pasien (id_pasien),
+  KEY idx_lampiran_kunjungan (id_kunjungan),
+  KEY idx_lampiran_sha256 (sha256),
+  CONSTRAINT fk_lampiran_pasien FOREIGN KEY (id_pasien) REFERENCES Pasien (id_pasien),
+  CONSTRAINT fk_lampiran_kunjungan FOREIGN KEY (id_kunjungan) REFERENCES Riwayat_Kunjungan (id_kunjungan)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
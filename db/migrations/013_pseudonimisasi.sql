@@ -0,0 +1,53 @@
+-- File: db/migrations/013_pseudonimisasi.sql
+-- Permintaan penghapusan data pasien (UU Pelindungan Data Pribadi). Rekam medis dan billing wajib
+-- disimpan, sehingga pasien tidak pernah di-DELETE (FK lama memakai ON DELETE CASCADE); data
+-- identitas (nama, NIK, telepon, alamat) diganti token. Permintaan diajukan, diputuskan manajemen,
+-- lalu dilaksanakan; semua langkah tercatat di Permintaan_Hapus_Data.
+-- Mode Pseudonim tidak bisa dibalik. Mode Vault (legal hold) menyimpan data asli terenkripsi
+-- AES-256-GCM (VAULT_KEY) di Vault_Pasien sehingga bisa dipulihkan.
+
+ALTER TABLE Pasien
+  ADD COLUMN IF NOT EXISTS pseudonim_at DATETIME NULL DEFAULT NULL;
+
+CREATE TABLE IF NOT EXISTS Permintaan_Hapus_Data (
+  id_permintaan     INT(11)      NOT NULL AUTO_INCREMENT,
+  id_pasien         INT(11)      NOT NULL,
+  mode              ENUM('Pseudonim','Vault') NOT NULL,
+  alasan            VARCHAR(255) NOT NULL,
+  status            ENUM('Diajukan','Disetujui','Ditolak','Dilaksanakan','Dipulihkan') NOT NULL DEFAULT 'Diajukan',
+  diajukan_oleh     INT(11)      DEFAULT NULL, -- id_karyawan / id_management
+  role_pengaju      VARCHAR(30)  DEFAULT NULL,
+  diputuskan_oleh   INT(11)      DEFAULT NULL, -- id_management
+  diputuskan_at     DATETIME     DEFAULT NULL,
+  catatan_keputusan VARCHAR(255) DEFAULT NULL,
+  dilaksanakan_oleh INT(11)      DEFAULT NULL, -- id_management
+  dilaksanakan_at   DATETIME     DEFAULT NULL,
+  token             VARCHAR(20)  DEFAULT NULL, -- token pengganti nama / NIK pasien
+  dipulihkan_oleh   INT(11)      DEFAULT NULL, -- id_management
+  dipulihkan_at     DATETIME     DEFAULT NULL,
+  alasan_pulih      VARCHAR(255) DEFAULT NULL,
+  created_at        DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP(),
+  PRIMARY KEY (id_permintaan),
+  KEY idx_permintaan_hapus_pasien (id_pasien),
+  KEY idx_permintaan_hapus_status (status),
+  CONSTRAINT fk_permintaan_hapus_pasien FOREIGN KEY (id_pasien) REFERENCES Pasien (id_pasien)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+-- data: JSON terenkripsi berisi data Pasien, snapshot Pasien_Versi, dan lampiran identitas
+-- yang disembunyikan. Dikosongkan (NULL) setelah dipulihkan.
+CREATE TABLE IF NOT EXISTS Vault_Pasien (
+  id_permintaan INT(11)     NOT NULL,
+  id_pasien     INT(11)     NOT NULL,
+  key_id        CHAR(16)    NOT NULL,
+  nonce         VARBINARY(12) DEFAULT NULL,
+  data          MEDIUMBLOB  DEFAULT NULL,
+  created_at    DATETIME    NOT NULL DEFAULT CURRENT_TIMESTAMP(),
+  dibuka_at     DATETIME    DEFAULT NULL,
+  PRIMARY KEY (id_permintaan),
+  CONSTRAINT fk_vault_pasien_permintaan FOREIGN KEY (id_permintaan) REFERENCES Permintaan_Hapus_Data (id_permintaan)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+-- Pengaman terakhir: DELETE pada Pasien akan ikut menghapus rekam medis lewat CASCADE.
+DROP TRIGGER IF EXISTS trg_pasien_tolak_delete;
+CREATE TRIGGER trg_pasien_tolak_delete BEFORE DELETE ON Pasien FOR EACH ROW
+  SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'Pasien tidak boleh dihapus; gunakan permintaan hapus data (pseudonimisasi)';
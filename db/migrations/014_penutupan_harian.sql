@@ -0,0 +1,39 @@
+-- File: db/migrations/014_penutupan_harian.sql
+-- Penutupan antrian harian. Antrian yang tertinggal di Menunggu / Ditunda saat hari berganti
+-- ditandai Tidak Hadir, yang tertinggal di Screening / Pra-Konsultasi dibatalkan; billing yang
+-- belum dibayar ikut dibatalkan. Hasilnya direkap per poli per tanggal di Penutupan_Harian.
+-- Penutupan berjalan otomatis (PENUTUPAN_HARIAN_JAM) dan bisa dipicu manual oleh manajemen.
+
+-- Sama seperti migrasi 004: id 8 harus Tidak Hadir sesuai konstanta antrian.StatusTidakHadir.
+DELIMITER //
+IF EXISTS (SELECT 1 FROM Status_Antrian WHERE (id_status = 8) <> (status = 'Tidak Hadir')) THEN
+  SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'Status_Antrian: id 8 harus Tidak Hadir (antrian.StatusTidakHadir)';
+END IF //
+DELIMITER ;
+
+INSERT IGNORE INTO Status_Antrian (id_status, status) VALUES
+  (8, 'Tidak Hadir');
+
+-- Kolom jumlah status (total_antrian .. masih_konsultasi) adalah kondisi akhir setelah penutupan
+-- terakhir; ditutup & billing_dibatalkan diakumulasi dari semua penutupan pada tanggal tersebut.
+CREATE TABLE IF NOT EXISTS Penutupan_Harian (
+  id_penutupan       INT(11)     NOT NULL AUTO_INCREMENT,
+  tanggal            DATE        NOT NULL,
+  id_poli            INT(11)     NOT NULL,
+  total_antrian      INT(11)     NOT NULL DEFAULT 0,
+  pulang             INT(11)     NOT NULL DEFAULT 0,
+  dibatalkan         INT(11)     NOT NULL DEFAULT 0,
+  tidak_hadir        INT(11)     NOT NULL DEFAULT 0,
+  masih_konsultasi   INT(11)     NOT NULL DEFAULT 0, -- tidak ditutup otomatis, perlu diselesaikan dokter
+  ditutup            INT(11)     NOT NULL DEFAULT 0, -- antrian yang statusnya diubah oleh penutupan
+  billing_dibatalkan INT(11)     NOT NULL DEFAULT 0,
+  sumber             ENUM('Otomatis','Manual') NOT NULL DEFAULT 'Otomatis',
+  dijalankan_oleh    INT(11)     DEFAULT NULL, -- id_management (NULL untuk job otomatis)
+  role               VARCHAR(30) DEFAULT NULL,
+  created_at         DATETIME    NOT NULL DEFAULT CURRENT_TIMESTAMP(),
+  updated_at         DATETIME    NOT NULL DEFAULT CURRENT_TIMESTAMP() ON UPDATE CURRENT_TIMESTAMP(),
+  PRIMARY KEY (id_penutupan),
+  UNIQUE KEY uq_penutupan_tanggal_poli (tanggal, id_poli),
+  KEY idx_penutupan_poli (id_poli),
+  CONSTRAINT fk_penutupan_poli FOREIGN KEY (id_poli) REFERENCES Poliklinik (id_poli)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
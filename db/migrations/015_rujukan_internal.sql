@@ -0,0 +1,20 @@
+-- File: db/migrations/015_rujukan_internal.sql
+-- Rujukan internal: dokter mengirim pasien ke poli lain dalam kunjungan yang sama. Setiap baris
+-- Kunjungan_Poli kini menunjuk antrian di poli tersebut; baris rujukan ditandai id_antrian_asal
+-- dan menyimpan assessment poli tujuan. Billing tetap satu per kunjungan (milik antrian asal),
+-- tarif dokter & tindakan semua poli digabung saat pembayaran.
+
+ALTER TABLE Kunjungan_Poli
+  ADD COLUMN IF NOT EXISTS id_antrian      INT(11)      NULL DEFAULT NULL,
+  ADD COLUMN IF NOT EXISTS id_assessment   INT(11)      NULL DEFAULT NULL, -- hanya untuk baris rujukan
+  ADD COLUMN IF NOT EXISTS id_antrian_asal INT(11)      NULL DEFAULT NULL, -- NULL = poli pendaftaran
+  ADD COLUMN IF NOT EXISTS alasan_rujukan  VARCHAR(255) NULL DEFAULT NULL,
+  ADD COLUMN IF NOT EXISTS dirujuk_oleh    INT(11)      NULL DEFAULT NULL, -- id_karyawan dokter
+  ADD COLUMN IF NOT EXISTS dirujuk_at      DATETIME     NULL DEFAULT NULL,
+  ADD KEY IF NOT EXISTS idx_kunjungan_poli_antrian (id_antrian);
+
+-- Baris lama: antrian pendaftaran diambil dari Riwayat_Kunjungan.
+UPDATE Kunjungan_Poli kp
+JOIN Riwayat_Kunjungan rk ON rk.id_kunjungan = kp.id_kunjungan
+SET kp.id_antrian = rk.id_antrian
+WHERE kp.id_antrian IS NULL AND kp.id_antrian_asal IS NULL;
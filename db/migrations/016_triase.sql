@@ -0,0 +1,37 @@
+-- File: db/migrations/016_triase.sql
+-- Kategori triase (darurat, ibu hamil, disabilitas, lansia, anak) menentukan urutan panggilan.
+-- bobot = prioritas dasar (makin besar makin didahulukan), maks_tunggu_menit = target tunggu
+-- maksimal. Setiap kali target tunggu terlampaui, skor antrian bertambah sehingga pasien
+-- berprioritas rendah (termasuk tanpa triase) tidak tertahan terus di belakang.
+
+CREATE TABLE IF NOT EXISTS Kategori_Triase (
+  id_triase         INT(11)      NOT NULL AUTO_INCREMENT,
+  kode              VARCHAR(20)  NOT NULL,
+  nama              VARCHAR(100) NOT NULL,
+  bobot             INT(11)      NOT NULL DEFAULT 0,
+  maks_tunggu_menit INT(11)      NOT NULL,
+  aktif             TINYINT(1)   NOT NULL DEFAULT 1,
+  created_at        DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP(),
+  updated_at        DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP() ON UPDATE CURRENT_TIMESTAMP(),
+  PRIMARY KEY (id_triase),
+  UNIQUE KEY uq_triase_kode (kode),
+  CONSTRAINT chk_triase_bobot CHECK (bobot >= 0),
+  CONSTRAINT chk_triase_maks_tunggu CHECK (maks_tunggu_menit > 0)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+INSERT IGNORE INTO Kategori_Triase (kode, nama, bobot, maks_tunggu_menit) VALUES
+  ('DARURAT',     'Darurat',     1000,  5),
+  ('HAMIL',       'Ibu Hamil',    150, 30),
+  ('DISABILITAS', 'Disabilitas',  150, 30),
+  ('LANSIA',      'Lansia',       100, 45),
+  ('ANAK',        'Anak',          80, 60);
+
+-- Bobot & target tunggu disalin ke Antrian saat triase ditetapkan, sehingga perubahan master
+-- tidak mengubah antrian yang sudah berjalan dan ClaimNext tidak perlu JOIN saat mengunci.
+ALTER TABLE Antrian
+  ADD COLUMN IF NOT EXISTS id_triase          INT(11)  NULL DEFAULT NULL,
+  ADD COLUMN IF NOT EXISTS bobot_triase       INT(11)  NULL DEFAULT NULL,
+  ADD COLUMN IF NOT EXISTS maks_tunggu_triase INT(11)  NULL DEFAULT NULL, -- menit
+  ADD COLUMN IF NOT EXISTS triase_oleh        INT(11)  NULL DEFAULT NULL, -- id_karyawan
+  ADD COLUMN IF NOT EXISTS triase_at          DATETIME NULL DEFAULT NULL,
+  ADD KEY IF NOT EXISTS idx_antrian_triase (id_triase);
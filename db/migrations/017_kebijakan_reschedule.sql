@@ -0,0 +1,43 @@
+-- File: db/migrations/017_kebijakan_reschedule.sql
+-- Kebijakan reschedule per poli untuk pasien yang ditunda (Ditunda -> Menunggu):
+--   Sisip : disisipkan setelah sisip_setelah pasien yang sedang menunggu (0 = paling depan)
+--   Akhir : ditaruh di akhir antrian Menunggu saat itu
+--   Jam   : tetap Ditunda sampai jam_kembali hari ini, lalu otomatis masuk antrian paling depan
+-- maks_tunda membatasi berapa kali satu antrian boleh ditunda (NULL = tanpa batas).
+-- Poli tanpa baris di tabel ini memakai default: Sisip setelah 2 pasien, maks 3 kali tunda.
+
+CREATE TABLE IF NOT EXISTS Kebijakan_Reschedule (
+  id_poli       INT(11)  NOT NULL,
+  mode          ENUM('Sisip','Akhir','Jam') NOT NULL DEFAULT 'Sisip',
+  sisip_setelah INT(11)  NOT NULL DEFAULT 2,
+  jam_kembali   TIME     DEFAULT NULL,
+  maks_tunda    INT(11)  DEFAULT 3,
+  updated_by    INT(11)  DEFAULT NULL, -- id_management
+  updated_at    DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP() ON UPDATE CURRENT_TIMESTAMP(),
+  PRIMARY KEY (id_poli),
+  CONSTRAINT fk_kebijakan_reschedule_poli FOREIGN KEY (id_poli) REFERENCES Poliklinik (id_poli),
+  CONSTRAINT chk_kebijakan_reschedule_sisip CHECK (sisip_setelah >= 0),
+  CONSTRAINT chk_kebijakan_reschedule_maks CHECK (maks_tunda IS NULL OR maks_tunda >= 0),
+  CONSTRAINT chk_kebijakan_reschedule_jam CHECK (mode <> 'Jam' OR jam_kembali IS NOT NULL)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+-- jumlah_tunda dinaikkan setiap kali antrian ditunda; kembali_at diisi untuk mode Jam
+-- (antrian Ditunda yang sudah check-in ulang dan menunggu jam kembali). menunggu_sejak adalah
+-- waktu antrian kembali Menunggu setelah reschedule; lama tunggu untuk aturan keadilan triase
+-- dihitung dari sini (NULL = created_at), agar pasien yang ditunda tidak langsung melompat ke depan.
+ALTER TABLE Antrian
+  ADD COLUMN IF NOT EXISTS jumlah_tunda   INT(11)  NOT NULL DEFAULT 0,
+  ADD COLUMN IF NOT EXISTS kembali_at     DATETIME NULL DEFAULT NULL,
+  ADD COLUMN IF NOT EXISTS menunggu_sejak DATETIME NULL DEFAULT NULL,
+  ADD KEY IF NOT EXISTS idx_antrian_kembali (id_status, kembali_at);
+
+-- Antrian lama: hitung dari Antrian_Log (id 2 = Ditunda, dijaga migrasi 004).
+UPDATE Antrian a
+JOIN (
+  SELECT id_antrian, COUNT(*) AS n
+  FROM Antrian_Log
+  WHERE status_ke = 2
+  GROUP BY id_antrian
+) l ON l.id_antrian = a.id_antrian
+SET a.jumlah_tunda = l.n
+WHERE a.jumlah_tunda = 0;
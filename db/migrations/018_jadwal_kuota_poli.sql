@@ -0,0 +1,71 @@
+-- File: db/migrations/018_jadwal_kuota_poli.sql
+-- Jam operasional, hari libur, dan kuota pendaftaran harian per poli.
+-- Pendaftaran (walk-in) hanya diterima pada sesi Jadwal_Poli hari itu, bukan pada Libur_Poli,
+-- dan selama kuota harian / kuota sesi belum penuh. Poli tanpa baris Jadwal_Poli dianggap buka
+-- sepanjang hari (perilaku lama). Check-in janji temu dan rujukan internal tidak dihitung ke
+-- kuota ini (janji temu punya kuota sendiri).
+
+-- Kuota pendaftaran per hari (NULL = tanpa batas).
+ALTER TABLE Poliklinik
+  ADD COLUMN IF NOT EXISTS kuota_harian INT(11) DEFAULT NULL;
+
+-- Sesi buka mingguan. hari: 1 = Senin ... 7 = Minggu. Jika id_shift diisi, jam sesi mengikuti
+-- Shift (jam_buka / jam_tutup boleh NULL, atau diisi untuk mengganti jam shift). kuota adalah
+-- kuota pendaftaran sesi tersebut (NULL = hanya dibatasi kuota harian).
+CREATE TABLE IF NOT EXISTS Jadwal_Poli (
+  id_jadwal  INT(11)    NOT NULL AUTO_INCREMENT,
+  id_poli    INT(11)    NOT NULL,
+  hari       TINYINT(1) NOT NULL,
+  id_shift   INT(11)    DEFAULT NULL,
+  jam_buka   TIME       DEFAULT NULL,
+  jam_tutup  TIME       DEFAULT NULL,
+  kuota      INT(11)    DEFAULT NULL,
+  updated_by INT(11)    DEFAULT NULL, -- id_management
+  updated_at DATETIME   NOT NULL DEFAULT CURRENT_TIMESTAMP() ON UPDATE CURRENT_TIMESTAMP(),
+  PRIMARY KEY (id_jadwal),
+  KEY idx_jadwal_poli_hari (id_poli, hari),
+  CONSTRAINT fk_jadwal_poli_poli FOREIGN KEY (id_poli) REFERENCES Poliklinik (id_poli),
+  CONSTRAINT fk_jadwal_poli_shift FOREIGN KEY (id_shift) REFERENCES Shift (id_shift),
+  CONSTRAINT chk_jadwal_poli_hari CHECK (hari BETWEEN 1 AND 7),
+  CONSTRAINT chk_jadwal_poli_jam CHECK (id_shift IS NOT NULL OR (jam_buka IS NOT NULL AND jam_tutup IS NOT NULL)),
+  CONSTRAINT chk_jadwal_poli_kuota CHECK (kuota IS NULL OR kuota >= 0)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+-- Hari libur. id_poli NULL berarti seluruh klinik libur.
+CREATE TABLE IF NOT EXISTS Libur_Poli (
+  id_libur   INT(11)      NOT NULL AUTO_INCREMENT,
+  tanggal    DATE         NOT NULL,
+  id_poli    INT(11)      DEFAULT NULL,
+  keterangan VARCHAR(255) NOT NULL,
+  created_by INT(11)      DEFAULT NULL, -- id_management
+  created_at DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP(),
+  PRIMARY KEY (id_libur),
+  KEY idx_libur_poli_tanggal (tanggal, id_poli),
+  CONSTRAINT fk_libur_poli_poli FOREIGN KEY (id_poli) REFERENCES Poliklinik (id_poli)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+-- Daftar tunggu pasien yang mendaftar saat kuota penuh atau di luar jam operasional.
+-- Petugas mendaftarkannya (menjadi Antrian) ketika kapasitas tersedia; yang belum didaftarkan
+-- sampai penutupan harian ditandai Kedaluwarsa.
+CREATE TABLE IF NOT EXISTS Daftar_Tunggu (
+  id_daftar_tunggu      INT(11)      NOT NULL AUTO_INCREMENT,
+  id_pasien             INT(11)      NOT NULL,
+  id_poli               INT(11)      NOT NULL,
+  tanggal               DATE         NOT NULL,
+  keluhan_utama         VARCHAR(255) DEFAULT NULL,
+  nama_penanggung_jawab VARCHAR(255) DEFAULT NULL,
+  id_penjamin           INT(11)      DEFAULT NULL,
+  nomor_peserta         VARCHAR(50)  DEFAULT NULL,
+  id_triase             INT(11)      DEFAULT NULL,
+  alasan                ENUM('Kuota','Jam') NOT NULL,
+  status                ENUM('Menunggu','Didaftarkan','Dibatalkan','Kedaluwarsa') NOT NULL DEFAULT 'Menunggu',
+  id_antrian            INT(11)      DEFAULT NULL, -- terisi saat didaftarkan
+  created_by            INT(11)      DEFAULT NULL, -- id_karyawan
+  created_at            DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP(),
+  updated_at            DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP() ON UPDATE CURRENT_TIMESTAMP(),
+  PRIMARY KEY (id_daftar_tunggu),
+  KEY idx_daftar_tunggu_poli (id_poli, tanggal, status),
+  CONSTRAINT fk_daftar_tunggu_pasien FOREIGN KEY (id_pasien) REFERENCES Pasien (id_pasien),
+  CONSTRAINT fk_daftar_tunggu_poli FOREIGN KEY (id_poli) REFERENCES Poliklinik (id_poli),
+  CONSTRAINT fk_daftar_tunggu_antrian FOREIGN KEY (id_antrian) REFERENCES Antrian (id_antrian)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
@@ -4,17 +4,20 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/c14220110/poliklinik-backend/config"
 	"github.com/c14220110/poliklinik-backend/internal/administrasi/services"
+	commonServices "github.com/c14220110/poliklinik-backend/internal/common/services"
 	"github.com/c14220110/poliklinik-backend/pkg/utils"
 	"github.com/labstack/echo/v4"
 )
 
 type AdministrasiController struct {
-	Service *services.AdministrasiService
+	Service  *services.AdministrasiService
+	Sessions *commonServices.SessionService
 }
 
-func NewAdministrasiController(service *services.AdministrasiService) *AdministrasiController {
-	return &AdministrasiController{Service: service}
+func NewAdministrasiController(service *services.AdministrasiService, sessions *commonServices.SessionService) *AdministrasiController {
+	return &AdministrasiController{Service: service, Sessions: sessions}
 }
 
 type LoginRequest struct {
@@ -41,18 +44,16 @@ func (ac *AdministrasiController) Login(c echo.Context) error {
 		})
 	}
 
-	// Set expiration token dengan durasi yang panjang untuk administrasi
-	expTime := time.Now().Add(999999 * time.Hour)
-	token, err := utils.GenerateJWTToken(
-		admin.ID_Admin,
-		"Administrasi",
-		admin.ID_Role,
-		admin.Privileges,
-		0, // idPoli tidak berlaku untuk administrasi
-		admin.Username,
-		admin.Nama,
-		expTime,
-	)
+	// Sesi administrasi berlaku selama REFRESH_TOKEN_TTL; access token diperbarui lewat /api/auth/refresh
+	expTime := time.Now().Add(config.LoadConfig().RefreshTokenTTL)
+	tokens, err := ac.Sessions.CreateSession(utils.Claims{
+		IDKaryawan: admin.ID_Admin,
+		Role:       "Administrasi",
+		IDRole:     admin.ID_Role,
+		Privileges: admin.Privileges,
+		Username:   admin.Username,
+		Nama:       admin.Nama,
+	}, expTime)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
 			"status":  http.StatusInternalServerError,
@@ -61,10 +62,11 @@ func (ac *AdministrasiController) Login(c echo.Context) error {
 		})
 	}
 
-	// Kembalikan hanya token saja
+	// data tetap berisi access token saja agar kompatibel dengan frontend lama
 	return c.JSON(http.StatusOK, map[string]interface{}{
-		"status":  http.StatusOK,
-		"message": "Login successful",
-		"data":    token,
+		"status":        http.StatusOK,
+		"message":       "Login successful",
+		"data":          tokens.AccessToken,
+		"refresh_token": tokens.RefreshToken,
 	})
 }
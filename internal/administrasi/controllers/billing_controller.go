@@ -86,6 +86,13 @@ func (bc *BillingController) InputBillingAssessment(c echo.Context) error {
 	if err := bc.Service.SaveBillingAssessment(
 		idAntrian, idAssessment, idKaryawanJWT, req); err != nil {
 
+		if errors.Is(err, services.ErrPersetujuanBelumAda) {
+			return c.JSON(http.StatusConflict, echo.Map{
+				"status":  http.StatusConflict,
+				"message": err.Error(),
+				"data":    nil,
+			})
+		}
 		return c.JSON(http.StatusInternalServerError, echo.Map{
 			"status":  http.StatusInternalServerError,
 			"message": "Failed to save billing: " + err.Error(),
@@ -196,6 +203,13 @@ func (bc *BillingController) BayarTagihan(c echo.Context) error {
 
 	// --- service call ---
 	result, err := bc.Service.BayarTagihan(idKunjungan, req.TipePembayaran)
+	if errors.Is(err, services.ErrRujukanBelumSelesai) {
+			return c.JSON(http.StatusConflict, map[string]interface{}{
+					"status":  http.StatusConflict,
+					"message": err.Error(),
+					"data":    nil,
+			})
+	}
 	if err != nil {
 			return c.JSON(http.StatusInternalServerError, map[string]interface{}{
 					"status":  http.StatusInternalServerError,
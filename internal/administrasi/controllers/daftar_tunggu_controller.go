@@ -0,0 +1,170 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/c14220110/poliklinik-backend/internal/administrasi/services"
+	"github.com/c14220110/poliklinik-backend/internal/common/antrian"
+	"github.com/c14220110/poliklinik-backend/internal/common/middlewares"
+	"github.com/c14220110/poliklinik-backend/pkg/utils"
+	"github.com/c14220110/poliklinik-backend/ws"
+	"github.com/labstack/echo/v4"
+)
+
+type DaftarTungguController struct {
+	Service *services.DaftarTungguService
+}
+
+func NewDaftarTungguController(service *services.DaftarTungguService) *DaftarTungguController {
+	return &DaftarTungguController{Service: service}
+}
+
+// ListDaftarTungguHandler mengembalikan daftar tunggu pendaftaran.
+// GET /api/administrasi/daftar-tunggu?tanggal=YYYY-MM-DD&id_poli={id}&status=Menunggu
+// tanggal kosong berarti hari ini.
+func (dc *DaftarTungguController) ListDaftarTungguHandler(c echo.Context) error {
+	tanggal := c.QueryParam("tanggal")
+	if tanggal != "" {
+		if _, err := time.Parse("2006-01-02", tanggal); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"status":  http.StatusBadRequest,
+				"message": "format tanggal tidak valid, gunakan YYYY-MM-DD",
+				"data":    nil,
+			})
+		}
+	}
+	idPoli := 0
+	if s := c.QueryParam("id_poli"); s != "" {
+		v, err := strconv.Atoi(s)
+		if err != nil || v <= 0 {
+			return c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"status":  http.StatusBadRequest,
+				"message": "id_poli harus berupa angka",
+				"data":    nil,
+			})
+		}
+		idPoli = v
+	}
+
+	list, err := dc.Service.ListDaftarTunggu(tanggal, idPoli, c.QueryParam("status"))
+	if err != nil {
+		return daftarTungguError(c, err, "Gagal mengambil daftar tunggu")
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status":  http.StatusOK,
+		"message": "Daftar tunggu berhasil diambil",
+		"data":    list,
+	})
+}
+
+// DaftarkanHandler memasukkan pasien daftar tunggu ke antrian jika kapasitas poli tersedia.
+// PUT /api/administrasi/daftar-tunggu/proses?id_daftar_tunggu={id}
+func (dc *DaftarTungguController) DaftarkanHandler(c echo.Context) error {
+	idDaftarTunggu, err := strconv.Atoi(c.QueryParam("id_daftar_tunggu"))
+	if err != nil || idDaftarTunggu <= 0 {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"status":  http.StatusBadRequest,
+			"message": "id_daftar_tunggu harus berupa angka",
+			"data":    nil,
+		})
+	}
+
+	claims, _ := c.Get(string(middlewares.ContextKeyClaims)).(*utils.Claims)
+	hasil, err := dc.Service.Daftarkan(idDaftarTunggu, antrian.ActorFromClaims(claims))
+	if err != nil {
+		if status, ok := penjaminErrorStatus(err); ok {
+			return c.JSON(status, map[string]interface{}{
+				"status":  status,
+				"message": err.Error(),
+				"data":    nil,
+			})
+		}
+		if status, ok := triaseErrorStatus(err); ok {
+			return c.JSON(status, map[string]interface{}{
+				"status":  status,
+				"message": err.Error(),
+				"data":    nil,
+			})
+		}
+		return daftarTungguError(c, err, "Gagal mendaftarkan pasien dari daftar tunggu")
+	}
+
+	// Sama seperti pendaftaran biasa: beri tahu kasir ada billing baru.
+	billingMsg, _ := json.Marshal(map[string]interface{}{
+		"type": "billing_update",
+		"data": map[string]interface{}{
+			"id_kunjungan": hasil.IDKunjungan,
+			"id_pasien":    hasil.IDPasien,
+			"id_rm":        hasil.IDRM,
+			"nama_poli":    hasil.NamaPoli,
+			"status":       "Belum",
+		},
+	})
+	ws.HubInstance.Publish(billingMsg, ws.TopicRoleAdministrasi)
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status":  http.StatusOK,
+		"message": "Pasien daftar tunggu berhasil didaftarkan",
+		"data":    hasil,
+	})
+}
+
+// BatalkanDaftarTungguHandler mengeluarkan pasien dari daftar tunggu.
+// PUT /api/administrasi/daftar-tunggu/batal?id_daftar_tunggu={id}
+func (dc *DaftarTungguController) BatalkanDaftarTungguHandler(c echo.Context) error {
+	idDaftarTunggu, err := strconv.Atoi(c.QueryParam("id_daftar_tunggu"))
+	if err != nil || idDaftarTunggu <= 0 {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"status":  http.StatusBadRequest,
+			"message": "id_daftar_tunggu harus berupa angka",
+			"data":    nil,
+		})
+	}
+
+	if err := dc.Service.Batalkan(idDaftarTunggu); err != nil {
+		return daftarTungguError(c, err, "Gagal membatalkan daftar tunggu")
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status":  http.StatusOK,
+		"message": "Daftar tunggu berhasil dibatalkan",
+		"data":    map[string]interface{}{"id_daftar_tunggu": idDaftarTunggu},
+	})
+}
+
+// kapasitasErrorStatus memetakan penolakan pendaftaran karena libur, jam operasional,
+// kuota, atau daftar tunggu ganda.
+func kapasitasErrorStatus(err error) (status int, ok bool) {
+	switch {
+	case errors.Is(err, antrian.ErrPoliLibur), errors.Is(err, antrian.ErrPoliTutup),
+		errors.Is(err, antrian.ErrKuotaPenuh), errors.Is(err, services.ErrDaftarTungguGanda):
+		return http.StatusConflict, true
+	}
+	return 0, false
+}
+
+func daftarTungguError(c echo.Context, err error, fallback string) error {
+	status, ok := kapasitasErrorStatus(err)
+	switch {
+	case ok:
+	case errors.Is(err, services.ErrDaftarTungguTidakDitemukan), strings.Contains(err.Error(), "tidak ditemukan"):
+		status = http.StatusNotFound
+	case errors.Is(err, services.ErrDaftarTungguStatus):
+		status = http.StatusConflict
+	default:
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"status":  http.StatusInternalServerError,
+			"message": fallback,
+			"data":    nil,
+		})
+	}
+	return c.JSON(status, map[string]interface{}{
+		"status":  status,
+		"message": err.Error(),
+		"data":    nil,
+	})
+}
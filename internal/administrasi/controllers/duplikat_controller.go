@@ -0,0 +1,280 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/c14220110/poliklinik-backend/internal/administrasi/models"
+	"github.com/c14220110/poliklinik-backend/internal/administrasi/services"
+	"github.com/c14220110/poliklinik-backend/internal/common/middlewares"
+	"github.com/c14220110/poliklinik-backend/pkg/utils"
+	"github.com/labstack/echo/v4"
+)
+
+type DuplikatController struct {
+	Service *services.DuplikatService
+}
+
+func NewDuplikatController(service *services.DuplikatService) *DuplikatController {
+	return &DuplikatController{Service: service}
+}
+
+// FindDuplicatesHandler mencari pasien lain yang kemungkinan sama dengan id_pasien.
+// GET /api/administrasi/pasien/duplikat?id_pasien={id}&min_skor={0..1}
+func (dc *DuplikatController) FindDuplicatesHandler(c echo.Context) error {
+	idPasien, err := strconv.Atoi(c.QueryParam("id_pasien"))
+	if err != nil || idPasien <= 0 {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"status":  http.StatusBadRequest,
+			"message": "id_pasien harus berupa angka",
+			"data":    nil,
+		})
+	}
+	minSkor, err := services.ParseMinSkor(c.QueryParam("min_skor"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"status":  http.StatusBadRequest,
+			"message": err.Error(),
+			"data":    nil,
+		})
+	}
+
+	pasien, kandidat, err := dc.Service.FindDuplicates(idPasien, minSkor)
+	if err != nil {
+		if strings.Contains(err.Error(), "tidak ditemukan") {
+			return c.JSON(http.StatusNotFound, map[string]interface{}{
+				"status":  http.StatusNotFound,
+				"message": err.Error(),
+				"data":    nil,
+			})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"status":  http.StatusInternalServerError,
+			"message": "Gagal mencari duplikat pasien",
+			"data":    nil,
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status":  http.StatusOK,
+		"message": "Kandidat duplikat berhasil diambil",
+		"data": map[string]interface{}{
+			"pasien":   pasien,
+			"kandidat": kandidat,
+		},
+	})
+}
+
+// FindDuplicatePairsHandler memindai seluruh pasien aktif untuk pasangan yang mirip.
+// GET /api/management/pasien/duplikat?min_skor={0..1}&limit={n}
+func (dc *DuplikatController) FindDuplicatePairsHandler(c echo.Context) error {
+	minSkor, err := services.ParseMinSkor(c.QueryParam("min_skor"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"status":  http.StatusBadRequest,
+			"message": err.Error(),
+			"data":    nil,
+		})
+	}
+	limit := 100
+	if limitStr := c.QueryParam("limit"); limitStr != "" {
+		limit, err = strconv.Atoi(limitStr)
+		if err != nil || limit <= 0 {
+			return c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"status":  http.StatusBadRequest,
+				"message": "limit harus berupa angka positif",
+				"data":    nil,
+			})
+		}
+	}
+
+	list, err := dc.Service.FindDuplicatePairs(minSkor, limit)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"status":  http.StatusInternalServerError,
+			"message": "Gagal mencari pasangan duplikat",
+			"data":    nil,
+		})
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status":  http.StatusOK,
+		"message": "Pasangan duplikat berhasil diambil",
+		"data":    list,
+	})
+}
+
+// AjukanMergeHandler mencatat pengajuan penggabungan pasien untuk disetujui manajemen.
+// POST /api/administrasi/pasien/merge
+func (dc *DuplikatController) AjukanMergeHandler(c echo.Context) error {
+	claims, ok := c.Get(string(middlewares.ContextKeyClaims)).(*utils.Claims)
+	if !ok || claims == nil {
+		return c.JSON(http.StatusUnauthorized, map[string]interface{}{
+			"status":  http.StatusUnauthorized,
+			"message": "Invalid or missing token claims",
+			"data":    nil,
+		})
+	}
+
+	var req models.MergeRequest
+	if err := c.Bind(&req); err != nil || req.IDPasienUtama <= 0 || req.IDPasienDuplikat <= 0 {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"status":  http.StatusBadRequest,
+			"message": "id_pasien_utama dan id_pasien_duplikat wajib diisi",
+			"data":    nil,
+		})
+	}
+
+	idMerge, err := dc.Service.AjukanMerge(req, claims.IDKaryawan)
+	if err != nil {
+		return mergeError(c, err, "Gagal mengajukan merge pasien")
+	}
+	return c.JSON(http.StatusCreated, map[string]interface{}{
+		"status":  http.StatusCreated,
+		"message": "Pengajuan merge pasien berhasil dibuat, menunggu persetujuan manajemen",
+		"data": map[string]interface{}{
+			"id_merge": idMerge,
+		},
+	})
+}
+
+// GetMergeListHandler menampilkan riwayat pengajuan merge.
+// GET /api/management/pasien/merge?status={Diajukan|Digabung|Ditolak|Dibatalkan}
+func (dc *DuplikatController) GetMergeListHandler(c echo.Context) error {
+	status := c.QueryParam("status")
+	switch status {
+	case "", services.MergeDiajukan, services.MergeDigabung, services.MergeDitolak, services.MergeDibatalkan:
+	default:
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"status":  http.StatusBadRequest,
+			"message": "status tidak valid",
+			"data":    nil,
+		})
+	}
+
+	list, err := dc.Service.GetMergeList(status)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"status":  http.StatusInternalServerError,
+			"message": "Gagal mengambil daftar merge pasien",
+			"data":    nil,
+		})
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status":  http.StatusOK,
+		"message": "Daftar merge pasien berhasil diambil",
+		"data":    list,
+	})
+}
+
+// SetujuiMergeHandler menjalankan penggabungan pasien.
+// PUT /api/management/pasien/merge/setujui?id_merge={id}
+func (dc *DuplikatController) SetujuiMergeHandler(c echo.Context) error {
+	claims, idMerge, done := dc.mergeParams(c)
+	if done != nil {
+		return done
+	}
+
+	moved, err := dc.Service.SetujuiMerge(idMerge, claims.IDKaryawan)
+	if err != nil {
+		return mergeError(c, err, "Gagal menggabungkan data pasien")
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status":  http.StatusOK,
+		"message": "Data pasien berhasil digabung",
+		"data": map[string]interface{}{
+			"id_merge":        idMerge,
+			"jumlah_dipindah": moved,
+		},
+	})
+}
+
+// TolakMergeHandler menolak pengajuan merge.
+// PUT /api/management/pasien/merge/tolak?id_merge={id}&alasan={teks}
+func (dc *DuplikatController) TolakMergeHandler(c echo.Context) error {
+	claims, idMerge, done := dc.mergeParams(c)
+	if done != nil {
+		return done
+	}
+
+	if err := dc.Service.TolakMerge(idMerge, claims.IDKaryawan, c.QueryParam("alasan")); err != nil {
+		return mergeError(c, err, "Gagal menolak pengajuan merge")
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status":  http.StatusOK,
+		"message": "Pengajuan merge berhasil ditolak",
+		"data":    map[string]interface{}{"id_merge": idMerge},
+	})
+}
+
+// BatalkanMergeHandler mengembalikan data pasien ke kondisi sebelum merge.
+// PUT /api/management/pasien/merge/batalkan?id_merge={id}&alasan={teks}
+func (dc *DuplikatController) BatalkanMergeHandler(c echo.Context) error {
+	claims, idMerge, done := dc.mergeParams(c)
+	if done != nil {
+		return done
+	}
+
+	restored, err := dc.Service.BatalkanMerge(idMerge, claims.IDKaryawan, c.QueryParam("alasan"))
+	if err != nil {
+		return mergeError(c, err, "Gagal membatalkan merge pasien")
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status":  http.StatusOK,
+		"message": "Merge pasien berhasil dibatalkan",
+		"data": map[string]interface{}{
+			"id_merge":            idMerge,
+			"jumlah_dikembalikan": restored,
+		},
+	})
+}
+
+// mergeParams membaca claims dan id_merge; jika gagal, done berisi response yang sudah ditulis.
+func (dc *DuplikatController) mergeParams(c echo.Context) (claims *utils.Claims, idMerge int, done error) {
+	claims, ok := c.Get(string(middlewares.ContextKeyClaims)).(*utils.Claims)
+	if !ok || claims == nil {
+		return nil, 0, c.JSON(http.StatusUnauthorized, map[string]interface{}{
+			"status":  http.StatusUnauthorized,
+			"message": "Invalid or missing token claims",
+			"data":    nil,
+		})
+	}
+	idMerge, err := strconv.Atoi(c.QueryParam("id_merge"))
+	if err != nil || idMerge <= 0 {
+		return nil, 0, c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"status":  http.StatusBadRequest,
+			"message": "id_merge harus berupa angka",
+			"data":    nil,
+		})
+	}
+	return claims, idMerge, nil
+}
+
+func mergeError(c echo.Context, err error, fallback string) error {
+	switch {
+	case errors.Is(err, services.ErrMergeTidakDitemukan), strings.Contains(err.Error(), "tidak ditemukan"):
+		return c.JSON(http.StatusNotFound, map[string]interface{}{
+			"status":  http.StatusNotFound,
+			"message": err.Error(),
+			"data":    nil,
+		})
+	case errors.Is(err, services.ErrMergeStatus), errors.Is(err, services.ErrPasienSudahDigabung):
+		return c.JSON(http.StatusConflict, map[string]interface{}{
+			"status":  http.StatusConflict,
+			"message": err.Error(),
+			"data":    nil,
+		})
+	case strings.Contains(err.Error(), "tidak boleh sama"):
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"status":  http.StatusBadRequest,
+			"message": err.Error(),
+			"data":    nil,
+		})
+	}
+	return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+		"status":  http.StatusInternalServerError,
+		"message": fallback,
+		"data":    nil,
+	})
+}
@@ -0,0 +1,209 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/c14220110/poliklinik-backend/internal/administrasi/models"
+	"github.com/c14220110/poliklinik-backend/internal/administrasi/services"
+	"github.com/c14220110/poliklinik-backend/internal/common/antrian"
+	"github.com/c14220110/poliklinik-backend/internal/common/middlewares"
+	"github.com/c14220110/poliklinik-backend/pkg/utils"
+	"github.com/labstack/echo/v4"
+)
+
+type HapusDataController struct {
+	Service *services.HapusDataService
+}
+
+func NewHapusDataController(service *services.HapusDataService) *HapusDataController {
+	return &HapusDataController{Service: service}
+}
+
+// AjukanPermintaanHandler mencatat permintaan hapus data pasien.
+// POST /api/administrasi/pasien/hapus-data
+// POST /api/management/pasien/hapus-data
+func (hc *HapusDataController) AjukanPermintaanHandler(c echo.Context) error {
+	var req models.PermintaanHapusRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"status":  http.StatusBadRequest,
+			"message": "Invalid request payload: " + err.Error(),
+			"data":    nil,
+		})
+	}
+	if req.IDPasien <= 0 {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"status":  http.StatusBadRequest,
+			"message": "id_pasien wajib diisi",
+			"data":    nil,
+		})
+	}
+
+	claims, _ := c.Get(string(middlewares.ContextKeyClaims)).(*utils.Claims)
+	id, err := hc.Service.AjukanPermintaan(req, antrian.ActorFromClaims(claims))
+	if err != nil {
+		return hapusDataError(c, err, "Gagal mengajukan permintaan hapus data")
+	}
+	return c.JSON(http.StatusCreated, map[string]interface{}{
+		"status":  http.StatusCreated,
+		"message": "Permintaan hapus data berhasil dicatat, menunggu persetujuan manajemen",
+		"data":    map[string]interface{}{"id_permintaan": id},
+	})
+}
+
+// GetPermintaanListHandler menampilkan riwayat permintaan hapus data.
+// GET /api/management/pasien/hapus-data?status={Diajukan|Disetujui|Ditolak|Dilaksanakan|Dipulihkan}
+func (hc *HapusDataController) GetPermintaanListHandler(c echo.Context) error {
+	status := c.QueryParam("status")
+	switch status {
+	case "", services.HapusDiajukan, services.HapusDisetujui, services.HapusDitolak,
+		services.HapusDilaksanakan, services.HapusDipulihkan:
+	default:
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"status":  http.StatusBadRequest,
+			"message": "status tidak valid",
+			"data":    nil,
+		})
+	}
+
+	list, err := hc.Service.GetPermintaanList(status)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"status":  http.StatusInternalServerError,
+			"message": "Gagal mengambil daftar permintaan hapus data",
+			"data":    nil,
+		})
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status":  http.StatusOK,
+		"message": "Daftar permintaan hapus data berhasil diambil",
+		"data":    list,
+	})
+}
+
+// SetujuiPermintaanHandler menyetujui permintaan hapus data.
+// PUT /api/management/pasien/hapus-data/setujui?id_permintaan={id}&catatan={teks}
+func (hc *HapusDataController) SetujuiPermintaanHandler(c echo.Context) error {
+	claims, idPermintaan, done := hc.permintaanParams(c)
+	if done != nil {
+		return done
+	}
+
+	if err := hc.Service.SetujuiPermintaan(idPermintaan, claims.IDKaryawan, c.QueryParam("catatan")); err != nil {
+		return hapusDataError(c, err, "Gagal menyetujui permintaan hapus data")
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status":  http.StatusOK,
+		"message": "Permintaan hapus data disetujui",
+		"data":    map[string]interface{}{"id_permintaan": idPermintaan},
+	})
+}
+
+// TolakPermintaanHandler menolak permintaan hapus data.
+// PUT /api/management/pasien/hapus-data/tolak?id_permintaan={id}&catatan={teks}
+func (hc *HapusDataController) TolakPermintaanHandler(c echo.Context) error {
+	claims, idPermintaan, done := hc.permintaanParams(c)
+	if done != nil {
+		return done
+	}
+
+	if err := hc.Service.TolakPermintaan(idPermintaan, claims.IDKaryawan, c.QueryParam("catatan")); err != nil {
+		return hapusDataError(c, err, "Gagal menolak permintaan hapus data")
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status":  http.StatusOK,
+		"message": "Permintaan hapus data ditolak",
+		"data":    map[string]interface{}{"id_permintaan": idPermintaan},
+	})
+}
+
+// LaksanakanPermintaanHandler menjalankan pseudonimisasi untuk permintaan yang sudah disetujui.
+// PUT /api/management/pasien/hapus-data/laksanakan?id_permintaan={id}
+func (hc *HapusDataController) LaksanakanPermintaanHandler(c echo.Context) error {
+	claims, idPermintaan, done := hc.permintaanParams(c)
+	if done != nil {
+		return done
+	}
+
+	hasil, err := hc.Service.LaksanakanPermintaan(idPermintaan, claims.IDKaryawan)
+	if err != nil {
+		return hapusDataError(c, err, "Gagal melaksanakan permintaan hapus data")
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status":  http.StatusOK,
+		"message": "Data identitas pasien berhasil dipseudonimisasi",
+		"data":    hasil,
+	})
+}
+
+// PulihkanPermintaanHandler mengembalikan data identitas dari vault (legal hold).
+// PUT /api/management/pasien/hapus-data/pulihkan?id_permintaan={id}&alasan={teks}
+func (hc *HapusDataController) PulihkanPermintaanHandler(c echo.Context) error {
+	claims, idPermintaan, done := hc.permintaanParams(c)
+	if done != nil {
+		return done
+	}
+
+	hasil, err := hc.Service.PulihkanPermintaan(idPermintaan, claims.IDKaryawan, c.QueryParam("alasan"))
+	if err != nil {
+		return hapusDataError(c, err, "Gagal memulihkan data pasien")
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status":  http.StatusOK,
+		"message": "Data identitas pasien berhasil dipulihkan dari vault",
+		"data":    hasil,
+	})
+}
+
+// permintaanParams membaca claims dan id_permintaan; jika gagal, done berisi response yang sudah ditulis.
+func (hc *HapusDataController) permintaanParams(c echo.Context) (claims *utils.Claims, idPermintaan int, done error) {
+	claims, ok := c.Get(string(middlewares.ContextKeyClaims)).(*utils.Claims)
+	if !ok || claims == nil {
+		return nil, 0, c.JSON(http.StatusUnauthorized, map[string]interface{}{
+			"status":  http.StatusUnauthorized,
+			"message": "Invalid or missing token claims",
+			"data":    nil,
+		})
+	}
+	idPermintaan, err := strconv.Atoi(c.QueryParam("id_permintaan"))
+	if err != nil || idPermintaan <= 0 {
+		return nil, 0, c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"status":  http.StatusBadRequest,
+			"message": "id_permintaan harus berupa angka",
+			"data":    nil,
+		})
+	}
+	return claims, idPermintaan, nil
+}
+
+func hapusDataError(c echo.Context, err error, fallback string) error {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, services.ErrPermintaanHapusTidakDitemukan), strings.Contains(err.Error(), "tidak ditemukan"):
+		status = http.StatusNotFound
+	case errors.Is(err, services.ErrPermintaanHapusStatus), errors.Is(err, services.ErrPasienSudahDigabung),
+		errors.Is(err, services.ErrNIKDipakai):
+		status = http.StatusConflict
+	case errors.Is(err, services.ErrPermintaanHapusTidakValid):
+		status = http.StatusBadRequest
+	case errors.Is(err, utils.ErrVaultKey), errors.Is(err, utils.ErrVaultRusak):
+		// Konfigurasi server, bukan kesalahan pengguna; pesan tetap ditampilkan agar bisa ditindaklanjuti.
+		return c.JSON(http.StatusServiceUnavailable, map[string]interface{}{
+			"status":  http.StatusServiceUnavailable,
+			"message": err.Error(),
+			"data":    nil,
+		})
+	}
+	message := err.Error()
+	if status == http.StatusInternalServerError {
+		message = fallback
+	}
+	return c.JSON(status, map[string]interface{}{
+		"status":  status,
+		"message": message,
+		"data":    nil,
+	})
+}
@@ -0,0 +1,103 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/c14220110/poliklinik-backend/internal/administrasi/services"
+	"github.com/c14220110/poliklinik-backend/internal/common/antrian"
+	"github.com/c14220110/poliklinik-backend/internal/common/middlewares"
+	"github.com/c14220110/poliklinik-backend/pkg/utils"
+	"github.com/labstack/echo/v4"
+)
+
+// maksUkuranImportPasien membatasi ukuran file CSV yang diunggah (10 MiB).
+const maksUkuranImportPasien = 10 << 20
+
+type ImportPasienController struct {
+	Service *services.ImportPasienService
+}
+
+func NewImportPasienController(service *services.ImportPasienService) *ImportPasienController {
+	return &ImportPasienController{Service: service}
+}
+
+// ImportPasienHandler mengimpor pasien dari file CSV (multipart, field "file").
+// Default-nya dry-run: hanya mengembalikan laporan validasi per baris. Kirim dry_run=false
+// untuk menyimpan; penyimpanan hanya dilakukan jika semua baris valid.
+// POST /api/management/pasien/import?dry_run={true|false}
+func (ic *ImportPasienController) ImportPasienHandler(c echo.Context) error {
+	dryRun := true
+	if raw := c.QueryParam("dry_run"); raw != "" {
+		var err error
+		if dryRun, err = strconv.ParseBool(raw); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"status":  http.StatusBadRequest,
+				"message": "dry_run harus true atau false",
+				"data":    nil,
+			})
+		}
+	}
+
+	file, err := c.FormFile("file")
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"status":  http.StatusBadRequest,
+			"message": "file CSV wajib diunggah",
+			"data":    nil,
+		})
+	}
+	if file.Size > maksUkuranImportPasien {
+		return c.JSON(http.StatusRequestEntityTooLarge, map[string]interface{}{
+			"status":  http.StatusRequestEntityTooLarge,
+			"message": "ukuran file CSV maksimal 10 MiB",
+			"data":    nil,
+		})
+	}
+	src, err := file.Open()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"status":  http.StatusInternalServerError,
+			"message": "Gagal membuka file",
+			"data":    nil,
+		})
+	}
+	defer src.Close()
+
+	claims, _ := c.Get(string(middlewares.ContextKeyClaims)).(*utils.Claims)
+	laporan, err := ic.Service.Import(src, dryRun, antrian.ActorFromClaims(claims))
+	switch {
+	case errors.Is(err, services.ErrImportDitolak):
+		return c.JSON(http.StatusUnprocessableEntity, map[string]interface{}{
+			"status":  http.StatusUnprocessableEntity,
+			"message": err.Error(),
+			"data":    laporan,
+		})
+	case errors.Is(err, services.ErrImportTidakValid):
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"status":  http.StatusBadRequest,
+			"message": err.Error(),
+			"data":    nil,
+		})
+	case err != nil:
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"status":  http.StatusInternalServerError,
+			"message": "Gagal mengimpor pasien",
+			"data":    nil,
+		})
+	}
+
+	if dryRun {
+		return c.JSON(http.StatusOK, map[string]interface{}{
+			"status":  http.StatusOK,
+			"message": "Validasi import selesai (dry-run, tidak ada data yang disimpan)",
+			"data":    laporan,
+		})
+	}
+	return c.JSON(http.StatusCreated, map[string]interface{}{
+		"status":  http.StatusCreated,
+		"message": "Import pasien berhasil",
+		"data":    laporan,
+	})
+}
@@ -0,0 +1,266 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/c14220110/poliklinik-backend/internal/administrasi/models"
+	"github.com/c14220110/poliklinik-backend/internal/administrasi/services"
+	"github.com/c14220110/poliklinik-backend/internal/common/antrian"
+	"github.com/c14220110/poliklinik-backend/internal/common/middlewares"
+	"github.com/c14220110/poliklinik-backend/pkg/utils"
+	"github.com/labstack/echo/v4"
+)
+
+type JanjiTemuController struct {
+	Service *services.JanjiTemuService
+}
+
+func NewJanjiTemuController(service *services.JanjiTemuService) *JanjiTemuController {
+	return &JanjiTemuController{Service: service}
+}
+
+// BuatJanjiTemuHandler memesan janji temu untuk tanggal mendatang.
+// POST /api/administrasi/janji-temu
+func (jc *JanjiTemuController) BuatJanjiTemuHandler(c echo.Context) error {
+	claims, ok := c.Get(string(middlewares.ContextKeyClaims)).(*utils.Claims)
+	if !ok || claims == nil {
+		return c.JSON(http.StatusUnauthorized, map[string]interface{}{
+			"status":  http.StatusUnauthorized,
+			"message": "Invalid or missing token claims",
+			"data":    nil,
+		})
+	}
+
+	var req models.JanjiTemuRequest
+	if err := c.Bind(&req); err != nil || req.IDPasien <= 0 || req.IDPoli <= 0 || req.Tanggal == "" {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"status":  http.StatusBadRequest,
+			"message": "id_pasien, id_poli, dan tanggal wajib diisi",
+			"data":    nil,
+		})
+	}
+
+	janji, err := jc.Service.BuatJanjiTemu(req, claims.IDKaryawan)
+	if err != nil {
+		return janjiTemuError(c, err, "Gagal memesan janji temu")
+	}
+	return c.JSON(http.StatusCreated, map[string]interface{}{
+		"status":  http.StatusCreated,
+		"message": "Janji temu berhasil dipesan",
+		"data":    janji,
+	})
+}
+
+// ListJanjiTemuHandler menampilkan janji temu.
+// GET /api/administrasi/janji-temu?tanggal=&id_poli=&id_pasien=&status=
+func (jc *JanjiTemuController) ListJanjiTemuHandler(c echo.Context) error {
+	tanggal := c.QueryParam("tanggal")
+	if tanggal != "" {
+		if _, err := time.Parse("2006-01-02", tanggal); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"status":  http.StatusBadRequest,
+				"message": "format tanggal tidak valid, gunakan YYYY-MM-DD",
+				"data":    nil,
+			})
+		}
+	}
+	idPoli, _ := strconv.Atoi(c.QueryParam("id_poli"))
+	idPasien, _ := strconv.Atoi(c.QueryParam("id_pasien"))
+	if tanggal == "" && idPasien <= 0 {
+		tanggal = time.Now().Format("2006-01-02")
+	}
+	status := c.QueryParam("status")
+	switch status {
+	case "", services.JanjiTemuDipesan, services.JanjiTemuHadir, services.JanjiTemuDibatalkan, services.JanjiTemuKedaluwarsa:
+	default:
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"status":  http.StatusBadRequest,
+			"message": "status tidak valid",
+			"data":    nil,
+		})
+	}
+
+	list, err := jc.Service.ListJanjiTemu(tanggal, idPoli, idPasien, status)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"status":  http.StatusInternalServerError,
+			"message": "Gagal mengambil janji temu",
+			"data":    nil,
+		})
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status":  http.StatusOK,
+		"message": "Janji temu berhasil diambil",
+		"data":    list,
+	})
+}
+
+// GetKuotaHandler menampilkan sisa kuota janji temu.
+// GET /api/administrasi/janji-temu/kuota?id_poli={id}&tanggal={YYYY-MM-DD}
+func (jc *JanjiTemuController) GetKuotaHandler(c echo.Context) error {
+	idPoli, err := strconv.Atoi(c.QueryParam("id_poli"))
+	if err != nil || idPoli <= 0 {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"status":  http.StatusBadRequest,
+			"message": "id_poli harus berupa angka",
+			"data":    nil,
+		})
+	}
+	tanggal := c.QueryParam("tanggal")
+	if _, err := time.Parse("2006-01-02", tanggal); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"status":  http.StatusBadRequest,
+			"message": "tanggal wajib diisi dengan format YYYY-MM-DD",
+			"data":    nil,
+		})
+	}
+
+	kuota, err := jc.Service.GetKuota(idPoli, tanggal)
+	if err != nil {
+		return janjiTemuError(c, err, "Gagal mengambil kuota janji temu")
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status":  http.StatusOK,
+		"message": "Kuota janji temu berhasil diambil",
+		"data":    kuota,
+	})
+}
+
+type SetKuotaJanjiTemuRequest struct {
+	IDPoli  int    `json:"id_poli"`
+	Tanggal string `json:"tanggal"` // kosong = kuota default poli
+	Kuota   int    `json:"kuota"`
+}
+
+// SetKuotaHandler mengatur kuota janji temu default poli atau untuk tanggal tertentu.
+// PUT /api/management/janji-temu/kuota
+func (jc *JanjiTemuController) SetKuotaHandler(c echo.Context) error {
+	claims, ok := c.Get(string(middlewares.ContextKeyClaims)).(*utils.Claims)
+	if !ok || claims == nil {
+		return c.JSON(http.StatusUnauthorized, map[string]interface{}{
+			"status":  http.StatusUnauthorized,
+			"message": "Invalid or missing token claims",
+			"data":    nil,
+		})
+	}
+
+	var req SetKuotaJanjiTemuRequest
+	if err := c.Bind(&req); err != nil || req.IDPoli <= 0 || req.Kuota < 0 {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"status":  http.StatusBadRequest,
+			"message": "id_poli dan kuota (>= 0) wajib diisi",
+			"data":    nil,
+		})
+	}
+	if req.Tanggal != "" {
+		if _, err := time.Parse("2006-01-02", req.Tanggal); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"status":  http.StatusBadRequest,
+				"message": "format tanggal tidak valid, gunakan YYYY-MM-DD",
+				"data":    nil,
+			})
+		}
+	}
+
+	if err := jc.Service.SetKuota(req.IDPoli, req.Tanggal, req.Kuota, claims.IDKaryawan); err != nil {
+		return janjiTemuError(c, err, "Gagal menyimpan kuota janji temu")
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status":  http.StatusOK,
+		"message": "Kuota janji temu berhasil disimpan",
+		"data":    req,
+	})
+}
+
+// BatalkanJanjiTemuHandler membatalkan janji temu yang belum check-in.
+// PUT /api/administrasi/janji-temu/batalkan?id_janji_temu={id}&alasan={teks}
+func (jc *JanjiTemuController) BatalkanJanjiTemuHandler(c echo.Context) error {
+	idJanjiTemu, err := strconv.Atoi(c.QueryParam("id_janji_temu"))
+	if err != nil || idJanjiTemu <= 0 {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"status":  http.StatusBadRequest,
+			"message": "id_janji_temu harus berupa angka",
+			"data":    nil,
+		})
+	}
+
+	if err := jc.Service.BatalkanJanjiTemu(idJanjiTemu, c.QueryParam("alasan")); err != nil {
+		return janjiTemuError(c, err, "Gagal membatalkan janji temu")
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status":  http.StatusOK,
+		"message": "Janji temu berhasil dibatalkan",
+		"data":    map[string]interface{}{"id_janji_temu": idJanjiTemu},
+	})
+}
+
+// CheckInJanjiTemuHandler mengubah janji temu hari ini menjadi antrian.
+// PUT /api/administrasi/janji-temu/check-in?id_janji_temu={id}&id_penjamin={id}&nomor_peserta={no}
+// id_penjamin dan nomor_peserta opsional (tanpa id_penjamin = pasien umum).
+func (jc *JanjiTemuController) CheckInJanjiTemuHandler(c echo.Context) error {
+	idJanjiTemu, err := strconv.Atoi(c.QueryParam("id_janji_temu"))
+	if err != nil || idJanjiTemu <= 0 {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"status":  http.StatusBadRequest,
+			"message": "id_janji_temu harus berupa angka",
+			"data":    nil,
+		})
+	}
+
+	var penjamin models.PilihanPenjamin
+	if raw := c.QueryParam("id_penjamin"); raw != "" {
+		if penjamin.IDPenjamin, err = strconv.Atoi(raw); err != nil || penjamin.IDPenjamin <= 0 {
+			return c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"status":  http.StatusBadRequest,
+				"message": "id_penjamin harus berupa angka",
+				"data":    nil,
+			})
+		}
+		penjamin.NomorPeserta = c.QueryParam("nomor_peserta")
+	}
+
+	claims, _ := c.Get(string(middlewares.ContextKeyClaims)).(*utils.Claims)
+	hasil, err := jc.Service.CheckIn(idJanjiTemu, antrian.ActorFromClaims(claims), penjamin)
+	if err != nil {
+		if status, ok := penjaminErrorStatus(err); ok {
+			return c.JSON(status, map[string]interface{}{
+				"status":  status,
+				"message": err.Error(),
+				"data":    nil,
+			})
+		}
+		return janjiTemuError(c, err, "Gagal check-in janji temu")
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status":  http.StatusOK,
+		"message": "Check-in janji temu berhasil, pasien masuk antrian",
+		"data":    hasil,
+	})
+}
+
+func janjiTemuError(c echo.Context, err error, fallback string) error {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, services.ErrJanjiTemuTidakDitemukan), strings.Contains(err.Error(), "tidak ditemukan"):
+		status = http.StatusNotFound
+	case errors.Is(err, services.ErrKuotaJanjiTemuPenuh), errors.Is(err, services.ErrJanjiTemuGanda),
+		errors.Is(err, services.ErrJanjiTemuStatus), errors.Is(err, services.ErrJanjiTemuLewatCutoff),
+		errors.Is(err, services.ErrPasienSudahDigabung), errors.Is(err, services.ErrJanjiTemuAntrianAktif):
+		status = http.StatusConflict
+	case errors.Is(err, services.ErrJanjiTemuTanggal), strings.Contains(err.Error(), "tidak boleh negatif"):
+		status = http.StatusBadRequest
+	}
+	message := err.Error()
+	if status == http.StatusInternalServerError {
+		message = fallback
+	}
+	return c.JSON(status, map[string]interface{}{
+		"status":  status,
+		"message": message,
+		"data":    nil,
+	})
+}
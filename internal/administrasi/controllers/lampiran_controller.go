@@ -0,0 +1,246 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/c14220110/poliklinik-backend/config"
+	"github.com/c14220110/poliklinik-backend/internal/administrasi/models"
+	"github.com/c14220110/poliklinik-backend/internal/administrasi/services"
+	"github.com/c14220110/poliklinik-backend/internal/common/antrian"
+	"github.com/c14220110/poliklinik-backend/internal/common/middlewares"
+	"github.com/c14220110/poliklinik-backend/pkg/utils"
+	"github.com/labstack/echo/v4"
+)
+
+type LampiranController struct {
+	Service *services.LampiranService
+}
+
+func NewLampiranController(service *services.LampiranService) *LampiranController {
+	return &LampiranController{Service: service}
+}
+
+// UploadLampiranHandler mengunggah lampiran pasien (multipart form).
+// POST /api/administrasi/lampiran
+// Form: file, id_pasien, id_kunjungan (opsional), jenis, keterangan (opsional), sha256 (opsional)
+func (lc *LampiranController) UploadLampiranHandler(c echo.Context) error {
+	idPasien, err := strconv.Atoi(c.FormValue("id_pasien"))
+	if err != nil || idPasien <= 0 {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"status":  http.StatusBadRequest,
+			"message": "id_pasien harus berupa angka",
+			"data":    nil,
+		})
+	}
+	in := models.LampiranUpload{
+		IDPasien:   idPasien,
+		Jenis:      c.FormValue("jenis"),
+		Keterangan: c.FormValue("keterangan"),
+		SHA256:     c.FormValue("sha256"),
+	}
+	if raw := c.FormValue("id_kunjungan"); raw != "" {
+		idKunjungan, err := strconv.Atoi(raw)
+		if err != nil || idKunjungan <= 0 {
+			return c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"status":  http.StatusBadRequest,
+				"message": "id_kunjungan harus berupa angka",
+				"data":    nil,
+			})
+		}
+		in.IDKunjungan = &idKunjungan
+	}
+
+	file, err := c.FormFile("file")
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"status":  http.StatusBadRequest,
+			"message": "file wajib diunggah",
+			"data":    nil,
+		})
+	}
+	// Cek awal dari header multipart; batas sebenarnya tetap ditegakkan saat file dibaca.
+	if max := config.LoadConfig().LampiranMaxSize; file.Size > max {
+		return c.JSON(http.StatusRequestEntityTooLarge, map[string]interface{}{
+			"status":  http.StatusRequestEntityTooLarge,
+			"message": fmt.Sprintf("ukuran lampiran melebihi batas (maksimal %d byte)", max),
+			"data":    nil,
+		})
+	}
+	src, err := file.Open()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"status":  http.StatusInternalServerError,
+			"message": "Gagal membuka file",
+			"data":    nil,
+		})
+	}
+	defer src.Close()
+	in.NamaFile = file.Filename
+
+	claims, _ := c.Get(string(middlewares.ContextKeyClaims)).(*utils.Claims)
+	lampiran, err := lc.Service.Simpan(in, src, antrian.ActorFromClaims(claims))
+	if err != nil {
+		return lampiranError(c, err, "Gagal mengunggah lampiran")
+	}
+	return c.JSON(http.StatusCreated, map[string]interface{}{
+		"status":  http.StatusCreated,
+		"message": "Lampiran berhasil diunggah",
+		"data":    lampiran,
+	})
+}
+
+// ListLampiranHandler mengembalikan lampiran pasien, opsional dibatasi ke satu kunjungan.
+// GET /api/administrasi/lampiran?id_pasien={id}&id_kunjungan={id}
+// GET /api/dokter/lampiran?id_pasien={id}&id_kunjungan={id}
+func (lc *LampiranController) ListLampiranHandler(c echo.Context) error {
+	idPasien, err := strconv.Atoi(c.QueryParam("id_pasien"))
+	if err != nil || idPasien <= 0 {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"status":  http.StatusBadRequest,
+			"message": "id_pasien harus berupa angka",
+			"data":    nil,
+		})
+	}
+	var idKunjungan int
+	if raw := c.QueryParam("id_kunjungan"); raw != "" {
+		if idKunjungan, err = strconv.Atoi(raw); err != nil || idKunjungan <= 0 {
+			return c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"status":  http.StatusBadRequest,
+				"message": "id_kunjungan harus berupa angka",
+				"data":    nil,
+			})
+		}
+	}
+	claims, _ := c.Get(string(middlewares.ContextKeyClaims)).(*utils.Claims)
+	if err := lc.cekAksesPoli(claims, idPasien); err != nil {
+		return lampiranError(c, err, "Gagal mengambil lampiran")
+	}
+	list, err := lc.Service.List(idPasien, idKunjungan)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"status":  http.StatusInternalServerError,
+			"message": "Gagal mengambil lampiran",
+			"data":    nil,
+		})
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status":  http.StatusOK,
+		"message": "Lampiran berhasil diambil",
+		"data":    list,
+	})
+}
+
+// UnduhLampiranHandler mengirim isi file lampiran. Lampiran tidak disajikan lewat /uploads
+// sehingga hanya bisa diunduh oleh pengguna yang lolos policy route ini (dan, untuk dokter,
+// hanya lampiran pasien yang mengantri di poli token hari ini).
+// GET /api/administrasi/lampiran/unduh?id_lampiran={id}
+// GET /api/dokter/lampiran/unduh?id_lampiran={id}
+func (lc *LampiranController) UnduhLampiranHandler(c echo.Context) error {
+	idLampiran, err := strconv.Atoi(c.QueryParam("id_lampiran"))
+	if err != nil || idLampiran <= 0 {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"status":  http.StatusBadRequest,
+			"message": "id_lampiran harus berupa angka",
+			"data":    nil,
+		})
+	}
+	meta, err := lc.Service.GetByID(idLampiran)
+	if err != nil {
+		return lampiranError(c, err, "Gagal mengunduh lampiran")
+	}
+	claims, _ := c.Get(string(middlewares.ContextKeyClaims)).(*utils.Claims)
+	if err := lc.cekAksesPoli(claims, meta.IDPasien); err != nil {
+		return lampiranError(c, err, "Gagal mengunduh lampiran")
+	}
+	lampiran, data, err := lc.Service.Baca(idLampiran)
+	if err != nil {
+		return lampiranError(c, err, "Gagal mengunduh lampiran")
+	}
+
+	h := c.Response().Header()
+	h.Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", namaFileAman(lampiran.NamaFile)))
+	h.Set("X-Content-Type-Options", "nosniff")
+	h.Set("Cache-Control", "no-store")
+	h.Set("X-Checksum-SHA256", lampiran.SHA256)
+	return c.Blob(http.StatusOK, lampiran.MimeType, data)
+}
+
+// HapusLampiranHandler menghapus (soft delete) lampiran.
+// PUT /api/administrasi/lampiran/hapus?id_lampiran={id}
+func (lc *LampiranController) HapusLampiranHandler(c echo.Context) error {
+	idLampiran, err := strconv.Atoi(c.QueryParam("id_lampiran"))
+	if err != nil || idLampiran <= 0 {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"status":  http.StatusBadRequest,
+			"message": "id_lampiran harus berupa angka",
+			"data":    nil,
+		})
+	}
+
+	claims, _ := c.Get(string(middlewares.ContextKeyClaims)).(*utils.Claims)
+	if err := lc.Service.Hapus(idLampiran, antrian.ActorFromClaims(claims)); err != nil {
+		return lampiranError(c, err, "Gagal menghapus lampiran")
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status":  http.StatusOK,
+		"message": "Lampiran berhasil dihapus",
+		"data":    map[string]interface{}{"id_lampiran": idLampiran},
+	})
+}
+
+// cekAksesPoli membatasi token dokter (terikat ke satu poli) ke pasien yang mengantri di
+// poli tersebut hari ini. Token administrasi tidak dibatasi.
+func (lc *LampiranController) cekAksesPoli(claims *utils.Claims, idPasien int) error {
+	if claims == nil || claims.Role != middlewares.RoleDokter {
+		return nil
+	}
+	if claims.IDPoli == 0 {
+		return services.ErrLampiranLuarPoli
+	}
+	return lc.Service.CekAksesPoli(idPasien, claims.IDPoli)
+}
+
+// namaFileAman membuang karakter yang bisa merusak header Content-Disposition.
+func namaFileAman(nama string) string {
+	nama = strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f || r == '"' || r == '\\' || r > 0x7e {
+			return '_'
+		}
+		return r
+	}, nama)
+	if nama == "" {
+		return "lampiran"
+	}
+	return nama
+}
+
+func lampiranError(c echo.Context, err error, fallback string) error {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, services.ErrLampiranTidakDitemukan), errors.Is(err, services.ErrKunjunganNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, services.ErrLampiranTidakValid):
+		status = http.StatusBadRequest
+	case errors.Is(err, services.ErrLampiranLuarPoli):
+		status = http.StatusForbidden
+	case errors.Is(err, services.ErrLampiranTerlaluBesar):
+		status = http.StatusRequestEntityTooLarge
+	case errors.Is(err, services.ErrLampiranGanda), errors.Is(err, services.ErrPasienSudahDigabung):
+		status = http.StatusConflict
+	case strings.Contains(err.Error(), "tidak ditemukan"):
+		status = http.StatusNotFound
+	}
+	message := err.Error()
+	if status == http.StatusInternalServerError && !errors.Is(err, services.ErrLampiranRusak) {
+		message = fallback
+	}
+	return c.JSON(status, map[string]interface{}{
+		"status":  status,
+		"message": message,
+		"data":    nil,
+	})
+}
@@ -3,13 +3,16 @@ package controllers
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/c14220110/poliklinik-backend/config"
 	"github.com/c14220110/poliklinik-backend/internal/administrasi/models"
 	"github.com/c14220110/poliklinik-backend/internal/administrasi/services"
+	"github.com/c14220110/poliklinik-backend/internal/common/antrian"
 	common "github.com/c14220110/poliklinik-backend/internal/common/middlewares"
 	jwtUtils "github.com/c14220110/poliklinik-backend/pkg/utils"
 	"github.com/c14220110/poliklinik-backend/ws"
@@ -54,9 +57,18 @@ func (pc *PasienController) RegisterPasien(c echo.Context) error {
 			"data":    nil,
 		})
 	}
+	// Validasi struktur NIK + cocokkan dengan tanggal lahir & jenis kelamin
+	peringatanNIK, err := jwtUtils.ValidateNIK(req.Nik, parsedDate, req.JenisKelamin, config.LoadConfig().NIKStrict)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"status":  http.StatusBadRequest,
+			"message": err.Error(),
+			"data":    nil,
+		})
+	}
 	// Ambil operatorID dari JWT
 	claims := c.Get(string(common.ContextKeyClaims)).(*jwtUtils.Claims)
-	operatorID := claims.IDKaryawan
+	actor := antrian.ActorFromClaims(claims)
 
 	// Cari ID agama berdasarkan nama agama
 	var idAgama int
@@ -105,8 +117,8 @@ func (pc *PasienController) RegisterPasien(c echo.Context) error {
 		Pekerjaan:        req.Pekerjaan,
 	}
 // Panggil service
-patientID, idAntrian, nomorAntrian, idRM, idStatus, namaPoli, idKunjungan, err :=
-    pc.Service.RegisterPasienWithKunjungan(p, req.IDPoli, operatorID, req.KeluhanUtama, req.PenanggungJawab)
+hasil, err := pc.Service.RegisterPasienWithKunjungan(p, req.IDPoli, actor, req.KeluhanUtama, req.PenanggungJawab,
+        models.PilihanPenjamin{IDPenjamin: req.IDPenjamin, NomorPeserta: req.NomorPeserta}, req.IDTriase, req.DaftarTunggu)
 if err != nil {
     if err.Error() == "NIK sudah terdaftar" {
         return c.JSON(http.StatusConflict, map[string]interface{}{
@@ -115,6 +127,27 @@ if err != nil {
             "data":    nil,
         })
     }
+    if status, ok := penjaminErrorStatus(err); ok {
+        return c.JSON(status, map[string]interface{}{
+            "status":  status,
+            "message": err.Error(),
+            "data":    nil,
+        })
+    }
+    if status, ok := triaseErrorStatus(err); ok {
+        return c.JSON(status, map[string]interface{}{
+            "status":  status,
+            "message": err.Error(),
+            "data":    nil,
+        })
+    }
+    if status, ok := kapasitasErrorStatus(err); ok {
+        return c.JSON(status, map[string]interface{}{
+            "status":  status,
+            "message": err.Error(),
+            "data":    nil,
+        })
+    }
     return c.JSON(http.StatusInternalServerError, map[string]interface{}{
         "status":  http.StatusInternalServerError,
         "message": "Gagal mendaftarkan pasien: " + err.Error(),
@@ -122,33 +155,27 @@ if err != nil {
     })
 }
 
-// Siapkan payload WS untuk antrian_update
-inner := map[string]interface{}{
-    "id_antrian":     idAntrian,
-    "id_pasien":      patientID,
-    "id_poli":        req.IDPoli,
-    "id_rm":          idRM,
-    "id_status":      idStatus,
-    "nama":           req.Nama,
-    "nama_poli":      namaPoli,
-    "nomor_antrian":  nomorAntrian,
-    "priority_order": nomorAntrian,
-    "status":         "Menunggu",
-}
-wrapper := map[string]interface{}{
-    "type": "antrian_update",
-    "data": inner,
+// Poli penuh / di luar jam: pasien tercatat di daftar tunggu, belum ada antrian & billing
+if hasil.DaftarTunggu != nil {
+    return c.JSON(http.StatusAccepted, map[string]interface{}{
+        "status":  http.StatusAccepted,
+        "message": "Pasien terdaftar dan masuk daftar tunggu " + hasil.NamaPoli + ": " + hasil.DaftarTunggu.Keterangan,
+        "data": map[string]interface{}{
+            "id_pasien":      hasil.IDPasien,
+            "id_rm":          hasil.IDRM,
+            "daftar_tunggu":  hasil.DaftarTunggu,
+            "peringatan_nik": peringatanNIK,
+        },
+    })
 }
-msg, _ := json.Marshal(wrapper)
-ws.HubInstance.Broadcast <- msg
 
 // Siapkan payload WS untuk billing_update
 billingInner := map[string]interface{}{
-    "id_kunjungan": idKunjungan,
-    "id_pasien":    patientID,
+    "id_kunjungan": hasil.IDKunjungan,
+    "id_pasien":    hasil.IDPasien,
     "nama_pasien":  req.Nama,
-    "id_rm":     idRM,
-    "nama_poli":  namaPoli,
+    "id_rm":     hasil.IDRM,
+    "nama_poli":  hasil.NamaPoli,
     "status":       "Belum",
 }
 billingWrapper := map[string]interface{}{
@@ -156,16 +183,18 @@ billingWrapper := map[string]interface{}{
     "data": billingInner,
 }
 billingMsg, _ := json.Marshal(billingWrapper)
-ws.HubInstance.Broadcast <- billingMsg
+ws.HubInstance.Publish(billingMsg, ws.TopicRoleAdministrasi)
 
 // Response API
 return c.JSON(http.StatusOK, map[string]interface{}{
     "status":  http.StatusOK,
     "message": "Pasien berhasil didaftarkan",
     "data": map[string]interface{}{
-        "id_pasien":     patientID,
-        "id_antrian":    idAntrian,
-        "nomor_antrian": nomorAntrian,
+        "id_pasien":     hasil.IDPasien,
+        "id_antrian":    hasil.IDAntrian,
+        "nomor_antrian": hasil.NomorAntrian,
+        "kode_tiket":    hasil.KodeTiket,
+        "peringatan_nik": peringatanNIK,
     },
 })
 }
@@ -196,6 +225,20 @@ func (pc *PasienController) UpdateKunjungan(c echo.Context) error {
 			"data":    nil,
 		})
 	}
+	// Validasi NIK. NIK di sini adalah kunci pasien lama, jadi pada mode lenient NIK lama yang
+	// strukturnya salah tidak memblokir kunjungan, hanya menjadi peringatan.
+	strictNIK := config.LoadConfig().NIKStrict
+	peringatanNIK, err := jwtUtils.ValidateNIK(req.Nik, parsedDate, req.JenisKelamin, strictNIK)
+	if err != nil {
+		if strictNIK || !errors.Is(err, jwtUtils.ErrNIKTidakValid) {
+			return c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"status":  http.StatusBadRequest,
+				"message": err.Error(),
+				"data":    nil,
+			})
+		}
+		peringatanNIK = []string{err.Error()}
+	}
 
 	// Cari id_agama berdasarkan nama agama
 	var idAgama int
@@ -247,10 +290,32 @@ func (pc *PasienController) UpdateKunjungan(c echo.Context) error {
 		Pekerjaan:        req.Pekerjaan,
 	}
 
-// Panggil service dengan penanggung_jawab
-idPasien, idAntrian, nomorAntrian, idRM, idStatus, namaPoli, idKunjungan, err :=
-    pc.Service.UpdatePasienAndRegisterKunjungan(p, req.IDPoli, req.KeluhanUtama, req.PenanggungJawab)
+// Panggil service dengan penanggung_jawab; operator diambil dari JWT
+claims, _ := c.Get(string(common.ContextKeyClaims)).(*jwtUtils.Claims)
+hasil, err := pc.Service.UpdatePasienAndRegisterKunjungan(p, req.IDPoli, req.KeluhanUtama, req.PenanggungJawab, antrian.ActorFromClaims(claims),
+        models.PilihanPenjamin{IDPenjamin: req.IDPenjamin, NomorPeserta: req.NomorPeserta}, req.IDTriase, req.DaftarTunggu)
 if err != nil {
+    if status, ok := penjaminErrorStatus(err); ok {
+        return c.JSON(status, map[string]interface{}{
+            "status":  status,
+            "message": err.Error(),
+            "data":    nil,
+        })
+    }
+    if status, ok := triaseErrorStatus(err); ok {
+        return c.JSON(status, map[string]interface{}{
+            "status":  status,
+            "message": err.Error(),
+            "data":    nil,
+        })
+    }
+    if status, ok := kapasitasErrorStatus(err); ok {
+        return c.JSON(status, map[string]interface{}{
+            "status":  status,
+            "message": err.Error(),
+            "data":    nil,
+        })
+    }
     return c.JSON(http.StatusInternalServerError, map[string]interface{}{
         "status":  http.StatusInternalServerError,
         "message": "Failed to register kunjungan: " + err.Error(),
@@ -258,33 +323,27 @@ if err != nil {
     })
 }
 
-// Broadcast WebSocket untuk antrian_update
-inner := map[string]interface{}{
-    "id_antrian":     idAntrian,
-    "id_pasien":      idPasien,
-    "id_poli":        req.IDPoli,
-    "id_rm":          idRM,
-    "id_status":      idStatus,
-    "nama":           req.Nama,
-    "nama_poli":      namaPoli,
-    "nomor_antrian":  nomorAntrian,
-    "priority_order": nomorAntrian,
-    "status":         "Menunggu",
-}
-wrapper := map[string]interface{}{
-    "type": "antrian_update",
-    "data": inner,
+// Poli penuh / di luar jam: pasien masuk daftar tunggu, belum ada antrian & billing
+if hasil.DaftarTunggu != nil {
+    return c.JSON(http.StatusAccepted, map[string]interface{}{
+        "status":  http.StatusAccepted,
+        "message": "Pasien masuk daftar tunggu " + hasil.NamaPoli + ": " + hasil.DaftarTunggu.Keterangan,
+        "data": map[string]interface{}{
+            "id_pasien":      hasil.IDPasien,
+            "id_rm":          hasil.IDRM,
+            "daftar_tunggu":  hasil.DaftarTunggu,
+            "peringatan_nik": peringatanNIK,
+        },
+    })
 }
-msg, _ := json.Marshal(wrapper)
-ws.HubInstance.Broadcast <- msg
 
 // Broadcast WebSocket untuk billing_update
 billingInner := map[string]interface{}{
-    "id_kunjungan": idKunjungan,
-    "id_pasien":    idPasien,
+    "id_kunjungan": hasil.IDKunjungan,
+    "id_pasien":    hasil.IDPasien,
     "nama_pasien":  req.Nama,
-    "id_rm":     idRM,
-    "nama_poli":  namaPoli,
+    "id_rm":     hasil.IDRM,
+    "nama_poli":  hasil.NamaPoli,
     "status":       "Belum",
 }
 billingWrapper := map[string]interface{}{
@@ -292,16 +351,18 @@ billingWrapper := map[string]interface{}{
     "data": billingInner,
 }
 billingMsg, _ := json.Marshal(billingWrapper)
-ws.HubInstance.Broadcast <- billingMsg
+ws.HubInstance.Publish(billingMsg, ws.TopicRoleAdministrasi)
 
 // Response
 return c.JSON(http.StatusOK, map[string]interface{}{
     "status":  http.StatusOK,
     "message": "Kunjungan registered successfully",
     "data": map[string]interface{}{
-        "id_pasien":     idPasien,
-        "id_antrian":    idAntrian,
-        "nomor_antrian": nomorAntrian,
+        "id_pasien":     hasil.IDPasien,
+        "id_antrian":    hasil.IDAntrian,
+        "nomor_antrian": hasil.NomorAntrian,
+        "kode_tiket":    hasil.KodeTiket,
+        "peringatan_nik": peringatanNIK,
     },
 })
 }
@@ -344,6 +405,59 @@ func (pc *PasienController) GetAllPasienData(c echo.Context) error {
 	})
 }
 
+// SearchPasienHandler mencari pasien dengan ranking berdasarkan NIK, id_rm, no telp, tanggal lahir, dan nama.
+// GET /api/administrasi/pasien/cari?q=&nik=&id_rm=&no_telp=&tanggal_lahir=&nama=&page=&limit=
+func (pc *PasienController) SearchPasienHandler(c echo.Context) error {
+	page, limit := 1, 20
+	if p := c.QueryParam("page"); p != "" {
+		if v, err := strconv.Atoi(p); err == nil && v > 0 {
+			page = v
+		}
+	}
+	if l := c.QueryParam("limit"); l != "" {
+		if v, err := strconv.Atoi(l); err == nil && v > 0 && v <= 100 {
+			limit = v
+		}
+	}
+
+	list, total, err := pc.Service.SearchPasien(models.PencarianPasien{
+		Q:            c.QueryParam("q"),
+		NIK:          c.QueryParam("nik"),
+		IDRM:         c.QueryParam("id_rm"),
+		NoTelp:       c.QueryParam("no_telp"),
+		TanggalLahir: c.QueryParam("tanggal_lahir"),
+		Nama:         c.QueryParam("nama"),
+		Page:         page,
+		Limit:        limit,
+	})
+	if err != nil {
+		if errors.Is(err, services.ErrKriteriaPencarian) {
+			return c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"status":  http.StatusBadRequest,
+				"message": err.Error(),
+				"data":    nil,
+			})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"status":  http.StatusInternalServerError,
+			"message": "Failed to search pasien: " + err.Error(),
+			"data":    nil,
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status":  http.StatusOK,
+		"message": "Data pasien retrieved successfully",
+		"data": map[string]interface{}{
+			"pasien":   list,
+			"total":    total,
+			"page":     page,
+			"limit":    limit,
+			"max_page": (total + limit - 1) / limit,
+		},
+	})
+}
+
 
 
 func (pc *PasienController) TundaPasienHandler(c echo.Context) error {
@@ -364,7 +478,15 @@ func (pc *PasienController) TundaPasienHandler(c echo.Context) error {
         })
     }
 
-    if err := pc.Service.TundaPasien(idAntrian); err != nil {
+    claims, _ := c.Get(string(common.ContextKeyClaims)).(*jwtUtils.Claims)
+    if err := pc.Service.TundaPasien(idAntrian, antrian.ActorFromClaims(claims), c.QueryParam("alasan")); err != nil {
+        if errors.Is(err, antrian.ErrTransisiTidakValid) || errors.Is(err, services.ErrBatasTunda) {
+            return c.JSON(http.StatusConflict, map[string]interface{}{
+                "status":  http.StatusConflict,
+                "message": err.Error(),
+                "data":    nil,
+            })
+        }
         if strings.Contains(err.Error(), "tidak ditemukan") {
             return c.JSON(http.StatusNotFound, map[string]interface{}{
                 "status":  http.StatusNotFound,
@@ -379,26 +501,6 @@ func (pc *PasienController) TundaPasienHandler(c echo.Context) error {
         })
     }
 
-    // Siapkan payload broadcast dengan wrapper "type" & "data"
-    inner := map[string]interface{}{
-        "id_antrian": idAntrian,
-        "status":     "Ditunda",
-    }
-    wrapper := map[string]interface{}{
-        "type": "antrian_update",
-        "data": inner,
-    }
-
-    messageJSON, err := json.Marshal(wrapper)
-    if err != nil {
-        return c.JSON(http.StatusInternalServerError, map[string]interface{}{
-            "status":  http.StatusInternalServerError,
-            "message": "Gagal membuat pesan JSON: " + err.Error(),
-            "data":    nil,
-        })
-    }
-    ws.HubInstance.Broadcast <- messageJSON
-
     return c.JSON(http.StatusOK, map[string]interface{}{
         "status":  http.StatusOK,
         "message": "Pasien berhasil ditunda",
@@ -426,9 +528,10 @@ func (pc *PasienController) RescheduleAntrianHandler(c echo.Context) error {
         })
     }
 
-    newPriority, err := pc.Service.RescheduleAntrianPriority(idAntrian)
+    claims, _ := c.Get(string(common.ContextKeyClaims)).(*jwtUtils.Claims)
+    hasil, err := pc.Service.RescheduleAntrianPriority(idAntrian, antrian.ActorFromClaims(claims), c.QueryParam("alasan"))
     if err != nil {
-        if strings.Contains(err.Error(), "tidak ditemukan") || strings.Contains(err.Error(), "tidak dalam status 'Ditunda'") {
+        if strings.Contains(err.Error(), "tidak ditemukan") || errors.Is(err, antrian.ErrTransisiTidakValid) {
             return c.JSON(http.StatusBadRequest, map[string]interface{}{
                 "status":  http.StatusBadRequest,
                 "message": err.Error(),
@@ -442,35 +545,10 @@ func (pc *PasienController) RescheduleAntrianHandler(c echo.Context) error {
         })
     }
 
-    // Siapkan payload broadcast dengan wrapper "type" & "data"
-    inner := map[string]interface{}{
-        "id_antrian":         idAntrian,
-        "new_priority_order": newPriority,
-        "status":             "Menunggu",
-    }
-    wrapper := map[string]interface{}{
-        "type": "antrian_update",
-        "data": inner,
-    }
-
-    messageJSON, err := json.Marshal(wrapper)
-    if err != nil {
-        return c.JSON(http.StatusInternalServerError, map[string]interface{}{
-            "status":  http.StatusInternalServerError,
-            "message": "Gagal membuat pesan broadcast: " + err.Error(),
-            "data":    nil,
-        })
-    }
-
-    // Kirim pesan ke WebSocket
-    ws.HubInstance.Broadcast <- messageJSON
-
     return c.JSON(http.StatusOK, map[string]interface{}{
         "status":  http.StatusOK,
         "message": "Antrian berhasil di-reschedule",
-        "data": map[string]interface{}{
-            "new_priority_order": newPriority,
-        },
+        "data":    hasil,
     })
 }
 
@@ -531,8 +609,16 @@ func (pc *PasienController) BatalkanAntrianHandler(c echo.Context) error {
 	}
 
 	// 3. Panggil fungsi service untuk membatalkan antrian
-	idKunjungan, err := pc.Service.BatalkanAntrian(idAntrian)
+	claims, _ := c.Get(string(common.ContextKeyClaims)).(*jwtUtils.Claims)
+	idKunjungan, err := pc.Service.BatalkanAntrian(idAntrian, antrian.ActorFromClaims(claims), c.QueryParam("alasan"))
 	if err != nil {
+			if errors.Is(err, antrian.ErrTransisiTidakValid) {
+					return c.JSON(http.StatusConflict, map[string]interface{}{
+							"status":  http.StatusConflict,
+							"message": err.Error(),
+							"data":    nil,
+					})
+			}
 			if strings.Contains(err.Error(), "tidak ditemukan") {
 					return c.JSON(http.StatusNotFound, map[string]interface{}{
 							"status":  http.StatusNotFound,
@@ -547,26 +633,7 @@ func (pc *PasienController) BatalkanAntrianHandler(c echo.Context) error {
 			})
 	}
 
-	// 4. Broadcast pertama: antrian_update
-	inner := map[string]interface{}{
-			"id_antrian": idAntrian,
-			"status":     "Dibatalkan",
-	}
-	wrapper := map[string]interface{}{
-			"type": "antrian_update",
-			"data": inner,
-	}
-	msg, err := json.Marshal(wrapper)
-	if err != nil {
-			return c.JSON(http.StatusInternalServerError, map[string]interface{}{
-					"status":  http.StatusInternalServerError,
-					"message": "Gagal membuat pesan broadcast: " + err.Error(),
-					"data":    nil,
-			})
-	}
-	ws.HubInstance.Broadcast <- msg
-
-	// 5. Broadcast kedua: billing_update
+	// 4. Broadcast billing_update (event antrian_cancelled dikirim oleh service)
 	billingInner := map[string]interface{}{
 			"id_kunjungan": idKunjungan,
 			"status":       "Dibatalkan",
@@ -583,9 +650,9 @@ func (pc *PasienController) BatalkanAntrianHandler(c echo.Context) error {
 					"data":    nil,
 			})
 	}
-	ws.HubInstance.Broadcast <- billingMsg
+	ws.HubInstance.Publish(billingMsg, ws.TopicRoleAdministrasi)
 
-	// 6. Respons sukses
+	// 5. Respons sukses
 	return c.JSON(http.StatusOK, map[string]interface{}{
 			"status":  http.StatusOK,
 			"message": "Antrian berhasil dibatalkan",
@@ -643,4 +710,31 @@ func (pc *PasienController) GetAgamaList(c echo.Context) error {
 		"message": "Agama list retrieved successfully",
 		"data":    agamaList,
 	})
-}
\ No newline at end of file
+}
+// GetAntrianLogHandler mengembalikan riwayat perubahan status sebuah antrian.
+// GET /api/administrasi/antrian/log?id_antrian={id}
+func (pc *PasienController) GetAntrianLogHandler(c echo.Context) error {
+	idAntrian, err := strconv.Atoi(c.QueryParam("id_antrian"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"status":  http.StatusBadRequest,
+			"message": "id_antrian harus berupa angka",
+			"data":    nil,
+		})
+	}
+
+	list, err := pc.Service.GetAntrianLog(idAntrian)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"status":  http.StatusInternalServerError,
+			"message": "Gagal mengambil riwayat antrian: " + err.Error(),
+			"data":    nil,
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status":  http.StatusOK,
+		"message": "Riwayat antrian retrieved successfully",
+		"data":    list,
+	})
+}
@@ -0,0 +1,149 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/c14220110/poliklinik-backend/internal/administrasi/services"
+	"github.com/c14220110/poliklinik-backend/internal/common/antrian"
+	"github.com/c14220110/poliklinik-backend/internal/common/middlewares"
+	"github.com/c14220110/poliklinik-backend/pkg/utils"
+	"github.com/labstack/echo/v4"
+)
+
+type PasienVersiController struct {
+	Service *services.PasienVersiService
+}
+
+func NewPasienVersiController(service *services.PasienVersiService) *PasienVersiController {
+	return &PasienVersiController{Service: service}
+}
+
+// GetVersiListHandler mengembalikan riwayat versi data demografis pasien, terbaru lebih dulu.
+// GET /api/administrasi/pasien/versi?id_pasien={id}
+// GET /api/management/pasien/versi?id_pasien={id}
+func (vc *PasienVersiController) GetVersiListHandler(c echo.Context) error {
+	idPasien, err := strconv.Atoi(c.QueryParam("id_pasien"))
+	if err != nil || idPasien <= 0 {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"status":  http.StatusBadRequest,
+			"message": "id_pasien harus berupa angka",
+			"data":    nil,
+		})
+	}
+
+	list, err := vc.Service.GetVersiList(idPasien)
+	if err != nil {
+		return versiError(c, err, "Gagal mengambil riwayat versi pasien")
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status":  http.StatusOK,
+		"message": "Riwayat versi pasien berhasil diambil",
+		"data":    list,
+	})
+}
+
+// GetDiffHandler menampilkan kolom yang berubah di antara dua versi.
+// Tanpa ke = versi terbaru; tanpa dari = versi tepat sebelum ke.
+// GET /api/management/pasien/versi/diff?id_pasien={id}&dari={versi}&ke={versi}
+func (vc *PasienVersiController) GetDiffHandler(c echo.Context) error {
+	idPasien, err := strconv.Atoi(c.QueryParam("id_pasien"))
+	if err != nil || idPasien <= 0 {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"status":  http.StatusBadRequest,
+			"message": "id_pasien harus berupa angka",
+			"data":    nil,
+		})
+	}
+	var dari, ke int
+	for nama, dst := range map[string]*int{"dari": &dari, "ke": &ke} {
+		raw := c.QueryParam(nama)
+		if raw == "" {
+			continue
+		}
+		if *dst, err = strconv.Atoi(raw); err != nil || *dst <= 0 {
+			return c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"status":  http.StatusBadRequest,
+				"message": nama + " harus berupa nomor versi",
+				"data":    nil,
+			})
+		}
+	}
+
+	diff, err := vc.Service.GetDiff(idPasien, dari, ke)
+	if err != nil {
+		return versiError(c, err, "Gagal membandingkan versi pasien")
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status":  http.StatusOK,
+		"message": "Perbandingan versi pasien berhasil diambil",
+		"data":    diff,
+	})
+}
+
+// RestoreVersiHandler mengembalikan data pasien ke versi tertentu (dicatat sebagai versi baru).
+// PUT /api/management/pasien/versi/restore?id_pasien={id}&versi={versi}
+func (vc *PasienVersiController) RestoreVersiHandler(c echo.Context) error {
+	claims, ok := c.Get(string(middlewares.ContextKeyClaims)).(*utils.Claims)
+	if !ok || claims == nil {
+		return c.JSON(http.StatusUnauthorized, map[string]interface{}{
+			"status":  http.StatusUnauthorized,
+			"message": "Invalid or missing token claims",
+			"data":    nil,
+		})
+	}
+	idPasien, err := strconv.Atoi(c.QueryParam("id_pasien"))
+	if err != nil || idPasien <= 0 {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"status":  http.StatusBadRequest,
+			"message": "id_pasien harus berupa angka",
+			"data":    nil,
+		})
+	}
+	versi, err := strconv.Atoi(c.QueryParam("versi"))
+	if err != nil || versi <= 0 {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"status":  http.StatusBadRequest,
+			"message": "versi harus berupa angka",
+			"data":    nil,
+		})
+	}
+
+	baru, err := vc.Service.RestoreVersi(idPasien, versi, antrian.ActorFromClaims(claims))
+	if err != nil {
+		return versiError(c, err, "Gagal mengembalikan versi pasien")
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status":  http.StatusOK,
+		"message": "Data pasien berhasil dikembalikan",
+		"data": map[string]interface{}{
+			"id_pasien":  idPasien,
+			"dari_versi": versi,
+			"versi_baru": baru,
+		},
+	})
+}
+
+func versiError(c echo.Context, err error, fallback string) error {
+	switch {
+	case errors.Is(err, services.ErrVersiTidakDitemukan), strings.Contains(err.Error(), "tidak ditemukan"):
+		return c.JSON(http.StatusNotFound, map[string]interface{}{
+			"status":  http.StatusNotFound,
+			"message": err.Error(),
+			"data":    nil,
+		})
+	case errors.Is(err, services.ErrNIKDipakai):
+		return c.JSON(http.StatusConflict, map[string]interface{}{
+			"status":  http.StatusConflict,
+			"message": err.Error(),
+			"data":    nil,
+		})
+	}
+	return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+		"status":  http.StatusInternalServerError,
+		"message": fallback,
+		"data":    nil,
+	})
+}
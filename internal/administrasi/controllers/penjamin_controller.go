@@ -0,0 +1,316 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/c14220110/poliklinik-backend/internal/administrasi/models"
+	"github.com/c14220110/poliklinik-backend/internal/administrasi/services"
+	"github.com/c14220110/poliklinik-backend/internal/common/middlewares"
+	"github.com/c14220110/poliklinik-backend/pkg/utils"
+	"github.com/labstack/echo/v4"
+)
+
+type PenjaminController struct {
+	Service *services.PenjaminService
+}
+
+func NewPenjaminController(service *services.PenjaminService) *PenjaminController {
+	return &PenjaminController{Service: service}
+}
+
+// ListPenjaminAktifHandler mengembalikan penjamin aktif untuk pilihan di pendaftaran.
+// GET /api/administrasi/penjamin
+func (pc *PenjaminController) ListPenjaminAktifHandler(c echo.Context) error {
+	return pc.listPenjamin(c, true)
+}
+
+// ListPenjaminHandler mengembalikan semua penjamin beserta aturan tanggungannya.
+// GET /api/management/penjamin
+func (pc *PenjaminController) ListPenjaminHandler(c echo.Context) error {
+	return pc.listPenjamin(c, false)
+}
+
+func (pc *PenjaminController) listPenjamin(c echo.Context, hanyaAktif bool) error {
+	list, err := pc.Service.ListPenjamin(hanyaAktif)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"status":  http.StatusInternalServerError,
+			"message": "Gagal mengambil daftar penjamin",
+			"data":    nil,
+		})
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status":  http.StatusOK,
+		"message": "Daftar penjamin berhasil diambil",
+		"data":    list,
+	})
+}
+
+// TambahPenjaminHandler menambah penjamin baru.
+// POST /api/management/penjamin  body: {"nama": "...", "jenis": "BPJS|Asuransi|Perusahaan"}
+func (pc *PenjaminController) TambahPenjaminHandler(c echo.Context) error {
+	var req models.Penjamin
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"status":  http.StatusBadRequest,
+			"message": "Invalid request payload: " + err.Error(),
+			"data":    nil,
+		})
+	}
+	id, err := pc.Service.TambahPenjamin(req)
+	if err != nil {
+		return penjaminError(c, err, "Gagal menambah penjamin")
+	}
+	return c.JSON(http.StatusCreated, map[string]interface{}{
+		"status":  http.StatusCreated,
+		"message": "Penjamin berhasil ditambahkan",
+		"data":    map[string]interface{}{"id_penjamin": id},
+	})
+}
+
+// UpdatePenjaminHandler mengubah nama, jenis, dan status aktif penjamin.
+// PUT /api/management/penjamin?id_penjamin={id}  body: {"nama": "...", "jenis": "...", "aktif": true}
+// aktif boleh dikosongkan (status tidak berubah).
+func (pc *PenjaminController) UpdatePenjaminHandler(c echo.Context) error {
+	idPenjamin, err := strconv.Atoi(c.QueryParam("id_penjamin"))
+	if err != nil || idPenjamin <= 0 {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"status":  http.StatusBadRequest,
+			"message": "id_penjamin harus berupa angka",
+			"data":    nil,
+		})
+	}
+	var req struct {
+		Nama  string `json:"nama"`
+		Jenis string `json:"jenis"`
+		Aktif *bool  `json:"aktif"`
+	}
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"status":  http.StatusBadRequest,
+			"message": "Invalid request payload: " + err.Error(),
+			"data":    nil,
+		})
+	}
+	if err := pc.Service.UpdatePenjamin(idPenjamin, models.Penjamin{Nama: req.Nama, Jenis: req.Jenis}, req.Aktif); err != nil {
+		return penjaminError(c, err, "Gagal mengupdate penjamin")
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status":  http.StatusOK,
+		"message": "Penjamin berhasil diupdate",
+		"data":    map[string]interface{}{"id_penjamin": idPenjamin},
+	})
+}
+
+// SetAturanHandler mengganti seluruh aturan tanggungan penjamin.
+// PUT /api/management/penjamin/aturan?id_penjamin={id}
+// body: {"aturan": [{"komponen": "tindakan", "id_icd9_cm": "89.52", "persen_tanggungan": 100, "plafon": null}]}
+func (pc *PenjaminController) SetAturanHandler(c echo.Context) error {
+	idPenjamin, err := strconv.Atoi(c.QueryParam("id_penjamin"))
+	if err != nil || idPenjamin <= 0 {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"status":  http.StatusBadRequest,
+			"message": "id_penjamin harus berupa angka",
+			"data":    nil,
+		})
+	}
+	var req struct {
+		Aturan []models.AturanPenjamin `json:"aturan"`
+	}
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"status":  http.StatusBadRequest,
+			"message": "Invalid request payload: " + err.Error(),
+			"data":    nil,
+		})
+	}
+	if err := pc.Service.SetAturan(idPenjamin, req.Aturan); err != nil {
+		return penjaminError(c, err, "Gagal menyimpan aturan penjamin")
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status":  http.StatusOK,
+		"message": "Aturan penjamin berhasil disimpan",
+		"data": map[string]interface{}{
+			"id_penjamin":   idPenjamin,
+			"jumlah_aturan": len(req.Aturan),
+		},
+	})
+}
+
+// GetKepesertaanHandler mengembalikan kepesertaan penjamin milik pasien.
+// GET /api/administrasi/pasien/penjamin?id_pasien={id}
+func (pc *PenjaminController) GetKepesertaanHandler(c echo.Context) error {
+	idPasien, err := strconv.Atoi(c.QueryParam("id_pasien"))
+	if err != nil || idPasien <= 0 {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"status":  http.StatusBadRequest,
+			"message": "id_pasien harus berupa angka",
+			"data":    nil,
+		})
+	}
+	list, err := pc.Service.GetKepesertaanPasien(idPasien)
+	if err != nil {
+		return penjaminError(c, err, "Gagal mengambil kepesertaan pasien")
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status":  http.StatusOK,
+		"message": "Kepesertaan pasien berhasil diambil",
+		"data":    list,
+	})
+}
+
+// TambahKepesertaanHandler mendaftarkan pasien sebagai peserta penjamin.
+// POST /api/administrasi/pasien/penjamin?id_pasien={id}
+func (pc *PenjaminController) TambahKepesertaanHandler(c echo.Context) error {
+	claims, ok := c.Get(string(middlewares.ContextKeyClaims)).(*utils.Claims)
+	if !ok || claims == nil {
+		return c.JSON(http.StatusUnauthorized, map[string]interface{}{
+			"status":  http.StatusUnauthorized,
+			"message": "Invalid or missing token claims",
+			"data":    nil,
+		})
+	}
+	idPasien, err := strconv.Atoi(c.QueryParam("id_pasien"))
+	if err != nil || idPasien <= 0 {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"status":  http.StatusBadRequest,
+			"message": "id_pasien harus berupa angka",
+			"data":    nil,
+		})
+	}
+	var req models.KepesertaanRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"status":  http.StatusBadRequest,
+			"message": "Invalid request payload: " + err.Error(),
+			"data":    nil,
+		})
+	}
+	id, err := pc.Service.TambahKepesertaan(idPasien, req, claims.IDKaryawan)
+	if err != nil {
+		return penjaminError(c, err, "Gagal menambah kepesertaan")
+	}
+	return c.JSON(http.StatusCreated, map[string]interface{}{
+		"status":  http.StatusCreated,
+		"message": "Kepesertaan berhasil ditambahkan",
+		"data":    map[string]interface{}{"id_kepesertaan": id},
+	})
+}
+
+// UpdateKepesertaanHandler mengubah nomor peserta dan masa berlaku kepesertaan.
+// PUT /api/administrasi/pasien/penjamin?id_kepesertaan={id}
+func (pc *PenjaminController) UpdateKepesertaanHandler(c echo.Context) error {
+	idKepesertaan, err := strconv.Atoi(c.QueryParam("id_kepesertaan"))
+	if err != nil || idKepesertaan <= 0 {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"status":  http.StatusBadRequest,
+			"message": "id_kepesertaan harus berupa angka",
+			"data":    nil,
+		})
+	}
+	var req models.KepesertaanRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"status":  http.StatusBadRequest,
+			"message": "Invalid request payload: " + err.Error(),
+			"data":    nil,
+		})
+	}
+	if err := pc.Service.UpdateKepesertaan(idKepesertaan, req); err != nil {
+		return penjaminError(c, err, "Gagal mengupdate kepesertaan")
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status":  http.StatusOK,
+		"message": "Kepesertaan berhasil diupdate",
+		"data":    map[string]interface{}{"id_kepesertaan": idKepesertaan},
+	})
+}
+
+// ListKepesertaanBelumVerifikasiHandler mengembalikan kepesertaan dari pendaftaran yang menunggu verifikasi.
+// GET /api/management/penjamin/verifikasi
+func (pc *PenjaminController) ListKepesertaanBelumVerifikasiHandler(c echo.Context) error {
+	list, err := pc.Service.ListKepesertaanBelumVerifikasi()
+	if err != nil {
+		return penjaminError(c, err, "Gagal mengambil kepesertaan")
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status":  http.StatusOK,
+		"message": "Kepesertaan belum terverifikasi berhasil diambil",
+		"data":    list,
+	})
+}
+
+// VerifikasiKepesertaanHandler mengonfirmasi kepesertaan dari pendaftaran beserta masa berlakunya.
+// PUT /api/management/penjamin/verifikasi?id_kepesertaan={id}
+// body: {"berlaku_mulai": "2025-01-01", "berlaku_sampai": "2025-12-31"}
+func (pc *PenjaminController) VerifikasiKepesertaanHandler(c echo.Context) error {
+	claims, ok := c.Get(string(middlewares.ContextKeyClaims)).(*utils.Claims)
+	if !ok || claims == nil {
+		return c.JSON(http.StatusUnauthorized, map[string]interface{}{
+			"status":  http.StatusUnauthorized,
+			"message": "Invalid or missing token claims",
+			"data":    nil,
+		})
+	}
+	idKepesertaan, err := strconv.Atoi(c.QueryParam("id_kepesertaan"))
+	if err != nil || idKepesertaan <= 0 {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"status":  http.StatusBadRequest,
+			"message": "id_kepesertaan harus berupa angka",
+			"data":    nil,
+		})
+	}
+	var req models.KepesertaanRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"status":  http.StatusBadRequest,
+			"message": "Invalid request payload: " + err.Error(),
+			"data":    nil,
+		})
+	}
+	if err := pc.Service.VerifikasiKepesertaan(idKepesertaan, req, claims.IDKaryawan); err != nil {
+		return penjaminError(c, err, "Gagal memverifikasi kepesertaan")
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status":  http.StatusOK,
+		"message": "Kepesertaan berhasil diverifikasi",
+		"data":    map[string]interface{}{"id_kepesertaan": idKepesertaan},
+	})
+}
+
+// penjaminErrorStatus memetakan error penjamin/kepesertaan ke status HTTP;
+// ok = false jika err bukan error penjamin. Dipakai juga oleh handler pendaftaran.
+func penjaminErrorStatus(err error) (status int, ok bool) {
+	switch {
+	case errors.Is(err, services.ErrPenjaminTidakDitemukan), errors.Is(err, services.ErrKepesertaanTidakDitemukan):
+		return http.StatusNotFound, true
+	case errors.Is(err, services.ErrNomorPesertaDipakai):
+		return http.StatusConflict, true
+	case errors.Is(err, services.ErrPenjaminTidakValid), errors.Is(err, services.ErrKepesertaanTidakValid),
+		errors.Is(err, services.ErrKepesertaanTidakBerlaku):
+		return http.StatusBadRequest, true
+	}
+	return 0, false
+}
+
+func penjaminError(c echo.Context, err error, fallback string) error {
+	status, ok := penjaminErrorStatus(err)
+	if !ok && strings.Contains(err.Error(), "tidak ditemukan") {
+		status, ok = http.StatusNotFound, true
+	}
+	if !ok {
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"status":  http.StatusInternalServerError,
+			"message": fallback,
+			"data":    nil,
+		})
+	}
+	return c.JSON(status, map[string]interface{}{
+		"status":  status,
+		"message": err.Error(),
+		"data":    nil,
+	})
+}
@@ -0,0 +1,235 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/c14220110/poliklinik-backend/internal/administrasi/models"
+	"github.com/c14220110/poliklinik-backend/internal/administrasi/services"
+	"github.com/c14220110/poliklinik-backend/internal/common/antrian"
+	"github.com/c14220110/poliklinik-backend/internal/common/middlewares"
+	"github.com/c14220110/poliklinik-backend/pkg/utils"
+	"github.com/labstack/echo/v4"
+)
+
+type PersetujuanController struct {
+	Service *services.PersetujuanService
+}
+
+func NewPersetujuanController(service *services.PersetujuanService) *PersetujuanController {
+	return &PersetujuanController{Service: service}
+}
+
+// ListTemplateAktifHandler mengembalikan template aktif untuk ditandatangani.
+// GET /api/administrasi/persetujuan/template?id_poli={id}&id_icd9_cm={kode}
+// GET /api/dokter/persetujuan/template?id_poli={id}&id_icd9_cm={kode}
+func (pc *PersetujuanController) ListTemplateAktifHandler(c echo.Context) error {
+	return pc.listTemplate(c, true)
+}
+
+// ListTemplateHandler mengembalikan semua template, termasuk yang tidak aktif.
+// GET /api/management/persetujuan/template?id_poli={id}&id_icd9_cm={kode}
+func (pc *PersetujuanController) ListTemplateHandler(c echo.Context) error {
+	return pc.listTemplate(c, false)
+}
+
+func (pc *PersetujuanController) listTemplate(c echo.Context, hanyaAktif bool) error {
+	var idPoli int
+	if raw := c.QueryParam("id_poli"); raw != "" {
+		var err error
+		if idPoli, err = strconv.Atoi(raw); err != nil || idPoli <= 0 {
+			return c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"status":  http.StatusBadRequest,
+				"message": "id_poli harus berupa angka",
+				"data":    nil,
+			})
+		}
+	}
+	list, err := pc.Service.ListTemplate(idPoli, c.QueryParam("id_icd9_cm"), hanyaAktif)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"status":  http.StatusInternalServerError,
+			"message": "Gagal mengambil template persetujuan",
+			"data":    nil,
+		})
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status":  http.StatusOK,
+		"message": "Template persetujuan berhasil diambil",
+		"data":    list,
+	})
+}
+
+// TambahTemplateHandler menambah template persetujuan.
+// POST /api/management/persetujuan/template
+func (pc *PersetujuanController) TambahTemplateHandler(c echo.Context) error {
+	claims, ok := c.Get(string(middlewares.ContextKeyClaims)).(*utils.Claims)
+	if !ok || claims == nil {
+		return c.JSON(http.StatusUnauthorized, map[string]interface{}{
+			"status":  http.StatusUnauthorized,
+			"message": "Invalid or missing token claims",
+			"data":    nil,
+		})
+	}
+	var req models.TemplatePersetujuan
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"status":  http.StatusBadRequest,
+			"message": "Invalid request payload: " + err.Error(),
+			"data":    nil,
+		})
+	}
+	id, err := pc.Service.TambahTemplate(req, claims.IDKaryawan)
+	if err != nil {
+		return persetujuanError(c, err, "Gagal menambah template persetujuan")
+	}
+	return c.JSON(http.StatusCreated, map[string]interface{}{
+		"status":  http.StatusCreated,
+		"message": "Template persetujuan berhasil ditambahkan",
+		"data":    map[string]interface{}{"id_template": id},
+	})
+}
+
+// UpdateTemplateHandler mengubah judul, isi, wajib, dan aktif template.
+// PUT /api/management/persetujuan/template?id_template={id}
+func (pc *PersetujuanController) UpdateTemplateHandler(c echo.Context) error {
+	claims, ok := c.Get(string(middlewares.ContextKeyClaims)).(*utils.Claims)
+	if !ok || claims == nil {
+		return c.JSON(http.StatusUnauthorized, map[string]interface{}{
+			"status":  http.StatusUnauthorized,
+			"message": "Invalid or missing token claims",
+			"data":    nil,
+		})
+	}
+	idTemplate, err := strconv.Atoi(c.QueryParam("id_template"))
+	if err != nil || idTemplate <= 0 {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"status":  http.StatusBadRequest,
+			"message": "id_template harus berupa angka",
+			"data":    nil,
+		})
+	}
+	var req struct {
+		Judul string `json:"judul"`
+		Isi   string `json:"isi"`
+		Wajib *bool  `json:"wajib"`
+		Aktif *bool  `json:"aktif"`
+	}
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"status":  http.StatusBadRequest,
+			"message": "Invalid request payload: " + err.Error(),
+			"data":    nil,
+		})
+	}
+	if err := pc.Service.UpdateTemplate(idTemplate, req.Judul, req.Isi, req.Wajib, req.Aktif, claims.IDKaryawan); err != nil {
+		return persetujuanError(c, err, "Gagal mengupdate template persetujuan")
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status":  http.StatusOK,
+		"message": "Template persetujuan berhasil diupdate",
+		"data":    map[string]interface{}{"id_template": idTemplate},
+	})
+}
+
+// GetPersetujuanKunjunganHandler mengembalikan persetujuan pada kunjungan dan yang belum lengkap.
+// GET /api/administrasi/persetujuan?id_kunjungan={id}
+// GET /api/dokter/persetujuan?id_kunjungan={id}
+func (pc *PersetujuanController) GetPersetujuanKunjunganHandler(c echo.Context) error {
+	idKunjungan, err := strconv.Atoi(c.QueryParam("id_kunjungan"))
+	if err != nil || idKunjungan <= 0 {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"status":  http.StatusBadRequest,
+			"message": "id_kunjungan harus berupa angka",
+			"data":    nil,
+		})
+	}
+	status, err := pc.Service.GetStatusKunjungan(idKunjungan)
+	if err != nil {
+		return persetujuanError(c, err, "Gagal mengambil persetujuan kunjungan")
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status":  http.StatusOK,
+		"message": "Persetujuan kunjungan berhasil diambil",
+		"data":    status,
+	})
+}
+
+// CatatPersetujuanHandler mencatat persetujuan yang ditandatangani pasien / penanggung jawab.
+// POST /api/administrasi/persetujuan?id_kunjungan={id}
+// POST /api/dokter/persetujuan?id_kunjungan={id}
+func (pc *PersetujuanController) CatatPersetujuanHandler(c echo.Context) error {
+	idKunjungan, err := strconv.Atoi(c.QueryParam("id_kunjungan"))
+	if err != nil || idKunjungan <= 0 {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"status":  http.StatusBadRequest,
+			"message": "id_kunjungan harus berupa angka",
+			"data":    nil,
+		})
+	}
+	var req models.PersetujuanRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"status":  http.StatusBadRequest,
+			"message": "Invalid request payload: " + err.Error(),
+			"data":    nil,
+		})
+	}
+
+	claims, _ := c.Get(string(middlewares.ContextKeyClaims)).(*utils.Claims)
+	id, err := pc.Service.CatatPersetujuan(idKunjungan, req, antrian.ActorFromClaims(claims))
+	if err != nil {
+		return persetujuanError(c, err, "Gagal mencatat persetujuan")
+	}
+	return c.JSON(http.StatusCreated, map[string]interface{}{
+		"status":  http.StatusCreated,
+		"message": "Persetujuan berhasil dicatat",
+		"data":    map[string]interface{}{"id_persetujuan": id},
+	})
+}
+
+// CabutPersetujuanHandler mencabut persetujuan yang sudah dicatat.
+// PUT /api/administrasi/persetujuan/cabut?id_persetujuan={id}&alasan={teks}
+func (pc *PersetujuanController) CabutPersetujuanHandler(c echo.Context) error {
+	idPersetujuan, err := strconv.Atoi(c.QueryParam("id_persetujuan"))
+	if err != nil || idPersetujuan <= 0 {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"status":  http.StatusBadRequest,
+			"message": "id_persetujuan harus berupa angka",
+			"data":    nil,
+		})
+	}
+
+	claims, _ := c.Get(string(middlewares.ContextKeyClaims)).(*utils.Claims)
+	if err := pc.Service.CabutPersetujuan(idPersetujuan, c.QueryParam("alasan"), antrian.ActorFromClaims(claims)); err != nil {
+		return persetujuanError(c, err, "Gagal mencabut persetujuan")
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status":  http.StatusOK,
+		"message": "Persetujuan berhasil dicabut",
+		"data":    map[string]interface{}{"id_persetujuan": idPersetujuan},
+	})
+}
+
+func persetujuanError(c echo.Context, err error, fallback string) error {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, services.ErrTemplatePersetujuanTidakDitemukan), errors.Is(err, services.ErrPersetujuanTidakDitemukan),
+		errors.Is(err, services.ErrKunjunganNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, services.ErrPersetujuanSudahAda):
+		status = http.StatusConflict
+	case errors.Is(err, services.ErrPersetujuanTidakValid):
+		status = http.StatusBadRequest
+	}
+	message := err.Error()
+	if status == http.StatusInternalServerError {
+		message = fallback
+	}
+	return c.JSON(status, map[string]interface{}{
+		"status":  status,
+		"message": message,
+		"data":    nil,
+	})
+}
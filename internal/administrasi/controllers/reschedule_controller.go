@@ -0,0 +1,110 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/c14220110/poliklinik-backend/internal/administrasi/models"
+	"github.com/c14220110/poliklinik-backend/internal/administrasi/services"
+	"github.com/c14220110/poliklinik-backend/internal/common/middlewares"
+	"github.com/c14220110/poliklinik-backend/pkg/utils"
+	"github.com/labstack/echo/v4"
+)
+
+type RescheduleController struct {
+	Service *services.RescheduleService
+}
+
+func NewRescheduleController(service *services.RescheduleService) *RescheduleController {
+	return &RescheduleController{Service: service}
+}
+
+// GetKebijakanHandler mengembalikan kebijakan reschedule semua poli aktif atau satu poli.
+// GET /api/management/reschedule?id_poli={id}, GET /api/administrasi/reschedule?id_poli={id}
+func (rc *RescheduleController) GetKebijakanHandler(c echo.Context) error {
+	var idPoli *int
+	if s := c.QueryParam("id_poli"); s != "" {
+		v, err := strconv.Atoi(s)
+		if err != nil || v <= 0 {
+			return c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"status":  http.StatusBadRequest,
+				"message": "id_poli harus berupa angka",
+				"data":    nil,
+			})
+		}
+		idPoli = &v
+	}
+	list, err := rc.Service.GetKebijakan(idPoli)
+	if err != nil {
+		return rescheduleError(c, err, "Gagal mengambil kebijakan reschedule")
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status":  http.StatusOK,
+		"message": "Kebijakan reschedule berhasil diambil",
+		"data":    list,
+	})
+}
+
+// SetKebijakanHandler menyimpan kebijakan reschedule satu poli.
+// PUT /api/management/reschedule?id_poli={id}
+// body: {"mode": "Sisip|Akhir|Jam", "sisip_setelah": 2, "jam_kembali": "13:00", "maks_tunda": 3}
+// maks_tunda null berarti tanpa batas.
+func (rc *RescheduleController) SetKebijakanHandler(c echo.Context) error {
+	claims, ok := c.Get(string(middlewares.ContextKeyClaims)).(*utils.Claims)
+	if !ok || claims == nil {
+		return c.JSON(http.StatusUnauthorized, map[string]interface{}{
+			"status":  http.StatusUnauthorized,
+			"message": "Invalid or missing token claims",
+			"data":    nil,
+		})
+	}
+	idPoli, err := strconv.Atoi(c.QueryParam("id_poli"))
+	if err != nil || idPoli <= 0 {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"status":  http.StatusBadRequest,
+			"message": "id_poli harus berupa angka",
+			"data":    nil,
+		})
+	}
+	var req models.KebijakanReschedule
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"status":  http.StatusBadRequest,
+			"message": "Invalid request payload: " + err.Error(),
+			"data":    nil,
+		})
+	}
+	req.IDPoli = idPoli
+	if err := rc.Service.SetKebijakan(req, claims.IDKaryawan); err != nil {
+		return rescheduleError(c, err, "Gagal menyimpan kebijakan reschedule")
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status":  http.StatusOK,
+		"message": "Kebijakan reschedule berhasil disimpan",
+		"data":    map[string]interface{}{"id_poli": idPoli},
+	})
+}
+
+func rescheduleError(c echo.Context, err error, fallback string) error {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, services.ErrKebijakanRescheduleTidakValid):
+		status = http.StatusBadRequest
+	case strings.Contains(err.Error(), "tidak ditemukan"):
+		status = http.StatusNotFound
+	}
+	if status == http.StatusInternalServerError {
+		return c.JSON(status, map[string]interface{}{
+			"status":  status,
+			"message": fallback,
+			"data":    nil,
+		})
+	}
+	return c.JSON(status, map[string]interface{}{
+		"status":  status,
+		"message": err.Error(),
+		"data":    nil,
+	})
+}
@@ -0,0 +1,177 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/c14220110/poliklinik-backend/internal/administrasi/models"
+	"github.com/c14220110/poliklinik-backend/internal/administrasi/services"
+	"github.com/c14220110/poliklinik-backend/internal/common/antrian"
+	"github.com/c14220110/poliklinik-backend/internal/common/middlewares"
+	"github.com/c14220110/poliklinik-backend/pkg/utils"
+	"github.com/labstack/echo/v4"
+)
+
+type TriaseController struct {
+	Service *services.TriaseService
+}
+
+func NewTriaseController(service *services.TriaseService) *TriaseController {
+	return &TriaseController{Service: service}
+}
+
+// ListTriaseAktifHandler mengembalikan kategori triase aktif untuk pilihan di pendaftaran & screening.
+// GET /api/administrasi/triase, GET /api/screening/triase
+func (tc *TriaseController) ListTriaseAktifHandler(c echo.Context) error {
+	return tc.listTriase(c, true)
+}
+
+// ListTriaseHandler mengembalikan semua kategori triase.
+// GET /api/management/triase
+func (tc *TriaseController) ListTriaseHandler(c echo.Context) error {
+	return tc.listTriase(c, false)
+}
+
+func (tc *TriaseController) listTriase(c echo.Context, hanyaAktif bool) error {
+	list, err := tc.Service.ListTriase(hanyaAktif)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"status":  http.StatusInternalServerError,
+			"message": "Gagal mengambil kategori triase",
+			"data":    nil,
+		})
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status":  http.StatusOK,
+		"message": "Kategori triase berhasil diambil",
+		"data":    list,
+	})
+}
+
+// TambahTriaseHandler menambah kategori triase.
+// POST /api/management/triase  body: {"kode": "...", "nama": "...", "bobot": 100, "maks_tunggu_menit": 45}
+func (tc *TriaseController) TambahTriaseHandler(c echo.Context) error {
+	var req models.KategoriTriase
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"status":  http.StatusBadRequest,
+			"message": "Invalid request payload: " + err.Error(),
+			"data":    nil,
+		})
+	}
+	id, err := tc.Service.TambahTriase(req)
+	if err != nil {
+		return triaseError(c, err, "Gagal menambah kategori triase")
+	}
+	return c.JSON(http.StatusCreated, map[string]interface{}{
+		"status":  http.StatusCreated,
+		"message": "Kategori triase berhasil ditambahkan",
+		"data":    map[string]interface{}{"id_triase": id},
+	})
+}
+
+// UpdateTriaseHandler mengubah kategori triase.
+// PUT /api/management/triase?id_triase={id}
+// body: {"kode": "...", "nama": "...", "bobot": 100, "maks_tunggu_menit": 45, "aktif": true}
+// aktif boleh dikosongkan (status tidak berubah).
+func (tc *TriaseController) UpdateTriaseHandler(c echo.Context) error {
+	idTriase, err := strconv.Atoi(c.QueryParam("id_triase"))
+	if err != nil || idTriase <= 0 {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"status":  http.StatusBadRequest,
+			"message": "id_triase harus berupa angka",
+			"data":    nil,
+		})
+	}
+	var req struct {
+		models.KategoriTriase
+		Aktif *bool `json:"aktif"`
+	}
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"status":  http.StatusBadRequest,
+			"message": "Invalid request payload: " + err.Error(),
+			"data":    nil,
+		})
+	}
+	if err := tc.Service.UpdateTriase(idTriase, req.KategoriTriase, req.Aktif); err != nil {
+		return triaseError(c, err, "Gagal mengupdate kategori triase")
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status":  http.StatusOK,
+		"message": "Kategori triase berhasil diupdate",
+		"data":    map[string]interface{}{"id_triase": idTriase},
+	})
+}
+
+// TetapkanTriaseHandler menetapkan kategori triase antrian oleh suster.
+// PUT /api/screening/antrian/triase?id_antrian={id}&id_triase={id}  (id_triase=0 menghapus triase)
+func (tc *TriaseController) TetapkanTriaseHandler(c echo.Context) error {
+	claims, ok := c.Get(string(middlewares.ContextKeyClaims)).(*utils.Claims)
+	if !ok || claims == nil {
+		return c.JSON(http.StatusUnauthorized, map[string]interface{}{
+			"status":  http.StatusUnauthorized,
+			"message": "Invalid or missing token claims",
+			"data":    nil,
+		})
+	}
+	idAntrian, err := strconv.Atoi(c.QueryParam("id_antrian"))
+	if err != nil || idAntrian <= 0 {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"status":  http.StatusBadRequest,
+			"message": "id_antrian harus berupa angka",
+			"data":    nil,
+		})
+	}
+	idTriase, err := strconv.Atoi(c.QueryParam("id_triase"))
+	if err != nil || idTriase < 0 {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"status":  http.StatusBadRequest,
+			"message": "id_triase harus berupa angka (0 untuk menghapus triase)",
+			"data":    nil,
+		})
+	}
+
+	hasil, err := tc.Service.TetapkanTriase(idAntrian, idTriase, claims.IDPoli, antrian.ActorFromClaims(claims))
+	if err != nil {
+		return triaseError(c, err, "Gagal menetapkan triase")
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status":  http.StatusOK,
+		"message": "Triase antrian berhasil disimpan",
+		"data":    hasil,
+	})
+}
+
+func triaseErrorStatus(err error) (status int, ok bool) {
+	switch {
+	case errors.Is(err, services.ErrTriaseTidakDitemukan), errors.Is(err, antrian.ErrAntrianTidakDitemukan):
+		return http.StatusNotFound, true
+	case errors.Is(err, services.ErrTriasePoliTidakSama):
+		return http.StatusForbidden, true
+	case errors.Is(err, services.ErrTriaseTidakValid):
+		return http.StatusBadRequest, true
+	}
+	return 0, false
+}
+
+func triaseError(c echo.Context, err error, fallback string) error {
+	status, ok := triaseErrorStatus(err)
+	if !ok && strings.Contains(err.Error(), "tidak ditemukan") {
+		status, ok = http.StatusNotFound, true
+	}
+	if !ok {
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"status":  http.StatusInternalServerError,
+			"message": fallback,
+			"data":    nil,
+		})
+	}
+	return c.JSON(status, map[string]interface{}{
+		"status":  status,
+		"message": err.Error(),
+		"data":    nil,
+	})
+}
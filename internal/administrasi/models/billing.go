@@ -23,6 +23,19 @@ type DetailBilling struct {
 	Obat                   []ObatDetail     `json:"obat"`
 	Tindakan               []TindakanDetail `json:"tindakan"`
 	WaktuDibayar           *string          `json:"waktu_dibayar"`
+	Penjamin               *string          `json:"penjamin"` // nil = pasien umum
+	NomorPeserta           *string          `json:"nomor_peserta"`
+	PenjaminTerverifikasi  *bool            `json:"penjamin_terverifikasi"` // false = dibayar penuh pasien sampai diverifikasi
+	DitanggungPenjamin     *float64         `json:"ditanggung_penjamin"` // terisi setelah dibayar
+	DibayarPasien          *float64         `json:"dibayar_pasien"`
+	Rujukan                []RujukanDetail  `json:"rujukan"` // poli rujukan internal dalam kunjungan yang sama
+}
+
+type RujukanDetail struct {
+	NamaPoli      string  `json:"nama_poli"`
+	StatusAntrian string  `json:"status_antrian"`
+	NamaDokter    string  `json:"nama_dokter"`
+	BiayaDokter   float64 `json:"biaya_dokter"`
 }
 
 type ObatDetail struct {
@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+// DaftarTunggu adalah pasien yang mendaftar saat poli penuh atau di luar jam operasional.
+// Alasan: Kuota / Jam. Status: Menunggu, Didaftarkan, Dibatalkan, Kedaluwarsa.
+type DaftarTunggu struct {
+	IDDaftarTunggu      int       `json:"id_daftar_tunggu"`
+	IDPasien            int64     `json:"id_pasien"`
+	NamaPasien          string    `json:"nama_pasien,omitempty"`
+	IDRM                string    `json:"id_rm,omitempty"`
+	IDPoli              int       `json:"id_poli"`
+	NamaPoli            string    `json:"nama_poli,omitempty"`
+	Tanggal             string    `json:"tanggal"`
+	KeluhanUtama        string    `json:"keluhan_utama"`
+	NamaPenanggungJawab string    `json:"nama_penanggung_jawab"`
+	IDPenjamin          *int      `json:"id_penjamin"`
+	NomorPeserta        *string   `json:"nomor_peserta"`
+	IDTriase            *int      `json:"id_triase"`
+	Alasan              string    `json:"alasan"`
+	Keterangan          string    `json:"keterangan,omitempty"`
+	Status              string    `json:"status"`
+	Posisi              *int      `json:"posisi"` // urutan di antara yang masih Menunggu (1 = berikutnya)
+	IDAntrian           *int      `json:"id_antrian"`
+	CreatedAt           time.Time `json:"created_at"`
+}
+
+// HasilDaftarTunggu adalah antrian yang dibuat saat pasien daftar tunggu didaftarkan.
+type HasilDaftarTunggu struct {
+	IDDaftarTunggu int    `json:"id_daftar_tunggu"`
+	IDPasien       int64  `json:"id_pasien"`
+	IDRM           string `json:"id_rm"`
+	IDKunjungan    int64  `json:"id_kunjungan"`
+	IDAntrian      int64  `json:"id_antrian"`
+	NomorAntrian   int64  `json:"nomor_antrian"`
+	KodeTiket      string `json:"kode_tiket"`
+	NamaPoli       string `json:"nama_poli"`
+}
@@ -0,0 +1,62 @@
+package models
+
+import "time"
+
+// RingkasanPasien adalah data pasien yang dibandingkan saat mencari duplikat.
+type RingkasanPasien struct {
+	IDPasien     int    `json:"id_pasien"`
+	IDRM         string `json:"id_rm"`
+	Nama         string `json:"nama"`
+	TanggalLahir string `json:"tanggal_lahir"`
+	NIK          string `json:"nik"`
+	NoTelp       string `json:"no_telp"`
+	Alamat       string `json:"alamat"`
+}
+
+// SkorDuplikat berisi kemiripan per kolom (0..1) dan skor total berbobot.
+type SkorDuplikat struct {
+	Nama         float64 `json:"nama"`
+	TanggalLahir float64 `json:"tanggal_lahir"`
+	NoTelp       float64 `json:"no_telp"`
+	Alamat       float64 `json:"alamat"`
+	Total        float64 `json:"total"`
+	NIKMirip     bool    `json:"nik_mirip"` // NIK berbeda <= 2 karakter (kemungkinan salah ketik)
+}
+
+type KandidatDuplikat struct {
+	Pasien RingkasanPasien `json:"pasien"`
+	Skor   SkorDuplikat    `json:"skor"`
+}
+
+type PasanganDuplikat struct {
+	PasienA RingkasanPasien `json:"pasien_a"`
+	PasienB RingkasanPasien `json:"pasien_b"`
+	Skor    SkorDuplikat    `json:"skor"`
+}
+
+// MergeRequest adalah pengajuan penggabungan oleh administrasi.
+type MergeRequest struct {
+	IDPasienUtama    int    `json:"id_pasien_utama"`
+	IDPasienDuplikat int    `json:"id_pasien_duplikat"`
+	Alasan           string `json:"alasan"`
+}
+
+// PasienMerge adalah satu baris audit Pasien_Merge.
+type PasienMerge struct {
+	IDMerge          int        `json:"id_merge"`
+	IDPasienUtama    int        `json:"id_pasien_utama"`
+	NamaPasienUtama  string     `json:"nama_pasien_utama"`
+	IDPasienDuplikat int        `json:"id_pasien_duplikat"`
+	NamaDuplikat     string     `json:"nama_pasien_duplikat"`
+	Skor             *float64   `json:"skor"`
+	Alasan           *string    `json:"alasan"`
+	Status           string     `json:"status"`
+	DiajukanOleh     *int       `json:"diajukan_oleh"`
+	DiputuskanOleh   *int       `json:"diputuskan_oleh"`
+	DiputuskanAt     *time.Time `json:"diputuskan_at"`
+	DibatalkanOleh   *int       `json:"dibatalkan_oleh"`
+	DibatalkanAt     *time.Time `json:"dibatalkan_at"`
+	AlasanBatal      *string    `json:"alasan_batal"`
+	JumlahBaris      int        `json:"jumlah_baris_dipindah"`
+	CreatedAt        time.Time  `json:"created_at"`
+}
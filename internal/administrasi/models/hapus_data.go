@@ -0,0 +1,41 @@
+package models
+
+import "time"
+
+// PermintaanHapusData adalah satu baris audit Permintaan_Hapus_Data.
+type PermintaanHapusData struct {
+	IDPermintaan     int        `json:"id_permintaan"`
+	IDPasien         int        `json:"id_pasien"`
+	NamaPasien       string     `json:"nama_pasien"` // sudah berupa token setelah dilaksanakan
+	Mode             string     `json:"mode"`        // Pseudonim, Vault
+	Alasan           string     `json:"alasan"`
+	Status           string     `json:"status"`
+	DiajukanOleh     *int       `json:"diajukan_oleh"`
+	RolePengaju      *string    `json:"role_pengaju"`
+	DiputuskanOleh   *int       `json:"diputuskan_oleh"`
+	DiputuskanAt     *time.Time `json:"diputuskan_at"`
+	CatatanKeputusan *string    `json:"catatan_keputusan"`
+	DilaksanakanOleh *int       `json:"dilaksanakan_oleh"`
+	DilaksanakanAt   *time.Time `json:"dilaksanakan_at"`
+	Token            *string    `json:"token"`
+	DipulihkanOleh   *int       `json:"dipulihkan_oleh"`
+	DipulihkanAt     *time.Time `json:"dipulihkan_at"`
+	AlasanPulih      *string    `json:"alasan_pulih"`
+	CreatedAt        time.Time  `json:"created_at"`
+}
+
+type PermintaanHapusRequest struct {
+	IDPasien int    `json:"id_pasien"`
+	Mode     string `json:"mode"` // Pseudonim (default) atau Vault
+	Alasan   string `json:"alasan"`
+}
+
+// HasilHapusData merangkum apa yang diubah saat permintaan dilaksanakan / dipulihkan.
+type HasilHapusData struct {
+	IDPermintaan int    `json:"id_permintaan"`
+	Token        string `json:"token,omitempty"`
+	JumlahPasien int    `json:"jumlah_pasien"` // pasien utama + pasien duplikat yang digabung ke dalamnya
+	JumlahVersi  int    `json:"jumlah_versi"`
+	JumlahBerkas int    `json:"jumlah_lampiran"`
+	JumlahKolom  int    `json:"jumlah_kolom_terkait"` // penanggung jawab, nomor peserta, penanda tangan
+}
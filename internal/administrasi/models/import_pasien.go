@@ -0,0 +1,34 @@
+package models
+
+// Status baris hasil import pasien.
+const (
+	ImportValid   = "valid"   // lolos validasi (dry-run) atau siap diimpor
+	ImportDiimpor = "diimpor" // sudah disimpan
+	ImportKonflik = "konflik" // NIK sudah terdaftar atau ganda di dalam file
+	ImportGagal   = "gagal"   // data tidak valid
+)
+
+// BarisImportPasien adalah laporan validasi satu baris CSV. Baris dihitung dari 1 untuk
+// header, sehingga sama dengan nomor baris di spreadsheet.
+type BarisImportPasien struct {
+	Baris      int      `json:"baris"`
+	NIK        string   `json:"nik"`
+	Nama       string   `json:"nama"`
+	Status     string   `json:"status"`
+	Error      []string `json:"error,omitempty"`
+	Peringatan []string `json:"peringatan,omitempty"`
+	IDPasien   *int64   `json:"id_pasien,omitempty"` // terisi jika NIK konflik dengan pasien terdaftar / setelah diimpor
+	IDRM       string   `json:"id_rm,omitempty"`
+}
+
+// LaporanImportPasien adalah ringkasan import. Import bersifat semua-atau-tidak-sama-sekali:
+// jika ada baris gagal / konflik, tidak ada baris yang disimpan (Diimpor = 0).
+type LaporanImportPasien struct {
+	DryRun  bool                `json:"dry_run"`
+	Total   int                 `json:"total"`
+	Valid   int                 `json:"valid"`
+	Konflik int                 `json:"konflik"`
+	Gagal   int                 `json:"gagal"`
+	Diimpor int                 `json:"diimpor"`
+	Baris   []BarisImportPasien `json:"baris"`
+}
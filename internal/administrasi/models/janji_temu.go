@@ -0,0 +1,49 @@
+package models
+
+import "time"
+
+// JanjiTemuRequest adalah payload pemesanan janji temu.
+type JanjiTemuRequest struct {
+	IDPasien     int    `json:"id_pasien"`
+	IDPoli       int    `json:"id_poli"`
+	IDDokter     *int   `json:"id_dokter"`
+	Tanggal      string `json:"tanggal"` // YYYY-MM-DD, minimal besok
+	KeluhanUtama string `json:"keluhan_utama"`
+}
+
+type JanjiTemu struct {
+	IDJanjiTemu  int       `json:"id_janji_temu"`
+	IDPasien     int       `json:"id_pasien"`
+	NamaPasien   string    `json:"nama_pasien"`
+	IDRM         string    `json:"id_rm"`
+	IDPoli       int       `json:"id_poli"`
+	NamaPoli     string    `json:"nama_poli"`
+	IDDokter     *int      `json:"id_dokter"`
+	NamaDokter   *string   `json:"nama_dokter"`
+	Tanggal      string    `json:"tanggal"`
+	NomorSlot    int       `json:"nomor_slot"`
+	KeluhanUtama string    `json:"keluhan_utama"`
+	Status       string    `json:"status"`
+	IDAntrian    *int      `json:"id_antrian"`
+	AlasanBatal  *string   `json:"alasan_batal"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// KuotaJanjiTemu adalah kuota dan pemakaian janji temu satu poli pada satu tanggal.
+type KuotaJanjiTemu struct {
+	IDPoli  int    `json:"id_poli"`
+	Tanggal string `json:"tanggal"`
+	Kuota   int    `json:"kuota"`
+	Terisi  int    `json:"terisi"`
+	Sisa    int    `json:"sisa"`
+}
+
+// CheckInJanjiTemu adalah hasil check-in: antrian yang dibuat dari janji temu.
+type CheckInJanjiTemu struct {
+	IDJanjiTemu   int    `json:"id_janji_temu"`
+	IDAntrian     int64  `json:"id_antrian"`
+	NomorAntrian  int64  `json:"nomor_antrian"`
+	PriorityOrder int    `json:"priority_order"`
+	KodeTiket     string `json:"kode_tiket"`
+	IDKunjungan   int64  `json:"id_kunjungan"`
+}
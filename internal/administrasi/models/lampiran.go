@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+type Lampiran struct {
+	IDLampiran   int       `json:"id_lampiran"`
+	IDPasien     int       `json:"id_pasien"`
+	IDKunjungan  *int      `json:"id_kunjungan"`
+	Jenis        string    `json:"jenis"`
+	NamaFile     string    `json:"nama_file"`
+	MimeType     string    `json:"mime_type"`
+	Ukuran       int64     `json:"ukuran"`
+	SHA256       string    `json:"sha256"`
+	Keterangan   *string   `json:"keterangan"`
+	DiunggahOleh *int      `json:"diunggah_oleh"`
+	Role         *string   `json:"role"`
+	CreatedAt    time.Time `json:"created_at"`
+	Path         string    `json:"-"`
+}
+
+// LampiranUpload adalah metadata yang dikirim bersama file (multipart form).
+type LampiranUpload struct {
+	IDPasien    int
+	IDKunjungan *int
+	Jenis       string
+	NamaFile    string
+	Keterangan  string
+	SHA256      string // opsional; jika diisi harus sama dengan checksum file yang diterima
+}
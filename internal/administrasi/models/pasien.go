@@ -37,4 +37,23 @@ type ExtendedPasienRequest struct {
 	StatusPerkawinan  string `json:"status_perkawinan"`
 	Pekerjaan         string `json:"pekerjaan"`
 	PenanggungJawab   string `json:"penanggung_jawab"`
+	IDPenjamin        int    `json:"id_penjamin"`   // opsional, 0 = pasien umum
+	NomorPeserta      string `json:"nomor_peserta"` // opsional jika pasien sudah punya kepesertaan
+	IDTriase          int    `json:"id_triase"`     // opsional, 0 = tanpa kategori triase
+	DaftarTunggu      bool   `json:"daftar_tunggu"` // masuk daftar tunggu jika kuota penuh / di luar jam
+}
+
+// HasilPendaftaran adalah hasil pendaftaran kunjungan pasien baru maupun pasien lama. Jika
+// pasien masuk daftar tunggu, DaftarTunggu terisi dan antrian, kunjungan, serta billing belum
+// dibuat (IDAntrian / IDKunjungan 0).
+type HasilPendaftaran struct {
+	IDPasien     int64         `json:"id_pasien"`
+	IDRM         string        `json:"id_rm"`
+	IDKunjungan  int64         `json:"id_kunjungan"`
+	IDAntrian    int64         `json:"id_antrian"`
+	NomorAntrian int64         `json:"nomor_antrian"`
+	KodeTiket    string        `json:"kode_tiket"`
+	IDStatus     int           `json:"id_status"`
+	NamaPoli     string        `json:"nama_poli"`
+	DaftarTunggu *DaftarTunggu `json:"daftar_tunggu"`
 }
\ No newline at end of file
@@ -0,0 +1,47 @@
+package models
+
+import "time"
+
+// DataPasien adalah kolom demografis Pasien yang disimpan di setiap versi.
+// Urutan field menentukan urutan JSON, sehingga dua snapshot bisa dibandingkan langsung.
+type DataPasien struct {
+	Nama             string `json:"nama"`
+	TanggalLahir     string `json:"tanggal_lahir"`
+	JenisKelamin     string `json:"jenis_kelamin"`
+	TempatLahir      string `json:"tempat_lahir"`
+	NIK              string `json:"nik"`
+	Kelurahan        string `json:"kelurahan"`
+	Kecamatan        string `json:"kecamatan"`
+	KotaTinggal      string `json:"kota_tinggal"`
+	Alamat           string `json:"alamat"`
+	NoTelp           string `json:"no_telp"`
+	IDAgama          *int   `json:"id_agama"`
+	StatusPerkawinan *int   `json:"status_perkawinan"`
+	Pekerjaan        string `json:"pekerjaan"`
+}
+
+type PasienVersi struct {
+	IDPasien   int        `json:"id_pasien"`
+	Versi      int        `json:"versi"`
+	Data       DataPasien `json:"data"`
+	Sumber     string     `json:"sumber"`
+	DiubahOleh *int       `json:"diubah_oleh"`
+	NamaOleh   *string    `json:"nama_pengubah"`
+	Role       *string    `json:"role"`
+	Keterangan *string    `json:"keterangan"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// PerubahanKolom adalah satu kolom yang berbeda antara dua versi.
+type PerubahanKolom struct {
+	Kolom string      `json:"kolom"`
+	Dari  interface{} `json:"dari"`
+	Ke    interface{} `json:"ke"`
+}
+
+type DiffPasienVersi struct {
+	IDPasien  int              `json:"id_pasien"`
+	DariVersi int              `json:"dari_versi"`
+	KeVersi   int              `json:"ke_versi"`
+	Perubahan []PerubahanKolom `json:"perubahan"`
+}
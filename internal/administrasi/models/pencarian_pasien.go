@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// PencarianPasien adalah kriteria pencarian pasien di meja pendaftaran.
+// Semua kriteria opsional; pasien yang cocok dengan lebih banyak kriteria berada di atas.
+type PencarianPasien struct {
+	Q            string // teks bebas: dicocokkan ke NIK, id_rm, no telp, tanggal lahir, dan nama
+	NIK          string // cocok jika NIK diawali nilai ini
+	IDRM         string
+	NoTelp       string
+	TanggalLahir string // YYYY-MM-DD atau DD-MM-YYYY
+	Nama         string
+	Page         int
+	Limit        int
+}
+
+// HasilPencarianPasien adalah satu pasien beserta kunjungan terakhirnya.
+type HasilPencarianPasien struct {
+	IDPasien          int        `json:"id_pasien"`
+	IDRM              string     `json:"id_rm"`
+	Nama              string     `json:"nama"`
+	NIK               string     `json:"nik"`
+	TanggalLahir      string     `json:"tanggal_lahir"`
+	JenisKelamin      string     `json:"jenis_kelamin"`
+	NoTelp            string     `json:"no_telp"`
+	Alamat            string     `json:"alamat"`
+	KunjunganTerakhir *time.Time `json:"kunjungan_terakhir"`
+	PoliTerakhir      *string    `json:"poli_terakhir"`
+	Skor              int        `json:"skor"`
+	Cocok             []string   `json:"cocok"` // kriteria yang cocok, mis. "nik", "id_rm"
+}
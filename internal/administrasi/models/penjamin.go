@@ -0,0 +1,59 @@
+package models
+
+import "time"
+
+type Penjamin struct {
+	IDPenjamin int              `json:"id_penjamin"`
+	Nama       string           `json:"nama"`
+	Jenis      string           `json:"jenis"` // BPJS, Asuransi, Perusahaan
+	Aktif      bool             `json:"aktif"`
+	Aturan     []AturanPenjamin `json:"aturan,omitempty"`
+	CreatedAt  time.Time        `json:"created_at"`
+	UpdatedAt  time.Time        `json:"updated_at"`
+}
+
+// AturanPenjamin adalah tanggungan penjamin untuk satu komponen tagihan (dokter, obat, tindakan).
+// IDICD9CM hanya dipakai untuk komponen tindakan; kosong berarti berlaku untuk semua tindakan.
+type AturanPenjamin struct {
+	Komponen         string   `json:"komponen"`
+	IDICD9CM         *string  `json:"id_icd9_cm"`
+	PersenTanggungan float64  `json:"persen_tanggungan"`
+	Plafon           *float64 `json:"plafon"`
+}
+
+type KepesertaanPenjamin struct {
+	IDKepesertaan int       `json:"id_kepesertaan"`
+	IDPasien      int       `json:"id_pasien"`
+	NamaPasien    string    `json:"nama_pasien,omitempty"`
+	IDPenjamin    int       `json:"id_penjamin"`
+	NamaPenjamin  string    `json:"nama_penjamin"`
+	JenisPenjamin string    `json:"jenis_penjamin"`
+	NomorPeserta  string    `json:"nomor_peserta"`
+	BerlakuMulai  string    `json:"berlaku_mulai"`
+	BerlakuSampai *string   `json:"berlaku_sampai"`
+	Berlaku       bool      `json:"berlaku"`       // berlaku hari ini
+	Terverifikasi bool      `json:"terverifikasi"` // false = dicatat saat pendaftaran, belum ditanggung
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+type KepesertaanRequest struct {
+	IDPenjamin    int    `json:"id_penjamin"`
+	NomorPeserta  string `json:"nomor_peserta"`
+	BerlakuMulai  string `json:"berlaku_mulai"`  // YYYY-MM-DD, default hari ini
+	BerlakuSampai string `json:"berlaku_sampai"` // YYYY-MM-DD, kosong = tanpa batas
+}
+
+// PilihanPenjamin adalah penjamin yang dipilih saat pendaftaran. IDPenjamin 0 = pasien umum.
+type PilihanPenjamin struct {
+	IDPenjamin   int    `json:"id_penjamin"`
+	NomorPeserta string `json:"nomor_peserta"`
+}
+
+// PembagianTagihan adalah rincian pembagian satu komponen tagihan.
+type PembagianTagihan struct {
+	Komponen   string  `json:"komponen"`
+	IDICD9CM   string  `json:"id_icd9_cm,omitempty"`
+	Total      float64 `json:"total"`
+	Ditanggung float64 `json:"ditanggung_penjamin"`
+	Dibayar    float64 `json:"dibayar_pasien"`
+}
@@ -0,0 +1,52 @@
+package models
+
+import "time"
+
+type TemplatePersetujuan struct {
+	IDTemplate int       `json:"id_template"`
+	Judul      string    `json:"judul"`
+	Jenis      string    `json:"jenis"` // Umum, Berbagi Data, Tindakan
+	Isi        string    `json:"isi"`
+	IDPoli     *int      `json:"id_poli"`    // Umum / Berbagi Data; nil = semua poli
+	IDICD9CM   *string   `json:"id_icd9_cm"` // Tindakan
+	Wajib      bool      `json:"wajib"`
+	Aktif      bool      `json:"aktif"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+type PersetujuanPasien struct {
+	IDPersetujuan     int        `json:"id_persetujuan"`
+	IDKunjungan       int        `json:"id_kunjungan"`
+	IDTemplate        int        `json:"id_template"`
+	Jenis             string     `json:"jenis"`
+	IDICD9CM          *string    `json:"id_icd9_cm"`
+	Judul             string     `json:"judul"`
+	Isi               string     `json:"isi"`
+	Setuju            bool       `json:"setuju"`
+	PenandaTangan     string     `json:"penanda_tangan"` // Pasien, Penanggung Jawab
+	NamaPenandaTangan string     `json:"nama_penanda_tangan"`
+	Hubungan          string     `json:"hubungan"`
+	DitandatanganiAt  time.Time  `json:"ditandatangani_at"`
+	DicatatOleh       *int       `json:"dicatat_oleh"`
+	Role              *string    `json:"role"`
+	DicabutAt         *time.Time `json:"dicabut_at"`
+	AlasanCabut       *string    `json:"alasan_cabut"`
+}
+
+type PersetujuanRequest struct {
+	IDTemplate        int    `json:"id_template"`
+	Setuju            *bool  `json:"setuju"`              // default true
+	PenandaTangan     string `json:"penanda_tangan"`      // Pasien (default) atau Penanggung Jawab
+	NamaPenandaTangan string `json:"nama_penanda_tangan"` // default nama pasien / nama_penanggung_jawab antrian
+	Hubungan          string `json:"hubungan"`            // wajib untuk Penanggung Jawab
+	DitandatanganiAt  string `json:"ditandatangani_at"`   // YYYY-MM-DD HH:MM:SS, default sekarang
+}
+
+// StatusPersetujuanKunjungan berisi persetujuan yang tercatat pada kunjungan dan template wajib
+// umum / berbagi data untuk poli kunjungan yang belum disetujui.
+type StatusPersetujuanKunjungan struct {
+	IDKunjungan  int                   `json:"id_kunjungan"`
+	Persetujuan  []PersetujuanPasien   `json:"persetujuan"`
+	BelumLengkap []TemplatePersetujuan `json:"belum_lengkap"`
+}
@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// KebijakanReschedule adalah aturan pengembalian pasien Ditunda ke antrian untuk satu poli.
+// Mode: Sisip (setelah SisipSetelah pasien), Akhir (akhir antrian), Jam (kembali pada JamKembali).
+// MaksTunda nil berarti tanpa batas.
+type KebijakanReschedule struct {
+	IDPoli       int        `json:"id_poli"`
+	NamaPoli     string     `json:"nama_poli,omitempty"`
+	Mode         string     `json:"mode"`
+	SisipSetelah int        `json:"sisip_setelah"`
+	JamKembali   *string    `json:"jam_kembali"` // HH:MM
+	MaksTunda    *int       `json:"maks_tunda"`
+	Default      bool       `json:"default"` // true jika poli belum punya kebijakan sendiri
+	UpdatedAt    *time.Time `json:"updated_at"`
+}
+
+// HasilReschedule menjelaskan slot yang dipilih saat pasien Ditunda dijadwalkan ulang.
+// Pada mode Jam sebelum JamKembali, antrian tetap Ditunda dan KembaliAt terisi.
+type HasilReschedule struct {
+	IDAntrian     int        `json:"id_antrian"`
+	IDStatus      int        `json:"id_status"`
+	Status        string     `json:"status"`
+	Mode          string     `json:"mode"`
+	PriorityOrder *int64     `json:"new_priority_order"`
+	Posisi        *int       `json:"posisi"` // urutan panggilan saat ini (1 = berikutnya)
+	KembaliAt     *time.Time `json:"kembali_at"`
+	JumlahTunda   int        `json:"jumlah_tunda"`
+	MaksTunda     *int       `json:"maks_tunda"`
+	Keterangan    string     `json:"keterangan"`
+}
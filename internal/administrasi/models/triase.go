@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// KategoriTriase menentukan prioritas panggilan. Bobot makin besar makin didahulukan;
+// MaksTungguMenit adalah target tunggu maksimal sebelum skor antrian dinaikkan.
+type KategoriTriase struct {
+	IDTriase        int       `json:"id_triase"`
+	Kode            string    `json:"kode"`
+	Nama            string    `json:"nama"`
+	Bobot           int       `json:"bobot"`
+	MaksTungguMenit int       `json:"maks_tunggu_menit"`
+	Aktif           bool      `json:"aktif"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// TriaseAntrian adalah kategori triase yang tercatat pada satu antrian. IDTriase nil berarti
+// triase dihapus (pasien kembali ke urutan biasa).
+type TriaseAntrian struct {
+	IDAntrian       int     `json:"id_antrian"`
+	IDTriase        *int    `json:"id_triase"`
+	NamaTriase      *string `json:"nama_triase"`
+	Bobot           int     `json:"bobot"`
+	MaksTungguMenit *int    `json:"maks_tunggu_menit"`
+}
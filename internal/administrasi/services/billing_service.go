@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/c14220110/poliklinik-backend/internal/administrasi/models"
+	"github.com/c14220110/poliklinik-backend/internal/common/antrian"
 	"github.com/c14220110/poliklinik-backend/ws"
 )
 
@@ -43,7 +44,7 @@ func (s *BillingService) GetBillingData(idPoliFilter, statusFilter string) ([]ma
 			JOIN Riwayat_Kunjungan rk ON b.id_kunjungan = rk.id_kunjungan
 			JOIN Rekam_Medis rm ON rk.id_rm = rm.id_rm
 			JOIN Pasien p ON rm.id_pasien = p.id_pasien
-			JOIN Kunjungan_Poli kp ON rk.id_kunjungan = kp.id_kunjungan
+			JOIN Kunjungan_Poli kp ON rk.id_kunjungan = kp.id_kunjungan AND kp.id_antrian_asal IS NULL
 			JOIN Poliklinik pl ON kp.id_poli = pl.id_poli
 	`
 
@@ -161,6 +162,22 @@ func (svc *BillingService) SaveBillingAssessment(
 		return fmt.Errorf("assessment does not belong to given antrian")
 	}
 
+	// 1a. Tindakan yang membutuhkan persetujuan wajib harus sudah disetujui pada kunjungan ini
+	var idKunjungan int
+	if idKunjungan, err = kunjunganAntrian(tx, idAntrian); err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("kunjungan for antrian not found")
+		}
+		return err
+	}
+	kodeTindakan := make([]string, 0, len(in.Tindakan))
+	for _, td := range in.Tindakan {
+		kodeTindakan = append(kodeTindakan, td.Tindakan)
+	}
+	if err = cekPersetujuanTindakan(tx, idKunjungan, kodeTindakan); err != nil {
+		return err
+	}
+
 	// 2. Menyiapkan prepared statements
 	stmtSel, err := tx.Prepare(`SELECT display, harga FROM ICD9_CM WHERE id_icd9_cm = ?`)
 	if err != nil {
@@ -232,7 +249,12 @@ func (svc *BillingService) GetDetailBilling(idKunjungan int) (*models.DetailBill
 					COALESCE(ka.nama, '') AS karyawan_yang_ditugaskan,
 					COALESCE(kb.nama, '') AS nama_administrasi,
 					b.id_status,
-					b.updated_at
+					b.updated_at,
+					pj.nama,
+					kpj.nomor_peserta,
+					kpj.terverifikasi,
+					b.ditanggung_penjamin,
+					b.dibayar_pasien
 			FROM Riwayat_Kunjungan rk
 			JOIN Antrian a ON rk.id_antrian = a.id_antrian
 			JOIN Poliklinik pol ON a.id_poli = pol.id_poli
@@ -244,12 +266,17 @@ func (svc *BillingService) GetDetailBilling(idKunjungan int) (*models.DetailBill
 			LEFT JOIN Billing_Assessment ba ON b.id_assessment = ba.id_assessment
 			LEFT JOIN Karyawan ka ON ba.id_karyawan = ka.id_karyawan
 			LEFT JOIN Karyawan kb ON b.id_karyawan = kb.id_karyawan
+			LEFT JOIN Kepesertaan_Penjamin kpj ON rk.id_kepesertaan = kpj.id_kepesertaan
+			LEFT JOIN Penjamin pj ON kpj.id_penjamin = pj.id_penjamin
 			WHERE rk.id_kunjungan = ?
 	`
 	row := svc.DB.QueryRow(query, idKunjungan)
 	var detail models.DetailBilling
 	var idStatus sql.NullInt64
 	var updatedAt sql.NullTime
+	var namaPenjamin, nomorPeserta sql.NullString
+	var penjaminTerverifikasi sql.NullBool
+	var ditanggungPenjamin, dibayarPasien sql.NullFloat64
 	err = row.Scan(
 			&detail.NamaPasien,
 			&detail.IDRM,
@@ -260,6 +287,11 @@ func (svc *BillingService) GetDetailBilling(idKunjungan int) (*models.DetailBill
 			&detail.NamaAdministrasi,
 			&idStatus,
 			&updatedAt,
+			&namaPenjamin,
+			&nomorPeserta,
+			&penjaminTerverifikasi,
+			&ditanggungPenjamin,
+			&dibayarPasien,
 	)
 	if err == sql.ErrNoRows {
 			return nil, ErrKunjunganNotFound
@@ -273,6 +305,17 @@ func (svc *BillingService) GetDetailBilling(idKunjungan int) (*models.DetailBill
 			waktuDibayarStr := updatedAt.Time.Format("2006-01-02 15:04:05")
 			detail.WaktuDibayar = &waktuDibayarStr
 	}
+	if namaPenjamin.Valid {
+			detail.Penjamin = &namaPenjamin.String
+			detail.NomorPeserta = &nomorPeserta.String
+			detail.PenjaminTerverifikasi = &penjaminTerverifikasi.Bool
+	}
+	if ditanggungPenjamin.Valid {
+			detail.DitanggungPenjamin = &ditanggungPenjamin.Float64
+	}
+	if dibayarPasien.Valid {
+			detail.DibayarPasien = &dibayarPasien.Float64
+	}
 
 	// Query untuk daftar obat (unchanged)
 	obatQuery := `
@@ -360,9 +403,13 @@ func (svc *BillingService) GetDetailBilling(idKunjungan int) (*models.DetailBill
 					ba.total_harga_tindakan
 			FROM Billing_Assessment ba
 			JOIN ICD9_CM icd ON ba.id_icd9_cm = icd.id_icd9_cm
-			WHERE ba.id_assessment = (SELECT id_assessment FROM Riwayat_Kunjungan WHERE id_kunjungan = ?)
+			WHERE ba.id_assessment IN (
+					SELECT id_assessment FROM Riwayat_Kunjungan WHERE id_kunjungan = ?
+					UNION
+					SELECT id_assessment FROM Kunjungan_Poli
+					WHERE id_kunjungan = ? AND id_antrian_asal IS NOT NULL AND id_assessment IS NOT NULL)
 	`
-	rows, err = svc.DB.Query(tindakanQuery, idKunjungan)
+	rows, err = svc.DB.Query(tindakanQuery, idKunjungan, idKunjungan)
 	if err != nil {
 			return nil, err
 	}
@@ -383,11 +430,46 @@ func (svc *BillingService) GetDetailBilling(idKunjungan int) (*models.DetailBill
 			detail.Tindakan = append(detail.Tindakan, tindakan)
 	}
 
+	// Poli rujukan internal yang tarif dokternya digabung ke tagihan ini
+	rujukanQuery := `
+			SELECT
+					pol.nama_poli,
+					sa.status,
+					COALESCE(k.nama, '') AS nama_dokter,
+					COALESCE(td.harga, 0) AS biaya_dokter
+			FROM Kunjungan_Poli kp
+			JOIN Poliklinik pol ON kp.id_poli = pol.id_poli
+			JOIN Antrian a ON kp.id_antrian = a.id_antrian
+			JOIN Status_Antrian sa ON a.id_status = sa.id_status
+			LEFT JOIN Assessment ass ON kp.id_assessment = ass.id_assessment
+			LEFT JOIN Karyawan k ON ass.id_karyawan = k.id_karyawan
+			LEFT JOIN Tarif_Dokter td ON k.id_karyawan = td.id_karyawan
+			WHERE kp.id_kunjungan = ? AND kp.id_antrian_asal IS NOT NULL
+			ORDER BY kp.id_antrian
+	`
+	rows, err = svc.DB.Query(rujukanQuery, idKunjungan)
+	if err != nil {
+			return nil, err
+	}
+	defer rows.Close()
+
+	detail.Rujukan = []models.RujukanDetail{}
+	for rows.Next() {
+			var r models.RujukanDetail
+			if err := rows.Scan(&r.NamaPoli, &r.StatusAntrian, &r.NamaDokter, &r.BiayaDokter); err != nil {
+					return nil, err
+			}
+			detail.Rujukan = append(detail.Rujukan, r)
+	}
+
 	return &detail, nil
 }
 
 var (
 	ErrKunjunganNotFound = errors.New("kunjungan not found")
+	// ErrRujukanBelumSelesai: tagihan gabungan belum bisa dibayar selama antrian rujukan internal
+	// dalam kunjungan yang sama belum pulang / dibatalkan.
+	ErrRujukanBelumSelesai = errors.New("masih ada rujukan internal yang belum selesai pada kunjungan ini")
 )
 
 
@@ -425,21 +507,44 @@ func (s *BillingService) BayarTagihan(
 			return nil, fmt.Errorf("tagihan sudah dibayar")
 	}
 
-	// 2) Hitung harga dokter (jika ada assessment)
+	// 1a) Tagihan digabung untuk semua poli dalam kunjungan; rujukan internal yang masih
+	// berjalan harus selesai dulu agar tarif & tindakannya ikut tertagih.
+	var rujukanAktif int
+	if err = tx.QueryRow(`
+			SELECT COUNT(*)
+			FROM Kunjungan_Poli kp
+			JOIN Antrian a ON kp.id_antrian = a.id_antrian
+			WHERE kp.id_kunjungan = ? AND kp.id_antrian_asal IS NOT NULL AND a.id_status IN (?, ?, ?, ?, ?)`,
+			idKunjungan, antrian.StatusMenunggu, antrian.StatusDitunda, antrian.StatusScreening,
+			antrian.StatusPraKonsultasi, antrian.StatusKonsultasi,
+	).Scan(&rujukanAktif); err != nil {
+			return nil, fmt.Errorf("gagal memeriksa rujukan internal: %v", err)
+	}
+	if rujukanAktif > 0 {
+			return nil, ErrRujukanBelumSelesai
+	}
+	assessments, err := assessmentKunjungan(tx, idKunjungan, idAssessment)
+	if err != nil {
+			return nil, err
+	}
+
+	// 2) Hitung harga dokter per assessment (poli pendaftaran + poli rujukan internal)
 	var hargaDokter float64
-	if idAssessment.Valid {
+	for _, id := range assessments {
 			var idKaryawanAssess int
 			if err = tx.QueryRow(
 					`SELECT id_karyawan FROM Assessment WHERE id_assessment = ?`,
-					idAssessment.Int64,
+					id,
 			).Scan(&idKaryawanAssess); err != nil && err != sql.ErrNoRows {
 					return nil, fmt.Errorf("gagal mengambil id_karyawan: %v", err)
 			}
 			if err == nil {
+					var harga float64
 					tx.QueryRow(
 							`SELECT harga FROM Tarif_Dokter WHERE id_karyawan = ?`,
 							idKaryawanAssess,
-					).Scan(&hargaDokter) // nol jika tidak ada
+					).Scan(&harga) // nol jika tidak ada
+					hargaDokter += harga
 			}
 	}
 
@@ -454,24 +559,56 @@ func (s *BillingService) BayarTagihan(
 
 	// 4) Hitung total tindakan
 	var totalTindakan float64
-	if idAssessment.Valid {
+	if len(assessments) > 0 {
+			args := make([]interface{}, len(assessments))
+			for i, id := range assessments {
+					args[i] = id
+			}
 			tx.QueryRow(
 					`SELECT COALESCE(SUM(total_harga_tindakan),0) 
 					 FROM Billing_Assessment 
-					 WHERE id_assessment = ?`,
-					idAssessment.Int64,
+					 WHERE id_assessment IN (`+strings.TrimSuffix(strings.Repeat("?,", len(args)), ",")+`)`,
+					args...,
 			).Scan(&totalTindakan)
 	}
 
 	// 5) Total keseluruhan
 	total := hargaDokter + totalObat + totalTindakan
 
+	// 5a) Bagi total antara penjamin (kepesertaan yang dipilih saat pendaftaran) dan pasien.
+	// Kepesertaan yang belum terverifikasi diperlakukan sebagai pasien umum.
+	var (
+			idPenjamin   sql.NullInt64
+			namaPenjamin sql.NullString
+	)
+	if err = tx.QueryRow(`
+			SELECT kp.id_penjamin, pj.nama
+			FROM Riwayat_Kunjungan rk
+			LEFT JOIN Kepesertaan_Penjamin kp ON rk.id_kepesertaan = kp.id_kepesertaan AND kp.terverifikasi = 1
+			LEFT JOIN Penjamin pj ON kp.id_penjamin = pj.id_penjamin
+			WHERE rk.id_kunjungan = ?`,
+			idKunjungan,
+	).Scan(&idPenjamin, &namaPenjamin); err != nil {
+			return nil, fmt.Errorf("gagal mengambil penjamin kunjungan: %v", err)
+	}
+	rincian, err := hitungPembagian(tx, idPenjamin.Int64, hargaDokter, totalObat, assessments)
+	if err != nil {
+			return nil, err
+	}
+	var ditanggungPenjamin float64
+	for _, r := range rincian {
+			ditanggungPenjamin += r.Ditanggung
+	}
+	ditanggungPenjamin = pembulatan(ditanggungPenjamin)
+	dibayarPasien := pembulatan(total - ditanggungPenjamin)
+
 	// 6) Update Billing (by PK id_billing)
 	if _, err = tx.Exec(`
 			UPDATE Billing
-			SET tipe_pembayaran = ?, total = ?, id_status = 2
+			SET tipe_pembayaran = ?, total = ?, id_status = 2,
+			    id_penjamin = ?, ditanggung_penjamin = ?, dibayar_pasien = ?
 			WHERE id_billing = ?`,
-			tipePembayaran, total, idBilling,
+			tipePembayaran, total, idPenjamin, ditanggungPenjamin, dibayarPasien, idBilling,
 	); err != nil {
 			return nil, fmt.Errorf("gagal memperbarui billing: %v", err)
 	}
@@ -488,7 +625,7 @@ func (s *BillingService) BayarTagihan(
 			JOIN Antrian A ON B.id_antrian = A.id_antrian
 			JOIN Pasien P   ON A.id_pasien  = P.id_pasien
 			JOIN Riwayat_Kunjungan RK ON B.id_kunjungan = RK.id_kunjungan
-			JOIN Kunjungan_Poli KP   ON RK.id_kunjungan = KP.id_kunjungan
+			JOIN Kunjungan_Poli KP   ON RK.id_kunjungan = KP.id_kunjungan AND KP.id_antrian_asal IS NULL
 			JOIN Poliklinik Pol      ON KP.id_poli = Pol.id_poli
 			WHERE B.id_billing = ?`,
 			idBilling,
@@ -515,7 +652,7 @@ func (s *BillingService) BayarTagihan(
 			},
 	}
 	msg, _ := json.Marshal(payload)
-	ws.HubInstance.Broadcast <- msg
+	ws.HubInstance.Publish(msg, ws.TopicRoleAdministrasi)
 
 	// broadcast kedua
 	payload2 := map[string]interface{}{
@@ -528,7 +665,7 @@ func (s *BillingService) BayarTagihan(
 			},
 	}
 	msg2, _ := json.Marshal(payload2)
-	ws.HubInstance.Broadcast <- msg2
+	ws.HubInstance.Publish(msg2, ws.TopicRoleAdministrasi)
 
 	// 8) prepare response
 	result := map[string]interface{}{
@@ -536,6 +673,11 @@ func (s *BillingService) BayarTagihan(
 			"total_obat":     totalObat,
 			"total_tindakan": totalTindakan,
 			"total":          total,
+			"id_penjamin":    nullIntPtr(idPenjamin),
+			"nama_penjamin":  namaPenjamin.String,
+			"ditanggung_penjamin": ditanggungPenjamin,
+			"dibayar_pasien":      dibayarPasien,
+			"rincian":             rincian,
 	}
 	return result, nil
 }
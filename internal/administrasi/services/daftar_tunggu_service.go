@@ -0,0 +1,314 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/c14220110/poliklinik-backend/internal/administrasi/models"
+	"github.com/c14220110/poliklinik-backend/internal/common/antrian"
+	"github.com/c14220110/poliklinik-backend/ws"
+)
+
+// Status pada Daftar_Tunggu.
+const (
+	DaftarTungguMenunggu    = "Menunggu"
+	DaftarTungguDidaftarkan = "Didaftarkan"
+	DaftarTungguDibatalkan  = "Dibatalkan"
+	DaftarTungguKedaluwarsa = "Kedaluwarsa"
+)
+
+var (
+	ErrDaftarTungguTidakDitemukan = errors.New("daftar tunggu tidak ditemukan")
+	ErrDaftarTungguStatus         = errors.New("status daftar tunggu tidak sesuai")
+	ErrDaftarTungguGanda          = errors.New("pasien sudah ada di daftar tunggu poli ini hari ini")
+)
+
+type DaftarTungguService struct {
+	DB *sql.DB
+}
+
+func NewDaftarTungguService(db *sql.DB) *DaftarTungguService {
+	return &DaftarTungguService{DB: db}
+}
+
+// kapasitasPendaftaran memeriksa kapasitas idPoli di dalam transaksi pendaftaran (baris poli
+// terkunci sampai commit). tunggu true berarti poli tidak bisa menerima pendaftaran tetapi
+// pasien boleh dicatat di daftar tunggu (diminta dan alasannya bukan libur); selain itu
+// penolakan dikembalikan sebagai err.
+func kapasitasPendaftaran(tx *sql.Tx, idPoli int, daftarTunggu bool) (kap *antrian.Kapasitas, tunggu bool, err error) {
+	kap, err = antrian.CekKapasitas(tx, idPoli, time.Now())
+	if err == nil {
+		return kap, false, nil
+	}
+	if kap == nil || !daftarTunggu || !kap.BisaTunggu {
+		return kap, false, err
+	}
+	return kap, true, nil
+}
+
+// masukDaftarTunggu mencatat pasien ke Daftar_Tunggu hari ini. Penjamin dan triase hanya
+// diperiksa keberadaannya; validasi lengkap dilakukan saat pasien didaftarkan.
+func masukDaftarTunggu(
+	tx *sql.Tx,
+	idPasien int64,
+	idRM string,
+	idPoli int,
+	keluhanUtama, namaPenanggungJawab string,
+	penjamin models.PilihanPenjamin,
+	idTriase int,
+	kap *antrian.Kapasitas,
+	actor antrian.Actor,
+) (*models.DaftarTunggu, error) {
+	var ada bool
+	if err := tx.QueryRow(`
+		SELECT EXISTS(SELECT 1 FROM Daftar_Tunggu WHERE id_pasien = ? AND id_poli = ? AND tanggal = ? AND status = ?)`,
+		idPasien, idPoli, kap.Tanggal, DaftarTungguMenunggu).Scan(&ada); err != nil {
+		return nil, fmt.Errorf("gagal memeriksa daftar tunggu: %v", err)
+	}
+	if ada {
+		return nil, ErrDaftarTungguGanda
+	}
+
+	d := &models.DaftarTunggu{
+		IDPasien:            idPasien,
+		IDRM:                idRM,
+		IDPoli:              idPoli,
+		Tanggal:             kap.Tanggal,
+		KeluhanUtama:        keluhanUtama,
+		NamaPenanggungJawab: namaPenanggungJawab,
+		Alasan:              kap.Alasan,
+		Keterangan:          kap.Keterangan,
+		Status:              DaftarTungguMenunggu,
+		CreatedAt:           time.Now(),
+	}
+	if penjamin.IDPenjamin != 0 {
+		var aktif bool
+		err := tx.QueryRow("SELECT aktif FROM Penjamin WHERE id_penjamin = ?", penjamin.IDPenjamin).Scan(&aktif)
+		if err == sql.ErrNoRows {
+			return nil, ErrPenjaminTidakDitemukan
+		}
+		if err != nil {
+			return nil, fmt.Errorf("gagal mengambil penjamin: %v", err)
+		}
+		if !aktif {
+			return nil, fmt.Errorf("%w: penjamin tidak aktif", ErrKepesertaanTidakBerlaku)
+		}
+		d.IDPenjamin = &penjamin.IDPenjamin
+		if penjamin.NomorPeserta != "" {
+			d.NomorPeserta = &penjamin.NomorPeserta
+		}
+	}
+	if idTriase != 0 {
+		var aktif bool
+		err := tx.QueryRow("SELECT aktif FROM Kategori_Triase WHERE id_triase = ?", idTriase).Scan(&aktif)
+		if err == sql.ErrNoRows {
+			return nil, ErrTriaseTidakDitemukan
+		}
+		if err != nil {
+			return nil, fmt.Errorf("gagal mengambil kategori triase: %v", err)
+		}
+		if !aktif {
+			return nil, fmt.Errorf("%w: kategori triase tidak aktif", ErrTriaseTidakValid)
+		}
+		d.IDTriase = &idTriase
+	}
+
+	var createdBy interface{}
+	if actor.IDKaryawan > 0 {
+		createdBy = actor.IDKaryawan
+	}
+	res, err := tx.Exec(`
+		INSERT INTO Daftar_Tunggu
+		  (id_pasien, id_poli, tanggal, keluhan_utama, nama_penanggung_jawab,
+		   id_penjamin, nomor_peserta, id_triase, alasan, status, created_by)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		idPasien, idPoli, d.Tanggal, keluhanUtama, namaPenanggungJawab,
+		d.IDPenjamin, d.NomorPeserta, d.IDTriase, d.Alasan, d.Status, createdBy)
+	if err != nil {
+		return nil, fmt.Errorf("gagal menyimpan daftar tunggu: %v", err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return nil, fmt.Errorf("gagal mengambil id daftar tunggu: %v", err)
+	}
+	d.IDDaftarTunggu = int(id)
+
+	var posisi int
+	if err := tx.QueryRow(`
+		SELECT COUNT(*) FROM Daftar_Tunggu
+		WHERE id_poli = ? AND tanggal = ? AND status = ? AND id_daftar_tunggu <= ?`,
+		idPoli, d.Tanggal, DaftarTungguMenunggu, d.IDDaftarTunggu).Scan(&posisi); err != nil {
+		return nil, fmt.Errorf("gagal menghitung posisi daftar tunggu: %v", err)
+	}
+	d.Posisi = &posisi
+	if err := tx.QueryRow(`SELECT nama_poli FROM Poliklinik WHERE id_poli = ?`, idPoli).Scan(&d.NamaPoli); err != nil {
+		return nil, fmt.Errorf("select nama_poli: %v", err)
+	}
+	return d, nil
+}
+
+// ListDaftarTunggu mengembalikan daftar tunggu pada tanggal tersebut (kosong = hari ini).
+// idPoli 0 dan status kosong berarti tanpa filter. Posisi hanya terisi untuk yang masih Menunggu.
+func (s *DaftarTungguService) ListDaftarTunggu(tanggal string, idPoli int, status string) ([]models.DaftarTunggu, error) {
+	if tanggal == "" {
+		tanggal = time.Now().Format("2006-01-02")
+	}
+	query := `
+		SELECT dt.id_daftar_tunggu, dt.id_pasien, p.Nama, IFNULL(rm.id_rm, ''), dt.id_poli, pl.nama_poli,
+		       DATE_FORMAT(dt.tanggal, '%Y-%m-%d'), IFNULL(dt.keluhan_utama, ''), IFNULL(dt.nama_penanggung_jawab, ''),
+		       dt.id_penjamin, dt.nomor_peserta, dt.id_triase, dt.alasan, dt.status, dt.id_antrian, dt.created_at
+		FROM Daftar_Tunggu dt
+		JOIN Pasien p ON p.id_pasien = dt.id_pasien
+		JOIN Poliklinik pl ON pl.id_poli = dt.id_poli
+		LEFT JOIN Rekam_Medis rm ON rm.id_pasien = dt.id_pasien AND rm.digabung_dari IS NULL
+		WHERE dt.tanggal = ?`
+	args := []interface{}{tanggal}
+	if idPoli > 0 {
+		query += " AND dt.id_poli = ?"
+		args = append(args, idPoli)
+	}
+	if status != "" {
+		query += " AND dt.status = ?"
+		args = append(args, status)
+	}
+	query += " ORDER BY dt.id_poli, dt.id_daftar_tunggu"
+
+	rows, err := s.DB.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("gagal mengambil daftar tunggu: %v", err)
+	}
+	defer rows.Close()
+
+	list := []models.DaftarTunggu{}
+	posisi := map[int]int{}
+	for rows.Next() {
+		var (
+			d                    models.DaftarTunggu
+			idPenjamin, idTriase sql.NullInt64
+			idAntrian            sql.NullInt64
+			nomorPeserta         sql.NullString
+		)
+		if err := rows.Scan(&d.IDDaftarTunggu, &d.IDPasien, &d.NamaPasien, &d.IDRM, &d.IDPoli, &d.NamaPoli,
+			&d.Tanggal, &d.KeluhanUtama, &d.NamaPenanggungJawab,
+			&idPenjamin, &nomorPeserta, &idTriase, &d.Alasan, &d.Status, &idAntrian, &d.CreatedAt); err != nil {
+			return nil, fmt.Errorf("gagal membaca daftar tunggu: %v", err)
+		}
+		d.IDPenjamin = nullIntPtr(idPenjamin)
+		d.IDTriase = nullIntPtr(idTriase)
+		d.IDAntrian = nullIntPtr(idAntrian)
+		d.NomorPeserta = nullStringPtr(nomorPeserta)
+		if d.Status == DaftarTungguMenunggu {
+			posisi[d.IDPoli]++
+			p := posisi[d.IDPoli]
+			d.Posisi = &p
+		}
+		list = append(list, d)
+	}
+	return list, rows.Err()
+}
+
+// Daftarkan membuat kunjungan dan antrian untuk pasien daftar tunggu hari ini. Kapasitas poli
+// diperiksa ulang; jika masih penuh / di luar jam, penolakannya dikembalikan dan pasien tetap
+// di daftar tunggu.
+func (s *DaftarTungguService) Daftarkan(idDaftarTunggu int, actor antrian.Actor) (*models.HasilDaftarTunggu, error) {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("gagal memulai transaksi: %v", err)
+	}
+	defer tx.Rollback()
+
+	var (
+		idPoli                       int
+		idPasien                     int64
+		tanggal, status, keluhan, pj string
+		idPenjamin, idTriase         sql.NullInt64
+		nomorPeserta                 sql.NullString
+	)
+	err = tx.QueryRow(`
+		SELECT id_pasien, id_poli, DATE_FORMAT(tanggal, '%Y-%m-%d'), status,
+		       IFNULL(keluhan_utama, ''), IFNULL(nama_penanggung_jawab, ''), id_penjamin, nomor_peserta, id_triase
+		FROM Daftar_Tunggu WHERE id_daftar_tunggu = ? FOR UPDATE`, idDaftarTunggu,
+	).Scan(&idPasien, &idPoli, &tanggal, &status, &keluhan, &pj, &idPenjamin, &nomorPeserta, &idTriase)
+	if err == sql.ErrNoRows {
+		return nil, ErrDaftarTungguTidakDitemukan
+	}
+	if err != nil {
+		return nil, fmt.Errorf("gagal mengambil daftar tunggu: %v", err)
+	}
+	if status != DaftarTungguMenunggu {
+		return nil, fmt.Errorf("%w: status saat ini %s", ErrDaftarTungguStatus, status)
+	}
+	if today := time.Now().Format("2006-01-02"); tanggal != today {
+		return nil, fmt.Errorf("%w: daftar tunggu tanggal %s", ErrDaftarTungguStatus, tanggal)
+	}
+	if _, err := antrian.CekKapasitas(tx, idPoli, time.Now()); err != nil {
+		return nil, err
+	}
+
+	// Pasien bisa saja sudah digabung setelah masuk daftar tunggu.
+	var mergedInto sql.NullInt64
+	if err := tx.QueryRow("SELECT merged_into FROM Pasien WHERE id_pasien = ?", idPasien).Scan(&mergedInto); err != nil {
+		return nil, fmt.Errorf("gagal mengambil pasien: %v", err)
+	}
+	if mergedInto.Valid {
+		idPasien = mergedInto.Int64
+	}
+	hasil := &models.HasilDaftarTunggu{IDDaftarTunggu: idDaftarTunggu, IDPasien: idPasien}
+	if err := tx.QueryRow(`
+		SELECT id_rm FROM Rekam_Medis WHERE id_pasien = ? AND digabung_dari IS NULL
+		ORDER BY created_at DESC LIMIT 1`, idPasien).Scan(&hasil.IDRM); err != nil {
+		return nil, fmt.Errorf("gagal mengambil Rekam_Medis pasien: %v", err)
+	}
+
+	k, err := buatKunjungan(tx, idPasien, hasil.IDRM, idPoli, keluhan, pj,
+		sql.NullInt64{}, actor.IDKaryawan, actor, "pendaftaran dari daftar tunggu")
+	if err != nil {
+		return nil, err
+	}
+	penjamin := models.PilihanPenjamin{IDPenjamin: int(idPenjamin.Int64), NomorPeserta: nomorPeserta.String}
+	if _, err := pilihPenjamin(tx, idPasien, k.IDKunjungan, penjamin, actor.IDKaryawan); err != nil {
+		return nil, err
+	}
+	if idTriase.Valid {
+		if _, err := tetapkanTriase(tx, k.IDAntrian, int(idTriase.Int64), actor.IDKaryawan); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := tx.Exec(`UPDATE Daftar_Tunggu SET status = ?, id_antrian = ? WHERE id_daftar_tunggu = ?`,
+		DaftarTungguDidaftarkan, k.IDAntrian, idDaftarTunggu); err != nil {
+		return nil, fmt.Errorf("gagal mengupdate daftar tunggu: %v", err)
+	}
+	if err := tx.QueryRow(`SELECT nama_poli FROM Poliklinik WHERE id_poli = ?`, idPoli).Scan(&hasil.NamaPoli); err != nil {
+		return nil, fmt.Errorf("select nama_poli: %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("gagal commit transaksi: %v", err)
+	}
+	antrian.PublishEvent(s.DB, ws.EventAntrianCreated, int(k.IDAntrian), 0, antrian.StatusMenunggu, actor, "pendaftaran dari daftar tunggu")
+
+	hasil.IDKunjungan, hasil.IDAntrian, hasil.NomorAntrian, hasil.KodeTiket = k.IDKunjungan, k.IDAntrian, k.NomorAntrian, k.KodeTiket
+	return hasil, nil
+}
+
+// Batalkan mengeluarkan pasien dari daftar tunggu.
+func (s *DaftarTungguService) Batalkan(idDaftarTunggu int) error {
+	res, err := s.DB.Exec(`UPDATE Daftar_Tunggu SET status = ? WHERE id_daftar_tunggu = ? AND status = ?`,
+		DaftarTungguDibatalkan, idDaftarTunggu, DaftarTungguMenunggu)
+	if err != nil {
+		return fmt.Errorf("gagal membatalkan daftar tunggu: %v", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		var status string
+		err := s.DB.QueryRow("SELECT status FROM Daftar_Tunggu WHERE id_daftar_tunggu = ?", idDaftarTunggu).Scan(&status)
+		if err == sql.ErrNoRows {
+			return ErrDaftarTungguTidakDitemukan
+		}
+		if err != nil {
+			return fmt.Errorf("gagal mengambil daftar tunggu: %v", err)
+		}
+		return fmt.Errorf("%w: status saat ini %s", ErrDaftarTungguStatus, status)
+	}
+	return nil
+}
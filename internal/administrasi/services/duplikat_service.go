@@ -0,0 +1,607 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/c14220110/poliklinik-backend/internal/administrasi/models"
+	"github.com/c14220110/poliklinik-backend/pkg/utils"
+)
+
+// Bobot skor duplikat. Nama paling menentukan; tanggal lahir & no telp menguatkan;
+// alamat paling sering ditulis berbeda sehingga bobotnya paling kecil.
+const (
+	bobotNama         = 0.40
+	bobotTanggalLahir = 0.25
+	bobotNoTelp       = 0.20
+	bobotAlamat       = 0.15
+
+	// DefaultMinSkorDuplikat dipakai jika min_skor tidak diisi.
+	DefaultMinSkorDuplikat = 0.6
+)
+
+// Status pada Pasien_Merge.
+const (
+	MergeDiajukan   = "Diajukan"
+	MergeDigabung   = "Digabung"
+	MergeDitolak    = "Ditolak"
+	MergeDibatalkan = "Dibatalkan"
+)
+
+var (
+	ErrMergeTidakDitemukan = errors.New("pengajuan merge tidak ditemukan")
+	ErrMergeStatus         = errors.New("status pengajuan merge tidak sesuai")
+	ErrPasienSudahDigabung = errors.New("pasien sudah digabung ke pasien lain")
+)
+
+// mergeTarget adalah kolom yang dipindahkan saat merge. Pasien_Merge_Detail hanya boleh
+// merujuk tabel/kolom di daftar ini, sehingga aman dipakai untuk menyusun query revert.
+type mergeTarget struct {
+	tabel string
+	pk    string
+	kolom string
+}
+
+var (
+	targetRiwayatKunjungan = mergeTarget{"Riwayat_Kunjungan", "id_kunjungan", "id_rm"}
+	mergeTargetsPasien     = []mergeTarget{
+		{"Antrian", "id_antrian", "id_pasien"},
+		{"Screening", "id_screening", "id_pasien"},
+		{"Assessment", "id_assessment", "id_pasien"},
+		{"Kepesertaan_Penjamin", "id_kepesertaan", "id_pasien"},
+		{"Lampiran", "id_lampiran", "id_pasien"},
+		{"Rekam_Medis", "id_rm", "id_pasien"},
+	}
+)
+
+func findMergeTarget(tabel, kolom string) (mergeTarget, bool) {
+	for _, t := range append(mergeTargetsPasien, targetRiwayatKunjungan) {
+		if t.tabel == tabel && t.kolom == kolom {
+			return t, true
+		}
+	}
+	return mergeTarget{}, false
+}
+
+type DuplikatService struct {
+	DB *sql.DB
+}
+
+func NewDuplikatService(db *sql.DB) *DuplikatService {
+	return &DuplikatService{DB: db}
+}
+
+const ringkasanPasienColumns = `
+	p.id_pasien,
+	IFNULL((SELECT rm.id_rm FROM Rekam_Medis rm WHERE rm.id_pasien = p.id_pasien AND rm.digabung_dari IS NULL ORDER BY rm.created_at DESC LIMIT 1), ''),
+	p.nama, DATE_FORMAT(p.tanggal_lahir, '%Y-%m-%d'), IFNULL(p.nik, ''), IFNULL(p.no_telp, ''), IFNULL(p.alamat, '')`
+
+func scanRingkasan(scanner interface{ Scan(...interface{}) error }) (models.RingkasanPasien, error) {
+	var r models.RingkasanPasien
+	err := scanner.Scan(&r.IDPasien, &r.IDRM, &r.Nama, &r.TanggalLahir, &r.NIK, &r.NoTelp, &r.Alamat)
+	return r, err
+}
+
+// SkorDuplikat membandingkan dua pasien berdasarkan nama, tanggal lahir, no telp, dan alamat.
+func SkorDuplikat(a, b models.RingkasanPasien) models.SkorDuplikat {
+	skor := models.SkorDuplikat{
+		Nama:         utils.StringSimilarity(a.Nama, b.Nama),
+		TanggalLahir: skorTanggalLahir(a.TanggalLahir, b.TanggalLahir),
+		NoTelp:       skorNoTelp(a.NoTelp, b.NoTelp),
+		Alamat:       utils.TokenSimilarity(a.Alamat, b.Alamat),
+		NIKMirip:     a.NIK != "" && b.NIK != "" && utils.Levenshtein(a.NIK, b.NIK) <= 2,
+	}
+	total := bobotNama*skor.Nama + bobotTanggalLahir*skor.TanggalLahir +
+		bobotNoTelp*skor.NoTelp + bobotAlamat*skor.Alamat
+	skor.Total = math.Round(total*1000) / 1000
+	return skor
+}
+
+// skorTanggalLahir: sama persis = 1; salah ketik satu digit atau tanggal/bulan tertukar = 0.6.
+func skorTanggalLahir(a, b string) float64 {
+	if a == "" || b == "" {
+		return 0
+	}
+	if a == b {
+		return 1
+	}
+	ta, errA := time.Parse("2006-01-02", a)
+	tb, errB := time.Parse("2006-01-02", b)
+	if errA != nil || errB != nil {
+		return 0
+	}
+	if ta.Year() == tb.Year() && int(ta.Month()) == tb.Day() && ta.Day() == int(tb.Month()) {
+		return 0.6
+	}
+	if utils.Levenshtein(ta.Format("20060102"), tb.Format("20060102")) == 1 {
+		return 0.6
+	}
+	return 0
+}
+
+func skorNoTelp(a, b string) float64 {
+	a, b = utils.NormalizePhone(a), utils.NormalizePhone(b)
+	if len(a) < 6 || len(b) < 6 {
+		return 0
+	}
+	if a == b {
+		return 1
+	}
+	if utils.Levenshtein(a, b) == 1 {
+		return 0.7
+	}
+	return 0
+}
+
+// FindDuplicates mencari pasien lain yang kemungkinan sama dengan idPasien.
+// Kandidat disaring dulu di SQL (tanggal lahir sama, 8 digit akhir no telp sama,
+// atau SOUNDEX nama sama), lalu dinilai dengan SkorDuplikat.
+func (s *DuplikatService) FindDuplicates(idPasien int, minSkor float64) (*models.RingkasanPasien, []models.KandidatDuplikat, error) {
+	row := s.DB.QueryRow(`SELECT `+ringkasanPasienColumns+` FROM Pasien p WHERE p.id_pasien = ?`, idPasien)
+	target, err := scanRingkasan(row)
+	if err == sql.ErrNoRows {
+		return nil, nil, fmt.Errorf("pasien dengan id %d tidak ditemukan", idPasien)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("gagal mengambil pasien: %v", err)
+	}
+
+	rows, err := s.DB.Query(`
+		SELECT `+ringkasanPasienColumns+`
+		FROM Pasien p
+		WHERE p.id_pasien <> ? AND p.merged_into IS NULL
+		  AND (p.tanggal_lahir = ?
+		       OR (LENGTH(?) >= 8 AND RIGHT(p.no_telp, 8) = RIGHT(?, 8))
+		       OR SOUNDEX(p.nama) = SOUNDEX(?))
+		LIMIT 500`,
+		idPasien, target.TanggalLahir, target.NoTelp, target.NoTelp, target.Nama,
+	)
+	if err != nil {
+		return nil, nil, fmt.Errorf("gagal mencari kandidat duplikat: %v", err)
+	}
+	defer rows.Close()
+
+	list := []models.KandidatDuplikat{}
+	for rows.Next() {
+		kandidat, err := scanRingkasan(rows)
+		if err != nil {
+			return nil, nil, fmt.Errorf("gagal membaca kandidat duplikat: %v", err)
+		}
+		skor := SkorDuplikat(target, kandidat)
+		if skor.Total >= minSkor {
+			list = append(list, models.KandidatDuplikat{Pasien: kandidat, Skor: skor})
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Skor.Total > list[j].Skor.Total })
+	return &target, list, nil
+}
+
+// FindDuplicatePairs memindai seluruh pasien aktif dan mengembalikan pasangan yang mirip,
+// skor tertinggi lebih dulu. Pasangan disaring di SQL dengan tanggal lahir atau no telp yang sama.
+func (s *DuplikatService) FindDuplicatePairs(minSkor float64, limit int) ([]models.PasanganDuplikat, error) {
+	rows, err := s.DB.Query(`
+		SELECT a.id_pasien, b.id_pasien
+		FROM Pasien a
+		JOIN Pasien b ON a.id_pasien < b.id_pasien
+		 AND (a.tanggal_lahir = b.tanggal_lahir
+		      OR (LENGTH(a.no_telp) >= 8 AND RIGHT(a.no_telp, 8) = RIGHT(b.no_telp, 8)))
+		WHERE a.merged_into IS NULL AND b.merged_into IS NULL
+		LIMIT 5000`)
+	if err != nil {
+		return nil, fmt.Errorf("gagal mencari pasangan duplikat: %v", err)
+	}
+	type pasangan struct{ a, b int }
+	var kandidat []pasangan
+	ids := map[int]bool{}
+	for rows.Next() {
+		var p pasangan
+		if err := rows.Scan(&p.a, &p.b); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("gagal membaca pasangan duplikat: %v", err)
+		}
+		kandidat = append(kandidat, p)
+		ids[p.a], ids[p.b] = true, true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	pasien := map[int]models.RingkasanPasien{}
+	for id := range ids {
+		r, err := scanRingkasan(s.DB.QueryRow(`SELECT `+ringkasanPasienColumns+` FROM Pasien p WHERE p.id_pasien = ?`, id))
+		if err != nil {
+			return nil, fmt.Errorf("gagal mengambil pasien %d: %v", id, err)
+		}
+		pasien[id] = r
+	}
+
+	list := []models.PasanganDuplikat{}
+	for _, p := range kandidat {
+		skor := SkorDuplikat(pasien[p.a], pasien[p.b])
+		if skor.Total >= minSkor {
+			list = append(list, models.PasanganDuplikat{PasienA: pasien[p.a], PasienB: pasien[p.b], Skor: skor})
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Skor.Total > list[j].Skor.Total })
+	if limit > 0 && len(list) > limit {
+		list = list[:limit]
+	}
+	return list, nil
+}
+
+// AjukanMerge mencatat pengajuan penggabungan; data belum dipindahkan sampai disetujui manajemen.
+func (s *DuplikatService) AjukanMerge(req models.MergeRequest, idKaryawan int) (int64, error) {
+	if req.IDPasienUtama == req.IDPasienDuplikat {
+		return 0, fmt.Errorf("pasien utama dan duplikat tidak boleh sama")
+	}
+
+	var utama, duplikat models.RingkasanPasien
+	for _, p := range []struct {
+		id   int
+		dest *models.RingkasanPasien
+	}{{req.IDPasienUtama, &utama}, {req.IDPasienDuplikat, &duplikat}} {
+		var mergedInto sql.NullInt64
+		err := s.DB.QueryRow("SELECT merged_into FROM Pasien WHERE id_pasien = ?", p.id).Scan(&mergedInto)
+		if err == sql.ErrNoRows {
+			return 0, fmt.Errorf("pasien dengan id %d tidak ditemukan", p.id)
+		}
+		if err != nil {
+			return 0, fmt.Errorf("gagal mengambil pasien: %v", err)
+		}
+		if mergedInto.Valid {
+			return 0, fmt.Errorf("%w: id %d", ErrPasienSudahDigabung, p.id)
+		}
+		r, err := scanRingkasan(s.DB.QueryRow(`SELECT `+ringkasanPasienColumns+` FROM Pasien p WHERE p.id_pasien = ?`, p.id))
+		if err != nil {
+			return 0, fmt.Errorf("gagal mengambil pasien: %v", err)
+		}
+		*p.dest = r
+	}
+
+	var pending int
+	if err := s.DB.QueryRow(`
+		SELECT COUNT(*) FROM Pasien_Merge
+		WHERE status = ? AND (id_pasien_duplikat IN (?, ?) OR id_pasien_utama = ?)`,
+		MergeDiajukan, req.IDPasienUtama, req.IDPasienDuplikat, req.IDPasienDuplikat,
+	).Scan(&pending); err != nil {
+		return 0, fmt.Errorf("gagal memeriksa pengajuan merge: %v", err)
+	}
+	if pending > 0 {
+		return 0, fmt.Errorf("%w: pasien sudah memiliki pengajuan merge yang belum diputuskan", ErrMergeStatus)
+	}
+
+	var alasan sql.NullString
+	if req.Alasan != "" {
+		alasan = sql.NullString{String: req.Alasan, Valid: true}
+	}
+	res, err := s.DB.Exec(`
+		INSERT INTO Pasien_Merge (id_pasien_utama, id_pasien_duplikat, skor, alasan, status, diajukan_oleh, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, NOW())`,
+		req.IDPasienUtama, req.IDPasienDuplikat, SkorDuplikat(utama, duplikat).Total, alasan, MergeDiajukan, idKaryawan,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("gagal menyimpan pengajuan merge: %v", err)
+	}
+	return res.LastInsertId()
+}
+
+// lockMerge mengunci baris Pasien_Merge dan memastikan statusnya sesuai.
+func lockMerge(tx *sql.Tx, idMerge int, status string) (idUtama, idDuplikat int, err error) {
+	var current string
+	err = tx.QueryRow(`
+		SELECT id_pasien_utama, id_pasien_duplikat, status
+		FROM Pasien_Merge WHERE id_merge = ? FOR UPDATE`, idMerge,
+	).Scan(&idUtama, &idDuplikat, &current)
+	if err == sql.ErrNoRows {
+		return 0, 0, ErrMergeTidakDitemukan
+	}
+	if err != nil {
+		return 0, 0, fmt.Errorf("gagal mengambil pengajuan merge: %v", err)
+	}
+	if current != status {
+		return 0, 0, fmt.Errorf("%w: status saat ini %s", ErrMergeStatus, current)
+	}
+	return idUtama, idDuplikat, nil
+}
+
+// SetujuiMerge memindahkan Riwayat_Kunjungan (ke id_rm pasien utama) serta Antrian, Screening,
+// Assessment, Kepesertaan_Penjamin, Lampiran, dan Rekam_Medis (ke id_pasien utama), lalu
+// menandai pasien duplikat merged_into = utama. Nilai lama setiap baris dicatat di
+// Pasien_Merge_Detail. Rekam_Medis yang dipindah ditandai digabung_dari = duplikat sehingga
+// pasien utama tetap memiliki satu nomor RM aktif, sementara nomor RM lama tetap menunjuk ke
+// pasien utama.
+func (s *DuplikatService) SetujuiMerge(idMerge, idManagement int) (int64, error) {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("gagal memulai transaksi: %v", err)
+	}
+	defer tx.Rollback()
+
+	idUtama, idDuplikat, err := lockMerge(tx, idMerge, MergeDiajukan)
+	if err != nil {
+		return 0, err
+	}
+
+	// Kunci kedua pasien dengan urutan id tetap agar tidak deadlock dengan merge lain.
+	rows, err := tx.Query(`
+		SELECT id_pasien, merged_into FROM Pasien
+		WHERE id_pasien IN (?, ?) ORDER BY id_pasien FOR UPDATE`, idUtama, idDuplikat)
+	if err != nil {
+		return 0, fmt.Errorf("gagal mengunci pasien: %v", err)
+	}
+	found := 0
+	for rows.Next() {
+		var id int
+		var mergedInto sql.NullInt64
+		if err := rows.Scan(&id, &mergedInto); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("gagal membaca pasien: %v", err)
+		}
+		found++
+		if mergedInto.Valid {
+			rows.Close()
+			return 0, fmt.Errorf("%w: id %d", ErrPasienSudahDigabung, id)
+		}
+	}
+	rows.Close()
+	if found != 2 {
+		return 0, fmt.Errorf("pasien pada pengajuan merge tidak ditemukan")
+	}
+
+	var idRMUtama string
+	err = tx.QueryRow(`
+		SELECT id_rm FROM Rekam_Medis WHERE id_pasien = ? AND digabung_dari IS NULL
+		ORDER BY created_at DESC LIMIT 1`, idUtama).Scan(&idRMUtama)
+	if err != nil {
+		return 0, fmt.Errorf("gagal mengambil Rekam_Medis pasien utama: %v", err)
+	}
+
+	var moved int64
+
+	// 1. Riwayat_Kunjungan dari semua nomor RM duplikat -> nomor RM pasien utama.
+	if _, err := tx.Exec(`
+		INSERT INTO Pasien_Merge_Detail (id_merge, tabel, id_baris, kolom, nilai_lama)
+		SELECT ?, ?, rk.id_kunjungan, ?, rk.id_rm
+		FROM Riwayat_Kunjungan rk
+		JOIN Rekam_Medis rm ON rk.id_rm = rm.id_rm
+		WHERE rm.id_pasien = ?`,
+		idMerge, targetRiwayatKunjungan.tabel, targetRiwayatKunjungan.kolom, idDuplikat,
+	); err != nil {
+		return 0, fmt.Errorf("gagal mencatat Riwayat_Kunjungan: %v", err)
+	}
+	res, err := tx.Exec(`
+		UPDATE Riwayat_Kunjungan rk
+		JOIN Rekam_Medis rm ON rk.id_rm = rm.id_rm
+		SET rk.id_rm = ?
+		WHERE rm.id_pasien = ?`, idRMUtama, idDuplikat)
+	if err != nil {
+		return 0, fmt.Errorf("gagal memindahkan Riwayat_Kunjungan: %v", err)
+	}
+	n, _ := res.RowsAffected()
+	moved += n
+
+	// 2. Antrian, Screening, Assessment, Kepesertaan_Penjamin, Lampiran, Rekam_Medis -> id_pasien utama.
+	// Baris Rekam_Medis yang berasal dari merge sebelumnya tetap mencatat pasien asalnya.
+	if _, err := tx.Exec(`
+		UPDATE Rekam_Medis SET digabung_dari = IFNULL(digabung_dari, ?)
+		WHERE id_pasien = ?`, idDuplikat, idDuplikat); err != nil {
+		return 0, fmt.Errorf("gagal menandai Rekam_Medis duplikat: %v", err)
+	}
+	for _, t := range mergeTargetsPasien {
+		if _, err := tx.Exec(fmt.Sprintf(`
+			INSERT INTO Pasien_Merge_Detail (id_merge, tabel, id_baris, kolom, nilai_lama)
+			SELECT ?, ?, %s, ?, %s FROM %s WHERE %s = ?`, t.pk, t.kolom, t.tabel, t.kolom),
+			idMerge, t.tabel, t.kolom, idDuplikat,
+		); err != nil {
+			return 0, fmt.Errorf("gagal mencatat %s: %v", t.tabel, err)
+		}
+		res, err := tx.Exec(fmt.Sprintf(`UPDATE %s SET %s = ? WHERE %s = ?`, t.tabel, t.kolom, t.kolom), idUtama, idDuplikat)
+		if err != nil {
+			return 0, fmt.Errorf("gagal memindahkan %s: %v", t.tabel, err)
+		}
+		n, _ := res.RowsAffected()
+		moved += n
+	}
+
+	// 3. Tandai duplikat (tidak dihapus) dan tutup pengajuan.
+	if _, err := tx.Exec("UPDATE Pasien SET merged_into = ? WHERE id_pasien = ?", idUtama, idDuplikat); err != nil {
+		return 0, fmt.Errorf("gagal menandai pasien duplikat: %v", err)
+	}
+	if _, err := tx.Exec(`
+		UPDATE Pasien_Merge SET status = ?, diputuskan_oleh = ?, diputuskan_at = NOW()
+		WHERE id_merge = ?`, MergeDigabung, idManagement, idMerge); err != nil {
+		return 0, fmt.Errorf("gagal mengupdate Pasien_Merge: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("gagal commit transaksi: %v", err)
+	}
+	return moved, nil
+}
+
+// TolakMerge menolak pengajuan tanpa memindahkan data.
+func (s *DuplikatService) TolakMerge(idMerge, idManagement int, alasan string) error {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return fmt.Errorf("gagal memulai transaksi: %v", err)
+	}
+	defer tx.Rollback()
+
+	if _, _, err := lockMerge(tx, idMerge, MergeDiajukan); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`
+		UPDATE Pasien_Merge SET status = ?, diputuskan_oleh = ?, diputuskan_at = NOW(),
+		       alasan_batal = NULLIF(?, '')
+		WHERE id_merge = ?`, MergeDitolak, idManagement, alasan, idMerge); err != nil {
+		return fmt.Errorf("gagal mengupdate Pasien_Merge: %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("gagal commit transaksi: %v", err)
+	}
+	return nil
+}
+
+// BatalkanMerge mengembalikan semua baris di Pasien_Merge_Detail ke nilai lamanya dan
+// mengaktifkan kembali pasien duplikat. Tidak bisa dilakukan jika pasien utama sudah
+// digabung lagi ke pasien lain (batalkan merge yang lebih baru terlebih dahulu).
+func (s *DuplikatService) BatalkanMerge(idMerge, idManagement int, alasan string) (int64, error) {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("gagal memulai transaksi: %v", err)
+	}
+	defer tx.Rollback()
+
+	idUtama, idDuplikat, err := lockMerge(tx, idMerge, MergeDigabung)
+	if err != nil {
+		return 0, err
+	}
+	var mergedInto sql.NullInt64
+	if err := tx.QueryRow("SELECT merged_into FROM Pasien WHERE id_pasien = ? FOR UPDATE", idUtama).Scan(&mergedInto); err != nil {
+		return 0, fmt.Errorf("gagal mengambil pasien utama: %v", err)
+	}
+	if mergedInto.Valid {
+		return 0, fmt.Errorf("%w: pasien utama %d sudah digabung ke pasien %d", ErrPasienSudahDigabung, idUtama, mergedInto.Int64)
+	}
+
+	rows, err := tx.Query(`
+		SELECT tabel, id_baris, kolom, nilai_lama
+		FROM Pasien_Merge_Detail WHERE id_merge = ? ORDER BY id_detail`, idMerge)
+	if err != nil {
+		return 0, fmt.Errorf("gagal mengambil Pasien_Merge_Detail: %v", err)
+	}
+	type detail struct{ tabel, idBaris, kolom, nilaiLama string }
+	var details []detail
+	for rows.Next() {
+		var d detail
+		if err := rows.Scan(&d.tabel, &d.idBaris, &d.kolom, &d.nilaiLama); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("gagal membaca Pasien_Merge_Detail: %v", err)
+		}
+		details = append(details, d)
+	}
+	rows.Close()
+
+	var restored int64
+	for _, d := range details {
+		t, ok := findMergeTarget(d.tabel, d.kolom)
+		if !ok {
+			return 0, fmt.Errorf("Pasien_Merge_Detail berisi tabel tidak dikenal: %s.%s", d.tabel, d.kolom)
+		}
+		res, err := tx.Exec(fmt.Sprintf("UPDATE %s SET %s = ? WHERE %s = ?", t.tabel, t.kolom, t.pk), d.nilaiLama, d.idBaris)
+		if err != nil {
+			return 0, fmt.Errorf("gagal mengembalikan %s %s: %v", d.tabel, d.idBaris, err)
+		}
+		n, _ := res.RowsAffected()
+		restored += n
+	}
+	if _, err := tx.Exec(`
+		UPDATE Rekam_Medis SET digabung_dari = NULL
+		WHERE id_pasien = ? AND digabung_dari = ?`, idDuplikat, idDuplikat); err != nil {
+		return 0, fmt.Errorf("gagal mengaktifkan kembali Rekam_Medis duplikat: %v", err)
+	}
+
+	if _, err := tx.Exec("UPDATE Pasien SET merged_into = NULL WHERE id_pasien = ?", idDuplikat); err != nil {
+		return 0, fmt.Errorf("gagal mengaktifkan kembali pasien duplikat: %v", err)
+	}
+	if _, err := tx.Exec(`
+		UPDATE Pasien_Merge SET status = ?, dibatalkan_oleh = ?, dibatalkan_at = NOW(),
+		       alasan_batal = NULLIF(?, '')
+		WHERE id_merge = ?`, MergeDibatalkan, idManagement, alasan, idMerge); err != nil {
+		return 0, fmt.Errorf("gagal mengupdate Pasien_Merge: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("gagal commit transaksi: %v", err)
+	}
+	return restored, nil
+}
+
+// GetMergeList mengembalikan riwayat pengajuan merge, terbaru lebih dulu.
+func (s *DuplikatService) GetMergeList(status string) ([]models.PasienMerge, error) {
+	query := `
+		SELECT m.id_merge, m.id_pasien_utama, pu.nama, m.id_pasien_duplikat, pd.nama,
+		       m.skor, m.alasan, m.status, m.diajukan_oleh, m.diputuskan_oleh, m.diputuskan_at,
+		       m.dibatalkan_oleh, m.dibatalkan_at, m.alasan_batal, m.created_at,
+		       (SELECT COUNT(*) FROM Pasien_Merge_Detail d WHERE d.id_merge = m.id_merge)
+		FROM Pasien_Merge m
+		JOIN Pasien pu ON m.id_pasien_utama = pu.id_pasien
+		JOIN Pasien pd ON m.id_pasien_duplikat = pd.id_pasien`
+	args := []interface{}{}
+	if status != "" {
+		query += " WHERE m.status = ?"
+		args = append(args, status)
+	}
+	query += " ORDER BY m.created_at DESC, m.id_merge DESC"
+
+	rows, err := s.DB.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("gagal mengambil Pasien_Merge: %v", err)
+	}
+	defer rows.Close()
+
+	list := []models.PasienMerge{}
+	for rows.Next() {
+		var (
+			m                                        models.PasienMerge
+			skor                                     sql.NullFloat64
+			alasan, alasanBatal                      sql.NullString
+			diajukanOleh, diputuskanOleh, dibatalkan sql.NullInt64
+			diputuskanAt, dibatalkanAt               sql.NullTime
+		)
+		if err := rows.Scan(&m.IDMerge, &m.IDPasienUtama, &m.NamaPasienUtama, &m.IDPasienDuplikat, &m.NamaDuplikat,
+			&skor, &alasan, &m.Status, &diajukanOleh, &diputuskanOleh, &diputuskanAt,
+			&dibatalkan, &dibatalkanAt, &alasanBatal, &m.CreatedAt, &m.JumlahBaris); err != nil {
+			return nil, fmt.Errorf("gagal membaca Pasien_Merge: %v", err)
+		}
+		if skor.Valid {
+			m.Skor = &skor.Float64
+		}
+		if alasan.Valid {
+			m.Alasan = &alasan.String
+		}
+		if alasanBatal.Valid {
+			m.AlasanBatal = &alasanBatal.String
+		}
+		m.DiajukanOleh = nullIntPtr(diajukanOleh)
+		m.DiputuskanOleh = nullIntPtr(diputuskanOleh)
+		m.DibatalkanOleh = nullIntPtr(dibatalkan)
+		if diputuskanAt.Valid {
+			m.DiputuskanAt = &diputuskanAt.Time
+		}
+		if dibatalkanAt.Valid {
+			m.DibatalkanAt = &dibatalkanAt.Time
+		}
+		list = append(list, m)
+	}
+	return list, rows.Err()
+}
+
+func nullIntPtr(v sql.NullInt64) *int {
+	if !v.Valid {
+		return nil
+	}
+	i := int(v.Int64)
+	return &i
+}
+
+// ParseMinSkor membaca parameter min_skor (0..1); kosong berarti DefaultMinSkorDuplikat.
+func ParseMinSkor(raw string) (float64, error) {
+	if raw == "" {
+		return DefaultMinSkorDuplikat, nil
+	}
+	v, err := strconv.ParseFloat(raw, 64)
+	if err != nil || v < 0 || v > 1 {
+		return 0, fmt.Errorf("min_skor harus berupa angka 0 sampai 1")
+	}
+	return v, nil
+}
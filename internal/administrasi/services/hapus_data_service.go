@@ -0,0 +1,726 @@
+package services
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/c14220110/poliklinik-backend/config"
+	"github.com/c14220110/poliklinik-backend/internal/administrasi/models"
+	"github.com/c14220110/poliklinik-backend/internal/common/antrian"
+	"github.com/c14220110/poliklinik-backend/internal/common/middlewares"
+	"github.com/c14220110/poliklinik-backend/pkg/utils"
+)
+
+// Mode dan status pada Permintaan_Hapus_Data.
+const (
+	HapusModePseudonim = "Pseudonim"
+	HapusModeVault     = "Vault"
+
+	HapusDiajukan     = "Diajukan"
+	HapusDisetujui    = "Disetujui"
+	HapusDitolak      = "Ditolak"
+	HapusDilaksanakan = "Dilaksanakan"
+	HapusDipulihkan   = "Dipulihkan"
+)
+
+var (
+	ErrPermintaanHapusTidakDitemukan = errors.New("permintaan hapus data tidak ditemukan")
+	ErrPermintaanHapusStatus         = errors.New("status permintaan hapus data tidak sesuai")
+	ErrPermintaanHapusTidakValid     = errors.New("permintaan hapus data tidak valid")
+)
+
+// jenisLampiranIdentitas adalah lampiran yang isinya data identitas (bukan dokumen klinis),
+// sehingga ikut disembunyikan saat pseudonimisasi.
+var jenisLampiranIdentitas = []interface{}{"KTP", "Kartu BPJS", "Kartu Asuransi"}
+
+// kolomIdentitas adalah kolom data identitas di luar tabel Pasien yang ikut diganti token.
+// Baris tabel (alias t) dicari lewat ekspresi pasien, dengan join tambahan bila tabel tidak
+// menyimpan id_pasien sendiri. Kolom unik diganti token yang disambung pk barisnya.
+type kolomIdentitas struct {
+	tabel  string
+	pk     string
+	kolom  string
+	join   string
+	pasien string
+	unik   bool
+}
+
+// kolomIdentitasTerkait juga menjadi daftar putih tabel/kolom saat memulihkan isi vault.
+var kolomIdentitasTerkait = []kolomIdentitas{
+	{tabel: "Antrian", pk: "id_antrian", kolom: "nama_penanggung_jawab", pasien: "t.id_pasien"},
+	{tabel: "Kepesertaan_Penjamin", pk: "id_kepesertaan", kolom: "nomor_peserta", pasien: "t.id_pasien", unik: true},
+	{tabel: "Persetujuan_Pasien", pk: "id_persetujuan", kolom: "nama_penanda_tangan", pasien: "a.id_pasien",
+		join: "JOIN Riwayat_Kunjungan rk ON rk.id_kunjungan = t.id_kunjungan JOIN Antrian a ON a.id_antrian = rk.id_antrian"},
+	{tabel: "Daftar_Tunggu", pk: "id_daftar_tunggu", kolom: "nama_penanggung_jawab", pasien: "t.id_pasien"},
+	{tabel: "Daftar_Tunggu", pk: "id_daftar_tunggu", kolom: "nomor_peserta", pasien: "t.id_pasien", unik: true},
+}
+
+func (k kolomIdentitas) kunci() string {
+	return k.tabel + "." + k.kolom
+}
+
+func findKolomIdentitas(kunci string) (kolomIdentitas, bool) {
+	for _, k := range kolomIdentitasTerkait {
+		if k.kunci() == kunci {
+			return k, true
+		}
+	}
+	return kolomIdentitas{}, false
+}
+
+type HapusDataService struct {
+	DB *sql.DB
+}
+
+func NewHapusDataService(db *sql.DB) *HapusDataService {
+	return &HapusDataService{DB: db}
+}
+
+// isiVault adalah data asli yang dienkripsi ke Vault_Pasien pada mode Vault.
+type isiVault struct {
+	Pasien   []pasienVault `json:"pasien"`
+	Lampiran []int         `json:"lampiran"` // lampiran identitas yang disembunyikan
+	// Kolom: "tabel.kolom" -> pk baris -> nilai asli, untuk kolomIdentitasTerkait.
+	Kolom map[string]map[string]string `json:"kolom,omitempty"`
+}
+
+type pasienVault struct {
+	IDPasien int64                   `json:"id_pasien"`
+	Data     models.DataPasien       `json:"data"`
+	Versi    map[int]json.RawMessage `json:"versi"` // nomor versi -> isi Pasien_Versi.data asli
+}
+
+// AjukanPermintaan mencatat permintaan hapus data dari pasien. Satu pasien hanya boleh punya
+// satu permintaan yang belum selesai.
+func (s *HapusDataService) AjukanPermintaan(req models.PermintaanHapusRequest, actor antrian.Actor) (int64, error) {
+	if req.Mode == "" {
+		req.Mode = HapusModePseudonim
+	}
+	if req.Mode != HapusModePseudonim && req.Mode != HapusModeVault {
+		return 0, fmt.Errorf("%w: mode harus Pseudonim atau Vault", ErrPermintaanHapusTidakValid)
+	}
+	req.Alasan = strings.TrimSpace(req.Alasan)
+	if req.Alasan == "" {
+		return 0, fmt.Errorf("%w: alasan wajib diisi", ErrPermintaanHapusTidakValid)
+	}
+	if req.Mode == HapusModeVault && config.LoadConfig().VaultKey == nil {
+		return 0, fmt.Errorf("%w: VAULT_KEY belum dikonfigurasi", utils.ErrVaultKey)
+	}
+
+	var (
+		mergedInto  sql.NullInt64
+		pseudonimAt sql.NullTime
+	)
+	err := s.DB.QueryRow("SELECT merged_into, pseudonim_at FROM Pasien WHERE id_pasien = ?", req.IDPasien).
+		Scan(&mergedInto, &pseudonimAt)
+	if err == sql.ErrNoRows {
+		return 0, fmt.Errorf("pasien dengan id %d tidak ditemukan", req.IDPasien)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("gagal mengambil pasien: %v", err)
+	}
+	if mergedInto.Valid {
+		return 0, fmt.Errorf("%w: gunakan id_pasien %d", ErrPasienSudahDigabung, mergedInto.Int64)
+	}
+	if pseudonimAt.Valid {
+		return 0, fmt.Errorf("%w: data pasien sudah dipseudonimisasi", ErrPermintaanHapusStatus)
+	}
+
+	var aktif int
+	if err := s.DB.QueryRow(`
+		SELECT COUNT(*) FROM Permintaan_Hapus_Data
+		WHERE id_pasien = ? AND status IN (?, ?)`, req.IDPasien, HapusDiajukan, HapusDisetujui,
+	).Scan(&aktif); err != nil {
+		return 0, fmt.Errorf("gagal memeriksa permintaan hapus data: %v", err)
+	}
+	if aktif > 0 {
+		return 0, fmt.Errorf("%w: pasien sudah memiliki permintaan yang belum dilaksanakan", ErrPermintaanHapusStatus)
+	}
+
+	res, err := s.DB.Exec(`
+		INSERT INTO Permintaan_Hapus_Data (id_pasien, mode, alasan, status, diajukan_oleh, role_pengaju, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, NOW())`,
+		req.IDPasien, req.Mode, req.Alasan, HapusDiajukan, actor.IDKaryawan, actor.Role,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("gagal menyimpan permintaan hapus data: %v", err)
+	}
+	return res.LastInsertId()
+}
+
+// lockPermintaanHapus mengunci baris Permintaan_Hapus_Data dan memastikan statusnya sesuai.
+func lockPermintaanHapus(tx *sql.Tx, idPermintaan int, status string) (p models.PermintaanHapusData, err error) {
+	var (
+		diajukanOleh sql.NullInt64
+		rolePengaju  sql.NullString
+	)
+	err = tx.QueryRow(`
+		SELECT id_permintaan, id_pasien, mode, status, diajukan_oleh, role_pengaju
+		FROM Permintaan_Hapus_Data WHERE id_permintaan = ? FOR UPDATE`, idPermintaan,
+	).Scan(&p.IDPermintaan, &p.IDPasien, &p.Mode, &p.Status, &diajukanOleh, &rolePengaju)
+	if err == sql.ErrNoRows {
+		return p, ErrPermintaanHapusTidakDitemukan
+	}
+	if err != nil {
+		return p, fmt.Errorf("gagal mengambil permintaan hapus data: %v", err)
+	}
+	if p.Status != status {
+		return p, fmt.Errorf("%w: status saat ini %s", ErrPermintaanHapusStatus, p.Status)
+	}
+	p.DiajukanOleh = nullIntPtr(diajukanOleh)
+	if rolePengaju.Valid {
+		p.RolePengaju = &rolePengaju.String
+	}
+	return p, nil
+}
+
+// SetujuiPermintaan menyetujui permintaan. Manajemen yang mengajukan tidak boleh menyetujui
+// permintaannya sendiri.
+func (s *HapusDataService) SetujuiPermintaan(idPermintaan, idManagement int, catatan string) error {
+	return s.putuskan(idPermintaan, idManagement, catatan, HapusDisetujui)
+}
+
+// TolakPermintaan menolak permintaan tanpa mengubah data pasien.
+func (s *HapusDataService) TolakPermintaan(idPermintaan, idManagement int, catatan string) error {
+	return s.putuskan(idPermintaan, idManagement, catatan, HapusDitolak)
+}
+
+func (s *HapusDataService) putuskan(idPermintaan, idManagement int, catatan, status string) error {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return fmt.Errorf("gagal memulai transaksi: %v", err)
+	}
+	defer tx.Rollback()
+
+	p, err := lockPermintaanHapus(tx, idPermintaan, HapusDiajukan)
+	if err != nil {
+		return err
+	}
+	if status == HapusDisetujui && p.RolePengaju != nil && *p.RolePengaju == middlewares.RoleManajemen &&
+		p.DiajukanOleh != nil && *p.DiajukanOleh == idManagement {
+		return fmt.Errorf("%w: permintaan tidak boleh disetujui oleh pengajunya sendiri", ErrPermintaanHapusStatus)
+	}
+	if _, err := tx.Exec(`
+		UPDATE Permintaan_Hapus_Data
+		SET status = ?, diputuskan_oleh = ?, diputuskan_at = NOW(), catatan_keputusan = NULLIF(?, '')
+		WHERE id_permintaan = ?`, status, idManagement, strings.TrimSpace(catatan), idPermintaan); err != nil {
+		return fmt.Errorf("gagal mengupdate Permintaan_Hapus_Data: %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("gagal commit transaksi: %v", err)
+	}
+	return nil
+}
+
+// tokenPseudonim membuat token acak 15 karakter heksadesimal (huruf besar).
+func tokenPseudonim() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("gagal membuat token: %v", err)
+	}
+	return strings.ToUpper(hex.EncodeToString(b))[:15], nil
+}
+
+// redaksiDataPasien mengganti nama & NIK dengan token dan mengosongkan telepon, alamat, serta
+// tempat lahir. NIK token diawali "X" sehingga tidak pernah lolos ParseNIK / bentrok dengan NIK asli.
+// Tanggal lahir, jenis kelamin, agama, status perkawinan, dan pekerjaan dipertahankan untuk
+// keperluan klinis dan statistik.
+func redaksiDataPasien(d models.DataPasien, token string) models.DataPasien {
+	d.Nama = "ANONIM " + token
+	d.NIK = "X" + token
+	d.TempatLahir = ""
+	d.Kelurahan = ""
+	d.Kecamatan = ""
+	d.KotaTinggal = ""
+	d.Alamat = ""
+	d.NoTelp = ""
+	return d
+}
+
+// redaksiKolomIdentitas mengganti kolomIdentitasTerkait milik pasien ids dengan token dan
+// mengembalikan nilai aslinya per kolom dan pk baris.
+func redaksiKolomIdentitas(tx *sql.Tx, ids []int64, token string) (map[string]map[string]string, int, error) {
+	placeholder := strings.TrimSuffix(strings.Repeat("?,", len(ids)), ",")
+	args := make([]interface{}, 0, len(ids))
+	for _, id := range ids {
+		args = append(args, id)
+	}
+
+	asli := map[string]map[string]string{}
+	jumlah := 0
+	for _, k := range kolomIdentitasTerkait {
+		rows, err := tx.Query(fmt.Sprintf(`
+			SELECT t.%s, t.%s FROM %s t %s
+			WHERE %s IN (%s) AND t.%s IS NOT NULL AND t.%s <> '' FOR UPDATE`,
+			k.pk, k.kolom, k.tabel, k.join, k.pasien, placeholder, k.kolom, k.kolom), args...)
+		if err != nil {
+			return nil, 0, fmt.Errorf("gagal mengambil %s: %v", k.kunci(), err)
+		}
+		nilai := map[string]string{}
+		for rows.Next() {
+			var pk, v string
+			if err := rows.Scan(&pk, &v); err != nil {
+				rows.Close()
+				return nil, 0, err
+			}
+			nilai[pk] = v
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, 0, err
+		}
+
+		for pk := range nilai {
+			baru := "ANONIM " + token
+			if k.unik {
+				baru = "X" + token + "-" + pk
+			}
+			if _, err := tx.Exec(fmt.Sprintf("UPDATE %s SET %s = ? WHERE %s = ?", k.tabel, k.kolom, k.pk), baru, pk); err != nil {
+				return nil, 0, fmt.Errorf("gagal mengganti %s %s: %v", k.kunci(), pk, err)
+			}
+		}
+		if len(nilai) > 0 {
+			asli[k.kunci()] = nilai
+			jumlah += len(nilai)
+		}
+	}
+	return asli, jumlah, nil
+}
+
+// pasienTergabung mengembalikan idPasien beserta semua pasien duplikat yang (langsung atau
+// bertingkat) sudah digabung ke dalamnya; data identitas mereka adalah orang yang sama.
+func pasienTergabung(tx *sql.Tx, idPasien int64) ([]int64, error) {
+	ids := []int64{idPasien}
+	for i := 0; i < len(ids) && len(ids) < 1000; i++ {
+		rows, err := tx.Query("SELECT id_pasien FROM Pasien WHERE merged_into = ? ORDER BY id_pasien FOR UPDATE", ids[i])
+		if err != nil {
+			return nil, fmt.Errorf("gagal mengambil pasien tergabung: %v", err)
+		}
+		for rows.Next() {
+			var id int64
+			if err := rows.Scan(&id); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			ids = append(ids, id)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+	return ids, nil
+}
+
+func vaultAAD(idPermintaan int, idPasien int) []byte {
+	return []byte(fmt.Sprintf("permintaan:%d:pasien:%d", idPermintaan, idPasien))
+}
+
+// LaksanakanPermintaan menjalankan permintaan yang sudah disetujui: data identitas pasien (dan
+// pasien duplikat yang digabung ke dalamnya), semua snapshot Pasien_Versi-nya, serta
+// kolomIdentitasTerkait (penanggung jawab, nomor peserta, penanda tangan persetujuan, daftar tunggu) diganti
+// token, dan lampiran identitas disembunyikan. Rekam medis, kunjungan, billing, dan isi
+// persetujuan tidak diubah. Pada mode Pseudonim file lampiran identitas dihapus dari disk; pada mode Vault
+// data asli dienkripsi ke Vault_Pasien dan file lampiran tetap disimpan.
+func (s *HapusDataService) LaksanakanPermintaan(idPermintaan, idManagement int) (*models.HasilHapusData, error) {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("gagal memulai transaksi: %v", err)
+	}
+	defer tx.Rollback()
+
+	p, err := lockPermintaanHapus(tx, idPermintaan, HapusDisetujui)
+	if err != nil {
+		return nil, err
+	}
+	key := config.LoadConfig().VaultKey
+	if p.Mode == HapusModeVault && key == nil {
+		return nil, fmt.Errorf("%w: VAULT_KEY belum dikonfigurasi", utils.ErrVaultKey)
+	}
+
+	var (
+		mergedInto  sql.NullInt64
+		pseudonimAt sql.NullTime
+	)
+	if err := tx.QueryRow("SELECT merged_into, pseudonim_at FROM Pasien WHERE id_pasien = ? FOR UPDATE", p.IDPasien).
+		Scan(&mergedInto, &pseudonimAt); err != nil {
+		return nil, fmt.Errorf("gagal mengambil pasien: %v", err)
+	}
+	if mergedInto.Valid {
+		return nil, fmt.Errorf("%w: pasien sudah digabung ke id_pasien %d", ErrPasienSudahDigabung, mergedInto.Int64)
+	}
+	if pseudonimAt.Valid {
+		return nil, fmt.Errorf("%w: data pasien sudah dipseudonimisasi", ErrPermintaanHapusStatus)
+	}
+	ids, err := pasienTergabung(tx, int64(p.IDPasien))
+	if err != nil {
+		return nil, err
+	}
+
+	// Merge yang masih diajukan akan memindahkan data lagi; putuskan dulu sebelum pseudonimisasi.
+	placeholder := strings.TrimSuffix(strings.Repeat("?,", len(ids)), ",")
+	args := make([]interface{}, 0, len(ids)*2+1)
+	args = append(args, MergeDiajukan)
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	var pendingMerge int
+	if err := tx.QueryRow(fmt.Sprintf(`
+		SELECT COUNT(*) FROM Pasien_Merge
+		WHERE status = ? AND (id_pasien_utama IN (%s) OR id_pasien_duplikat IN (%s))`, placeholder, placeholder),
+		args...).Scan(&pendingMerge); err != nil {
+		return nil, fmt.Errorf("gagal memeriksa pengajuan merge: %v", err)
+	}
+	if pendingMerge > 0 {
+		return nil, fmt.Errorf("%w: pasien masih memiliki pengajuan merge yang belum diputuskan", ErrPermintaanHapusStatus)
+	}
+
+	hasil := &models.HasilHapusData{IDPermintaan: idPermintaan, JumlahPasien: len(ids)}
+	vault := isiVault{Lampiran: []int{}}
+	for i, id := range ids {
+		token, err := tokenPseudonim()
+		if err != nil {
+			return nil, err
+		}
+		if i == 0 {
+			hasil.Token = token
+		}
+		asli, err := bacaDataPasien(tx, id)
+		if err != nil {
+			return nil, err
+		}
+		baru := redaksiDataPasien(asli, token)
+		if _, err := tx.Exec(`
+			UPDATE Pasien
+			SET Nama = ?, NIK = ?, Tempat_Lahir = ?, Kelurahan = ?, Kecamatan = ?, kota_tinggal = ?,
+			    Alamat = ?, No_Telp = ?, pseudonim_at = NOW()
+			WHERE id_pasien = ?`,
+			baru.Nama, baru.NIK, baru.TempatLahir, baru.Kelurahan, baru.Kecamatan, baru.KotaTinggal,
+			baru.Alamat, baru.NoTelp, id,
+		); err != nil {
+			return nil, fmt.Errorf("gagal mengganti data pasien %d: %v", id, err)
+		}
+
+		versi, err := redaksiVersiPasien(tx, id, token)
+		if err != nil {
+			return nil, err
+		}
+		hasil.JumlahVersi += len(versi)
+		vault.Pasien = append(vault.Pasien, pasienVault{IDPasien: id, Data: asli, Versi: versi})
+	}
+
+	// Penanggung jawab, nomor peserta penjamin, penanda tangan persetujuan, dan daftar tunggu.
+	vault.Kolom, hasil.JumlahKolom, err = redaksiKolomIdentitas(tx, ids, hasil.Token)
+	if err != nil {
+		return nil, err
+	}
+
+	// Lampiran identitas (scan KTP / kartu) disembunyikan.
+	lampiranArgs := make([]interface{}, 0, len(ids)+len(jenisLampiranIdentitas))
+	for _, id := range ids {
+		lampiranArgs = append(lampiranArgs, id)
+	}
+	lampiranArgs = append(lampiranArgs, jenisLampiranIdentitas...)
+	rows, err := tx.Query(fmt.Sprintf(`
+		SELECT id_lampiran, path FROM Lampiran
+		WHERE id_pasien IN (%s) AND jenis IN (%s) AND deleted_at IS NULL FOR UPDATE`,
+		placeholder, strings.TrimSuffix(strings.Repeat("?,", len(jenisLampiranIdentitas)), ",")),
+		lampiranArgs...)
+	if err != nil {
+		return nil, fmt.Errorf("gagal mengambil lampiran identitas: %v", err)
+	}
+	var berkas []string
+	for rows.Next() {
+		var id int
+		var path string
+		if err := rows.Scan(&id, &path); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		vault.Lampiran = append(vault.Lampiran, id)
+		berkas = append(berkas, path)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	for _, id := range vault.Lampiran {
+		if _, err := tx.Exec(`
+			UPDATE Lampiran SET deleted_at = NOW(), dihapus_oleh = ? WHERE id_lampiran = ?`,
+			idManagement, id); err != nil {
+			return nil, fmt.Errorf("gagal menyembunyikan lampiran %d: %v", id, err)
+		}
+	}
+	hasil.JumlahBerkas = len(vault.Lampiran)
+
+	if p.Mode == HapusModeVault {
+		plaintext, err := json.Marshal(vault)
+		if err != nil {
+			return nil, fmt.Errorf("gagal menyusun data vault: %v", err)
+		}
+		nonce, ciphertext, err := utils.SealVault(key, plaintext, vaultAAD(idPermintaan, p.IDPasien))
+		if err != nil {
+			return nil, err
+		}
+		if _, err := tx.Exec(`
+			INSERT INTO Vault_Pasien (id_permintaan, id_pasien, key_id, nonce, data, created_at)
+			VALUES (?, ?, ?, ?, ?, NOW())`,
+			idPermintaan, p.IDPasien, utils.VaultKeyID(key), nonce, ciphertext,
+		); err != nil {
+			return nil, fmt.Errorf("gagal menyimpan vault: %v", err)
+		}
+	}
+
+	if _, err := tx.Exec(`
+		UPDATE Permintaan_Hapus_Data
+		SET status = ?, dilaksanakan_oleh = ?, dilaksanakan_at = NOW(), token = ?
+		WHERE id_permintaan = ?`, HapusDilaksanakan, idManagement, hasil.Token, idPermintaan); err != nil {
+		return nil, fmt.Errorf("gagal mengupdate Permintaan_Hapus_Data: %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("gagal commit transaksi: %v", err)
+	}
+
+	if p.Mode == HapusModePseudonim {
+		dir := config.LoadConfig().LampiranDir
+		for _, path := range berkas {
+			if err := os.Remove(filepath.Join(dir, filepath.FromSlash(path))); err != nil && !os.IsNotExist(err) {
+				slog.Warn("Gagal menghapus file lampiran", "path", path, "id_permintaan", idPermintaan, "reason", err)
+			}
+		}
+	}
+	return hasil, nil
+}
+
+// redaksiVersiPasien mengganti data identitas di semua snapshot Pasien_Versi pasien dengan token
+// yang sama, dan mengembalikan isi asli per nomor versi.
+func redaksiVersiPasien(tx *sql.Tx, idPasien int64, token string) (map[int]json.RawMessage, error) {
+	rows, err := tx.Query("SELECT versi, data FROM Pasien_Versi WHERE id_pasien = ? FOR UPDATE", idPasien)
+	if err != nil {
+		return nil, fmt.Errorf("gagal mengambil versi pasien: %v", err)
+	}
+	asli := map[int]json.RawMessage{}
+	for rows.Next() {
+		var versi int
+		var data string
+		if err := rows.Scan(&versi, &data); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		asli[versi] = json.RawMessage(data)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for versi, raw := range asli {
+		var d models.DataPasien
+		if err := json.Unmarshal(raw, &d); err != nil {
+			return nil, fmt.Errorf("versi %d pasien %d tidak valid: %v", versi, idPasien, err)
+		}
+		baru, err := json.Marshal(redaksiDataPasien(d, token))
+		if err != nil {
+			return nil, err
+		}
+		if _, err := tx.Exec("UPDATE Pasien_Versi SET data = ? WHERE id_pasien = ? AND versi = ?",
+			string(baru), idPasien, versi); err != nil {
+			return nil, fmt.Errorf("gagal mengganti versi %d pasien %d: %v", versi, idPasien, err)
+		}
+	}
+	return asli, nil
+}
+
+// PulihkanPermintaan membuka Vault_Pasien dan mengembalikan data identitas, snapshot versi,
+// kolomIdentitasTerkait, dan lampiran yang disembunyikan (legal hold). Hanya untuk permintaan mode Vault yang sudah
+// dilaksanakan. Data vault dikosongkan setelah dipulihkan.
+func (s *HapusDataService) PulihkanPermintaan(idPermintaan, idManagement int, alasan string) (*models.HasilHapusData, error) {
+	alasan = strings.TrimSpace(alasan)
+	if alasan == "" {
+		return nil, fmt.Errorf("%w: alasan pemulihan wajib diisi", ErrPermintaanHapusTidakValid)
+	}
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("gagal memulai transaksi: %v", err)
+	}
+	defer tx.Rollback()
+
+	p, err := lockPermintaanHapus(tx, idPermintaan, HapusDilaksanakan)
+	if err != nil {
+		return nil, err
+	}
+	if p.Mode != HapusModeVault {
+		return nil, fmt.Errorf("%w: permintaan mode Pseudonim tidak bisa dipulihkan", ErrPermintaanHapusStatus)
+	}
+
+	var (
+		keyID       string
+		nonce, data []byte
+	)
+	err = tx.QueryRow(`
+		SELECT key_id, nonce, data FROM Vault_Pasien
+		WHERE id_permintaan = ? AND data IS NOT NULL FOR UPDATE`, idPermintaan,
+	).Scan(&keyID, &nonce, &data)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("%w: data vault tidak ada", ErrPermintaanHapusStatus)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("gagal mengambil vault: %v", err)
+	}
+	key := config.LoadConfig().VaultKey
+	if key == nil {
+		return nil, fmt.Errorf("%w: VAULT_KEY belum dikonfigurasi", utils.ErrVaultKey)
+	}
+	if utils.VaultKeyID(key) != keyID {
+		return nil, fmt.Errorf("%w: VAULT_KEY berbeda dengan kunci saat data disimpan (%s)", utils.ErrVaultKey, keyID)
+	}
+	plaintext, err := utils.OpenVault(key, nonce, data, vaultAAD(idPermintaan, p.IDPasien))
+	if err != nil {
+		return nil, err
+	}
+	var vault isiVault
+	if err := json.Unmarshal(plaintext, &vault); err != nil {
+		return nil, fmt.Errorf("%w: %v", utils.ErrVaultRusak, err)
+	}
+
+	hasil := &models.HasilHapusData{IDPermintaan: idPermintaan, JumlahPasien: len(vault.Pasien)}
+	for _, pv := range vault.Pasien {
+		d := pv.Data
+		var dipakai int
+		if err := tx.QueryRow("SELECT COUNT(*) FROM Pasien WHERE NIK = ? AND id_pasien <> ?", d.NIK, pv.IDPasien).
+			Scan(&dipakai); err != nil {
+			return nil, fmt.Errorf("gagal memeriksa NIK: %v", err)
+		}
+		if dipakai > 0 {
+			return nil, fmt.Errorf("%w: NIK pasien %d sudah terdaftar lagi sebagai pasien lain", ErrNIKDipakai, pv.IDPasien)
+		}
+		if _, err := tx.Exec(`
+			UPDATE Pasien
+			SET Nama = ?, NIK = ?, Tempat_Lahir = ?, Kelurahan = ?, Kecamatan = ?, kota_tinggal = ?,
+			    Alamat = ?, No_Telp = ?, pseudonim_at = NULL
+			WHERE id_pasien = ?`,
+			d.Nama, d.NIK, d.TempatLahir, d.Kelurahan, d.Kecamatan, d.KotaTinggal, d.Alamat, d.NoTelp, pv.IDPasien,
+		); err != nil {
+			return nil, fmt.Errorf("gagal memulihkan pasien %d: %v", pv.IDPasien, err)
+		}
+		for versi, raw := range pv.Versi {
+			if _, err := tx.Exec("UPDATE Pasien_Versi SET data = ? WHERE id_pasien = ? AND versi = ?",
+				string(raw), pv.IDPasien, versi); err != nil {
+				return nil, fmt.Errorf("gagal memulihkan versi %d pasien %d: %v", versi, pv.IDPasien, err)
+			}
+		}
+		hasil.JumlahVersi += len(pv.Versi)
+	}
+	for _, id := range vault.Lampiran {
+		if _, err := tx.Exec("UPDATE Lampiran SET deleted_at = NULL, dihapus_oleh = NULL WHERE id_lampiran = ?", id); err != nil {
+			return nil, fmt.Errorf("gagal memulihkan lampiran %d: %v", id, err)
+		}
+	}
+	hasil.JumlahBerkas = len(vault.Lampiran)
+	for kunci, nilai := range vault.Kolom {
+		k, ok := findKolomIdentitas(kunci)
+		if !ok {
+			return nil, fmt.Errorf("%w: vault berisi kolom tidak dikenal %s", utils.ErrVaultRusak, kunci)
+		}
+		for pk, v := range nilai {
+			if _, err := tx.Exec(fmt.Sprintf("UPDATE %s SET %s = ? WHERE %s = ?", k.tabel, k.kolom, k.pk), v, pk); err != nil {
+				return nil, fmt.Errorf("gagal memulihkan %s %s: %v", kunci, pk, err)
+			}
+		}
+		hasil.JumlahKolom += len(nilai)
+	}
+
+	if _, err := tx.Exec(`
+		UPDATE Vault_Pasien SET nonce = NULL, data = NULL, dibuka_at = NOW()
+		WHERE id_permintaan = ?`, idPermintaan); err != nil {
+		return nil, fmt.Errorf("gagal mengosongkan vault: %v", err)
+	}
+	if _, err := tx.Exec(`
+		UPDATE Permintaan_Hapus_Data
+		SET status = ?, dipulihkan_oleh = ?, dipulihkan_at = NOW(), alasan_pulih = ?
+		WHERE id_permintaan = ?`, HapusDipulihkan, idManagement, alasan, idPermintaan); err != nil {
+		return nil, fmt.Errorf("gagal mengupdate Permintaan_Hapus_Data: %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("gagal commit transaksi: %v", err)
+	}
+	return hasil, nil
+}
+
+// GetPermintaanList mengembalikan riwayat permintaan hapus data, terbaru lebih dulu.
+func (s *HapusDataService) GetPermintaanList(status string) ([]models.PermintaanHapusData, error) {
+	query := `
+		SELECT h.id_permintaan, h.id_pasien, p.nama, h.mode, h.alasan, h.status,
+		       h.diajukan_oleh, h.role_pengaju, h.diputuskan_oleh, h.diputuskan_at, h.catatan_keputusan,
+		       h.dilaksanakan_oleh, h.dilaksanakan_at, h.token,
+		       h.dipulihkan_oleh, h.dipulihkan_at, h.alasan_pulih, h.created_at
+		FROM Permintaan_Hapus_Data h
+		JOIN Pasien p ON p.id_pasien = h.id_pasien`
+	args := []interface{}{}
+	if status != "" {
+		query += " WHERE h.status = ?"
+		args = append(args, status)
+	}
+	query += " ORDER BY h.created_at DESC, h.id_permintaan DESC"
+
+	rows, err := s.DB.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("gagal mengambil Permintaan_Hapus_Data: %v", err)
+	}
+	defer rows.Close()
+
+	list := []models.PermintaanHapusData{}
+	for rows.Next() {
+		var (
+			h                                              models.PermintaanHapusData
+			diajukan, diputuskan, dilaksanakan, dipulihkan sql.NullInt64
+			role, catatan, token, alasanPulih              sql.NullString
+			diputuskanAt, dilaksanakanAt, dipulihkanAt     sql.NullTime
+		)
+		if err := rows.Scan(&h.IDPermintaan, &h.IDPasien, &h.NamaPasien, &h.Mode, &h.Alasan, &h.Status,
+			&diajukan, &role, &diputuskan, &diputuskanAt, &catatan,
+			&dilaksanakan, &dilaksanakanAt, &token,
+			&dipulihkan, &dipulihkanAt, &alasanPulih, &h.CreatedAt); err != nil {
+			return nil, fmt.Errorf("gagal membaca Permintaan_Hapus_Data: %v", err)
+		}
+		h.DiajukanOleh = nullIntPtr(diajukan)
+		h.DiputuskanOleh = nullIntPtr(diputuskan)
+		h.DilaksanakanOleh = nullIntPtr(dilaksanakan)
+		h.DipulihkanOleh = nullIntPtr(dipulihkan)
+		h.RolePengaju = nullStringPtr(role)
+		h.CatatanKeputusan = nullStringPtr(catatan)
+		h.Token = nullStringPtr(token)
+		h.AlasanPulih = nullStringPtr(alasanPulih)
+		h.DiputuskanAt = nullTimePtr(diputuskanAt)
+		h.DilaksanakanAt = nullTimePtr(dilaksanakanAt)
+		h.DipulihkanAt = nullTimePtr(dipulihkanAt)
+		list = append(list, h)
+	}
+	return list, rows.Err()
+}
+
+func nullStringPtr(v sql.NullString) *string {
+	if !v.Valid {
+		return nil
+	}
+	return &v.String
+}
+
+func nullTimePtr(v sql.NullTime) *time.Time {
+	if !v.Valid {
+		return nil
+	}
+	return &v.Time
+}
@@ -0,0 +1,395 @@
+package services
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/c14220110/poliklinik-backend/config"
+	"github.com/c14220110/poliklinik-backend/internal/administrasi/models"
+	"github.com/c14220110/poliklinik-backend/internal/common/antrian"
+	"github.com/c14220110/poliklinik-backend/pkg/utils"
+)
+
+// VersiImport adalah sumber Pasien_Versi untuk pasien yang dibuat lewat import CSV.
+const VersiImport = "import"
+
+// MaksBarisImport membatasi jumlah baris per import agar satu transaksi tidak terlalu besar.
+const MaksBarisImport = 5000
+
+var (
+	ErrImportTidakValid = errors.New("file import tidak valid")
+	ErrImportDitolak    = errors.New("import dibatalkan karena ada baris yang gagal / konflik")
+)
+
+// kolomImportWajib dan kolomImportOpsional adalah header CSV yang dikenali (tidak peka huruf
+// besar/kecil); kolom lain ditolak agar salah ketik tidak diam-diam diabaikan. kota_tempat_tinggal diterima sebagai alias kota_tinggal, sama seperti
+// payload pendaftaran.
+var (
+	kolomImportWajib    = []string{"nama", "tanggal_lahir", "nik", "agama", "status_perkawinan"}
+	kolomImportOpsional = []string{"jenis_kelamin", "tempat_lahir", "kelurahan", "kecamatan", "kota_tinggal",
+		"alamat", "no_telp", "pekerjaan"}
+	aliasKolomImport = map[string]string{"kota_tempat_tinggal": "kota_tinggal"}
+)
+
+// formatTanggalImport adalah format tanggal_lahir yang diterima. Spreadsheet lama sering
+// memakai DD/MM/YYYY; format ISO tetap dicoba lebih dulu.
+var formatTanggalImport = []string{"2006-01-02", "02/01/2006", "02-01-2006"}
+
+type ImportPasienService struct {
+	DB *sql.DB
+}
+
+func NewImportPasienService(db *sql.DB) *ImportPasienService {
+	return &ImportPasienService{DB: db}
+}
+
+// barisImport adalah baris CSV yang lolos validasi: indeksnya di laporan.Baris dan data pasiennya.
+type barisImport struct {
+	idx    int
+	pasien models.Pasien
+}
+
+// Import membaca CSV pasien (delimiter koma atau titik koma, header di baris pertama),
+// memvalidasi setiap baris, lalu—jika bukan dry-run dan semua baris valid—menyimpan semua
+// pasien beserta Rekam_Medis-nya dalam satu transaksi. Jika ada baris yang gagal / konflik
+// laporan tetap dikembalikan bersama ErrImportDitolak dan tidak ada yang disimpan.
+func (s *ImportPasienService) Import(src io.Reader, dryRun bool, actor antrian.Actor) (*models.LaporanImportPasien, error) {
+	records, err := bacaCSVImport(src)
+	if err != nil {
+		return nil, err
+	}
+	agama, err := s.petaAgama()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(records)-1 > MaksBarisImport {
+		return nil, fmt.Errorf("%w: maksimal %d baris per import, file berisi %d", ErrImportTidakValid, MaksBarisImport, len(records)-1)
+	}
+
+	dikenal := map[string]bool{}
+	for _, k := range append(kolomImportWajib, kolomImportOpsional...) {
+		dikenal[k] = true
+	}
+	header := records[0]
+	kolom := map[string]int{}
+	for i, h := range header {
+		h = strings.ToLower(strings.TrimSpace(h))
+		if alias, ok := aliasKolomImport[h]; ok {
+			h = alias
+		}
+		if !dikenal[h] {
+			return nil, fmt.Errorf("%w: kolom %q tidak dikenal", ErrImportTidakValid, h)
+		}
+		if _, dup := kolom[h]; dup {
+			return nil, fmt.Errorf("%w: kolom %s muncul lebih dari sekali", ErrImportTidakValid, h)
+		}
+		kolom[h] = i
+	}
+	for _, k := range kolomImportWajib {
+		if _, ok := kolom[k]; !ok {
+			return nil, fmt.Errorf("%w: kolom wajib %s tidak ada di header", ErrImportTidakValid, k)
+		}
+	}
+	ambil := func(rec []string, nama string) string {
+		if i, ok := kolom[nama]; ok && i < len(rec) {
+			return strings.TrimSpace(rec[i])
+		}
+		return ""
+	}
+
+	laporan := &models.LaporanImportPasien{DryRun: dryRun, Baris: []models.BarisImportPasien{}}
+	strict := config.LoadConfig().NIKStrict
+	nikDiFile := map[string]int{}
+	var valid []barisImport
+	for i, rec := range records[1:] {
+		if barisKosong(rec) {
+			continue
+		}
+		b := models.BarisImportPasien{
+			Baris:  i + 2,
+			NIK:    ambil(rec, "nik"),
+			Nama:   ambil(rec, "nama"),
+			Status: models.ImportValid,
+		}
+		p, errs, peringatan := validasiBarisImport(ambil, rec, agama, strict)
+		b.Error, b.Peringatan = errs, peringatan
+
+		if b.NIK != "" {
+			if baris, ok := nikDiFile[b.NIK]; ok {
+				b.Status = models.ImportKonflik
+				b.Error = append(b.Error, fmt.Sprintf("NIK sama dengan baris %d", baris))
+			} else {
+				nikDiFile[b.NIK] = b.Baris
+			}
+		}
+		if len(b.Error) > 0 && b.Status == models.ImportValid {
+			b.Status = models.ImportGagal
+		}
+		if b.Status == models.ImportValid {
+			valid = append(valid, barisImport{idx: len(laporan.Baris), pasien: p})
+		}
+		laporan.Baris = append(laporan.Baris, b)
+	}
+	laporan.Total = len(laporan.Baris)
+	if laporan.Total == 0 {
+		return nil, fmt.Errorf("%w: tidak ada baris data", ErrImportTidakValid)
+	}
+
+	if err := cekNIKTerdaftar(s.DB, laporan, valid); err != nil {
+		return nil, err
+	}
+	hitungLaporanImport(laporan)
+	if laporan.Gagal > 0 || laporan.Konflik > 0 {
+		if dryRun {
+			return laporan, nil
+		}
+		return laporan, ErrImportDitolak
+	}
+	if dryRun {
+		return laporan, nil
+	}
+
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// Cek ulang di dalam transaksi: NIK bisa saja didaftarkan loket setelah dry-run.
+	if err := cekNIKTerdaftar(tx, laporan, valid); err != nil {
+		return nil, err
+	}
+	hitungLaporanImport(laporan)
+	if laporan.Konflik > 0 {
+		return laporan, ErrImportDitolak
+	}
+
+	for _, v := range valid {
+		b, p := &laporan.Baris[v.idx], v.pasien
+		res, err := tx.Exec(`
+			INSERT INTO Pasien
+			  (Nama, Tanggal_Lahir, Jenis_Kelamin, Tempat_Lahir,
+			   NIK, Kelurahan, Kecamatan, Alamat, No_Telp, kota_tinggal,
+			   id_agama, status_perkawinan, pekerjaan)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			p.Nama, p.TanggalLahir, p.JenisKelamin, p.TempatLahir,
+			p.NIK, p.Kelurahan, p.Kecamatan, p.Alamat, p.NoTelp, p.KotaTinggal,
+			p.IDAgama, p.StatusPerkawinan, p.Pekerjaan,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("baris %d: gagal menyimpan pasien: %v", b.Baris, err)
+		}
+		idPasien, err := res.LastInsertId()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := simpanVersiPasien(tx, idPasien, VersiImport, &actor,
+			fmt.Sprintf("import CSV baris %d", b.Baris)); err != nil {
+			return nil, fmt.Errorf("baris %d: %v", b.Baris, err)
+		}
+		idRM, err := buatRekamMedis(tx, idPasien)
+		if err != nil {
+			return nil, fmt.Errorf("baris %d: %v", b.Baris, err)
+		}
+		b.IDPasien, b.IDRM, b.Status = &idPasien, idRM, models.ImportDiimpor
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	hitungLaporanImport(laporan)
+	return laporan, nil
+}
+
+// bacaCSVImport membaca seluruh CSV. Delimiter ditebak dari header: titik koma dipakai jika
+// header mengandung ';' dan tidak mengandung ',' (format ekspor Excel berlokal Indonesia).
+func bacaCSVImport(src io.Reader) ([][]string, error) {
+	raw, err := io.ReadAll(src)
+	if err != nil {
+		return nil, fmt.Errorf("gagal membaca file: %v", err)
+	}
+	raw = bytes.TrimPrefix(raw, []byte("\xef\xbb\xbf")) // BOM UTF-8 dari Excel
+	headerLine := raw
+	if i := bytes.IndexByte(raw, '\n'); i >= 0 {
+		headerLine = raw[:i]
+	}
+
+	r := csv.NewReader(bytes.NewReader(raw))
+	if bytes.ContainsRune(headerLine, ';') && !bytes.ContainsRune(headerLine, ',') {
+		r.Comma = ';'
+	}
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+	records, err := r.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrImportTidakValid, err)
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("%w: file kosong", ErrImportTidakValid)
+	}
+	return records, nil
+}
+
+func barisKosong(rec []string) bool {
+	for _, v := range rec {
+		if strings.TrimSpace(v) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// validasiBarisImport menerapkan aturan yang sama dengan pendaftaran pasien baru.
+func validasiBarisImport(ambil func([]string, string) string, rec []string, agama map[string]int, strictNIK bool) (models.Pasien, []string, []string) {
+	errs := []string{}
+	for _, k := range kolomImportWajib {
+		if ambil(rec, k) == "" {
+			errs = append(errs, k+" wajib diisi")
+		}
+	}
+
+	p := models.Pasien{
+		Nama:         ambil(rec, "nama"),
+		JenisKelamin: ambil(rec, "jenis_kelamin"),
+		TempatLahir:  ambil(rec, "tempat_lahir"),
+		NIK:          ambil(rec, "nik"),
+		Kelurahan:    ambil(rec, "kelurahan"),
+		Kecamatan:    ambil(rec, "kecamatan"),
+		KotaTinggal:  ambil(rec, "kota_tinggal"),
+		Alamat:       ambil(rec, "alamat"),
+		NoTelp:       ambil(rec, "no_telp"),
+		Pekerjaan:    ambil(rec, "pekerjaan"),
+	}
+
+	if raw := ambil(rec, "tanggal_lahir"); raw != "" {
+		for _, layout := range formatTanggalImport {
+			if t, err := time.Parse(layout, raw); err == nil {
+				p.TanggalLahir = t
+				break
+			}
+		}
+		if p.TanggalLahir.IsZero() {
+			errs = append(errs, "format tanggal_lahir tidak valid (YYYY-MM-DD atau DD/MM/YYYY)")
+		} else if p.TanggalLahir.After(time.Now()) {
+			errs = append(errs, "tanggal_lahir tidak boleh di masa depan")
+		}
+	}
+
+	var peringatan []string
+	if p.NIK != "" {
+		var err error
+		if peringatan, err = utils.ValidateNIK(p.NIK, p.TanggalLahir, p.JenisKelamin, strictNIK); err != nil {
+			errs = append(errs, err.Error())
+		}
+		if len(peringatan) == 0 {
+			peringatan = nil
+		}
+	}
+
+	if raw := ambil(rec, "agama"); raw != "" {
+		if id, ok := agama[strings.ToLower(raw)]; ok {
+			p.IDAgama = id
+		} else {
+			errs = append(errs, fmt.Sprintf("agama %q tidak ditemukan", raw))
+		}
+	}
+
+	switch strings.ToLower(ambil(rec, "status_perkawinan")) {
+	case "":
+	case "sudah kawin":
+		p.StatusPerkawinan = 1
+	case "belum kawin":
+		p.StatusPerkawinan = 0
+	default:
+		errs = append(errs, "status_perkawinan harus 'sudah kawin' atau 'belum kawin'")
+	}
+
+	if len(errs) == 0 {
+		errs = nil
+	}
+	return p, errs, peringatan
+}
+
+// petaAgama memetakan nama agama (huruf kecil) ke id_agama.
+func (s *ImportPasienService) petaAgama() (map[string]int, error) {
+	rows, err := s.DB.Query("SELECT id_agama, nama FROM Agama")
+	if err != nil {
+		return nil, fmt.Errorf("gagal mengambil daftar agama: %v", err)
+	}
+	defer rows.Close()
+
+	agama := map[string]int{}
+	for rows.Next() {
+		var id int
+		var nama string
+		if err := rows.Scan(&id, &nama); err != nil {
+			return nil, err
+		}
+		agama[strings.ToLower(strings.TrimSpace(nama))] = id
+	}
+	return agama, rows.Err()
+}
+
+// cekNIKTerdaftar menandai baris valid yang NIK-nya sudah dipakai pasien terdaftar
+// (termasuk pasien yang sudah digabung) sebagai konflik.
+func cekNIKTerdaftar(q antrian.Queryer, laporan *models.LaporanImportPasien, valid []barisImport) error {
+	const batch = 500
+	for start := 0; start < len(valid); start += batch {
+		end := start + batch
+		if end > len(valid) {
+			end = len(valid)
+		}
+		byNIK := map[string]*models.BarisImportPasien{}
+		args := make([]interface{}, 0, end-start)
+		for _, v := range valid[start:end] {
+			byNIK[v.pasien.NIK] = &laporan.Baris[v.idx]
+			args = append(args, v.pasien.NIK)
+		}
+		rows, err := q.Query(fmt.Sprintf(
+			"SELECT id_pasien, NIK FROM Pasien WHERE NIK IN (%s)",
+			strings.TrimSuffix(strings.Repeat("?,", len(args)), ",")), args...)
+		if err != nil {
+			return fmt.Errorf("gagal memeriksa NIK terdaftar: %v", err)
+		}
+		for rows.Next() {
+			var id int64
+			var nik string
+			if err := rows.Scan(&id, &nik); err != nil {
+				rows.Close()
+				return err
+			}
+			if b, ok := byNIK[nik]; ok && b.Status != models.ImportKonflik {
+				b.Status = models.ImportKonflik
+				b.IDPasien = &id
+				b.Error = append(b.Error, fmt.Sprintf("NIK sudah terdaftar (id_pasien %d)", id))
+			}
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func hitungLaporanImport(l *models.LaporanImportPasien) {
+	l.Valid, l.Konflik, l.Gagal, l.Diimpor = 0, 0, 0, 0
+	for _, b := range l.Baris {
+		switch b.Status {
+		case models.ImportValid:
+			l.Valid++
+		case models.ImportDiimpor:
+			l.Diimpor++
+		case models.ImportKonflik:
+			l.Konflik++
+		case models.ImportGagal:
+			l.Gagal++
+		}
+	}
+}
@@ -0,0 +1,471 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/c14220110/poliklinik-backend/config"
+	"github.com/c14220110/poliklinik-backend/internal/administrasi/models"
+	"github.com/c14220110/poliklinik-backend/internal/common/antrian"
+	"github.com/c14220110/poliklinik-backend/ws"
+)
+
+// Status pada Janji_Temu.
+const (
+	JanjiTemuDipesan     = "Dipesan"
+	JanjiTemuHadir       = "Hadir"
+	JanjiTemuDibatalkan  = "Dibatalkan"
+	JanjiTemuKedaluwarsa = "Kedaluwarsa"
+
+	// maksJanjiTemuHari adalah seberapa jauh ke depan janji temu boleh dipesan.
+	maksJanjiTemuHari = 90
+)
+
+var (
+	ErrJanjiTemuTidakDitemukan = errors.New("janji temu tidak ditemukan")
+	ErrJanjiTemuStatus         = errors.New("status janji temu tidak sesuai")
+	ErrJanjiTemuTanggal        = errors.New("tanggal janji temu tidak valid")
+	ErrKuotaJanjiTemuPenuh     = errors.New("kuota janji temu pada tanggal tersebut sudah penuh")
+	ErrJanjiTemuGanda          = errors.New("pasien sudah memiliki janji temu di poli dan tanggal yang sama")
+	ErrJanjiTemuLewatCutoff    = errors.New("batas waktu check-in janji temu sudah lewat")
+	ErrJanjiTemuAntrianAktif   = errors.New("pasien masih memiliki antrian aktif di poli ini hari ini")
+)
+
+type JanjiTemuService struct {
+	DB *sql.DB
+}
+
+func NewJanjiTemuService(db *sql.DB) *JanjiTemuService {
+	return &JanjiTemuService{DB: db}
+}
+
+// cutoffHariIni mengembalikan jam cutoff check-in untuk tanggal now.
+func cutoffHariIni(now time.Time) time.Time {
+	y, m, d := now.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, now.Location()).Add(config.LoadConfig().JanjiTemuCutoff)
+}
+
+// kuotaTerkunci membaca kuota & jumlah janji temu aktif. Baris Counter_Janji_Temu dikunci
+// sampai transaksi selesai sehingga dua pemesanan bersamaan tidak melewati kuota.
+func kuotaTerkunci(tx *sql.Tx, idPoli int, tanggal string) (kuota, terisi, counter int, err error) {
+	if _, err = tx.Exec(`INSERT IGNORE INTO Counter_Janji_Temu (id_poli, tanggal, count) VALUES (?, ?, 0)`, idPoli, tanggal); err != nil {
+		return 0, 0, 0, fmt.Errorf("gagal menyiapkan Counter_Janji_Temu: %v", err)
+	}
+	if err = tx.QueryRow(`
+		SELECT count FROM Counter_Janji_Temu
+		WHERE id_poli = ? AND tanggal = ? FOR UPDATE`, idPoli, tanggal,
+	).Scan(&counter); err != nil {
+		return 0, 0, 0, fmt.Errorf("gagal mengunci Counter_Janji_Temu: %v", err)
+	}
+	if kuota, terisi, err = hitungKuota(tx, idPoli, tanggal); err != nil {
+		return 0, 0, 0, err
+	}
+	return kuota, terisi, counter, nil
+}
+
+func hitungKuota(q antrian.Queryer, idPoli int, tanggal string) (kuota, terisi int, err error) {
+	err = q.QueryRow(`
+		SELECT COALESCE(k.kuota, p.kuota_janji_temu)
+		FROM Poliklinik p
+		LEFT JOIN Kuota_Janji_Temu k ON k.id_poli = p.id_poli AND k.tanggal = ?
+		WHERE p.id_poli = ?`, tanggal, idPoli,
+	).Scan(&kuota)
+	if err == sql.ErrNoRows {
+		return 0, 0, fmt.Errorf("poliklinik dengan id %d tidak ditemukan", idPoli)
+	}
+	if err != nil {
+		return 0, 0, fmt.Errorf("gagal mengambil kuota janji temu: %v", err)
+	}
+	if err = q.QueryRow(`
+		SELECT COUNT(*) FROM Janji_Temu
+		WHERE id_poli = ? AND tanggal = ? AND status IN (?, ?)`,
+		idPoli, tanggal, JanjiTemuDipesan, JanjiTemuHadir,
+	).Scan(&terisi); err != nil {
+		return 0, 0, fmt.Errorf("gagal menghitung janji temu: %v", err)
+	}
+	return kuota, terisi, nil
+}
+
+// GetKuota mengembalikan kuota, jumlah terisi, dan sisa slot janji temu satu poli pada satu tanggal.
+func (s *JanjiTemuService) GetKuota(idPoli int, tanggal string) (models.KuotaJanjiTemu, error) {
+	kuota, terisi, err := hitungKuota(s.DB, idPoli, tanggal)
+	if err != nil {
+		return models.KuotaJanjiTemu{}, err
+	}
+	return models.KuotaJanjiTemu{
+		IDPoli:  idPoli,
+		Tanggal: tanggal,
+		Kuota:   kuota,
+		Terisi:  terisi,
+		Sisa:    max(kuota-terisi, 0),
+	}, nil
+}
+
+// SetKuota mengatur kuota janji temu. Tanggal kosong mengubah kuota default poli;
+// jika diisi, kuota hanya berlaku untuk tanggal tersebut.
+func (s *JanjiTemuService) SetKuota(idPoli int, tanggal string, kuota, idManagement int) error {
+	if kuota < 0 {
+		return fmt.Errorf("kuota tidak boleh negatif")
+	}
+	var res sql.Result
+	var err error
+	if tanggal == "" {
+		res, err = s.DB.Exec("UPDATE Poliklinik SET kuota_janji_temu = ? WHERE id_poli = ?", kuota, idPoli)
+	} else {
+		res, err = s.DB.Exec(`
+			INSERT INTO Kuota_Janji_Temu (id_poli, tanggal, kuota, updated_by)
+			SELECT id_poli, ?, ?, ? FROM Poliklinik WHERE id_poli = ?
+			ON DUPLICATE KEY UPDATE kuota = VALUES(kuota), updated_by = VALUES(updated_by)`,
+			tanggal, kuota, idManagement, idPoli)
+	}
+	if err != nil {
+		return fmt.Errorf("gagal menyimpan kuota janji temu: %v", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		var exists int
+		if err := s.DB.QueryRow("SELECT COUNT(*) FROM Poliklinik WHERE id_poli = ?", idPoli).Scan(&exists); err == nil && exists == 0 {
+			return fmt.Errorf("poliklinik dengan id %d tidak ditemukan", idPoli)
+		}
+	}
+	return nil
+}
+
+// BuatJanjiTemu memesan slot janji temu untuk tanggal mendatang (mulai besok).
+func (s *JanjiTemuService) BuatJanjiTemu(req models.JanjiTemuRequest, idKaryawan int) (*models.JanjiTemu, error) {
+	tgl, err := time.ParseInLocation("2006-01-02", req.Tanggal, time.Local)
+	if err != nil {
+		return nil, fmt.Errorf("%w: gunakan format YYYY-MM-DD", ErrJanjiTemuTanggal)
+	}
+	y, m, d := time.Now().Date()
+	hariIni := time.Date(y, m, d, 0, 0, 0, 0, time.Local)
+	if !tgl.After(hariIni) {
+		return nil, fmt.Errorf("%w: janji temu minimal untuk besok, untuk hari ini gunakan pendaftaran kunjungan", ErrJanjiTemuTanggal)
+	}
+	if tgl.After(hariIni.AddDate(0, 0, maksJanjiTemuHari)) {
+		return nil, fmt.Errorf("%w: maksimal %d hari ke depan", ErrJanjiTemuTanggal, maksJanjiTemuHari)
+	}
+
+	var mergedInto sql.NullInt64
+	err = s.DB.QueryRow("SELECT merged_into FROM Pasien WHERE id_pasien = ?", req.IDPasien).Scan(&mergedInto)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("pasien dengan id %d tidak ditemukan", req.IDPasien)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("gagal mengambil pasien: %v", err)
+	}
+	if mergedInto.Valid {
+		return nil, fmt.Errorf("%w: gunakan id_pasien %d", ErrPasienSudahDigabung, mergedInto.Int64)
+	}
+	if req.IDDokter != nil {
+		var isDokter int
+		if err := s.DB.QueryRow(`
+			SELECT COUNT(*)
+			FROM Karyawan k
+			JOIN Detail_Role_Karyawan drk ON k.id_karyawan = drk.id_karyawan
+			JOIN Role r ON drk.id_role = r.id_role
+			WHERE k.id_karyawan = ? AND k.deleted_at IS NULL AND r.nama_role = 'Dokter'`,
+			*req.IDDokter,
+		).Scan(&isDokter); err != nil {
+			return nil, fmt.Errorf("gagal memeriksa dokter: %v", err)
+		}
+		if isDokter == 0 {
+			return nil, fmt.Errorf("dokter dengan id %d tidak ditemukan", *req.IDDokter)
+		}
+	}
+
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("gagal memulai transaksi: %v", err)
+	}
+	defer tx.Rollback()
+
+	kuota, terisi, counter, err := kuotaTerkunci(tx, req.IDPoli, req.Tanggal)
+	if err != nil {
+		return nil, err
+	}
+	if terisi >= kuota {
+		return nil, ErrKuotaJanjiTemuPenuh
+	}
+	var ganda int
+	if err := tx.QueryRow(`
+		SELECT COUNT(*) FROM Janji_Temu
+		WHERE id_pasien = ? AND id_poli = ? AND tanggal = ? AND status = ?`,
+		req.IDPasien, req.IDPoli, req.Tanggal, JanjiTemuDipesan,
+	).Scan(&ganda); err != nil {
+		return nil, fmt.Errorf("gagal memeriksa janji temu ganda: %v", err)
+	}
+	if ganda > 0 {
+		return nil, ErrJanjiTemuGanda
+	}
+
+	nomorSlot := counter + 1
+	if _, err := tx.Exec(`UPDATE Counter_Janji_Temu SET count = ? WHERE id_poli = ? AND tanggal = ?`,
+		nomorSlot, req.IDPoli, req.Tanggal); err != nil {
+		return nil, fmt.Errorf("gagal mengupdate Counter_Janji_Temu: %v", err)
+	}
+	res, err := tx.Exec(`
+		INSERT INTO Janji_Temu
+		  (id_pasien, id_poli, id_dokter, tanggal, nomor_slot, keluhan_utama, status, created_by, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, NOW())`,
+		req.IDPasien, req.IDPoli, req.IDDokter, req.Tanggal, nomorSlot, req.KeluhanUtama, JanjiTemuDipesan, idKaryawan,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("gagal menyimpan janji temu: %v", err)
+	}
+	idJanjiTemu, err := res.LastInsertId()
+	if err != nil {
+		return nil, fmt.Errorf("gagal mengambil id janji temu: %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("gagal commit transaksi: %v", err)
+	}
+	return s.GetJanjiTemuByID(int(idJanjiTemu))
+}
+
+const janjiTemuSelect = `
+	SELECT jt.id_janji_temu, jt.id_pasien, p.nama,
+	       IFNULL((SELECT rm.id_rm FROM Rekam_Medis rm WHERE rm.id_pasien = p.id_pasien AND rm.digabung_dari IS NULL ORDER BY rm.created_at DESC LIMIT 1), ''),
+	       jt.id_poli, pl.nama_poli, jt.id_dokter, k.nama,
+	       DATE_FORMAT(jt.tanggal, '%Y-%m-%d'), jt.nomor_slot, IFNULL(jt.keluhan_utama, ''),
+	       jt.status, jt.id_antrian, jt.alasan_batal, jt.created_at
+	FROM Janji_Temu jt
+	JOIN Pasien p ON jt.id_pasien = p.id_pasien
+	JOIN Poliklinik pl ON jt.id_poli = pl.id_poli
+	LEFT JOIN Karyawan k ON jt.id_dokter = k.id_karyawan`
+
+func scanJanjiTemu(scanner interface{ Scan(...interface{}) error }) (models.JanjiTemu, error) {
+	var (
+		j           models.JanjiTemu
+		idDokter    sql.NullInt64
+		namaDokter  sql.NullString
+		idAntrian   sql.NullInt64
+		alasanBatal sql.NullString
+	)
+	err := scanner.Scan(&j.IDJanjiTemu, &j.IDPasien, &j.NamaPasien, &j.IDRM, &j.IDPoli, &j.NamaPoli,
+		&idDokter, &namaDokter, &j.Tanggal, &j.NomorSlot, &j.KeluhanUtama,
+		&j.Status, &idAntrian, &alasanBatal, &j.CreatedAt)
+	if err != nil {
+		return j, err
+	}
+	j.IDDokter = nullIntPtr(idDokter)
+	j.IDAntrian = nullIntPtr(idAntrian)
+	if namaDokter.Valid {
+		j.NamaDokter = &namaDokter.String
+	}
+	if alasanBatal.Valid {
+		j.AlasanBatal = &alasanBatal.String
+	}
+	return j, nil
+}
+
+func (s *JanjiTemuService) GetJanjiTemuByID(idJanjiTemu int) (*models.JanjiTemu, error) {
+	j, err := scanJanjiTemu(s.DB.QueryRow(janjiTemuSelect+" WHERE jt.id_janji_temu = ?", idJanjiTemu))
+	if err == sql.ErrNoRows {
+		return nil, ErrJanjiTemuTidakDitemukan
+	}
+	if err != nil {
+		return nil, fmt.Errorf("gagal mengambil janji temu: %v", err)
+	}
+	return &j, nil
+}
+
+// ListJanjiTemu mengembalikan janji temu pada satu tanggal, urut poli lalu nomor slot.
+// idPoli = 0 dan status kosong berarti tanpa filter; idPasien > 0 menampilkan semua tanggal milik pasien.
+func (s *JanjiTemuService) ListJanjiTemu(tanggal string, idPoli, idPasien int, status string) ([]models.JanjiTemu, error) {
+	query := janjiTemuSelect + " WHERE 1=1"
+	args := []interface{}{}
+	if tanggal != "" {
+		query += " AND jt.tanggal = ?"
+		args = append(args, tanggal)
+	}
+	if idPoli > 0 {
+		query += " AND jt.id_poli = ?"
+		args = append(args, idPoli)
+	}
+	if idPasien > 0 {
+		query += " AND jt.id_pasien = ?"
+		args = append(args, idPasien)
+	}
+	if status != "" {
+		query += " AND jt.status = ?"
+		args = append(args, status)
+	}
+	query += " ORDER BY jt.tanggal, jt.id_poli, jt.nomor_slot"
+
+	rows, err := s.DB.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("gagal mengambil janji temu: %v", err)
+	}
+	defer rows.Close()
+
+	list := []models.JanjiTemu{}
+	for rows.Next() {
+		j, err := scanJanjiTemu(rows)
+		if err != nil {
+			return nil, fmt.Errorf("gagal membaca janji temu: %v", err)
+		}
+		list = append(list, j)
+	}
+	return list, rows.Err()
+}
+
+// BatalkanJanjiTemu membatalkan janji temu yang belum di-check-in; slotnya kembali tersedia untuk kuota.
+func (s *JanjiTemuService) BatalkanJanjiTemu(idJanjiTemu int, alasan string) error {
+	res, err := s.DB.Exec(`
+		UPDATE Janji_Temu SET status = ?, alasan_batal = NULLIF(?, '')
+		WHERE id_janji_temu = ? AND status = ?`,
+		JanjiTemuDibatalkan, alasan, idJanjiTemu, JanjiTemuDipesan)
+	if err != nil {
+		return fmt.Errorf("gagal membatalkan janji temu: %v", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		var status string
+		err := s.DB.QueryRow("SELECT status FROM Janji_Temu WHERE id_janji_temu = ?", idJanjiTemu).Scan(&status)
+		if err == sql.ErrNoRows {
+			return ErrJanjiTemuTidakDitemukan
+		}
+		if err != nil {
+			return fmt.Errorf("gagal mengambil janji temu: %v", err)
+		}
+		return fmt.Errorf("%w: status saat ini %s", ErrJanjiTemuStatus, status)
+	}
+	return nil
+}
+
+// CheckIn mengubah janji temu hari ini menjadi Antrian dengan priority_order = nomor_slot.
+// Hanya bisa dilakukan pada tanggal janji temu dan sebelum jam cutoff.
+func (s *JanjiTemuService) CheckIn(idJanjiTemu int, actor antrian.Actor, penjamin models.PilihanPenjamin) (*models.CheckInJanjiTemu, error) {
+	now := time.Now()
+
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("gagal memulai transaksi: %v", err)
+	}
+	defer tx.Rollback()
+
+	var (
+		idPasien, idPoli, nomorSlot int
+		tanggal, status, keluhan    string
+	)
+	err = tx.QueryRow(`
+		SELECT id_pasien, id_poli, nomor_slot, DATE_FORMAT(tanggal, '%Y-%m-%d'), status, IFNULL(keluhan_utama, '')
+		FROM Janji_Temu WHERE id_janji_temu = ? FOR UPDATE`, idJanjiTemu,
+	).Scan(&idPasien, &idPoli, &nomorSlot, &tanggal, &status, &keluhan)
+	if err == sql.ErrNoRows {
+		return nil, ErrJanjiTemuTidakDitemukan
+	}
+	if err != nil {
+		return nil, fmt.Errorf("gagal mengambil janji temu: %v", err)
+	}
+	if status != JanjiTemuDipesan {
+		return nil, fmt.Errorf("%w: status saat ini %s", ErrJanjiTemuStatus, status)
+	}
+	if tanggal != now.Format("2006-01-02") {
+		return nil, fmt.Errorf("%w: janji temu dijadwalkan tanggal %s", ErrJanjiTemuTanggal, tanggal)
+	}
+	if !now.Before(cutoffHariIni(now)) {
+		return nil, fmt.Errorf("%w (%s)", ErrJanjiTemuLewatCutoff, cutoffHariIni(now).Format("15:04"))
+	}
+
+	// Pasien bisa saja sudah digabung setelah memesan; kunjungan dicatat ke pasien utamanya.
+	var mergedInto sql.NullInt64
+	if err := tx.QueryRow("SELECT merged_into FROM Pasien WHERE id_pasien = ?", idPasien).Scan(&mergedInto); err != nil {
+		return nil, fmt.Errorf("gagal mengambil pasien: %v", err)
+	}
+	if mergedInto.Valid {
+		idPasien = int(mergedInto.Int64)
+	}
+
+	// Pasien yang sudah didaftarkan langsung (atau check-in lewat janji temu lain) di poli yang
+	// sama hari ini tidak boleh mendapat antrian kedua. Baris pasien dikunci agar dua check-in
+	// bersamaan tidak sama-sama lolos.
+	var terkunci int
+	if err := tx.QueryRow("SELECT COUNT(*) FROM Pasien WHERE id_pasien = ? FOR UPDATE", idPasien).Scan(&terkunci); err != nil {
+		return nil, fmt.Errorf("gagal mengunci pasien: %v", err)
+	}
+	var antrianAktif bool
+	if err := tx.QueryRow(`
+		SELECT EXISTS(
+			SELECT 1 FROM Antrian
+			WHERE id_pasien = ? AND id_poli = ? AND DATE(created_at) = CURDATE()
+			  AND id_status IN (?, ?, ?, ?, ?))`,
+		idPasien, idPoli, antrian.StatusMenunggu, antrian.StatusDitunda, antrian.StatusScreening,
+		antrian.StatusPraKonsultasi, antrian.StatusKonsultasi,
+	).Scan(&antrianAktif); err != nil {
+		return nil, fmt.Errorf("gagal memeriksa antrian aktif pasien: %v", err)
+	}
+	if antrianAktif {
+		return nil, ErrJanjiTemuAntrianAktif
+	}
+	var idRM string
+	if err := tx.QueryRow(`
+		SELECT id_rm FROM Rekam_Medis WHERE id_pasien = ? AND digabung_dari IS NULL
+		ORDER BY created_at DESC LIMIT 1`, idPasien).Scan(&idRM); err != nil {
+		return nil, fmt.Errorf("gagal mengambil Rekam_Medis pasien: %v", err)
+	}
+
+	k, err := buatKunjungan(tx, int64(idPasien), idRM, idPoli, keluhan, "",
+		sql.NullInt64{Int64: int64(nomorSlot), Valid: true}, actor.IDKaryawan, actor, "check-in janji temu")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := pilihPenjamin(tx, int64(idPasien), k.IDKunjungan, penjamin, actor.IDKaryawan); err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(`UPDATE Janji_Temu SET status = ?, id_antrian = ? WHERE id_janji_temu = ?`,
+		JanjiTemuHadir, k.IDAntrian, idJanjiTemu); err != nil {
+		return nil, fmt.Errorf("gagal mengupdate janji temu: %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("gagal commit transaksi: %v", err)
+	}
+	antrian.PublishEvent(s.DB, ws.EventAntrianCreated, int(k.IDAntrian), 0, antrian.StatusMenunggu, actor, "check-in janji temu")
+
+	return &models.CheckInJanjiTemu{
+		IDJanjiTemu:   idJanjiTemu,
+		IDAntrian:     k.IDAntrian,
+		NomorAntrian:  k.NomorAntrian,
+		PriorityOrder: nomorSlot,
+		KodeTiket:     k.KodeTiket,
+		IDKunjungan:   k.IDKunjungan,
+	}, nil
+}
+
+// ExpireJanjiTemu menandai Kedaluwarsa semua janji temu Dipesan yang tanggalnya sudah lewat,
+// termasuk janji temu hari ini jika now sudah melewati jam cutoff.
+func (s *JanjiTemuService) ExpireJanjiTemu(now time.Time) (int64, error) {
+	batas := now.Format("2006-01-02")
+	cond := "tanggal < ?"
+	if !now.Before(cutoffHariIni(now)) {
+		cond = "tanggal <= ?"
+	}
+	res, err := s.DB.Exec(`
+		UPDATE Janji_Temu SET status = ?
+		WHERE status = ? AND `+cond, JanjiTemuKedaluwarsa, JanjiTemuDipesan, batas)
+	if err != nil {
+		return 0, fmt.Errorf("gagal mengubah janji temu kedaluwarsa: %v", err)
+	}
+	return res.RowsAffected()
+}
+
+// RunExpiry menjalankan ExpireJanjiTemu setiap interval sampai ctx dibatalkan.
+func (s *JanjiTemuService) RunExpiry(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if n, err := s.ExpireJanjiTemu(time.Now()); err != nil {
+			slog.Error("Gagal memproses janji temu kedaluwarsa", "reason", err)
+		} else if n > 0 {
+			slog.Info("Janji temu kedaluwarsa", "jumlah", n)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/c14220110/poliklinik-backend/internal/common/antrian"
+)
+
+// kunjunganBaru adalah hasil buatKunjungan.
+type kunjunganBaru struct {
+	IDKunjungan  int64
+	IDAntrian    int64
+	NomorAntrian int64
+	KodeTiket    string
+	IDStatus     int
+}
+
+// buatKunjungan membuat Riwayat_Kunjungan, Kunjungan_Poli, Antrian hari ini (status Menunggu),
+// log & kode tiket antrian, serta Billing kosong untuk pasien yang id_rm-nya sudah ada.
+// priorityOrder yang tidak Valid berarti priority_order = nomor_antrian (urutan kedatangan).
+// idKaryawanBilling boleh nil. Harus dipanggil di dalam transaksi; event antrian dipublikasikan
+// oleh pemanggil setelah commit.
+func buatKunjungan(
+	tx *sql.Tx,
+	idPasien int64,
+	idRM string,
+	idPoli int,
+	keluhanUtama, namaPenanggungJawab string,
+	priorityOrder sql.NullInt64,
+	idKaryawanBilling interface{},
+	actor antrian.Actor,
+	alasan string,
+) (k kunjunganBaru, err error) {
+	// 1. Riwayat_Kunjungan
+	res, err := tx.Exec(`INSERT INTO Riwayat_Kunjungan (id_rm, catatan) VALUES (?, '')`, idRM)
+	if err != nil {
+		return k, fmt.Errorf("failed to insert Riwayat_Kunjungan: %v", err)
+	}
+	if k.IDKunjungan, err = res.LastInsertId(); err != nil {
+		return k, fmt.Errorf("failed to get last insert id Riwayat_Kunjungan: %v", err)
+	}
+
+	// 2. Kunjungan_Poli
+	if _, err = tx.Exec(`INSERT INTO Kunjungan_Poli (id_poli, id_kunjungan) VALUES (?, ?)`, idPoli, k.IDKunjungan); err != nil {
+		return k, fmt.Errorf("failed to insert into Kunjungan_Poli: %v", err)
+	}
+
+	// 3. Nomor antrian hari ini (Counter_Antrian, terkunci sampai commit)
+	if k.NomorAntrian, err = antrian.AllocateNomor(tx, idPoli); err != nil {
+		return k, err
+	}
+	if !priorityOrder.Valid {
+		priorityOrder = sql.NullInt64{Int64: k.NomorAntrian, Valid: true}
+	}
+
+	// 4. id_status “Menunggu”
+	if err = tx.QueryRow(`SELECT id_status FROM Status_Antrian WHERE status = 'Menunggu' LIMIT 1`).Scan(&k.IDStatus); err != nil {
+		return k, fmt.Errorf("failed to get id_status for 'Menunggu': %v", err)
+	}
+
+	// 5. Antrian
+	res, err = tx.Exec(`
+		INSERT INTO Antrian
+		  (id_pasien, id_poli, keluhan_utama, nomor_antrian,
+		   id_status, priority_order, created_at, nama_penanggung_jawab)
+		VALUES (?, ?, ?, ?, ?, ?, NOW(), ?)`,
+		idPasien, idPoli, keluhanUtama, k.NomorAntrian,
+		k.IDStatus, priorityOrder.Int64, namaPenanggungJawab,
+	)
+	if err != nil {
+		return k, fmt.Errorf("failed to insert into Antrian: %v", err)
+	}
+	if k.IDAntrian, err = res.LastInsertId(); err != nil {
+		return k, fmt.Errorf("failed to get id_antrian: %v", err)
+	}
+	if err = antrian.LogCreated(tx, k.IDAntrian, actor, alasan); err != nil {
+		return k, err
+	}
+	if k.KodeTiket, err = antrian.AssignKodeTiket(tx, k.IDAntrian); err != nil {
+		return k, err
+	}
+
+	// 6. Billing (belum ada assessment, tipe pembayaran & total)
+	if _, err = tx.Exec(`
+		INSERT INTO Billing
+		  (id_kunjungan, id_antrian, id_karyawan, id_assessment,
+		   tipe_pembayaran, total, id_status, created_at, updated_at)
+		VALUES (?, ?, ?, NULL, NULL, NULL, 1, NOW(), NOW())`,
+		k.IDKunjungan, k.IDAntrian, idKaryawanBilling,
+	); err != nil {
+		return k, fmt.Errorf("failed to insert into Billing: %v", err)
+	}
+
+	// 7. Riwayat_Kunjungan & Kunjungan_Poli ← id_antrian
+	if _, err = tx.Exec(`UPDATE Riwayat_Kunjungan SET id_antrian = ? WHERE id_kunjungan = ?`,
+		k.IDAntrian, k.IDKunjungan); err != nil {
+		return k, fmt.Errorf("failed to update Riwayat_Kunjungan: %v", err)
+	}
+	if _, err = tx.Exec(`UPDATE Kunjungan_Poli SET id_antrian = ? WHERE id_kunjungan = ? AND id_poli = ?`,
+		k.IDAntrian, k.IDKunjungan, idPoli); err != nil {
+		return k, fmt.Errorf("failed to update Kunjungan_Poli: %v", err)
+	}
+	return k, nil
+}
+
+// kunjunganAntrian mengembalikan id_kunjungan pemilik antrian, baik antrian pendaftaran
+// (Riwayat_Kunjungan.id_antrian) maupun antrian rujukan internal (Kunjungan_Poli.id_antrian).
+func kunjunganAntrian(q antrian.Queryer, idAntrian int) (idKunjungan int, err error) {
+	err = q.QueryRow(`
+		SELECT id_kunjungan FROM Riwayat_Kunjungan WHERE id_antrian = ?
+		UNION ALL
+		SELECT id_kunjungan FROM Kunjungan_Poli WHERE id_antrian = ? AND id_antrian_asal IS NOT NULL
+		LIMIT 1`, idAntrian, idAntrian,
+	).Scan(&idKunjungan)
+	return idKunjungan, err
+}
+
+// assessmentKunjungan mengembalikan semua assessment dalam satu kunjungan: assessment poli
+// pendaftaran (jika Valid) diikuti assessment poli rujukan internal.
+func assessmentKunjungan(tx *sql.Tx, idKunjungan int, utama sql.NullInt64) ([]int64, error) {
+	var list []int64
+	if utama.Valid {
+		list = append(list, utama.Int64)
+	}
+	rows, err := tx.Query(`
+		SELECT id_assessment FROM Kunjungan_Poli
+		WHERE id_kunjungan = ? AND id_antrian_asal IS NOT NULL AND id_assessment IS NOT NULL
+		ORDER BY id_antrian`, idKunjungan)
+	if err != nil {
+		return nil, fmt.Errorf("gagal mengambil assessment rujukan: %v", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("gagal membaca assessment rujukan: %v", err)
+		}
+		list = append(list, id)
+	}
+	return list, rows.Err()
+}
@@ -0,0 +1,308 @@
+package services
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/c14220110/poliklinik-backend/config"
+	"github.com/c14220110/poliklinik-backend/internal/administrasi/models"
+	"github.com/c14220110/poliklinik-backend/internal/common/antrian"
+)
+
+// jenisLampiran adalah klasifikasi dokumen yang diterima.
+var jenisLampiran = map[string]bool{
+	"KTP":            true,
+	"Kartu BPJS":     true,
+	"Kartu Asuransi": true,
+	"Surat Rujukan":  true,
+	"Lainnya":        true,
+}
+
+// mimeLampiran adalah tipe file yang diterima (dideteksi dari isi file, bukan dari header
+// Content-Type atau ekstensi kiriman klien) beserta ekstensi file yang disimpan.
+var mimeLampiran = map[string]string{
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+	"application/pdf": ".pdf",
+}
+
+var (
+	ErrLampiranTidakDitemukan = errors.New("lampiran tidak ditemukan")
+	ErrLampiranTidakValid     = errors.New("lampiran tidak valid")
+	ErrLampiranTerlaluBesar   = errors.New("ukuran lampiran melebihi batas")
+	ErrLampiranGanda          = errors.New("lampiran yang sama sudah ada untuk pasien ini")
+	ErrLampiranRusak          = errors.New("checksum file lampiran tidak cocok")
+	ErrLampiranLuarPoli       = errors.New("pasien tidak memiliki antrian di poli ini hari ini")
+)
+
+type LampiranService struct {
+	DB *sql.DB
+}
+
+func NewLampiranService(db *sql.DB) *LampiranService {
+	return &LampiranService{DB: db}
+}
+
+// Simpan memvalidasi dan menyimpan file lampiran. File dibaca sekali secara streaming:
+// 512 byte pertama untuk deteksi MIME, lalu ditulis ke file sementara sambil dihitung
+// sha256-nya, dan baru dipindah ke nama akhirnya setelah semua pemeriksaan lolos.
+func (s *LampiranService) Simpan(in models.LampiranUpload, src io.Reader, actor antrian.Actor) (*models.Lampiran, error) {
+	cfg := config.LoadConfig()
+	if !jenisLampiran[in.Jenis] {
+		return nil, fmt.Errorf("%w: jenis harus KTP, Kartu BPJS, Kartu Asuransi, Surat Rujukan, atau Lainnya", ErrLampiranTidakValid)
+	}
+	checksumKlien := strings.ToLower(strings.TrimSpace(in.SHA256))
+	if checksumKlien != "" {
+		if b, err := hex.DecodeString(checksumKlien); err != nil || len(b) != sha256.Size {
+			return nil, fmt.Errorf("%w: sha256 harus 64 karakter heksadesimal", ErrLampiranTidakValid)
+		}
+	}
+	if err := s.cekPemilik(in.IDPasien, in.IDKunjungan); err != nil {
+		return nil, err
+	}
+
+	// Deteksi MIME dari isi file
+	head := make([]byte, 512)
+	n, err := io.ReadFull(src, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return nil, fmt.Errorf("gagal membaca file: %v", err)
+	}
+	head = head[:n]
+	if n == 0 {
+		return nil, fmt.Errorf("%w: file kosong", ErrLampiranTidakValid)
+	}
+	mimeType := strings.SplitN(http.DetectContentType(head), ";", 2)[0]
+	ext, ok := mimeLampiran[mimeType]
+	if !ok {
+		return nil, fmt.Errorf("%w: tipe file %s tidak diterima (hanya JPEG, PNG, PDF)", ErrLampiranTidakValid, mimeType)
+	}
+
+	subdir := time.Now().Format("2006/01")
+	if err := os.MkdirAll(filepath.Join(cfg.LampiranDir, subdir), 0o750); err != nil {
+		return nil, fmt.Errorf("gagal membuat folder lampiran: %v", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Join(cfg.LampiranDir, subdir), ".upload-*")
+	if err != nil {
+		return nil, fmt.Errorf("gagal membuat file sementara: %v", err)
+	}
+	tmpName := tmp.Name()
+	defer os.Remove(tmpName) // no-op setelah file dipindah
+
+	hasher := sha256.New()
+	ukuran, err := io.Copy(io.MultiWriter(tmp, hasher), io.LimitReader(io.MultiReader(bytes.NewReader(head), src), cfg.LampiranMaxSize+1))
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("gagal menyimpan file: %v", err)
+	}
+	if ukuran > cfg.LampiranMaxSize {
+		return nil, fmt.Errorf("%w (maksimal %d byte)", ErrLampiranTerlaluBesar, cfg.LampiranMaxSize)
+	}
+	checksum := hex.EncodeToString(hasher.Sum(nil))
+	if checksumKlien != "" && checksumKlien != checksum {
+		return nil, fmt.Errorf("%w: sha256 kiriman %s, diterima %s", ErrLampiranTidakValid, checksumKlien, checksum)
+	}
+
+	var idGanda int
+	err = s.DB.QueryRow(`
+		SELECT id_lampiran FROM Lampiran
+		WHERE id_pasien = ? AND sha256 = ? AND deleted_at IS NULL LIMIT 1`,
+		in.IDPasien, checksum,
+	).Scan(&idGanda)
+	if err == nil {
+		return nil, fmt.Errorf("%w (id_lampiran %d)", ErrLampiranGanda, idGanda)
+	}
+	if err != sql.ErrNoRows {
+		return nil, fmt.Errorf("gagal memeriksa lampiran ganda: %v", err)
+	}
+
+	acak := make([]byte, 16)
+	if _, err := rand.Read(acak); err != nil {
+		return nil, fmt.Errorf("gagal membuat nama file: %v", err)
+	}
+	relPath := filepath.ToSlash(filepath.Join(subdir, hex.EncodeToString(acak)+ext))
+	if err := os.Rename(tmpName, filepath.Join(cfg.LampiranDir, relPath)); err != nil {
+		return nil, fmt.Errorf("gagal menyimpan file: %v", err)
+	}
+
+	namaFile := filepath.Base(strings.ReplaceAll(in.NamaFile, "\\", "/"))
+	if namaFile == "." || namaFile == "/" || namaFile == "" {
+		namaFile = "lampiran" + ext
+	}
+	res, err := s.DB.Exec(`
+		INSERT INTO Lampiran
+		  (id_pasien, id_kunjungan, jenis, nama_file, path, mime_type, ukuran, sha256,
+		   keterangan, diunggah_oleh, role, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, NULLIF(?, ''), ?, ?, NOW())`,
+		in.IDPasien, in.IDKunjungan, in.Jenis, namaFile, relPath, mimeType, ukuran, checksum,
+		in.Keterangan, actor.IDKaryawan, actor.Role,
+	)
+	if err != nil {
+		os.Remove(filepath.Join(cfg.LampiranDir, relPath))
+		return nil, fmt.Errorf("gagal menyimpan data lampiran: %v", err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	return s.GetByID(int(id))
+}
+
+// cekPemilik memastikan pasien ada (dan belum digabung) serta kunjungan milik pasien itu.
+func (s *LampiranService) cekPemilik(idPasien int, idKunjungan *int) error {
+	var mergedInto sql.NullInt64
+	err := s.DB.QueryRow("SELECT merged_into FROM Pasien WHERE id_pasien = ?", idPasien).Scan(&mergedInto)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("pasien dengan id %d tidak ditemukan", idPasien)
+	}
+	if err != nil {
+		return fmt.Errorf("gagal mengambil pasien: %v", err)
+	}
+	if mergedInto.Valid {
+		return fmt.Errorf("%w: gunakan id_pasien %d", ErrPasienSudahDigabung, mergedInto.Int64)
+	}
+	if idKunjungan == nil {
+		return nil
+	}
+	var pemilik int
+	err = s.DB.QueryRow(`
+		SELECT rm.id_pasien FROM Riwayat_Kunjungan rk
+		JOIN Rekam_Medis rm ON rm.id_rm = rk.id_rm
+		WHERE rk.id_kunjungan = ?`, *idKunjungan).Scan(&pemilik)
+	if err == sql.ErrNoRows {
+		return ErrKunjunganNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("gagal mengambil kunjungan: %v", err)
+	}
+	if pemilik != idPasien {
+		return fmt.Errorf("%w: kunjungan %d bukan milik pasien %d", ErrLampiranTidakValid, *idKunjungan, idPasien)
+	}
+	return nil
+}
+
+// CekAksesPoli memastikan pasien punya antrian (yang tidak dibatalkan) di idPoli hari ini.
+// Dipakai untuk token dokter: lampiran hanya bisa dibuka untuk pasien yang sedang ditangani poli itu.
+func (s *LampiranService) CekAksesPoli(idPasien, idPoli int) error {
+	var ada bool
+	if err := s.DB.QueryRow(`
+		SELECT EXISTS(
+			SELECT 1 FROM Antrian
+			WHERE id_pasien = ? AND id_poli = ? AND DATE(created_at) = CURDATE() AND id_status <> ?)`,
+		idPasien, idPoli, antrian.StatusDibatalkan,
+	).Scan(&ada); err != nil {
+		return fmt.Errorf("gagal memeriksa antrian pasien: %v", err)
+	}
+	if !ada {
+		return ErrLampiranLuarPoli
+	}
+	return nil
+}
+
+const lampiranSelect = `
+	SELECT id_lampiran, id_pasien, id_kunjungan, jenis, nama_file, path, mime_type, ukuran, sha256,
+	       keterangan, diunggah_oleh, role, created_at
+	FROM Lampiran`
+
+func scanLampiran(scanner interface{ Scan(...interface{}) error }) (models.Lampiran, error) {
+	var (
+		l                 models.Lampiran
+		idKunjungan, oleh sql.NullInt64
+		keterangan, role  sql.NullString
+	)
+	if err := scanner.Scan(&l.IDLampiran, &l.IDPasien, &idKunjungan, &l.Jenis, &l.NamaFile, &l.Path, &l.MimeType,
+		&l.Ukuran, &l.SHA256, &keterangan, &oleh, &role, &l.CreatedAt); err != nil {
+		return l, err
+	}
+	l.IDKunjungan = nullIntPtr(idKunjungan)
+	l.DiunggahOleh = nullIntPtr(oleh)
+	if keterangan.Valid {
+		l.Keterangan = &keterangan.String
+	}
+	if role.Valid {
+		l.Role = &role.String
+	}
+	return l, nil
+}
+
+// GetByID mengembalikan metadata lampiran yang belum dihapus.
+func (s *LampiranService) GetByID(idLampiran int) (*models.Lampiran, error) {
+	l, err := scanLampiran(s.DB.QueryRow(lampiranSelect+" WHERE id_lampiran = ? AND deleted_at IS NULL", idLampiran))
+	if err == sql.ErrNoRows {
+		return nil, ErrLampiranTidakDitemukan
+	}
+	if err != nil {
+		return nil, fmt.Errorf("gagal mengambil lampiran: %v", err)
+	}
+	return &l, nil
+}
+
+// List mengembalikan lampiran pasien (idKunjungan > 0 membatasi ke satu kunjungan), terbaru dulu.
+func (s *LampiranService) List(idPasien, idKunjungan int) ([]models.Lampiran, error) {
+	query := lampiranSelect + " WHERE id_pasien = ? AND deleted_at IS NULL"
+	args := []interface{}{idPasien}
+	if idKunjungan > 0 {
+		query += " AND id_kunjungan = ?"
+		args = append(args, idKunjungan)
+	}
+	rows, err := s.DB.Query(query+" ORDER BY created_at DESC, id_lampiran DESC", args...)
+	if err != nil {
+		return nil, fmt.Errorf("gagal mengambil lampiran: %v", err)
+	}
+	defer rows.Close()
+
+	list := []models.Lampiran{}
+	for rows.Next() {
+		l, err := scanLampiran(rows)
+		if err != nil {
+			return nil, fmt.Errorf("gagal membaca lampiran: %v", err)
+		}
+		list = append(list, l)
+	}
+	return list, rows.Err()
+}
+
+// Baca mengembalikan metadata dan isi file lampiran setelah checksum-nya dicocokkan dengan
+// yang tersimpan. Ukuran file dibatasi LAMPIRAN_MAX_SIZE sehingga aman dibaca ke memori.
+func (s *LampiranService) Baca(idLampiran int) (*models.Lampiran, []byte, error) {
+	l, err := s.GetByID(idLampiran)
+	if err != nil {
+		return nil, nil, err
+	}
+	full := filepath.Join(config.LoadConfig().LampiranDir, filepath.FromSlash(l.Path))
+	data, err := os.ReadFile(full)
+	if err != nil {
+		return nil, nil, fmt.Errorf("gagal membaca file lampiran: %v", err)
+	}
+	sum := sha256.Sum256(data)
+	if hex.EncodeToString(sum[:]) != l.SHA256 {
+		return nil, nil, fmt.Errorf("%w: id_lampiran %d", ErrLampiranRusak, idLampiran)
+	}
+	return l, data, nil
+}
+
+// Hapus menandai lampiran dihapus. File tetap disimpan untuk keperluan audit.
+func (s *LampiranService) Hapus(idLampiran int, actor antrian.Actor) error {
+	res, err := s.DB.Exec(`
+		UPDATE Lampiran SET deleted_at = NOW(), dihapus_oleh = ?
+		WHERE id_lampiran = ? AND deleted_at IS NULL`, actor.IDKaryawan, idLampiran)
+	if err != nil {
+		return fmt.Errorf("gagal menghapus lampiran: %v", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrLampiranTidakDitemukan
+	}
+	return nil
+}
@@ -0,0 +1,276 @@
+package services
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/c14220110/poliklinik-backend/internal/administrasi/models"
+	"github.com/c14220110/poliklinik-backend/internal/common/antrian"
+)
+
+// Sumber perubahan pada Pasien_Versi.
+const (
+	VersiAwal        = "awal"
+	VersiPendaftaran = "pendaftaran"
+	VersiKunjungan   = "kunjungan"
+	VersiRestore     = "restore"
+)
+
+var (
+	ErrVersiTidakDitemukan = errors.New("versi pasien tidak ditemukan")
+	ErrNIKDipakai          = errors.New("NIK pada versi tersebut sudah dipakai pasien lain")
+)
+
+type PasienVersiService struct {
+	DB *sql.DB
+}
+
+func NewPasienVersiService(db *sql.DB) *PasienVersiService {
+	return &PasienVersiService{DB: db}
+}
+
+// bacaDataPasien mengambil kolom demografis pasien saat ini (dan mengunci barisnya).
+func bacaDataPasien(tx *sql.Tx, idPasien int64) (models.DataPasien, error) {
+	var (
+		d                         models.DataPasien
+		idAgama, statusPerkawinan sql.NullInt64
+	)
+	err := tx.QueryRow(`
+		SELECT nama, IFNULL(DATE_FORMAT(tanggal_lahir, '%Y-%m-%d'), ''), IFNULL(jenis_kelamin, ''),
+		       IFNULL(tempat_lahir, ''), IFNULL(nik, ''), IFNULL(kelurahan, ''), IFNULL(kecamatan, ''),
+		       IFNULL(kota_tinggal, ''), IFNULL(alamat, ''), IFNULL(no_telp, ''),
+		       id_agama, status_perkawinan, IFNULL(pekerjaan, '')
+		FROM Pasien WHERE id_pasien = ? FOR UPDATE`, idPasien,
+	).Scan(&d.Nama, &d.TanggalLahir, &d.JenisKelamin, &d.TempatLahir, &d.NIK, &d.Kelurahan, &d.Kecamatan,
+		&d.KotaTinggal, &d.Alamat, &d.NoTelp, &idAgama, &statusPerkawinan, &d.Pekerjaan)
+	if err == sql.ErrNoRows {
+		return d, fmt.Errorf("pasien dengan id %d tidak ditemukan", idPasien)
+	}
+	if err != nil {
+		return d, fmt.Errorf("gagal mengambil data pasien: %v", err)
+	}
+	d.IDAgama = nullIntPtr(idAgama)
+	d.StatusPerkawinan = nullIntPtr(statusPerkawinan)
+	return d, nil
+}
+
+// simpanVersiPasien menyimpan snapshot data pasien saat ini sebagai versi baru, kecuali jika
+// sama persis dengan versi terakhir. Mengembalikan nomor versi terakhir setelah pemanggilan.
+// Harus dipanggil di dalam transaksi yang mengubah Pasien.
+func simpanVersiPasien(tx *sql.Tx, idPasien int64, sumber string, actor *antrian.Actor, keterangan string) (int, error) {
+	data, err := bacaDataPasien(tx, idPasien)
+	if err != nil {
+		return 0, err
+	}
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return 0, fmt.Errorf("gagal menyusun snapshot pasien: %v", err)
+	}
+
+	var versi int
+	var terakhir sql.NullString
+	err = tx.QueryRow(`
+		SELECT versi, data FROM Pasien_Versi
+		WHERE id_pasien = ? ORDER BY versi DESC LIMIT 1`, idPasien,
+	).Scan(&versi, &terakhir)
+	if err != nil && err != sql.ErrNoRows {
+		return 0, fmt.Errorf("gagal mengambil versi pasien: %v", err)
+	}
+	if terakhir.Valid && bytes.Equal([]byte(terakhir.String), raw) {
+		return versi, nil
+	}
+
+	var diubahOleh, role interface{}
+	if actor != nil {
+		diubahOleh, role = actor.IDKaryawan, actor.Role
+	}
+	if _, err := tx.Exec(`
+		INSERT INTO Pasien_Versi (id_pasien, versi, data, sumber, diubah_oleh, role, keterangan, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, NULLIF(?, ''), NOW())`,
+		idPasien, versi+1, string(raw), sumber, diubahOleh, role, keterangan,
+	); err != nil {
+		return 0, fmt.Errorf("gagal menyimpan versi pasien: %v", err)
+	}
+	return versi + 1, nil
+}
+
+// simpanVersiAwal membuat snapshot "awal" untuk pasien yang belum punya versi
+// (pasien yang terdaftar sebelum riwayat versi ada). Dipanggil sebelum Pasien diubah.
+func simpanVersiAwal(tx *sql.Tx, idPasien int64) error {
+	var ada int
+	if err := tx.QueryRow("SELECT COUNT(*) FROM Pasien_Versi WHERE id_pasien = ?", idPasien).Scan(&ada); err != nil {
+		return fmt.Errorf("gagal memeriksa versi pasien: %v", err)
+	}
+	if ada > 0 {
+		return nil
+	}
+	_, err := simpanVersiPasien(tx, idPasien, VersiAwal, nil, "data sebelum riwayat versi dicatat")
+	return err
+}
+
+const pasienVersiSelect = `
+	SELECT v.id_pasien, v.versi, v.data, v.sumber, v.diubah_oleh,
+	       CASE WHEN v.role = 'Manajemen'
+	            THEN (SELECT m.nama FROM Management m WHERE m.id_management = v.diubah_oleh)
+	            ELSE (SELECT k.nama FROM Karyawan k WHERE k.id_karyawan = v.diubah_oleh) END,
+	       v.role, v.keterangan, v.created_at
+	FROM Pasien_Versi v`
+
+func scanPasienVersi(scanner interface{ Scan(...interface{}) error }) (models.PasienVersi, error) {
+	var (
+		v                          models.PasienVersi
+		raw                        string
+		diubahOleh                 sql.NullInt64
+		namaOleh, role, keterangan sql.NullString
+	)
+	if err := scanner.Scan(&v.IDPasien, &v.Versi, &raw, &v.Sumber, &diubahOleh, &namaOleh, &role, &keterangan, &v.CreatedAt); err != nil {
+		return v, err
+	}
+	if err := json.Unmarshal([]byte(raw), &v.Data); err != nil {
+		return v, fmt.Errorf("snapshot versi %d tidak valid: %v", v.Versi, err)
+	}
+	v.DiubahOleh = nullIntPtr(diubahOleh)
+	if namaOleh.Valid {
+		v.NamaOleh = &namaOleh.String
+	}
+	if role.Valid {
+		v.Role = &role.String
+	}
+	if keterangan.Valid {
+		v.Keterangan = &keterangan.String
+	}
+	return v, nil
+}
+
+// GetVersiList mengembalikan semua versi data pasien, terbaru lebih dulu.
+func (s *PasienVersiService) GetVersiList(idPasien int) ([]models.PasienVersi, error) {
+	rows, err := s.DB.Query(pasienVersiSelect+" WHERE v.id_pasien = ? ORDER BY v.versi DESC", idPasien)
+	if err != nil {
+		return nil, fmt.Errorf("gagal mengambil versi pasien: %v", err)
+	}
+	defer rows.Close()
+
+	list := []models.PasienVersi{}
+	for rows.Next() {
+		v, err := scanPasienVersi(rows)
+		if err != nil {
+			return nil, fmt.Errorf("gagal membaca versi pasien: %v", err)
+		}
+		list = append(list, v)
+	}
+	return list, rows.Err()
+}
+
+func (s *PasienVersiService) getVersi(idPasien, versi int) (models.PasienVersi, error) {
+	v, err := scanPasienVersi(s.DB.QueryRow(pasienVersiSelect+" WHERE v.id_pasien = ? AND v.versi = ?", idPasien, versi))
+	if err == sql.ErrNoRows {
+		return v, fmt.Errorf("%w: versi %d", ErrVersiTidakDitemukan, versi)
+	}
+	return v, err
+}
+
+// GetDiff membandingkan dua versi. ke = 0 berarti versi terbaru; dari = 0 berarti versi sebelum ke.
+func (s *PasienVersiService) GetDiff(idPasien, dari, ke int) (*models.DiffPasienVersi, error) {
+	if ke == 0 {
+		if err := s.DB.QueryRow("SELECT IFNULL(MAX(versi), 0) FROM Pasien_Versi WHERE id_pasien = ?", idPasien).Scan(&ke); err != nil {
+			return nil, fmt.Errorf("gagal mengambil versi terbaru: %v", err)
+		}
+		if ke == 0 {
+			return nil, fmt.Errorf("%w: pasien %d belum memiliki riwayat versi", ErrVersiTidakDitemukan, idPasien)
+		}
+	}
+	if dari == 0 {
+		dari = ke - 1
+	}
+	if dari < 1 {
+		return nil, fmt.Errorf("%w: tidak ada versi sebelum versi %d", ErrVersiTidakDitemukan, ke)
+	}
+
+	vDari, err := s.getVersi(idPasien, dari)
+	if err != nil {
+		return nil, err
+	}
+	vKe, err := s.getVersi(idPasien, ke)
+	if err != nil {
+		return nil, err
+	}
+	return &models.DiffPasienVersi{
+		IDPasien:  idPasien,
+		DariVersi: dari,
+		KeVersi:   ke,
+		Perubahan: diffDataPasien(vDari.Data, vKe.Data),
+	}, nil
+}
+
+// diffDataPasien mengembalikan kolom (nama JSON) yang berbeda, urut sesuai field DataPasien.
+func diffDataPasien(a, b models.DataPasien) []models.PerubahanKolom {
+	list := []models.PerubahanKolom{}
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	t := va.Type()
+	for i := 0; i < t.NumField(); i++ {
+		fa, fb := va.Field(i).Interface(), vb.Field(i).Interface()
+		if reflect.DeepEqual(fa, fb) {
+			continue
+		}
+		kolom := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]
+		list = append(list, models.PerubahanKolom{Kolom: kolom, Dari: fa, Ke: fb})
+	}
+	return list
+}
+
+// RestoreVersi mengembalikan data demografis pasien ke isi versi tertentu. Hasilnya dicatat
+// sebagai versi baru (sumber "restore"), sehingga riwayat tidak pernah ditulis ulang.
+func (s *PasienVersiService) RestoreVersi(idPasien, versi int, actor antrian.Actor) (int, error) {
+	target, err := s.getVersi(idPasien, versi)
+	if err != nil {
+		return 0, err
+	}
+	d := target.Data
+
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("gagal memulai transaksi: %v", err)
+	}
+	defer tx.Rollback()
+
+	if err := simpanVersiAwal(tx, int64(idPasien)); err != nil {
+		return 0, err
+	}
+	var dipakai int
+	if err := tx.QueryRow("SELECT COUNT(*) FROM Pasien WHERE nik = ? AND id_pasien <> ?", d.NIK, idPasien).Scan(&dipakai); err != nil {
+		return 0, fmt.Errorf("gagal memeriksa NIK: %v", err)
+	}
+	if dipakai > 0 {
+		return 0, ErrNIKDipakai
+	}
+
+	var tanggalLahir interface{}
+	if d.TanggalLahir != "" {
+		tanggalLahir = d.TanggalLahir
+	}
+	if _, err := tx.Exec(`
+		UPDATE Pasien
+		SET nama = ?, tanggal_lahir = ?, jenis_kelamin = ?, tempat_lahir = ?, nik = ?,
+		    kelurahan = ?, kecamatan = ?, kota_tinggal = ?, alamat = ?, no_telp = ?,
+		    id_agama = ?, status_perkawinan = ?, pekerjaan = ?
+		WHERE id_pasien = ?`,
+		d.Nama, tanggalLahir, d.JenisKelamin, d.TempatLahir, d.NIK,
+		d.Kelurahan, d.Kecamatan, d.KotaTinggal, d.Alamat, d.NoTelp,
+		d.IDAgama, d.StatusPerkawinan, d.Pekerjaan, idPasien,
+	); err != nil {
+		return 0, fmt.Errorf("gagal mengembalikan data pasien: %v", err)
+	}
+	baru, err := simpanVersiPasien(tx, int64(idPasien), VersiRestore, &actor, fmt.Sprintf("restore dari versi %d", versi))
+	if err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("gagal commit transaksi: %v", err)
+	}
+	return baru, nil
+}
@@ -0,0 +1,241 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/c14220110/poliklinik-backend/internal/administrasi/models"
+	"github.com/c14220110/poliklinik-backend/pkg/utils"
+)
+
+// Bobot ranking pencarian pasien. Pengenal unik (id_rm, NIK lengkap, no telp) lebih
+// tinggi daripada tanggal lahir atau nama yang bisa dimiliki banyak pasien.
+const (
+	bobotCariIDRM           = 100
+	bobotCariNIK            = 90
+	bobotCariNoTelp         = 70
+	bobotCariNIKAwalan      = 60
+	bobotCariNamaSama       = 50
+	bobotCariTanggalLahir   = 30
+	bobotCariNamaAwalan     = 25
+	bobotCariNamaMengandung = 10
+)
+
+// ErrKriteriaPencarian menandai input pencarian yang tidak bisa dipakai (400).
+var ErrKriteriaPencarian = errors.New("kriteria pencarian tidak valid")
+
+// kriteriaPencarian adalah satu kondisi pencarian beserta bobot dan nama yang dilaporkan ke client.
+type kriteriaPencarian struct {
+	nama string
+	cond string
+	args []interface{}
+	skor int
+}
+
+// digitsOnlySQL membuang pemisah yang umum ditulis pada no telp (+, -, spasi, titik).
+const digitsOnlySQL = "REPLACE(REPLACE(REPLACE(REPLACE(IFNULL(p.no_telp, ''), '+', ''), '-', ''), ' ', ''), '.', '')"
+
+// kriteriaNIK: NIK yang sama persis hanya mendapat bobotCariNIK, tidak ditambah bobot awalan.
+func kriteriaNIK(nik string, awalan bool) []kriteriaPencarian {
+	list := []kriteriaPencarian{{"nik", "p.nik = ?", []interface{}{nik}, bobotCariNIK}}
+	if awalan {
+		list = append(list, kriteriaPencarian{"nik", "p.nik LIKE ? AND p.nik <> ?",
+			[]interface{}{escapeLike(nik) + "%", nik}, bobotCariNIKAwalan})
+	}
+	return list
+}
+
+func kriteriaIDRM(idRM string) kriteriaPencarian {
+	return kriteriaPencarian{"id_rm",
+		"EXISTS (SELECT 1 FROM Rekam_Medis rm WHERE rm.id_pasien = p.id_pasien AND rm.id_rm = ?)",
+		[]interface{}{idRM}, bobotCariIDRM}
+}
+
+// kriteriaNoTelp mencocokkan nomor tanpa awalan 0/62 sehingga "0812..." sama dengan "+62 812...".
+func kriteriaNoTelp(noTelp string) (kriteriaPencarian, bool) {
+	inti := strings.TrimPrefix(utils.NormalizePhone(noTelp), "0")
+	if len(inti) < 8 {
+		return kriteriaPencarian{}, false
+	}
+	return kriteriaPencarian{"no_telp",
+		fmt.Sprintf("RIGHT(%s, %d) = ?", digitsOnlySQL, len(inti)),
+		[]interface{}{inti}, bobotCariNoTelp}, true
+}
+
+func kriteriaTanggalLahir(tanggal string) (kriteriaPencarian, bool) {
+	t, ok := parseTanggalLahir(tanggal)
+	if !ok {
+		return kriteriaPencarian{}, false
+	}
+	return kriteriaPencarian{"tanggal_lahir", "p.tanggal_lahir = ?", []interface{}{t.Format("2006-01-02")}, bobotCariTanggalLahir}, true
+}
+
+// kriteriaNama saling lepas seperti kriteriaNIK: sama persis, lalu awalan, lalu mengandung.
+func kriteriaNama(nama string) []kriteriaPencarian {
+	awalan := escapeLike(nama) + "%"
+	return []kriteriaPencarian{
+		{"nama", "p.nama = ?", []interface{}{nama}, bobotCariNamaSama},
+		{"nama", "p.nama LIKE ? AND p.nama <> ?", []interface{}{awalan, nama}, bobotCariNamaAwalan},
+		{"nama", "p.nama LIKE ? AND p.nama NOT LIKE ?", []interface{}{"%" + escapeLike(nama) + "%", awalan}, bobotCariNamaMengandung},
+	}
+}
+
+func parseTanggalLahir(s string) (time.Time, bool) {
+	for _, layout := range []string{"2006-01-02", "02-01-2006", "02/01/2006"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// kriteriaDariQ menebak kriteria dari teks bebas: tanggal, angka (NIK/no telp/id_rm), atau nama.
+func kriteriaDariQ(q string) []kriteriaPencarian {
+	if k, ok := kriteriaTanggalLahir(q); ok {
+		return []kriteriaPencarian{k}
+	}
+	list := []kriteriaPencarian{kriteriaIDRM(q)}
+	angka := strings.TrimPrefix(q, "+")
+	angka = strings.NewReplacer(" ", "", "-", "").Replace(angka)
+	if isDigits(angka) {
+		list = append(list, kriteriaNIK(angka, true)...)
+		if k, ok := kriteriaNoTelp(angka); ok {
+			list = append(list, k)
+		}
+		return list
+	}
+	return append(list, kriteriaNama(q)...)
+}
+
+// SearchPasien mencari pasien aktif (belum digabung) berdasarkan NIK, id_rm, no telp,
+// tanggal lahir, dan nama. Setiap kriteria yang cocok menambah skor; hasil diurutkan
+// dari skor tertinggi lalu kunjungan terakhir.
+func (s *PendaftaranService) SearchPasien(f models.PencarianPasien) ([]models.HasilPencarianPasien, int, error) {
+	var kriteria []kriteriaPencarian
+	if q := strings.TrimSpace(f.Q); q != "" {
+		kriteria = append(kriteria, kriteriaDariQ(q)...)
+	}
+	if nik := strings.TrimSpace(f.NIK); nik != "" {
+		kriteria = append(kriteria, kriteriaNIK(nik, true)...)
+	}
+	if idRM := strings.TrimSpace(f.IDRM); idRM != "" {
+		kriteria = append(kriteria, kriteriaIDRM(idRM))
+	}
+	if noTelp := strings.TrimSpace(f.NoTelp); noTelp != "" {
+		k, ok := kriteriaNoTelp(noTelp)
+		if !ok {
+			return nil, 0, fmt.Errorf("%w: no_telp minimal 8 digit", ErrKriteriaPencarian)
+		}
+		kriteria = append(kriteria, k)
+	}
+	if tgl := strings.TrimSpace(f.TanggalLahir); tgl != "" {
+		k, ok := kriteriaTanggalLahir(tgl)
+		if !ok {
+			return nil, 0, fmt.Errorf("%w: gunakan format tanggal_lahir YYYY-MM-DD atau DD-MM-YYYY", ErrKriteriaPencarian)
+		}
+		kriteria = append(kriteria, k)
+	}
+	if nama := strings.TrimSpace(f.Nama); nama != "" {
+		kriteria = append(kriteria, kriteriaNama(nama)...)
+	}
+	if len(kriteria) == 0 {
+		return nil, 0, fmt.Errorf("%w: minimal satu kriteria pencarian wajib diisi", ErrKriteriaPencarian)
+	}
+
+	// skor = jumlah bobot kriteria yang cocok; cocok = nama kriteria yang cocok;
+	// WHERE = minimal satu kriteria cocok.
+	var skorParts, cocokParts, whereParts []string
+	var skorArgs, cocokArgs, whereArgs []interface{}
+	for _, k := range kriteria {
+		skorParts = append(skorParts, fmt.Sprintf("(CASE WHEN %s THEN %d ELSE 0 END)", k.cond, k.skor))
+		skorArgs = append(skorArgs, k.args...)
+		cocokParts = append(cocokParts, fmt.Sprintf("IF(%s, '%s', NULL)", k.cond, k.nama))
+		cocokArgs = append(cocokArgs, k.args...)
+		whereParts = append(whereParts, k.cond)
+		whereArgs = append(whereArgs, k.args...)
+	}
+	where := "p.merged_into IS NULL AND (" + strings.Join(whereParts, " OR ") + ")"
+
+	var total int
+	if err := s.DB.QueryRow("SELECT COUNT(*) FROM Pasien p WHERE "+where, whereArgs...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("count error: %v", err)
+	}
+	if total == 0 {
+		return []models.HasilPencarianPasien{}, 0, nil
+	}
+
+	query := `
+		SELECT p.id_pasien,
+		       IFNULL((SELECT rm.id_rm FROM Rekam_Medis rm WHERE rm.id_pasien = p.id_pasien AND rm.digabung_dari IS NULL ORDER BY rm.created_at DESC LIMIT 1), ''),
+		       p.nama, IFNULL(p.nik, ''), DATE_FORMAT(p.tanggal_lahir, '%Y-%m-%d'), IFNULL(p.jenis_kelamin, ''),
+		       IFNULL(p.no_telp, ''), IFNULL(p.alamat, ''),
+		       la.created_at, pl.nama_poli,
+		       ` + strings.Join(skorParts, " + ") + ` AS skor,
+		       CONCAT_WS(',', ` + strings.Join(cocokParts, ", ") + `)
+		FROM Pasien p
+		LEFT JOIN Antrian la ON la.id_antrian = (
+			SELECT a.id_antrian FROM Antrian a
+			WHERE a.id_pasien = p.id_pasien
+			ORDER BY a.created_at DESC, a.id_antrian DESC LIMIT 1)
+		LEFT JOIN Poliklinik pl ON la.id_poli = pl.id_poli
+		WHERE ` + where + `
+		ORDER BY skor DESC, la.created_at IS NULL, la.created_at DESC, p.id_pasien DESC
+		LIMIT ? OFFSET ?`
+	args := append(append(append(skorArgs, cocokArgs...), whereArgs...), f.Limit, (f.Page-1)*f.Limit)
+
+	rows, err := s.DB.Query(query, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("query error: %v", err)
+	}
+	defer rows.Close()
+
+	list := []models.HasilPencarianPasien{}
+	for rows.Next() {
+		var h models.HasilPencarianPasien
+		var kunjungan sql.NullTime
+		var poli sql.NullString
+		var cocok string
+		if err := rows.Scan(&h.IDPasien, &h.IDRM, &h.Nama, &h.NIK, &h.TanggalLahir, &h.JenisKelamin,
+			&h.NoTelp, &h.Alamat, &kunjungan, &poli, &h.Skor, &cocok); err != nil {
+			return nil, 0, fmt.Errorf("scan error: %v", err)
+		}
+		if kunjungan.Valid {
+			h.KunjunganTerakhir = &kunjungan.Time
+		}
+		if poli.Valid {
+			h.PoliTerakhir = &poli.String
+		}
+		h.Cocok = []string{}
+		seen := map[string]bool{}
+		for _, nama := range strings.Split(cocok, ",") {
+			if nama != "" && !seen[nama] {
+				seen[nama] = true
+				h.Cocok = append(h.Cocok, nama)
+			}
+		}
+		list = append(list, h)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+	return list, total, nil
+}
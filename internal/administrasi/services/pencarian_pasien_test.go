@@ -0,0 +1,131 @@
+package services
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// namaKriteria meringkas daftar kriteria menjadi "nama:skor" agar mudah dibandingkan.
+func namaKriteria(list []kriteriaPencarian) []string {
+	out := make([]string, len(list))
+	for i, k := range list {
+		out[i] = fmt.Sprintf("%s:%d", k.nama, k.skor)
+	}
+	return out
+}
+
+func TestKriteriaNIK(t *testing.T) {
+	list := kriteriaNIK("3171", false)
+	if len(list) != 1 || list[0].skor != bobotCariNIK {
+		t.Fatalf("tanpa awalan: %+v", list)
+	}
+
+	list = kriteriaNIK("3171", true)
+	if len(list) != 2 {
+		t.Fatalf("dengan awalan: %+v", list)
+	}
+	awalan := list[1]
+	if awalan.skor != bobotCariNIKAwalan {
+		t.Errorf("skor awalan = %d, want %d", awalan.skor, bobotCariNIKAwalan)
+	}
+	// NIK yang sama persis tidak boleh ikut mendapat bobot awalan.
+	if !strings.Contains(awalan.cond, "p.nik <> ?") {
+		t.Errorf("kondisi awalan tidak mengecualikan NIK sama persis: %q", awalan.cond)
+	}
+	if want := []interface{}{"3171%", "3171"}; !reflect.DeepEqual(awalan.args, want) {
+		t.Errorf("args awalan = %v, want %v", awalan.args, want)
+	}
+	if n := strings.Count(awalan.cond, "?"); n != len(awalan.args) {
+		t.Errorf("placeholder %d, args %d", n, len(awalan.args))
+	}
+}
+
+func TestKriteriaNama(t *testing.T) {
+	list := kriteriaNama("Siti_50%")
+	if len(list) != 3 {
+		t.Fatalf("kriteriaNama = %+v", list)
+	}
+	wantSkor := []int{bobotCariNamaSama, bobotCariNamaAwalan, bobotCariNamaMengandung}
+	for i, k := range list {
+		if k.skor != wantSkor[i] {
+			t.Errorf("kriteria %d skor = %d, want %d", i, k.skor, wantSkor[i])
+		}
+		if n := strings.Count(k.cond, "?"); n != len(k.args) {
+			t.Errorf("kriteria %d: placeholder %d, args %d", i, n, len(k.args))
+		}
+	}
+	awalan := `Siti\_50\%%`
+	if want := []interface{}{awalan, "Siti_50%"}; !reflect.DeepEqual(list[1].args, want) {
+		t.Errorf("args awalan = %v, want %v", list[1].args, want)
+	}
+	// "mengandung" mengecualikan kecocokan awalan, sehingga bobot tidak bertumpuk.
+	if want := []interface{}{`%Siti\_50\%%`, awalan}; !reflect.DeepEqual(list[2].args, want) {
+		t.Errorf("args mengandung = %v, want %v", list[2].args, want)
+	}
+	if !strings.Contains(list[2].cond, "NOT LIKE") || !strings.Contains(list[1].cond, "p.nama <> ?") {
+		t.Errorf("kondisi nama tidak saling lepas: %q / %q", list[1].cond, list[2].cond)
+	}
+}
+
+func TestKriteriaNoTelp(t *testing.T) {
+	tests := []struct {
+		in   string
+		inti string
+		ok   bool
+	}{
+		{"081234567890", "81234567890", true},
+		{"+62 812-3456-7890", "81234567890", true},
+		{"6281234567890", "81234567890", true},
+		{"0812.3456.7890", "81234567890", true},
+		{"0812345", "", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		k, ok := kriteriaNoTelp(tt.in)
+		if ok != tt.ok {
+			t.Errorf("kriteriaNoTelp(%q) ok = %v, want %v", tt.in, ok, tt.ok)
+			continue
+		}
+		if ok && (len(k.args) != 1 || k.args[0] != tt.inti || k.skor != bobotCariNoTelp) {
+			t.Errorf("kriteriaNoTelp(%q) = %+v, want inti %q", tt.in, k, tt.inti)
+		}
+	}
+}
+
+func TestKriteriaDariQ(t *testing.T) {
+	tests := []struct {
+		q    string
+		want []string
+	}{
+		{"1990-01-05", []string{"tanggal_lahir:30"}},
+		{"05-01-1990", []string{"tanggal_lahir:30"}},
+		{"3171014501900001", []string{"id_rm:100", "nik:90", "nik:60", "no_telp:70"}},
+		{"3171", []string{"id_rm:100", "nik:90", "nik:60"}},
+		{"+62 812-3456-7890", []string{"id_rm:100", "nik:90", "nik:60", "no_telp:70"}},
+		{"RM202500001", []string{"id_rm:100", "nama:50", "nama:25", "nama:10"}},
+		{"Budi", []string{"id_rm:100", "nama:50", "nama:25", "nama:10"}},
+	}
+	for _, tt := range tests {
+		if got := namaKriteria(kriteriaDariQ(tt.q)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("kriteriaDariQ(%q) = %v, want %v", tt.q, got, tt.want)
+		}
+	}
+}
+
+func TestBobotPencarian(t *testing.T) {
+	urut := []int{bobotCariIDRM, bobotCariNIK, bobotCariNoTelp, bobotCariNIKAwalan,
+		bobotCariNamaSama, bobotCariTanggalLahir, bobotCariNamaAwalan, bobotCariNamaMengandung}
+	for i := 1; i < len(urut); i++ {
+		if urut[i] >= urut[i-1] {
+			t.Errorf("bobot ke-%d (%d) tidak lebih kecil dari sebelumnya (%d)", i, urut[i], urut[i-1])
+		}
+	}
+}
+
+func TestEscapeLike(t *testing.T) {
+	if got, want := escapeLike(`a%b_c\d`), `a\%b\_c\\d`; got != want {
+		t.Errorf("escapeLike = %q, want %q", got, want)
+	}
+}
@@ -7,6 +7,8 @@ import (
 	"time"
 
 	"github.com/c14220110/poliklinik-backend/internal/administrasi/models"
+	"github.com/c14220110/poliklinik-backend/internal/common/antrian"
+	"github.com/c14220110/poliklinik-backend/ws"
 )
 
 type PendaftaranService struct {
@@ -18,27 +20,41 @@ func NewPendaftaranService(db *sql.DB) *PendaftaranService {
 }
 
 // RegisterPasienWithKunjungan mendaftarkan pasien, RM, kunjungan, antrian, dan billing.
+// actor adalah operator administrasi dari token; penjamin kosong berarti pasien umum,
+// idTriase 0 berarti tanpa kategori triase.
+// Pendaftaran ditolak jika poli libur, di luar jam operasional, atau kuotanya penuh. Jika
+// daftarTunggu true (dan poli tidak libur), pasien & RM tetap dibuat lalu pasien dicatat di
+// daftar tunggu: hasil.DaftarTunggu terisi dan IDAntrian / IDKunjungan 0.
 func (s *PendaftaranService) RegisterPasienWithKunjungan(
 	p models.Pasien,
-	idPoli, operatorID int,
+	idPoli int,
+	actor antrian.Actor,
 	keluhanUtama, namaPenanggungJawab string,
-) (patientID int64, idAntrian int64, nomorAntrian int64, idRM string, idStatus int,
-	namaPoli string, idKunjungan int64, err error) {
+	penjamin models.PilihanPenjamin,
+	idTriase int,
+	daftarTunggu bool,
+) (*models.HasilPendaftaran, error) {
+	operatorID := actor.IDKaryawan
 
 	// ---------- MULAI TRANSAKSI ----------
 	tx, err := s.DB.Begin()
 	if err != nil {
-		return
+		return nil, err
 	}
 	defer tx.Rollback()
 
 	// 1. Pastikan NIK belum terdaftar
 	var existingID int
 	if err = tx.QueryRow(`SELECT id_pasien FROM Pasien WHERE NIK = ?`, p.NIK).Scan(&existingID); err == nil {
-		err = fmt.Errorf("NIK sudah terdaftar")
-		return
+		return nil, fmt.Errorf("NIK sudah terdaftar")
 	} else if err != sql.ErrNoRows {
-		return
+		return nil, err
+	}
+
+	// 1a. Kapasitas poli (baris poli terkunci sampai commit)
+	kap, masukTunggu, err := kapasitasPendaftaran(tx, idPoli, daftarTunggu)
+	if err != nil {
+		return nil, err
 	}
 
 	// 2. Masukkan data pasien
@@ -53,146 +69,121 @@ func (s *PendaftaranService) RegisterPasienWithKunjungan(
 		p.IDAgama, p.StatusPerkawinan, p.Pekerjaan,
 	)
 	if err != nil {
-		return
+		return nil, err
+	}
+	h := &models.HasilPendaftaran{}
+	if h.IDPasien, err = res.LastInsertId(); err != nil {
+		return nil, err
 	}
-	if patientID, err = res.LastInsertId(); err != nil {
-		return
+	if _, err = simpanVersiPasien(tx, h.IDPasien, VersiPendaftaran, &actor, ""); err != nil {
+		return nil, err
 	}
 
-	// 3. Buat id_rm (Counter_RM)
-	tahun := time.Now().Year()
-	var count int
-	switch err = tx.QueryRow(`SELECT count FROM Counter_RM WHERE tahun = ? FOR UPDATE`, tahun).Scan(&count); {
-	case err == sql.ErrNoRows:
-		if _, err = tx.Exec(`INSERT INTO Counter_RM (tahun, count) VALUES (?, 1)`, tahun); err != nil {
-			err = fmt.Errorf("failed insert Counter_RM: %v", err)
-			return
+	// 3–4. Buat id_rm (Counter_RM) dan Rekam Medis
+	if h.IDRM, err = buatRekamMedis(tx, h.IDPasien); err != nil {
+		return nil, err
+	}
+	if masukTunggu {
+		if h.DaftarTunggu, err = masukDaftarTunggu(tx, h.IDPasien, h.IDRM, idPoli, keluhanUtama, namaPenanggungJawab,
+			penjamin, idTriase, kap, actor); err != nil {
+			return nil, err
 		}
-		count = 1
-	case err == nil:
-		count++
-		if _, err = tx.Exec(`UPDATE Counter_RM SET count = ? WHERE tahun = ?`, count, tahun); err != nil {
-			err = fmt.Errorf("failed update Counter_RM: %v", err)
-			return
+		h.NamaPoli = h.DaftarTunggu.NamaPoli
+		if err = tx.Commit(); err != nil {
+			return nil, err
 		}
-	default:
-		err = fmt.Errorf("failed select Counter_RM: %v", err)
-		return
-	}
-	idRM = fmt.Sprintf("RM%d%05d", tahun, count)
-
-	// 4. Rekam Medis
-	if _, err = tx.Exec(`INSERT INTO Rekam_Medis (id_rm, id_pasien) VALUES (?, ?)`, idRM, patientID); err != nil {
-		err = fmt.Errorf("insert Rekam_Medis: %v", err)
-		return
+		return h, nil
 	}
 
-	// 5. Riwayat_Kunjungan
-	if res, err = tx.Exec(`INSERT INTO Riwayat_Kunjungan (id_rm, catatan) VALUES (?, '')`, idRM); err != nil {
-		err = fmt.Errorf("insert Riwayat_Kunjungan: %v", err)
-		return
+	// 5–11. Riwayat_Kunjungan, Kunjungan_Poli, Antrian, Billing
+	k, err := buatKunjungan(tx, h.IDPasien, h.IDRM, idPoli, keluhanUtama, namaPenanggungJawab,
+		sql.NullInt64{}, operatorID, actor, "pendaftaran pasien baru")
+	if err != nil {
+		return nil, err
 	}
-	if idKunjungan, err = res.LastInsertId(); err != nil {
-		err = fmt.Errorf("lastInsertId Riwayat_Kunjungan: %v", err)
-		return
+	h.IDKunjungan, h.IDAntrian, h.NomorAntrian, h.KodeTiket, h.IDStatus = k.IDKunjungan, k.IDAntrian, k.NomorAntrian, k.KodeTiket, k.IDStatus
+	if _, err = pilihPenjamin(tx, h.IDPasien, h.IDKunjungan, penjamin, operatorID); err != nil {
+		return nil, err
 	}
-
-	// 6. Kunjungan_Poli
-	if _, err = tx.Exec(`INSERT INTO Kunjungan_Poli (id_poli, id_kunjungan) VALUES (?, ?)`, idPoli, idKunjungan); err != nil {
-		err = fmt.Errorf("insert Kunjungan_Poli: %v", err)
-		return
+	if idTriase != 0 {
+		if _, err = tetapkanTriase(tx, h.IDAntrian, idTriase, actor.IDKaryawan); err != nil {
+			return nil, err
+		}
 	}
 
-	// 7. Nomor antrian hari ini
-	today := time.Now().Format("2006-01-02")
-	var maxNomor sql.NullInt64
-	if err = tx.QueryRow(
-		`SELECT COALESCE(MAX(nomor_antrian),0) FROM Antrian WHERE id_poli = ? AND DATE(created_at) = ?`,
-		idPoli, today,
-	).Scan(&maxNomor); err != nil {
-		err = fmt.Errorf("max nomor_antrian: %v", err)
-		return
-	}
-	nomorAntrian = 1
-	if maxNomor.Valid && maxNomor.Int64 > 0 {
-		nomorAntrian = maxNomor.Int64 + 1
+	// 12. Ambil nama poli
+	if err = tx.QueryRow(`SELECT nama_poli FROM Poliklinik WHERE id_poli = ?`, idPoli).Scan(&h.NamaPoli); err != nil {
+		return nil, fmt.Errorf("select nama_poli: %v", err)
 	}
 
-	// 8. id_status “Menunggu”
-	if err = tx.QueryRow(`SELECT id_status FROM Status_Antrian WHERE status = 'Menunggu' LIMIT 1`).Scan(&idStatus); err != nil {
-		err = fmt.Errorf("id_status Menunggu: %v", err)
-		return
+	// ---------- COMMIT ----------
+	if err = tx.Commit(); err != nil {
+		return nil, err
 	}
+	antrian.PublishEvent(s.DB, ws.EventAntrianCreated, int(h.IDAntrian), 0, antrian.StatusMenunggu, actor, "pendaftaran pasien baru")
+	return h, nil
+}
 
-	// 9. Antrian
-	if res, err = tx.Exec(`
-		INSERT INTO Antrian
-		  (id_pasien, id_poli, keluhan_utama, nomor_antrian,
-		   id_status, priority_order, created_at, nama_penanggung_jawab)
-		VALUES (?, ?, ?, ?, ?, ?, NOW(), ?)`,
-		patientID, idPoli, keluhanUtama, nomorAntrian,
-		idStatus, nomorAntrian, namaPenanggungJawab,
-	); err != nil {
-		err = fmt.Errorf("insert Antrian: %v", err)
-		return
-	}
-	if idAntrian, err = res.LastInsertId(); err != nil {
-		err = fmt.Errorf("lastInsertId Antrian: %v", err)
-		return
-	}
-
-	// 10. Billing  **SUDAH DISESUAIKAN DENGAN id_assessment**
-	if _, err = tx.Exec(`
-		INSERT INTO Billing
-		  (id_kunjungan, id_antrian, id_karyawan, id_assessment,
-		   tipe_pembayaran, total, id_status, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?, 1, NOW(), NOW())`,
-		idKunjungan, idAntrian, operatorID,
-		nil,      // id_assessment  (belum ada / NULL)
-		nil, nil, // tipe_pembayaran, total
-	); err != nil {
-		err = fmt.Errorf("insert Billing: %v", err)
-		return
-	}
 
-	// 11. Update Riwayat_Kunjungan ← id_antrian
-	if _, err = tx.Exec(`UPDATE Riwayat_Kunjungan SET id_antrian = ? WHERE id_kunjungan = ?`,
-		idAntrian, idKunjungan); err != nil {
-		err = fmt.Errorf("update Riwayat_Kunjungan: %v", err)
-		return
+// buatRekamMedis mengambil nomor berikutnya dari Counter_RM tahun berjalan (baris counter
+// dikunci sampai transaksi selesai) lalu membuat Rekam_Medis untuk pasien.
+func buatRekamMedis(tx *sql.Tx, idPasien int64) (string, error) {
+	tahun := time.Now().Year()
+	var count int
+	switch err := tx.QueryRow(`SELECT count FROM Counter_RM WHERE tahun = ? FOR UPDATE`, tahun).Scan(&count); {
+	case err == sql.ErrNoRows:
+		if _, err = tx.Exec(`INSERT INTO Counter_RM (tahun, count) VALUES (?, 1)`, tahun); err != nil {
+			return "", fmt.Errorf("failed insert Counter_RM: %v", err)
+		}
+		count = 1
+	case err == nil:
+		count++
+		if _, err = tx.Exec(`UPDATE Counter_RM SET count = ? WHERE tahun = ?`, count, tahun); err != nil {
+			return "", fmt.Errorf("failed update Counter_RM: %v", err)
+		}
+	default:
+		return "", fmt.Errorf("failed select Counter_RM: %v", err)
 	}
+	idRM := fmt.Sprintf("RM%d%05d", tahun, count)
 
-	// 12. Ambil nama poli
-	if err = tx.QueryRow(`SELECT nama_poli FROM Poliklinik WHERE id_poli = ?`, idPoli).Scan(&namaPoli); err != nil {
-		err = fmt.Errorf("select nama_poli: %v", err)
-		return
+	if _, err := tx.Exec(`INSERT INTO Rekam_Medis (id_rm, id_pasien) VALUES (?, ?)`, idRM, idPasien); err != nil {
+		return "", fmt.Errorf("insert Rekam_Medis: %v", err)
 	}
-
-	// ---------- COMMIT ----------
-	err = tx.Commit()
-	return
+	return idRM, nil
 }
 
-
-
-// UpdatePasienAndRegisterKunjungan updates pasien & creates a new antrian without id_billing in RK
+// UpdatePasienAndRegisterKunjungan updates pasien & creates a new antrian without id_billing in RK.
+// Kapasitas poli dan daftarTunggu berlaku sama seperti RegisterPasienWithKunjungan.
 func (s *PendaftaranService) UpdatePasienAndRegisterKunjungan(
 	p models.Pasien,
 	idPoli int,
 	keluhanUtama string,
 	namaPenanggungJawab string,
-) (idPasien int64, idAntrian int64, nomorAntrian int64, idRM string, idStatus int, namaPoli string, idKunjungan int64, err error) {
+	actor antrian.Actor,
+	penjamin models.PilihanPenjamin,
+	idTriase int,
+	daftarTunggu bool,
+) (*models.HasilPendaftaran, error) {
 	tx, err := s.DB.Begin()
 	if err != nil {
-		return
+		return nil, err
 	}
 	defer tx.Rollback()
 
-	// 1. Cari pasien by NIK
-	err = tx.QueryRow("SELECT id_pasien FROM Pasien WHERE NIK = ?", p.NIK).Scan(&idPasien)
+	// 1. Cari pasien by NIK. Jika NIK milik pasien yang sudah digabung (merge),
+	// kunjungan dicatat ke pasien utamanya.
+	h := &models.HasilPendaftaran{}
+	var mergedInto sql.NullInt64
+	err = tx.QueryRow("SELECT id_pasien, merged_into FROM Pasien WHERE NIK = ?", p.NIK).Scan(&h.IDPasien, &mergedInto)
 	if err != nil {
-		err = fmt.Errorf("pasien with NIK %s not found: %v", p.NIK, err)
-		return
+		return nil, fmt.Errorf("pasien with NIK %s not found: %v", p.NIK, err)
+	}
+	for hop := 0; mergedInto.Valid && hop < 10; hop++ {
+		h.IDPasien = mergedInto.Int64
+		err = tx.QueryRow("SELECT merged_into FROM Pasien WHERE id_pasien = ?", h.IDPasien).Scan(&mergedInto)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve merged pasien: %v", err)
+		}
 	}
 
 	// 1a. Cek duplicate antrian hari ini
@@ -206,15 +197,22 @@ func (s *PendaftaranService) UpdatePasienAndRegisterKunjungan(
 		LIMIT 1`,
 		idPoli, today,
 	).Scan(&lastAntrianPasien)
-	if err == nil && lastAntrianPasien == idPasien {
-		err = fmt.Errorf("duplicate entry: pasien dengan NIK %s baru saja mengambil antrian", p.NIK)
-		return
+	if err == nil && lastAntrianPasien == h.IDPasien {
+		return nil, fmt.Errorf("duplicate entry: pasien dengan NIK %s baru saja mengambil antrian", p.NIK)
 	} else if err != nil && err != sql.ErrNoRows {
-		err = fmt.Errorf("failed to check antrian duplicate: %v", err)
-		return
+		return nil, fmt.Errorf("failed to check antrian duplicate: %v", err)
+	}
+
+	// 1b. Kapasitas poli (baris poli terkunci sampai commit)
+	kap, masukTunggu, err := kapasitasPendaftaran(tx, idPoli, daftarTunggu)
+	if err != nil {
+		return nil, err
 	}
 
-	// 2. Update data Pasien
+	// 2. Update data Pasien (data lama disimpan sebagai versi agar tidak hilang)
+	if err = simpanVersiAwal(tx, h.IDPasien); err != nil {
+		return nil, err
+	}
 	_, err = tx.Exec(`
 		UPDATE Pasien 
 		SET Nama=?, Tanggal_Lahir=?, Jenis_Kelamin=?, Tempat_Lahir=?,
@@ -224,124 +222,53 @@ func (s *PendaftaranService) UpdatePasienAndRegisterKunjungan(
 		p.Nama, p.TanggalLahir, p.JenisKelamin, p.TempatLahir,
 		p.Kelurahan, p.Kecamatan, p.KotaTinggal, p.Alamat, p.NoTelp,
 		p.IDAgama, p.StatusPerkawinan, p.Pekerjaan,
-		idPasien,
+		h.IDPasien,
 	)
 	if err != nil {
-		err = fmt.Errorf("failed to update pasien: %v", err)
-		return
+		return nil, fmt.Errorf("failed to update pasien: %v", err)
+	}
+	if _, err = simpanVersiPasien(tx, h.IDPasien, VersiKunjungan, &actor, ""); err != nil {
+		return nil, err
 	}
 
 	// 3. Ambil id_rm terbaru
 	err = tx.QueryRow(`
 		SELECT id_rm 
 		FROM Rekam_Medis 
-		WHERE id_pasien=? 
+		WHERE id_pasien=? AND digabung_dari IS NULL
 		ORDER BY created_at DESC 
 		LIMIT 1`,
-		idPasien,
-	).Scan(&idRM)
-	if err != nil {
-		err = fmt.Errorf("failed to get Rekam_Medis for pasien: %v", err)
-		return
-	}
-
-	// 4. Insert Riwayat_Kunjungan
-	res, err := tx.Exec(`
-		INSERT INTO Riwayat_Kunjungan (id_rm, catatan)
-		VALUES (?, ?)`,
-		idRM, "",
-	)
-	if err != nil {
-		err = fmt.Errorf("failed to insert Riwayat_Kunjungan: %v", err)
-		return
-	}
-	idKunjungan, err = res.LastInsertId()
-	if err != nil {
-		err = fmt.Errorf("failed to get last insert id Riwayat_Kunjungan: %v", err)
-		return
-	}
-
-	// 5. Insert Kunjungan_Poli
-	_, err = tx.Exec(`
-		INSERT INTO Kunjungan_Poli (id_poli, id_kunjungan)
-		VALUES (?, ?)`,
-		idPoli, idKunjungan,
-	)
+		h.IDPasien,
+	).Scan(&h.IDRM)
 	if err != nil {
-		err = fmt.Errorf("failed to insert into Kunjungan_Poli: %v", err)
-		return
+		return nil, fmt.Errorf("failed to get Rekam_Medis for pasien: %v", err)
 	}
-
-	// 6. Hitung nomor antrian hari ini
-	var maxNomor sql.NullInt64
-	err = tx.QueryRow(`
-		SELECT COALESCE(MAX(nomor_antrian),0)
-		FROM Antrian
-		WHERE id_poli=? AND DATE(created_at)=?`,
-		idPoli, today,
-	).Scan(&maxNomor)
-	if err != nil {
-		err = fmt.Errorf("failed to get max nomor antrian: %v", err)
-		return
-	}
-	nomorAntrian = 1
-	if maxNomor.Valid && maxNomor.Int64 > 0 {
-		nomorAntrian = maxNomor.Int64 + 1
-	}
-
-	// 7. Ambil id_status “Menunggu”
-	err = tx.QueryRow(`
-		SELECT id_status
-		FROM Status_Antrian
-		WHERE status='Menunggu' LIMIT 1`,
-	).Scan(&idStatus)
-	if err != nil {
-		err = fmt.Errorf("failed to get id_status for 'Menunggu': %v", err)
-		return
+	if masukTunggu {
+		if h.DaftarTunggu, err = masukDaftarTunggu(tx, h.IDPasien, h.IDRM, idPoli, keluhanUtama, namaPenanggungJawab,
+			penjamin, idTriase, kap, actor); err != nil {
+			return nil, err
+		}
+		h.NamaPoli = h.DaftarTunggu.NamaPoli
+		if err = tx.Commit(); err != nil {
+			return nil, err
+		}
+		return h, nil
 	}
 
-	// 8. Insert Antrian dengan nama_penanggung_jawab
-	res, err = tx.Exec(`
-		INSERT INTO Antrian
-		  (id_pasien, id_poli, keluhan_utama, nomor_antrian,
-		   id_status, priority_order, created_at, nama_penanggung_jawab)
-		VALUES (?, ?, ?, ?, ?, ?, NOW(), ?)`,
-		idPasien, idPoli, keluhanUtama, nomorAntrian, idStatus, nomorAntrian, namaPenanggungJawab,
-	)
+	// 4–10. Riwayat_Kunjungan, Kunjungan_Poli, Antrian, Billing (id_karyawan billing kosong)
+	k, err := buatKunjungan(tx, h.IDPasien, h.IDRM, idPoli, keluhanUtama, namaPenanggungJawab,
+		sql.NullInt64{}, nil, actor, "kunjungan pasien lama")
 	if err != nil {
-		err = fmt.Errorf("failed to insert into Antrian: %v", err)
-		return
-	}
-	idAntrian, err = res.LastInsertId()
-	if err != nil {
-		err = fmt.Errorf("failed to get id_antrian: %v", err)
-		return
+		return nil, err
 	}
-
-	// 9. Insert Billing (tetap dilakukan, tanpa update RK)
-	_, err = tx.Exec(`
-		INSERT INTO Billing
-		  (id_kunjungan, id_antrian, id_karyawan,
-		   id_assessment, tipe_pembayaran,
-		   total, id_status, created_at, updated_at)
-		VALUES (?, ?, NULL, NULL, NULL, NULL, 1, NOW(), NOW())`,
-		idKunjungan, idAntrian,
-	)
-	if err != nil {
-		err = fmt.Errorf("failed to insert into Billing: %v", err)
-		return
+	h.IDKunjungan, h.IDAntrian, h.NomorAntrian, h.KodeTiket, h.IDStatus = k.IDKunjungan, k.IDAntrian, k.NomorAntrian, k.KodeTiket, k.IDStatus
+	if _, err = pilihPenjamin(tx, h.IDPasien, h.IDKunjungan, penjamin, actor.IDKaryawan); err != nil {
+		return nil, err
 	}
-
-	// 10. Update Riwayat_Kunjungan hanya dengan id_antrian
-	_, err = tx.Exec(`
-		UPDATE Riwayat_Kunjungan
-		SET id_antrian = ?
-		WHERE id_kunjungan = ?`,
-		idAntrian, idKunjungan,
-	)
-	if err != nil {
-		err = fmt.Errorf("failed to update Riwayat_Kunjungan: %v", err)
-		return
+	if idTriase != 0 {
+		if _, err = tetapkanTriase(tx, h.IDAntrian, idTriase, actor.IDKaryawan); err != nil {
+			return nil, err
+		}
 	}
 
 	// 11. Ambil nama_poli
@@ -350,15 +277,17 @@ func (s *PendaftaranService) UpdatePasienAndRegisterKunjungan(
 		FROM Poliklinik
 		WHERE id_poli = ?`,
 		idPoli,
-	).Scan(&namaPoli)
+	).Scan(&h.NamaPoli)
 	if err != nil {
-		err = fmt.Errorf("failed to get nama_poli: %v", err)
-		return
+		return nil, fmt.Errorf("failed to get nama_poli: %v", err)
 	}
 
 	// Commit
-	err = tx.Commit()
-	return
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+	antrian.PublishEvent(s.DB, ws.EventAntrianCreated, int(h.IDAntrian), 0, antrian.StatusMenunggu, actor, "kunjungan pasien lama")
+	return h, nil
 }
 
 
@@ -371,7 +300,8 @@ func (s *PendaftaranService) GetAllPasienDataFiltered(namaFilter string, page, l
 		FROM Pasien p
 		LEFT JOIN Agama a ON p.id_agama = a.id_agama
 	`
-	conditions := []string{}
+	// Pasien yang sudah digabung ke pasien lain tidak ditampilkan
+	conditions := []string{"p.merged_into IS NULL"}
 	args := []interface{}{}
 
 	// Jika ada filter nama, tambahkan kondisi WHERE
@@ -444,132 +374,125 @@ func (s *PendaftaranService) GetAllPasienDataFiltered(namaFilter string, page, l
 	return results, nil
 }
 
-func (s *PendaftaranService) TundaPasien(idAntrian int) error {
-    // 1. Periksa apakah antrian ada
-    var exists bool
-    err := s.DB.QueryRow("SELECT EXISTS(SELECT 1 FROM Antrian WHERE id_antrian = ?)", idAntrian).Scan(&exists)
+// TundaPasien memindahkan antrian ke status Ditunda lewat state machine. Penundaan ditolak
+// (ErrBatasTunda) jika antrian sudah ditunda sebanyak maks_tunda kebijakan reschedule poli.
+func (s *PendaftaranService) TundaPasien(idAntrian int, actor antrian.Actor, alasan string) error {
+    tx, err := s.DB.Begin()
     if err != nil {
-        return fmt.Errorf("gagal memeriksa keberadaan antrian: %v", err)
-    }
-    if !exists {
-        return fmt.Errorf("antrian dengan id %d tidak ditemukan", idAntrian)
+        return fmt.Errorf("gagal memulai transaksi: %v", err)
     }
+    defer tx.Rollback()
 
-    // 2. Dapatkan id_status untuk "Ditunda"
-    var idStatus int
-    err = s.DB.QueryRow("SELECT id_status FROM Status_Antrian WHERE status = 'Ditunda' LIMIT 1").Scan(&idStatus)
-    if err != nil {
-        if err == sql.ErrNoRows {
-            return fmt.Errorf("status 'Ditunda' tidak ditemukan di tabel Status_Antrian")
-        }
-        return fmt.Errorf("gagal mendapatkan id_status untuk 'Ditunda': %v", err)
+    if _, err = antrian.Lock(tx, idAntrian); err != nil {
+        return err
     }
-
-    // 3. Update status antrian
-    query := `UPDATE Antrian SET id_status = ? WHERE id_antrian = ?`
-    result, err := s.DB.Exec(query, idStatus, idAntrian)
+    var idPoli, jumlahTunda int
+    if err = tx.QueryRow("SELECT id_poli, jumlah_tunda FROM Antrian WHERE id_antrian = ?", idAntrian).
+        Scan(&idPoli, &jumlahTunda); err != nil {
+        return fmt.Errorf("gagal menemukan antrian: %v", err)
+    }
+    k, err := kebijakanReschedule(tx, idPoli)
     if err != nil {
-        return fmt.Errorf("gagal mengupdate status antrian: %v", err)
+        return err
     }
 
-    // 4. Periksa apakah ada baris yang terupdate
-    rowsAffected, err := result.RowsAffected()
+    // Transisi dulu agar status yang tidak bisa ditunda dilaporkan sebagai transisi tidak valid
+    from, err := antrian.Transition(tx, idAntrian, antrian.StatusDitunda, actor, alasan)
     if err != nil {
-        return fmt.Errorf("gagal memeriksa jumlah baris yang terupdate: %v", err)
+        return err
     }
-    if rowsAffected == 0 {
-        return fmt.Errorf("tidak ada baris yang terupdate, antrian dengan id %d mungkin tidak ada", idAntrian)
+    if k.MaksTunda != nil && jumlahTunda >= *k.MaksTunda {
+        return fmt.Errorf("%w: antrian sudah ditunda %d kali (maksimal %d)", ErrBatasTunda, jumlahTunda, *k.MaksTunda)
     }
-
+    if _, err = tx.Exec("UPDATE Antrian SET jumlah_tunda = jumlah_tunda + 1, kembali_at = NULL WHERE id_antrian = ?", idAntrian); err != nil {
+        return fmt.Errorf("gagal mengupdate antrian: %v", err)
+    }
+    if err = tx.Commit(); err != nil {
+        return fmt.Errorf("gagal commit transaksi: %v", err)
+    }
+    antrian.PublishEvent(s.DB, ws.EventAntrianStatusChanged, idAntrian, from, antrian.StatusDitunda, actor, alasan)
     return nil
 }
 
 
-func (s *PendaftaranService) RescheduleAntrianPriority(idAntrian int) (int64, error) {
-    // 1. Periksa apakah antrian ada dan dalam status "Ditunda", sekaligus ambil id_poli
-    var currentStatus int
-    var idPoli int
-    err := s.DB.QueryRow("SELECT id_status, id_poli FROM Antrian WHERE id_antrian = ?", idAntrian).Scan(&currentStatus, &idPoli)
+// RescheduleAntrianPriority mengembalikan antrian Ditunda ke Menunggu sesuai kebijakan
+// reschedule poli (Sisip / Akhir / Jam). Pada mode Jam sebelum jam kembali, antrian tetap
+// Ditunda dengan kembali_at terisi dan dimasukkan kembali oleh RescheduleService.
+// Hasil menjelaskan slot yang dipilih dan ikut dikirim ke layar display.
+func (s *PendaftaranService) RescheduleAntrianPriority(idAntrian int, actor antrian.Actor, alasan string) (*models.HasilReschedule, error) {
+    tx, err := s.DB.Begin()
     if err != nil {
-        if err == sql.ErrNoRows {
-            return 0, fmt.Errorf("antrian dengan id %d tidak ditemukan", idAntrian)
-        }
-        return 0, fmt.Errorf("gagal menemukan antrian: %v", err)
+        return nil, fmt.Errorf("gagal memulai transaksi: %v", err)
     }
-    if currentStatus != 2 { // Misalkan 2 adalah id_status untuk "Ditunda"
-        return 0, fmt.Errorf("antrian tidak dalam status 'Ditunda', status saat ini: %d", currentStatus)
-    }
-
-    // 2. Tentukan hari ini
-    today := time.Now().Format("2006-01-02")
+    defer tx.Rollback()
 
-    // 3. Cari MIN(nomor_antrian) dari antrian dengan status "Menunggu" (id_status = 1)
-    var minWaiting sql.NullInt64
-    queryMin := `
-        SELECT MIN(nomor_antrian)
-        FROM Antrian
-        WHERE id_poli = ? AND DATE(created_at) = ? AND id_status = 1
-    `
-    err = s.DB.QueryRow(queryMin, idPoli, today).Scan(&minWaiting)
+    // 1. Kunci antrian & pastikan statusnya "Ditunda"
+    currentStatus, err := antrian.Lock(tx, idAntrian)
     if err != nil {
-        return 0, fmt.Errorf("gagal mendapatkan nomor antrian minimum untuk 'Menunggu': %v", err)
+        return nil, err
     }
-
-    // 4. Hitung jumlah antrian waiting (id_status = 1) untuk id_poli hari ini
-    var countWaiting int
-    queryCount := `
-        SELECT COUNT(*)
-        FROM Antrian
-        WHERE id_poli = ? AND DATE(created_at) = ? AND id_status = 1
-    `
-    err = s.DB.QueryRow(queryCount, idPoli, today).Scan(&countWaiting)
-    if err != nil {
-        return 0, fmt.Errorf("gagal menghitung jumlah antrian menunggu: %v", err)
+    if currentStatus != antrian.StatusDitunda {
+        return nil, fmt.Errorf("%w: antrian tidak dalam status 'Ditunda', status saat ini: %s",
+            antrian.ErrTransisiTidakValid, antrian.NamaStatus(currentStatus))
     }
-
-    // 5. Tentukan newPriority
-    var newPriority int64
-    if minWaiting.Valid {
-        if countWaiting >= 2 {
-            newPriority = minWaiting.Int64 + 2
-        } else {
-            newPriority = minWaiting.Int64 + 1
-        }
-    } else {
-        newPriority = 1 // Jika tidak ada antrian menunggu
+    var (
+        idPoli, jumlahTunda int
+        kembaliAt           sql.NullTime
+    )
+    if err = tx.QueryRow("SELECT id_poli, jumlah_tunda, kembali_at FROM Antrian WHERE id_antrian = ?", idAntrian).
+        Scan(&idPoli, &jumlahTunda, &kembaliAt); err != nil {
+        return nil, fmt.Errorf("gagal menemukan antrian: %v", err)
     }
 
-    // 6. Ambil id_status untuk "Menunggu"
-    var waitingStatus int
-    err = s.DB.QueryRow("SELECT id_status FROM Status_Antrian WHERE status = 'Menunggu' LIMIT 1").Scan(&waitingStatus)
+    // 2. Kebijakan poli
+    k, err := kebijakanReschedule(tx, idPoli)
     if err != nil {
-        if err == sql.ErrNoRows {
-            return 0, fmt.Errorf("status 'Menunggu' tidak ditemukan di tabel Status_Antrian")
-        }
-        return 0, fmt.Errorf("gagal mendapatkan id_status untuk 'Menunggu': %v", err)
+        return nil, err
     }
-
-    // 7. Update record antrian
-    updateQuery := `
-        UPDATE Antrian
-        SET priority_order = ?, id_status = ?
-        WHERE id_antrian = ?
-    `
-    result, err := s.DB.Exec(updateQuery, newPriority, waitingStatus, idAntrian)
-    if err != nil {
-        return 0, fmt.Errorf("gagal mengupdate antrian: %v", err)
+    hasil := &models.HasilReschedule{IDAntrian: idAntrian, Mode: k.Mode, JumlahTunda: jumlahTunda, MaksTunda: k.MaksTunda}
+
+    // 3. Tentukan slot
+    setelah := k.SisipSetelah
+    switch k.Mode {
+    case RescheduleAkhir:
+        setelah = -1
+    case RescheduleJam:
+        now := time.Now()
+        kembali, err := jamKembaliHariIni(now, *k.JamKembali)
+        if err != nil {
+            return nil, err
+        }
+        if kembaliAt.Valid {
+            kembali = kembaliAt.Time // jadwal yang sudah dijanjikan tidak berubah
+        }
+        if now.Before(kembali) {
+            // Tetap Ditunda sampai jam kembali
+            if _, err = tx.Exec("UPDATE Antrian SET kembali_at = ? WHERE id_antrian = ?", kembali, idAntrian); err != nil {
+                return nil, fmt.Errorf("gagal mengupdate antrian: %v", err)
+            }
+            if err = tx.Commit(); err != nil {
+                return nil, fmt.Errorf("gagal commit transaksi: %v", err)
+            }
+            hasil.IDStatus, hasil.Status = antrian.StatusDitunda, antrian.NamaStatus(antrian.StatusDitunda)
+            hasil.KembaliAt = &kembali
+            hasil.Keterangan = fmt.Sprintf("Masuk kembali ke depan antrian pukul %s", kembali.Format("15:04"))
+            antrian.PublishJadwalUlang(s.DB, ws.EventAntrianRescheduled, idAntrian, antrian.StatusDitunda,
+                antrian.StatusDitunda, actor, alasan, jadwalUlang(hasil))
+            return hasil, nil
+        }
+        setelah = 0 // jam kembali sudah lewat: langsung ke depan antrian
     }
 
-    // 8. Periksa apakah ada baris yang terupdate
-    rowsAffected, err := result.RowsAffected()
-    if err != nil {
-        return 0, fmt.Errorf("gagal memeriksa jumlah baris yang terupdate: %v", err)
+    // 4. Ditunda -> Menunggu lewat state machine, lalu set priority_order
+    if err = masukkanKembali(tx, hasil, idPoli, setelah, actor, alasan); err != nil {
+        return nil, err
     }
-    if rowsAffected == 0 {
-        return 0, fmt.Errorf("tidak ada baris yang terupdate, antrian dengan id %d mungkin tidak ada", idAntrian)
+    if err = tx.Commit(); err != nil {
+        return nil, fmt.Errorf("gagal commit transaksi: %v", err)
     }
-
-    return newPriority, nil
+    antrian.PublishJadwalUlang(s.DB, ws.EventAntrianRescheduled, idAntrian, antrian.StatusDitunda,
+        antrian.StatusMenunggu, actor, alasan, jadwalUlang(hasil))
+    return hasil, nil
 }
 
 // GetAntrianToday mengambil data antrian hari ini dengan join ke Pasien, Rekam_Medis, Poliklinik, dan Status_Antrian.
@@ -589,7 +512,7 @@ func (s *PendaftaranService) GetAntrianToday(statusFilter string) ([]map[string]
             a.priority_order
         FROM Antrian a
         JOIN Pasien p ON a.id_pasien = p.id_pasien
-        JOIN Rekam_Medis rm ON p.id_pasien = rm.id_pasien
+        JOIN Rekam_Medis rm ON p.id_pasien = rm.id_pasien AND rm.digabung_dari IS NULL
         JOIN Poliklinik pol ON a.id_poli = pol.id_poli
         JOIN Status_Antrian sa ON a.id_status = sa.id_status
         WHERE DATE(a.created_at) = CURDATE()
@@ -680,23 +603,22 @@ func (s *PendaftaranService) GetAllStatusAntrian() ([]map[string]interface{}, er
 	return list, nil
 }
 
-func (s *PendaftaranService) BatalkanAntrian(idAntrian int) (idKunjungan int, err error) {
-	// 1. Update status antrian to cancelled (id_status = 7)
-	updateAntrianQuery := "UPDATE Antrian SET id_status = ? WHERE id_antrian = ?"
-	result, err := s.DB.Exec(updateAntrianQuery, 7, idAntrian)
+// BatalkanAntrian memindahkan antrian ke Dibatalkan dan membatalkan billing-nya dalam satu transaksi.
+func (s *PendaftaranService) BatalkanAntrian(idAntrian int, actor antrian.Actor, alasan string) (idKunjungan int, err error) {
+	tx, err := s.DB.Begin()
 	if err != nil {
-			return 0, fmt.Errorf("gagal membatalkan antrian: %v", err)
+		return 0, fmt.Errorf("gagal memulai transaksi: %v", err)
 	}
-	rowsAffected, err := result.RowsAffected()
+	defer tx.Rollback()
+
+	// 1. Update status antrian ke Dibatalkan lewat state machine
+	from, err := antrian.Transition(tx, idAntrian, antrian.StatusDibatalkan, actor, alasan)
 	if err != nil {
-			return 0, fmt.Errorf("gagal memeriksa update antrian: %v", err)
-	}
-	if rowsAffected == 0 {
-			return 0, fmt.Errorf("antrian dengan id %d tidak ditemukan", idAntrian)
+		return 0, err
 	}
 
-	// 2. Retrieve id_kunjungan from Riwayat_Kunjungan
-	err = s.DB.QueryRow("SELECT id_kunjungan FROM Riwayat_Kunjungan WHERE id_antrian = ?", idAntrian).Scan(&idKunjungan)
+	// 2. Retrieve id_kunjungan (antrian pendaftaran atau antrian rujukan internal)
+	idKunjungan, err = kunjunganAntrian(tx, idAntrian)
 	if err != nil {
 			if err == sql.ErrNoRows {
 					return 0, fmt.Errorf("kunjungan untuk antrian %d tidak ditemukan", idAntrian)
@@ -704,19 +626,24 @@ func (s *PendaftaranService) BatalkanAntrian(idAntrian int) (idKunjungan int, er
 			return 0, fmt.Errorf("gagal mengambil id_kunjungan: %v", err)
 	}
 
-	// 3. Update billing status to cancelled (id_status = 4)
+	// 3. Update billing status to cancelled (id_status = 3). Billing milik antrian pendaftaran,
+	// sehingga membatalkan antrian rujukan internal tidak ikut membatalkan tagihan kunjungan.
 	updateBillingQuery := `
 			UPDATE Billing b
 			JOIN Riwayat_Kunjungan rk ON b.id_kunjungan = rk.id_kunjungan
 			SET b.id_status = 3
 			WHERE rk.id_antrian = ?
 	`
-	_, err = s.DB.Exec(updateBillingQuery, idAntrian)
+	_, err = tx.Exec(updateBillingQuery, idAntrian)
 	if err != nil {
 			return 0, fmt.Errorf("gagal mengupdate status billing: %v", err)
 	}
 	// Note: Not checking rowsAffected here; it’s acceptable if no billing exists yet
 
+	if err = tx.Commit(); err != nil {
+		return 0, fmt.Errorf("gagal commit transaksi: %v", err)
+	}
+	antrian.PublishEvent(s.DB, ws.EventAntrianCancelled, idAntrian, from, antrian.StatusDibatalkan, actor, alasan)
 	return idKunjungan, nil
 }
 
@@ -742,10 +669,11 @@ func (s *PendaftaranService) GetDetailAntrianByID(idAntrian int) (map[string]int
 			a.keluhan_utama,
 			a.nama_penanggung_jawab,
 			rm.id_rm,
-			a.nomor_antrian
+			a.nomor_antrian,
+			a.kode_tiket
 		FROM Antrian a
 		JOIN Pasien        p  ON a.id_pasien = p.id_pasien
-		JOIN Rekam_Medis   rm ON p.id_pasien = rm.id_pasien
+		JOIN Rekam_Medis   rm ON p.id_pasien = rm.id_pasien AND rm.digabung_dari IS NULL
 		LEFT JOIN Agama    ag ON p.id_agama  = ag.id_agama
 		WHERE a.id_antrian = ?
 		ORDER BY rm.created_at DESC
@@ -762,6 +690,7 @@ func (s *PendaftaranService) GetDetailAntrianByID(idAntrian int) (map[string]int
 		statusPK                      int          // 0/1
 		keluhan, penanggungJawab      string
 		idRM                          string
+		kodeTiket                     sql.NullString
 	)
 	if err := s.DB.QueryRow(query, idAntrian).Scan(
 		&idPasien, &nama, &jk, &tmpLhr,
@@ -769,7 +698,7 @@ func (s *PendaftaranService) GetDetailAntrianByID(idAntrian int) (map[string]int
 		&alamat, &kota, &kel, &kec,
 		&agama, &pekerjaan, &statusPK,
 		&keluhan, &penanggungJawab,
-		&idRM, &nomorAntrian,
+		&idRM, &nomorAntrian, &kodeTiket,
 	); err != nil {
 		return nil, fmt.Errorf("failed to get detail data: %v", err)
 	}
@@ -813,6 +742,7 @@ func (s *PendaftaranService) GetDetailAntrianByID(idAntrian int) (map[string]int
 	return map[string]interface{}{
 		"id_antrian":        idAntrian,
 		"nomor_antrian":     nomorAntrian,
+		"kode_tiket":        kodeTiket.String,
 		"id_pasien":         idPasien,
 		"nama_pasien":       nama,
 		"id_rm":             idRM,
@@ -863,4 +793,8 @@ func (s *PendaftaranService) GetAgamaList() ([]Agama, error) {
 	}
 
 	return agamaList, nil
-}
\ No newline at end of file
+}
+// GetAntrianLog mengembalikan riwayat perubahan status antrian (siapa & kapan).
+func (s *PendaftaranService) GetAntrianLog(idAntrian int) ([]antrian.LogEntry, error) {
+	return antrian.GetLog(s.DB, idAntrian)
+}
@@ -0,0 +1,567 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/c14220110/poliklinik-backend/internal/administrasi/models"
+)
+
+// Komponen tagihan pada Aturan_Penjamin.
+const (
+	KomponenDokter   = "dokter"
+	KomponenObat     = "obat"
+	KomponenTindakan = "tindakan"
+)
+
+var jenisPenjamin = map[string]bool{"BPJS": true, "Asuransi": true, "Perusahaan": true}
+
+var (
+	ErrPenjaminTidakDitemukan    = errors.New("penjamin tidak ditemukan")
+	ErrPenjaminTidakValid        = errors.New("data penjamin tidak valid")
+	ErrKepesertaanTidakDitemukan = errors.New("kepesertaan penjamin tidak ditemukan")
+	ErrKepesertaanTidakValid     = errors.New("data kepesertaan tidak valid")
+	ErrKepesertaanTidakBerlaku   = errors.New("kepesertaan penjamin tidak berlaku")
+	ErrNomorPesertaDipakai       = errors.New("nomor peserta sudah terdaftar pada kepesertaan lain")
+)
+
+type PenjaminService struct {
+	DB *sql.DB
+}
+
+func NewPenjaminService(db *sql.DB) *PenjaminService {
+	return &PenjaminService{DB: db}
+}
+
+// ListPenjamin mengembalikan penjamin beserta aturannya. hanyaAktif dipakai untuk pilihan
+// di pendaftaran.
+func (s *PenjaminService) ListPenjamin(hanyaAktif bool) ([]models.Penjamin, error) {
+	query := "SELECT id_penjamin, nama, jenis, aktif, created_at, updated_at FROM Penjamin"
+	if hanyaAktif {
+		query += " WHERE aktif = 1"
+	}
+	rows, err := s.DB.Query(query + " ORDER BY nama")
+	if err != nil {
+		return nil, fmt.Errorf("gagal mengambil penjamin: %v", err)
+	}
+	defer rows.Close()
+
+	list := []models.Penjamin{}
+	index := map[int]int{}
+	for rows.Next() {
+		var p models.Penjamin
+		if err := rows.Scan(&p.IDPenjamin, &p.Nama, &p.Jenis, &p.Aktif, &p.CreatedAt, &p.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("gagal membaca penjamin: %v", err)
+		}
+		p.Aturan = []models.AturanPenjamin{}
+		index[p.IDPenjamin] = len(list)
+		list = append(list, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	aturanRows, err := s.DB.Query(`
+		SELECT id_penjamin, komponen, id_icd9_cm, persen_tanggungan, plafon
+		FROM Aturan_Penjamin ORDER BY id_penjamin, komponen, id_icd9_cm IS NOT NULL, id_icd9_cm`)
+	if err != nil {
+		return nil, fmt.Errorf("gagal mengambil aturan penjamin: %v", err)
+	}
+	defer aturanRows.Close()
+	for aturanRows.Next() {
+		var (
+			idPenjamin int
+			a          models.AturanPenjamin
+			icd        sql.NullString
+			plafon     sql.NullFloat64
+		)
+		if err := aturanRows.Scan(&idPenjamin, &a.Komponen, &icd, &a.PersenTanggungan, &plafon); err != nil {
+			return nil, fmt.Errorf("gagal membaca aturan penjamin: %v", err)
+		}
+		i, ok := index[idPenjamin]
+		if !ok {
+			continue
+		}
+		if icd.Valid {
+			a.IDICD9CM = &icd.String
+		}
+		if plafon.Valid {
+			a.Plafon = &plafon.Float64
+		}
+		list[i].Aturan = append(list[i].Aturan, a)
+	}
+	return list, aturanRows.Err()
+}
+
+func validasiPenjamin(p models.Penjamin) error {
+	if strings.TrimSpace(p.Nama) == "" {
+		return fmt.Errorf("%w: nama harus diisi", ErrPenjaminTidakValid)
+	}
+	if !jenisPenjamin[p.Jenis] {
+		return fmt.Errorf("%w: jenis harus BPJS, Asuransi, atau Perusahaan", ErrPenjaminTidakValid)
+	}
+	return nil
+}
+
+// TambahPenjamin membuat penjamin baru (aktif) tanpa aturan; semua komponen belum ditanggung.
+func (s *PenjaminService) TambahPenjamin(p models.Penjamin) (int, error) {
+	if err := validasiPenjamin(p); err != nil {
+		return 0, err
+	}
+	res, err := s.DB.Exec(`
+		INSERT INTO Penjamin (nama, jenis, aktif, created_at, updated_at)
+		VALUES (?, ?, 1, NOW(), NOW())`, strings.TrimSpace(p.Nama), p.Jenis)
+	if err != nil {
+		if strings.Contains(err.Error(), "Duplicate entry") {
+			return 0, fmt.Errorf("%w: nama %s sudah dipakai", ErrPenjaminTidakValid, p.Nama)
+		}
+		return 0, fmt.Errorf("gagal menambah penjamin: %v", err)
+	}
+	id, err := res.LastInsertId()
+	return int(id), err
+}
+
+// UpdatePenjamin mengubah nama, jenis, dan (jika aktif tidak nil) status aktif penjamin.
+// Penjamin yang tidak aktif tidak bisa dipilih di pendaftaran baru, tetapi kunjungan yang
+// sudah tercatat tetap dihitung dengan aturannya.
+func (s *PenjaminService) UpdatePenjamin(idPenjamin int, p models.Penjamin, aktif *bool) error {
+	if err := validasiPenjamin(p); err != nil {
+		return err
+	}
+	res, err := s.DB.Exec(`
+		UPDATE Penjamin SET nama = ?, jenis = ?, aktif = IFNULL(?, aktif), updated_at = NOW()
+		WHERE id_penjamin = ?`, strings.TrimSpace(p.Nama), p.Jenis, aktif, idPenjamin)
+	if err != nil {
+		if strings.Contains(err.Error(), "Duplicate entry") {
+			return fmt.Errorf("%w: nama %s sudah dipakai", ErrPenjaminTidakValid, p.Nama)
+		}
+		return fmt.Errorf("gagal mengupdate penjamin: %v", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		var ada int
+		if err := s.DB.QueryRow("SELECT COUNT(*) FROM Penjamin WHERE id_penjamin = ?", idPenjamin).Scan(&ada); err == nil && ada == 0 {
+			return ErrPenjaminTidakDitemukan
+		}
+	}
+	return nil
+}
+
+// SetAturan mengganti seluruh aturan tanggungan penjamin.
+func (s *PenjaminService) SetAturan(idPenjamin int, aturan []models.AturanPenjamin) error {
+	seen := map[string]bool{}
+	for _, a := range aturan {
+		switch a.Komponen {
+		case KomponenDokter, KomponenObat, KomponenTindakan:
+		default:
+			return fmt.Errorf("%w: komponen %q harus dokter, obat, atau tindakan", ErrPenjaminTidakValid, a.Komponen)
+		}
+		if a.IDICD9CM != nil && a.Komponen != KomponenTindakan {
+			return fmt.Errorf("%w: id_icd9_cm hanya untuk komponen tindakan", ErrPenjaminTidakValid)
+		}
+		if a.PersenTanggungan < 0 || a.PersenTanggungan > 100 {
+			return fmt.Errorf("%w: persen_tanggungan harus 0-100", ErrPenjaminTidakValid)
+		}
+		if a.Plafon != nil && *a.Plafon < 0 {
+			return fmt.Errorf("%w: plafon tidak boleh negatif", ErrPenjaminTidakValid)
+		}
+		key := a.Komponen
+		if a.IDICD9CM != nil {
+			key += "/" + *a.IDICD9CM
+		}
+		if seen[key] {
+			return fmt.Errorf("%w: aturan %s ganda", ErrPenjaminTidakValid, key)
+		}
+		seen[key] = true
+	}
+
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return fmt.Errorf("gagal memulai transaksi: %v", err)
+	}
+	defer tx.Rollback()
+
+	var ada int
+	if err := tx.QueryRow("SELECT COUNT(*) FROM Penjamin WHERE id_penjamin = ? FOR UPDATE", idPenjamin).Scan(&ada); err != nil {
+		return fmt.Errorf("gagal mengambil penjamin: %v", err)
+	}
+	if ada == 0 {
+		return ErrPenjaminTidakDitemukan
+	}
+	if _, err := tx.Exec("DELETE FROM Aturan_Penjamin WHERE id_penjamin = ?", idPenjamin); err != nil {
+		return fmt.Errorf("gagal menghapus aturan penjamin: %v", err)
+	}
+	for _, a := range aturan {
+		if a.IDICD9CM != nil {
+			var n int
+			if err := tx.QueryRow("SELECT COUNT(*) FROM ICD9_CM WHERE id_icd9_cm = ?", *a.IDICD9CM).Scan(&n); err != nil {
+				return fmt.Errorf("gagal memeriksa ICD9_CM: %v", err)
+			}
+			if n == 0 {
+				return fmt.Errorf("%w: icd9_cm %s tidak ada", ErrPenjaminTidakValid, *a.IDICD9CM)
+			}
+		}
+		if _, err := tx.Exec(`
+			INSERT INTO Aturan_Penjamin (id_penjamin, komponen, id_icd9_cm, persen_tanggungan, plafon)
+			VALUES (?, ?, ?, ?, ?)`,
+			idPenjamin, a.Komponen, a.IDICD9CM, a.PersenTanggungan, a.Plafon,
+		); err != nil {
+			return fmt.Errorf("gagal menyimpan aturan penjamin: %v", err)
+		}
+	}
+	if _, err := tx.Exec("UPDATE Penjamin SET updated_at = NOW() WHERE id_penjamin = ?", idPenjamin); err != nil {
+		return fmt.Errorf("gagal mengupdate penjamin: %v", err)
+	}
+	return tx.Commit()
+}
+
+// GetKepesertaanPasien mengembalikan semua kepesertaan pasien, yang berlaku hari ini lebih dulu.
+func (s *PenjaminService) GetKepesertaanPasien(idPasien int) ([]models.KepesertaanPenjamin, error) {
+	rows, err := s.DB.Query(`
+		SELECT kp.id_kepesertaan, kp.id_pasien, kp.id_penjamin, pj.nama, pj.jenis, kp.nomor_peserta,
+		       DATE_FORMAT(kp.berlaku_mulai, '%Y-%m-%d'), DATE_FORMAT(kp.berlaku_sampai, '%Y-%m-%d'),
+		       kp.berlaku_mulai <= CURDATE() AND (kp.berlaku_sampai IS NULL OR kp.berlaku_sampai >= CURDATE()) AS berlaku,
+		       kp.terverifikasi, kp.created_at
+		FROM Kepesertaan_Penjamin kp
+		JOIN Penjamin pj ON pj.id_penjamin = kp.id_penjamin
+		WHERE kp.id_pasien = ?
+		ORDER BY berlaku DESC, kp.berlaku_mulai DESC`, idPasien)
+	if err != nil {
+		return nil, fmt.Errorf("gagal mengambil kepesertaan: %v", err)
+	}
+	defer rows.Close()
+
+	list := []models.KepesertaanPenjamin{}
+	for rows.Next() {
+		var (
+			k      models.KepesertaanPenjamin
+			sampai sql.NullString
+		)
+		if err := rows.Scan(&k.IDKepesertaan, &k.IDPasien, &k.IDPenjamin, &k.NamaPenjamin, &k.JenisPenjamin,
+			&k.NomorPeserta, &k.BerlakuMulai, &sampai, &k.Berlaku, &k.Terverifikasi, &k.CreatedAt); err != nil {
+			return nil, fmt.Errorf("gagal membaca kepesertaan: %v", err)
+		}
+		if sampai.Valid {
+			k.BerlakuSampai = &sampai.String
+		}
+		list = append(list, k)
+	}
+	return list, rows.Err()
+}
+
+// ListKepesertaanBelumVerifikasi mengembalikan kepesertaan yang dicatat otomatis saat
+// pendaftaran dan belum dikonfirmasi manajemen, yang terlama lebih dulu.
+func (s *PenjaminService) ListKepesertaanBelumVerifikasi() ([]models.KepesertaanPenjamin, error) {
+	rows, err := s.DB.Query(`
+		SELECT kp.id_kepesertaan, kp.id_pasien, p.nama, kp.id_penjamin, pj.nama, pj.jenis, kp.nomor_peserta,
+		       DATE_FORMAT(kp.berlaku_mulai, '%Y-%m-%d'), DATE_FORMAT(kp.berlaku_sampai, '%Y-%m-%d'),
+		       kp.berlaku_mulai <= CURDATE() AND (kp.berlaku_sampai IS NULL OR kp.berlaku_sampai >= CURDATE()) AS berlaku,
+		       kp.created_at
+		FROM Kepesertaan_Penjamin kp
+		JOIN Penjamin pj ON pj.id_penjamin = kp.id_penjamin
+		JOIN Pasien p ON p.id_pasien = kp.id_pasien
+		WHERE kp.terverifikasi = 0
+		ORDER BY kp.created_at, kp.id_kepesertaan`)
+	if err != nil {
+		return nil, fmt.Errorf("gagal mengambil kepesertaan: %v", err)
+	}
+	defer rows.Close()
+
+	list := []models.KepesertaanPenjamin{}
+	for rows.Next() {
+		var (
+			k      models.KepesertaanPenjamin
+			sampai sql.NullString
+		)
+		if err := rows.Scan(&k.IDKepesertaan, &k.IDPasien, &k.NamaPasien, &k.IDPenjamin, &k.NamaPenjamin, &k.JenisPenjamin,
+			&k.NomorPeserta, &k.BerlakuMulai, &sampai, &k.Berlaku, &k.CreatedAt); err != nil {
+			return nil, fmt.Errorf("gagal membaca kepesertaan: %v", err)
+		}
+		if sampai.Valid {
+			k.BerlakuSampai = &sampai.String
+		}
+		list = append(list, k)
+	}
+	return list, rows.Err()
+}
+
+// VerifikasiKepesertaan mengonfirmasi kepesertaan yang dicatat saat pendaftaran, dengan masa
+// berlaku dari kartu peserta. Sejak itu tagihan kunjungan yang belum dibayar dibagi dengan penjamin.
+func (s *PenjaminService) VerifikasiKepesertaan(idKepesertaan int, req models.KepesertaanRequest, idManagement int) error {
+	mulai, sampai, err := parseMasaBerlaku(req.BerlakuMulai, req.BerlakuSampai)
+	if err != nil {
+		return err
+	}
+	var terverifikasi bool
+	err = s.DB.QueryRow("SELECT terverifikasi FROM Kepesertaan_Penjamin WHERE id_kepesertaan = ?", idKepesertaan).Scan(&terverifikasi)
+	if err == sql.ErrNoRows {
+		return ErrKepesertaanTidakDitemukan
+	}
+	if err != nil {
+		return fmt.Errorf("gagal mengambil kepesertaan: %v", err)
+	}
+	if terverifikasi {
+		return fmt.Errorf("%w: kepesertaan sudah terverifikasi", ErrKepesertaanTidakValid)
+	}
+	if _, err := s.DB.Exec(`
+		UPDATE Kepesertaan_Penjamin
+		SET terverifikasi = 1, berlaku_mulai = ?, berlaku_sampai = ?, verified_by = ?, verified_at = NOW(), updated_at = NOW()
+		WHERE id_kepesertaan = ? AND terverifikasi = 0`, mulai, sampai, idManagement, idKepesertaan); err != nil {
+		return fmt.Errorf("gagal memverifikasi kepesertaan: %v", err)
+	}
+	return nil
+}
+
+// parseMasaBerlaku memvalidasi tanggal berlaku kepesertaan. mulai kosong = hari ini,
+// sampai kosong = tanpa batas (nil).
+func parseMasaBerlaku(mulai, sampai string) (string, interface{}, error) {
+	if mulai == "" {
+		mulai = time.Now().Format("2006-01-02")
+	}
+	tMulai, err := time.Parse("2006-01-02", mulai)
+	if err != nil {
+		return "", nil, fmt.Errorf("%w: berlaku_mulai harus YYYY-MM-DD", ErrKepesertaanTidakValid)
+	}
+	if sampai == "" {
+		return mulai, nil, nil
+	}
+	tSampai, err := time.Parse("2006-01-02", sampai)
+	if err != nil {
+		return "", nil, fmt.Errorf("%w: berlaku_sampai harus YYYY-MM-DD", ErrKepesertaanTidakValid)
+	}
+	if tSampai.Before(tMulai) {
+		return "", nil, fmt.Errorf("%w: berlaku_sampai sebelum berlaku_mulai", ErrKepesertaanTidakValid)
+	}
+	return mulai, sampai, nil
+}
+
+// TambahKepesertaan mendaftarkan pasien sebagai peserta penjamin.
+func (s *PenjaminService) TambahKepesertaan(idPasien int, req models.KepesertaanRequest, idKaryawan int) (int, error) {
+	nomor := strings.TrimSpace(req.NomorPeserta)
+	if req.IDPenjamin <= 0 || nomor == "" {
+		return 0, fmt.Errorf("%w: id_penjamin dan nomor_peserta harus diisi", ErrKepesertaanTidakValid)
+	}
+	mulai, sampai, err := parseMasaBerlaku(req.BerlakuMulai, req.BerlakuSampai)
+	if err != nil {
+		return 0, err
+	}
+
+	var ada int
+	if err := s.DB.QueryRow("SELECT COUNT(*) FROM Pasien WHERE id_pasien = ?", idPasien).Scan(&ada); err != nil {
+		return 0, fmt.Errorf("gagal mengambil pasien: %v", err)
+	}
+	if ada == 0 {
+		return 0, fmt.Errorf("pasien dengan id %d tidak ditemukan", idPasien)
+	}
+	if err := s.DB.QueryRow("SELECT COUNT(*) FROM Penjamin WHERE id_penjamin = ?", req.IDPenjamin).Scan(&ada); err != nil {
+		return 0, fmt.Errorf("gagal mengambil penjamin: %v", err)
+	}
+	if ada == 0 {
+		return 0, ErrPenjaminTidakDitemukan
+	}
+
+	res, err := s.DB.Exec(`
+		INSERT INTO Kepesertaan_Penjamin
+		  (id_pasien, id_penjamin, nomor_peserta, berlaku_mulai, berlaku_sampai, created_by, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, NOW(), NOW())`,
+		idPasien, req.IDPenjamin, nomor, mulai, sampai, idKaryawan,
+	)
+	if err != nil {
+		if strings.Contains(err.Error(), "Duplicate entry") {
+			return 0, ErrNomorPesertaDipakai
+		}
+		return 0, fmt.Errorf("gagal menambah kepesertaan: %v", err)
+	}
+	id, err := res.LastInsertId()
+	return int(id), err
+}
+
+// UpdateKepesertaan mengubah nomor peserta dan masa berlaku. Mengakhiri kepesertaan dilakukan
+// dengan mengisi berlaku_sampai; baris tidak dihapus karena dirujuk oleh kunjungan lama.
+func (s *PenjaminService) UpdateKepesertaan(idKepesertaan int, req models.KepesertaanRequest) error {
+	nomor := strings.TrimSpace(req.NomorPeserta)
+	if nomor == "" {
+		return fmt.Errorf("%w: nomor_peserta harus diisi", ErrKepesertaanTidakValid)
+	}
+	if req.BerlakuMulai == "" {
+		return fmt.Errorf("%w: berlaku_mulai harus diisi", ErrKepesertaanTidakValid)
+	}
+	mulai, sampai, err := parseMasaBerlaku(req.BerlakuMulai, req.BerlakuSampai)
+	if err != nil {
+		return err
+	}
+	res, err := s.DB.Exec(`
+		UPDATE Kepesertaan_Penjamin
+		SET nomor_peserta = ?, berlaku_mulai = ?, berlaku_sampai = ?, updated_at = NOW()
+		WHERE id_kepesertaan = ?`, nomor, mulai, sampai, idKepesertaan)
+	if err != nil {
+		if strings.Contains(err.Error(), "Duplicate entry") {
+			return ErrNomorPesertaDipakai
+		}
+		return fmt.Errorf("gagal mengupdate kepesertaan: %v", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		var ada int
+		if err := s.DB.QueryRow("SELECT COUNT(*) FROM Kepesertaan_Penjamin WHERE id_kepesertaan = ?", idKepesertaan).Scan(&ada); err == nil && ada == 0 {
+			return ErrKepesertaanTidakDitemukan
+		}
+	}
+	return nil
+}
+
+// pilihPenjamin mencatat kepesertaan yang dipakai kunjungan. Tanpa nomor_peserta dipakai
+// kepesertaan pasien yang berlaku hari ini; nomor_peserta yang belum terdaftar dicatat sebagai
+// kepesertaan baru yang belum terverifikasi, sehingga tagihan tidak dibagi dengan penjamin
+// sampai manajemen mengonfirmasinya. Mengembalikan id_kepesertaan (0 = pasien umum).
+// Harus dipanggil di dalam transaksi pendaftaran setelah kunjungan dibuat.
+func pilihPenjamin(tx *sql.Tx, idPasien, idKunjungan int64, pilihan models.PilihanPenjamin, createdBy interface{}) (int64, error) {
+	if pilihan.IDPenjamin == 0 {
+		return 0, nil
+	}
+	var aktif bool
+	err := tx.QueryRow("SELECT aktif FROM Penjamin WHERE id_penjamin = ?", pilihan.IDPenjamin).Scan(&aktif)
+	if err == sql.ErrNoRows {
+		return 0, ErrPenjaminTidakDitemukan
+	}
+	if err != nil {
+		return 0, fmt.Errorf("gagal mengambil penjamin: %v", err)
+	}
+	if !aktif {
+		return 0, fmt.Errorf("%w: penjamin tidak aktif", ErrKepesertaanTidakBerlaku)
+	}
+
+	nomor := strings.TrimSpace(pilihan.NomorPeserta)
+	var (
+		idKepesertaan, pemilik int64
+		berlaku                bool
+		masa                   string
+	)
+	err = tx.QueryRow(`
+		SELECT id_kepesertaan, id_pasien,
+		       berlaku_mulai <= CURDATE() AND (berlaku_sampai IS NULL OR berlaku_sampai >= CURDATE()) AS berlaku,
+		       CONCAT(DATE_FORMAT(berlaku_mulai, '%Y-%m-%d'), ' s/d ', IFNULL(DATE_FORMAT(berlaku_sampai, '%Y-%m-%d'), '-'))
+		FROM Kepesertaan_Penjamin
+		WHERE id_penjamin = ? AND (nomor_peserta = ? OR (? = '' AND id_pasien = ?))
+		ORDER BY berlaku DESC, berlaku_mulai DESC
+		LIMIT 1`,
+		pilihan.IDPenjamin, nomor, nomor, idPasien,
+	).Scan(&idKepesertaan, &pemilik, &berlaku, &masa)
+	switch {
+	case err == sql.ErrNoRows && nomor == "":
+		return 0, fmt.Errorf("%w: pasien belum terdaftar sebagai peserta, isi nomor_peserta", ErrKepesertaanTidakBerlaku)
+	case err == sql.ErrNoRows:
+		res, err := tx.Exec(`
+			INSERT INTO Kepesertaan_Penjamin
+			  (id_pasien, id_penjamin, nomor_peserta, berlaku_mulai, berlaku_sampai, terverifikasi, created_by, created_at, updated_at)
+			VALUES (?, ?, ?, CURDATE(), NULL, 0, ?, NOW(), NOW())`,
+			idPasien, pilihan.IDPenjamin, nomor, createdBy,
+		)
+		if err != nil {
+			return 0, fmt.Errorf("gagal menambah kepesertaan: %v", err)
+		}
+		if idKepesertaan, err = res.LastInsertId(); err != nil {
+			return 0, err
+		}
+	case err != nil:
+		return 0, fmt.Errorf("gagal mengambil kepesertaan: %v", err)
+	case pemilik != idPasien:
+		return 0, ErrNomorPesertaDipakai
+	case !berlaku:
+		return 0, fmt.Errorf("%w: masa berlaku %s", ErrKepesertaanTidakBerlaku, masa)
+	}
+
+	if _, err := tx.Exec("UPDATE Riwayat_Kunjungan SET id_kepesertaan = ? WHERE id_kunjungan = ?", idKepesertaan, idKunjungan); err != nil {
+		return 0, fmt.Errorf("gagal mencatat penjamin kunjungan: %v", err)
+	}
+	return idKepesertaan, nil
+}
+
+type aturanTanggungan struct {
+	persen float64
+	plafon sql.NullFloat64
+}
+
+func pembulatan(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+// hitungPembagian membagi komponen tagihan kunjungan antara penjamin dan pasien. Tanpa
+// penjamin (idPenjamin 0) semua dibayar pasien. Plafon aturan berlaku kumulatif per kunjungan.
+// assessments berisi semua assessment kunjungan (termasuk poli rujukan internal).
+func hitungPembagian(tx *sql.Tx, idPenjamin int64, hargaDokter, totalObat float64, assessments []int64) ([]models.PembagianTagihan, error) {
+	aturan := map[string]aturanTanggungan{}
+	if idPenjamin != 0 {
+		rows, err := tx.Query(`
+			SELECT komponen, IFNULL(id_icd9_cm, ''), persen_tanggungan, plafon
+			FROM Aturan_Penjamin WHERE id_penjamin = ?`, idPenjamin)
+		if err != nil {
+			return nil, fmt.Errorf("gagal mengambil aturan penjamin: %v", err)
+		}
+		for rows.Next() {
+			var komponen, icd string
+			var a aturanTanggungan
+			if err := rows.Scan(&komponen, &icd, &a.persen, &a.plafon); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("gagal membaca aturan penjamin: %v", err)
+			}
+			aturan[komponen+"/"+icd] = a
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	rincian := []models.PembagianTagihan{
+		{Komponen: KomponenDokter, Total: hargaDokter},
+		{Komponen: KomponenObat, Total: totalObat},
+	}
+	if len(assessments) > 0 {
+		args := make([]interface{}, len(assessments))
+		for i, id := range assessments {
+			args[i] = id
+		}
+		rows, err := tx.Query(`
+			SELECT id_icd9_cm, SUM(total_harga_tindakan)
+			FROM Billing_Assessment WHERE id_assessment IN (`+strings.TrimSuffix(strings.Repeat("?,", len(args)), ",")+`)
+			GROUP BY id_icd9_cm ORDER BY id_icd9_cm`, args...)
+		if err != nil {
+			return nil, fmt.Errorf("gagal mengambil tindakan: %v", err)
+		}
+		for rows.Next() {
+			r := models.PembagianTagihan{Komponen: KomponenTindakan}
+			if err := rows.Scan(&r.IDICD9CM, &r.Total); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("gagal membaca tindakan: %v", err)
+			}
+			rincian = append(rincian, r)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	terpakai := map[string]float64{}
+	for i := range rincian {
+		r := &rincian[i]
+		key := r.Komponen + "/" + r.IDICD9CM
+		a, ok := aturan[key]
+		if !ok && r.IDICD9CM != "" {
+			key = r.Komponen + "/"
+			a, ok = aturan[key]
+		}
+		if ok {
+			r.Ditanggung = pembulatan(r.Total * a.persen / 100)
+			if a.plafon.Valid {
+				r.Ditanggung = math.Max(0, math.Min(r.Ditanggung, a.plafon.Float64-terpakai[key]))
+			}
+			terpakai[key] += r.Ditanggung
+		}
+		r.Dibayar = pembulatan(r.Total - r.Ditanggung)
+	}
+	return rincian, nil
+}
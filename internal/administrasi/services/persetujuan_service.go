@@ -0,0 +1,449 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/c14220110/poliklinik-backend/internal/administrasi/models"
+	"github.com/c14220110/poliklinik-backend/internal/common/antrian"
+)
+
+// Jenis template persetujuan.
+const (
+	PersetujuanUmum        = "Umum"
+	PersetujuanBerbagiData = "Berbagi Data"
+	PersetujuanTindakan    = "Tindakan"
+)
+
+// Penanda tangan persetujuan.
+const (
+	PenandaTanganPasien          = "Pasien"
+	PenandaTanganPenanggungJawab = "Penanggung Jawab"
+)
+
+var (
+	ErrTemplatePersetujuanTidakDitemukan = errors.New("template persetujuan tidak ditemukan")
+	ErrPersetujuanTidakDitemukan         = errors.New("persetujuan tidak ditemukan")
+	ErrPersetujuanTidakValid             = errors.New("data persetujuan tidak valid")
+	ErrPersetujuanSudahAda               = errors.New("persetujuan untuk template ini sudah tercatat pada kunjungan")
+	ErrPersetujuanBelumAda               = errors.New("persetujuan tindakan belum ada")
+)
+
+type PersetujuanService struct {
+	DB *sql.DB
+}
+
+func NewPersetujuanService(db *sql.DB) *PersetujuanService {
+	return &PersetujuanService{DB: db}
+}
+
+const templatePersetujuanSelect = `
+	SELECT id_template, judul, jenis, isi, id_poli, id_icd9_cm, wajib, aktif, created_at, updated_at
+	FROM Template_Persetujuan`
+
+func scanTemplatePersetujuan(scanner interface{ Scan(...interface{}) error }) (models.TemplatePersetujuan, error) {
+	var (
+		t      models.TemplatePersetujuan
+		idPoli sql.NullInt64
+		icd    sql.NullString
+	)
+	if err := scanner.Scan(&t.IDTemplate, &t.Judul, &t.Jenis, &t.Isi, &idPoli, &icd, &t.Wajib, &t.Aktif, &t.CreatedAt, &t.UpdatedAt); err != nil {
+		return t, err
+	}
+	t.IDPoli = nullIntPtr(idPoli)
+	if icd.Valid {
+		t.IDICD9CM = &icd.String
+	}
+	return t, nil
+}
+
+func queryTemplatePersetujuan(db interface {
+	Query(string, ...interface{}) (*sql.Rows, error)
+}, where string, args ...interface{}) ([]models.TemplatePersetujuan, error) {
+	rows, err := db.Query(templatePersetujuanSelect+where+" ORDER BY jenis, judul", args...)
+	if err != nil {
+		return nil, fmt.Errorf("gagal mengambil template persetujuan: %v", err)
+	}
+	defer rows.Close()
+
+	list := []models.TemplatePersetujuan{}
+	for rows.Next() {
+		t, err := scanTemplatePersetujuan(rows)
+		if err != nil {
+			return nil, fmt.Errorf("gagal membaca template persetujuan: %v", err)
+		}
+		list = append(list, t)
+	}
+	return list, rows.Err()
+}
+
+// ListTemplate mengembalikan template persetujuan. idPoli > 0 membatasi ke template umum /
+// berbagi data yang berlaku di poli itu; idICD9CM membatasi ke template tindakan untuk kode itu.
+func (s *PersetujuanService) ListTemplate(idPoli int, idICD9CM string, hanyaAktif bool) ([]models.TemplatePersetujuan, error) {
+	conditions := []string{}
+	args := []interface{}{}
+	if hanyaAktif {
+		conditions = append(conditions, "aktif = 1")
+	}
+	if idPoli > 0 {
+		conditions = append(conditions, "jenis <> 'Tindakan' AND (id_poli IS NULL OR id_poli = ?)")
+		args = append(args, idPoli)
+	}
+	if idICD9CM != "" {
+		conditions = append(conditions, "jenis = 'Tindakan' AND id_icd9_cm = ?")
+		args = append(args, idICD9CM)
+	}
+	where := ""
+	if len(conditions) > 0 {
+		where = " WHERE " + strings.Join(conditions, " AND ")
+	}
+	return queryTemplatePersetujuan(s.DB, where, args...)
+}
+
+func (s *PersetujuanService) validasiTemplate(t models.TemplatePersetujuan) error {
+	if strings.TrimSpace(t.Judul) == "" || strings.TrimSpace(t.Isi) == "" {
+		return fmt.Errorf("%w: judul dan isi harus diisi", ErrPersetujuanTidakValid)
+	}
+	switch t.Jenis {
+	case PersetujuanUmum, PersetujuanBerbagiData:
+		if t.IDICD9CM != nil {
+			return fmt.Errorf("%w: id_icd9_cm hanya untuk jenis Tindakan", ErrPersetujuanTidakValid)
+		}
+		if t.IDPoli != nil {
+			var n int
+			if err := s.DB.QueryRow("SELECT COUNT(*) FROM Poliklinik WHERE id_poli = ?", *t.IDPoli).Scan(&n); err != nil {
+				return fmt.Errorf("gagal memeriksa poliklinik: %v", err)
+			}
+			if n == 0 {
+				return fmt.Errorf("%w: poliklinik %d tidak ada", ErrPersetujuanTidakValid, *t.IDPoli)
+			}
+		}
+	case PersetujuanTindakan:
+		if t.IDICD9CM == nil || *t.IDICD9CM == "" {
+			return fmt.Errorf("%w: id_icd9_cm harus diisi untuk jenis Tindakan", ErrPersetujuanTidakValid)
+		}
+		if t.IDPoli != nil {
+			return fmt.Errorf("%w: id_poli tidak dipakai untuk jenis Tindakan", ErrPersetujuanTidakValid)
+		}
+		var n int
+		if err := s.DB.QueryRow("SELECT COUNT(*) FROM ICD9_CM WHERE id_icd9_cm = ?", *t.IDICD9CM).Scan(&n); err != nil {
+			return fmt.Errorf("gagal memeriksa ICD9_CM: %v", err)
+		}
+		if n == 0 {
+			return fmt.Errorf("%w: icd9_cm %s tidak ada", ErrPersetujuanTidakValid, *t.IDICD9CM)
+		}
+	default:
+		return fmt.Errorf("%w: jenis harus Umum, Berbagi Data, atau Tindakan", ErrPersetujuanTidakValid)
+	}
+	return nil
+}
+
+// TambahTemplate membuat template persetujuan baru (aktif).
+func (s *PersetujuanService) TambahTemplate(t models.TemplatePersetujuan, idManagement int) (int, error) {
+	if err := s.validasiTemplate(t); err != nil {
+		return 0, err
+	}
+	res, err := s.DB.Exec(`
+		INSERT INTO Template_Persetujuan
+		  (judul, jenis, isi, id_poli, id_icd9_cm, wajib, aktif, created_by, updated_by, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, 1, ?, ?, NOW(), NOW())`,
+		strings.TrimSpace(t.Judul), t.Jenis, t.Isi, t.IDPoli, t.IDICD9CM, t.Wajib, idManagement, idManagement,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("gagal menambah template persetujuan: %v", err)
+	}
+	id, err := res.LastInsertId()
+	return int(id), err
+}
+
+// UpdateTemplate mengubah judul, isi, dan (jika tidak nil) wajib / aktif. Jenis dan cakupan
+// (poli / tindakan) tidak bisa diubah; buat template baru dan nonaktifkan yang lama.
+// Persetujuan yang sudah ditandatangani menyimpan salinan isinya sendiri.
+func (s *PersetujuanService) UpdateTemplate(idTemplate int, judul, isi string, wajib, aktif *bool, idManagement int) error {
+	if strings.TrimSpace(judul) == "" || strings.TrimSpace(isi) == "" {
+		return fmt.Errorf("%w: judul dan isi harus diisi", ErrPersetujuanTidakValid)
+	}
+	res, err := s.DB.Exec(`
+		UPDATE Template_Persetujuan
+		SET judul = ?, isi = ?, wajib = IFNULL(?, wajib), aktif = IFNULL(?, aktif),
+		    updated_by = ?, updated_at = NOW()
+		WHERE id_template = ?`,
+		strings.TrimSpace(judul), isi, wajib, aktif, idManagement, idTemplate,
+	)
+	if err != nil {
+		return fmt.Errorf("gagal mengupdate template persetujuan: %v", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		var ada int
+		if err := s.DB.QueryRow("SELECT COUNT(*) FROM Template_Persetujuan WHERE id_template = ?", idTemplate).Scan(&ada); err == nil && ada == 0 {
+			return ErrTemplatePersetujuanTidakDitemukan
+		}
+	}
+	return nil
+}
+
+const persetujuanPasienSelect = `
+	SELECT pp.id_persetujuan, pp.id_kunjungan, pp.id_template, tp.jenis, tp.id_icd9_cm, pp.judul, pp.isi,
+	       pp.setuju, pp.penanda_tangan, pp.nama_penanda_tangan, pp.hubungan, pp.ditandatangani_at,
+	       pp.dicatat_oleh, pp.role, pp.dicabut_at, pp.alasan_cabut
+	FROM Persetujuan_Pasien pp
+	JOIN Template_Persetujuan tp ON tp.id_template = pp.id_template`
+
+// GetStatusKunjungan mengembalikan persetujuan yang tercatat pada kunjungan (termasuk yang
+// dicabut) dan template wajib untuk poli kunjungan (termasuk poli rujukan internal) yang
+// belum disetujui.
+func (s *PersetujuanService) GetStatusKunjungan(idKunjungan int) (*models.StatusPersetujuanKunjungan, error) {
+	var idPoli int
+	err := s.DB.QueryRow("SELECT id_poli FROM Kunjungan_Poli WHERE id_kunjungan = ? LIMIT 1", idKunjungan).Scan(&idPoli)
+	if err == sql.ErrNoRows {
+		return nil, ErrKunjunganNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("gagal mengambil kunjungan: %v", err)
+	}
+
+	rows, err := s.DB.Query(persetujuanPasienSelect+" WHERE pp.id_kunjungan = ? ORDER BY pp.ditandatangani_at", idKunjungan)
+	if err != nil {
+		return nil, fmt.Errorf("gagal mengambil persetujuan: %v", err)
+	}
+	defer rows.Close()
+
+	status := &models.StatusPersetujuanKunjungan{IDKunjungan: idKunjungan, Persetujuan: []models.PersetujuanPasien{}}
+	for rows.Next() {
+		var (
+			p                 models.PersetujuanPasien
+			icd, role, alasan sql.NullString
+			dicatatOleh       sql.NullInt64
+			dicabutAt         sql.NullTime
+		)
+		if err := rows.Scan(&p.IDPersetujuan, &p.IDKunjungan, &p.IDTemplate, &p.Jenis, &icd, &p.Judul, &p.Isi,
+			&p.Setuju, &p.PenandaTangan, &p.NamaPenandaTangan, &p.Hubungan, &p.DitandatanganiAt,
+			&dicatatOleh, &role, &dicabutAt, &alasan); err != nil {
+			return nil, fmt.Errorf("gagal membaca persetujuan: %v", err)
+		}
+		if icd.Valid {
+			p.IDICD9CM = &icd.String
+		}
+		p.DicatatOleh = nullIntPtr(dicatatOleh)
+		if role.Valid {
+			p.Role = &role.String
+		}
+		if dicabutAt.Valid {
+			p.DicabutAt = &dicabutAt.Time
+		}
+		if alasan.Valid {
+			p.AlasanCabut = &alasan.String
+		}
+		status.Persetujuan = append(status.Persetujuan, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	status.BelumLengkap, err = queryTemplatePersetujuan(s.DB, `
+		WHERE aktif = 1 AND wajib = 1 AND jenis <> 'Tindakan'
+		  AND (id_poli IS NULL OR id_poli IN (SELECT id_poli FROM Kunjungan_Poli WHERE id_kunjungan = ?))
+		  AND id_template NOT IN (
+		      SELECT id_template FROM Persetujuan_Pasien
+		      WHERE id_kunjungan = ? AND setuju = 1 AND dicabut_at IS NULL)`,
+		idKunjungan, idKunjungan)
+	if err != nil {
+		return nil, err
+	}
+	return status, nil
+}
+
+// CatatPersetujuan mencatat persetujuan (atau penolakan) yang ditandatangani untuk kunjungan.
+// Penanda tangan Penanggung Jawab wajib menyebutkan hubungannya dengan pasien; namanya default
+// ke nama_penanggung_jawab antrian.
+func (s *PersetujuanService) CatatPersetujuan(idKunjungan int, req models.PersetujuanRequest, actor antrian.Actor) (int, error) {
+	if req.IDTemplate <= 0 {
+		return 0, fmt.Errorf("%w: id_template harus diisi", ErrPersetujuanTidakValid)
+	}
+	setuju := req.Setuju == nil || *req.Setuju
+	penandaTangan := req.PenandaTangan
+	if penandaTangan == "" {
+		penandaTangan = PenandaTanganPasien
+	}
+	if penandaTangan != PenandaTanganPasien && penandaTangan != PenandaTanganPenanggungJawab {
+		return 0, fmt.Errorf("%w: penanda_tangan harus Pasien atau Penanggung Jawab", ErrPersetujuanTidakValid)
+	}
+	hubungan := strings.TrimSpace(req.Hubungan)
+	if penandaTangan == PenandaTanganPasien {
+		hubungan = PenandaTanganPasien
+	} else if hubungan == "" {
+		return 0, fmt.Errorf("%w: hubungan penanggung jawab dengan pasien harus diisi", ErrPersetujuanTidakValid)
+	}
+	ditandatanganiAt := time.Now()
+	if req.DitandatanganiAt != "" {
+		t, err := time.ParseInLocation("2006-01-02 15:04:05", req.DitandatanganiAt, time.Local)
+		if err != nil {
+			return 0, fmt.Errorf("%w: ditandatangani_at harus YYYY-MM-DD HH:MM:SS", ErrPersetujuanTidakValid)
+		}
+		if t.After(time.Now()) {
+			return 0, fmt.Errorf("%w: ditandatangani_at tidak boleh di masa depan", ErrPersetujuanTidakValid)
+		}
+		ditandatanganiAt = t
+	}
+
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("gagal memulai transaksi: %v", err)
+	}
+	defer tx.Rollback()
+
+	// Kunjungan, poli, nama pasien & penanggung jawab
+	var (
+		idPoli             int
+		namaPasien, namaPJ string
+	)
+	err = tx.QueryRow(`
+		SELECT kp.id_poli, p.nama, IFNULL(a.nama_penanggung_jawab, '')
+		FROM Riwayat_Kunjungan rk
+		JOIN Kunjungan_Poli kp ON kp.id_kunjungan = rk.id_kunjungan AND kp.id_antrian_asal IS NULL
+		JOIN Antrian a ON a.id_antrian = rk.id_antrian
+		JOIN Pasien p ON p.id_pasien = a.id_pasien
+		WHERE rk.id_kunjungan = ?
+		LIMIT 1 FOR UPDATE`, idKunjungan,
+	).Scan(&idPoli, &namaPasien, &namaPJ)
+	if err == sql.ErrNoRows {
+		return 0, ErrKunjunganNotFound
+	}
+	if err != nil {
+		return 0, fmt.Errorf("gagal mengambil kunjungan: %v", err)
+	}
+
+	t, err := scanTemplatePersetujuan(tx.QueryRow(templatePersetujuanSelect+" WHERE id_template = ?", req.IDTemplate))
+	if err == sql.ErrNoRows {
+		return 0, ErrTemplatePersetujuanTidakDitemukan
+	}
+	if err != nil {
+		return 0, fmt.Errorf("gagal mengambil template persetujuan: %v", err)
+	}
+	if !t.Aktif {
+		return 0, fmt.Errorf("%w: template %d tidak aktif", ErrPersetujuanTidakValid, t.IDTemplate)
+	}
+	if t.IDPoli != nil && *t.IDPoli != idPoli {
+		// Template poli rujukan internal dalam kunjungan yang sama juga boleh dipakai.
+		var dirujuk int
+		if err := tx.QueryRow(`SELECT COUNT(*) FROM Kunjungan_Poli WHERE id_kunjungan = ? AND id_poli = ?`,
+			idKunjungan, *t.IDPoli).Scan(&dirujuk); err != nil {
+			return 0, fmt.Errorf("gagal memeriksa poli kunjungan: %v", err)
+		}
+		if dirujuk == 0 {
+			return 0, fmt.Errorf("%w: template %d bukan untuk poli kunjungan ini", ErrPersetujuanTidakValid, t.IDTemplate)
+		}
+	}
+
+	nama := strings.TrimSpace(req.NamaPenandaTangan)
+	if nama == "" {
+		if penandaTangan == PenandaTanganPasien {
+			nama = namaPasien
+		} else {
+			nama = namaPJ
+		}
+	}
+	if nama == "" {
+		return 0, fmt.Errorf("%w: nama_penanda_tangan harus diisi", ErrPersetujuanTidakValid)
+	}
+
+	var aktif int
+	if err := tx.QueryRow(`
+		SELECT COUNT(*) FROM Persetujuan_Pasien
+		WHERE id_kunjungan = ? AND id_template = ? AND dicabut_at IS NULL`,
+		idKunjungan, t.IDTemplate).Scan(&aktif); err != nil {
+		return 0, fmt.Errorf("gagal memeriksa persetujuan: %v", err)
+	}
+	if aktif > 0 {
+		return 0, ErrPersetujuanSudahAda
+	}
+
+	res, err := tx.Exec(`
+		INSERT INTO Persetujuan_Pasien
+		  (id_kunjungan, id_template, judul, isi, setuju, penanda_tangan, nama_penanda_tangan,
+		   hubungan, ditandatangani_at, dicatat_oleh, role, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, NOW())`,
+		idKunjungan, t.IDTemplate, t.Judul, t.Isi, setuju, penandaTangan, nama,
+		hubungan, ditandatanganiAt, actor.IDKaryawan, actor.Role,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("gagal menyimpan persetujuan: %v", err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("gagal commit transaksi: %v", err)
+	}
+	return int(id), nil
+}
+
+// CabutPersetujuan menandai persetujuan dicabut. Tindakan yang sudah diinput sebelumnya tidak
+// terhapus, tetapi tindakan baru yang membutuhkan persetujuan ini akan ditolak.
+func (s *PersetujuanService) CabutPersetujuan(idPersetujuan int, alasan string, actor antrian.Actor) error {
+	res, err := s.DB.Exec(`
+		UPDATE Persetujuan_Pasien
+		SET dicabut_at = NOW(), dicabut_oleh = ?, alasan_cabut = NULLIF(?, '')
+		WHERE id_persetujuan = ? AND dicabut_at IS NULL`,
+		actor.IDKaryawan, alasan, idPersetujuan,
+	)
+	if err != nil {
+		return fmt.Errorf("gagal mencabut persetujuan: %v", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		var ada int
+		if err := s.DB.QueryRow("SELECT COUNT(*) FROM Persetujuan_Pasien WHERE id_persetujuan = ?", idPersetujuan).Scan(&ada); err == nil && ada > 0 {
+			return fmt.Errorf("%w: persetujuan sudah dicabut", ErrPersetujuanTidakValid)
+		}
+		return ErrPersetujuanTidakDitemukan
+	}
+	return nil
+}
+
+// cekPersetujuanTindakan memastikan setiap tindakan yang punya template persetujuan wajib dan
+// aktif sudah disetujui (setuju = 1, belum dicabut) pada kunjungan. Dipanggil di dalam transaksi
+// SaveBillingAssessment.
+func cekPersetujuanTindakan(tx *sql.Tx, idKunjungan int, kodeTindakan []string) error {
+	if len(kodeTindakan) == 0 {
+		return nil
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(kodeTindakan)), ",")
+	args := []interface{}{idKunjungan}
+	for _, k := range kodeTindakan {
+		args = append(args, k)
+	}
+	rows, err := tx.Query(`
+		SELECT tp.id_icd9_cm, tp.judul
+		FROM Template_Persetujuan tp
+		WHERE tp.jenis = 'Tindakan' AND tp.wajib = 1 AND tp.aktif = 1
+		  AND NOT EXISTS (
+		      SELECT 1 FROM Persetujuan_Pasien pp
+		      WHERE pp.id_template = tp.id_template AND pp.id_kunjungan = ?
+		        AND pp.setuju = 1 AND pp.dicabut_at IS NULL)
+		  AND tp.id_icd9_cm IN (`+placeholders+`)
+		ORDER BY tp.id_icd9_cm, tp.judul`, args...)
+	if err != nil {
+		return fmt.Errorf("gagal memeriksa persetujuan tindakan: %v", err)
+	}
+	defer rows.Close()
+
+	kurang := []string{}
+	for rows.Next() {
+		var kode, judul string
+		if err := rows.Scan(&kode, &judul); err != nil {
+			return fmt.Errorf("gagal membaca persetujuan tindakan: %v", err)
+		}
+		kurang = append(kurang, fmt.Sprintf("%s (%s)", kode, judul))
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if len(kurang) > 0 {
+		return fmt.Errorf("%w: %s", ErrPersetujuanBelumAda, strings.Join(kurang, ", "))
+	}
+	return nil
+}
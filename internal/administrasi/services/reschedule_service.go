@@ -0,0 +1,402 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/c14220110/poliklinik-backend/internal/administrasi/models"
+	"github.com/c14220110/poliklinik-backend/internal/common/antrian"
+	"github.com/c14220110/poliklinik-backend/ws"
+)
+
+// Mode Kebijakan_Reschedule.
+const (
+	RescheduleSisip = "Sisip"
+	RescheduleAkhir = "Akhir"
+	RescheduleJam   = "Jam"
+)
+
+// Kebijakan bawaan untuk poli yang belum punya baris Kebijakan_Reschedule.
+const (
+	defaultSisipSetelah = 2
+	defaultMaksTunda    = 3
+)
+
+var (
+	ErrBatasTunda                    = errors.New("batas penundaan antrian sudah tercapai")
+	ErrKebijakanRescheduleTidakValid = errors.New("kebijakan reschedule tidak valid")
+)
+
+// actorSistem dicatat di Antrian_Log untuk antrian yang dikembalikan otomatis (mode Jam).
+var actorSistem = antrian.Actor{Role: "Sistem"}
+
+type RescheduleService struct {
+	DB *sql.DB
+}
+
+func NewRescheduleService(db *sql.DB) *RescheduleService {
+	return &RescheduleService{DB: db}
+}
+
+// kebijakanReschedule mengambil kebijakan poli, atau kebijakan bawaan jika belum diatur.
+func kebijakanReschedule(q antrian.Queryer, idPoli int) (models.KebijakanReschedule, error) {
+	maks := defaultMaksTunda
+	k := models.KebijakanReschedule{
+		IDPoli: idPoli, Mode: RescheduleSisip, SisipSetelah: defaultSisipSetelah, MaksTunda: &maks, Default: true,
+	}
+	var (
+		jam       sql.NullString
+		maksTunda sql.NullInt64
+		updatedAt time.Time
+	)
+	err := q.QueryRow(`
+		SELECT mode, sisip_setelah, TIME_FORMAT(jam_kembali, '%H:%i'), maks_tunda, updated_at
+		FROM Kebijakan_Reschedule WHERE id_poli = ?`, idPoli,
+	).Scan(&k.Mode, &k.SisipSetelah, &jam, &maksTunda, &updatedAt)
+	if err == sql.ErrNoRows {
+		return k, nil
+	}
+	if err != nil {
+		return k, fmt.Errorf("gagal mengambil kebijakan reschedule: %v", err)
+	}
+	k.Default, k.UpdatedAt, k.MaksTunda = false, &updatedAt, nil
+	if jam.Valid {
+		k.JamKembali = &jam.String
+	}
+	if maksTunda.Valid {
+		m := int(maksTunda.Int64)
+		k.MaksTunda = &m
+	}
+	return k, nil
+}
+
+// jamKembaliHariIni mengubah jam "HH:MM" menjadi waktu pada tanggal now.
+func jamKembaliHariIni(now time.Time, jam string) (time.Time, error) {
+	t, err := time.Parse("15:04", jam)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: jam_kembali harus berformat HH:MM", ErrKebijakanRescheduleTidakValid)
+	}
+	return time.Date(now.Year(), now.Month(), now.Day(), t.Hour(), t.Minute(), 0, 0, now.Location()), nil
+}
+
+// menunggu adalah satu antrian Menunggu pada urutan panggilan saat ini.
+type menunggu struct {
+	priority int64 // COALESCE(priority_order, nomor_antrian)
+	skor     int64 // antrian.SkorPanggilan
+}
+
+// slotReschedule menghitung priority_order baru untuk antrian idAntrian yang kembali Menunggu,
+// berdasarkan urutan panggilan (antrian.UrutanPanggilan) antrian Menunggu hari ini.
+// setelah >= 0 menyisipkan setelah sejumlah pasien terdepan (0 = paling depan); setelah < 0
+// atau pasien menunggu lebih sedikit dari itu berarti akhir antrian.
+func slotReschedule(tx *sql.Tx, idAntrian, idPoli, setelah int) (int64, error) {
+	// Lama tunggu dihitung ulang dari menunggu_sejak = NOW(), jadi skornya hanya bobot triase.
+	var skor int64
+	if err := tx.QueryRow("SELECT IFNULL(bobot_triase, 0) FROM Antrian WHERE id_antrian = ?", idAntrian).Scan(&skor); err != nil {
+		return 0, fmt.Errorf("gagal mengambil antrian: %v", err)
+	}
+	rows, err := tx.Query(`
+		SELECT COALESCE(priority_order, nomor_antrian), `+antrian.SkorPanggilan("")+`
+		FROM Antrian
+		WHERE id_poli = ? AND id_status = ? AND DATE(created_at) = CURDATE()
+		ORDER BY `+antrian.UrutanPanggilan(""),
+		idPoli, antrian.StatusMenunggu,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("gagal menghitung slot reschedule: %v", err)
+	}
+	defer rows.Close()
+	var list []menunggu
+	for rows.Next() {
+		var m menunggu
+		if err := rows.Scan(&m.priority, &m.skor); err != nil {
+			return 0, fmt.Errorf("gagal membaca antrian: %v", err)
+		}
+		list = append(list, m)
+	}
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	return pilihSlot(list, skor, setelah), nil
+}
+
+// pilihSlot memilih priority_order dari daftar antrian Menunggu (urut panggilan) untuk antrian
+// dengan skor tertentu. priority_order hanya mengurutkan antrian yang skornya sama: pasien
+// dengan skor lebih tinggi (triase / sudah melewati target tunggu) tetap dipanggil lebih dulu
+// dan yang lebih rendah tetap sesudahnya, apa pun mode kebijakannya. Jadi "setelah N pasien"
+// dihitung di dalam kelompok skor yang sama, dengan pasien berskor lebih tinggi terhitung di depan.
+func pilihSlot(list []menunggu, skor int64, setelah int) int64 {
+	if len(list) == 0 {
+		return 1 // tidak ada antrian Menunggu
+	}
+	minP, maxP := list[0].priority, list[0].priority
+	for _, m := range list {
+		minP, maxP = min(minP, m.priority), max(maxP, m.priority)
+	}
+	if setelah < 0 {
+		return maxP + 1
+	}
+
+	depan := 0
+	for depan < len(list) && list[depan].skor > skor {
+		depan++
+	}
+	akhir := depan
+	for akhir < len(list) && list[akhir].skor == skor {
+		akhir++
+	}
+	setara := list[depan:akhir]
+	k := max(setelah-depan, 0)
+	switch {
+	case len(setara) == 0 && k == 0:
+		return minP - 1
+	case len(setara) == 0 || k >= len(setara):
+		return maxP + 1
+	case k == 0:
+		return setara[0].priority - 1
+	default:
+		return setara[k-1].priority + 1
+	}
+}
+
+// masukkanKembali memindahkan antrian Ditunda (sudah dikunci) ke Menunggu pada slot sesuai
+// mode, lalu mengisi priority_order, posisi panggilan dan keterangan pada hasil.
+func masukkanKembali(tx *sql.Tx, hasil *models.HasilReschedule, idPoli, setelah int, actor antrian.Actor, alasan string) error {
+	priority, err := slotReschedule(tx, hasil.IDAntrian, idPoli, setelah)
+	if err != nil {
+		return err
+	}
+	if _, err = antrian.Transition(tx, hasil.IDAntrian, antrian.StatusMenunggu, actor, alasan); err != nil {
+		return err
+	}
+	if _, err = tx.Exec(`
+		UPDATE Antrian SET priority_order = ?, kembali_at = NULL, menunggu_sejak = NOW()
+		WHERE id_antrian = ?`, priority, hasil.IDAntrian); err != nil {
+		return fmt.Errorf("gagal mengupdate antrian: %v", err)
+	}
+
+	list, err := antrian.DaftarPanggilan(tx, idPoli, antrian.StatusMenunggu)
+	if err != nil {
+		return err
+	}
+	for i, u := range list {
+		if u.IDAntrian == hasil.IDAntrian {
+			posisi := i + 1
+			hasil.Posisi = &posisi
+			break
+		}
+	}
+	hasil.IDStatus, hasil.Status = antrian.StatusMenunggu, antrian.NamaStatus(antrian.StatusMenunggu)
+	hasil.PriorityOrder = &priority
+
+	switch {
+	case setelah < 0:
+		hasil.Keterangan = "Ditempatkan di akhir antrian"
+	case setelah == 0:
+		hasil.Keterangan = "Ditempatkan di depan antrian"
+	default:
+		hasil.Keterangan = fmt.Sprintf("Disisipkan setelah %d pasien yang menunggu", setelah)
+	}
+	if hasil.Posisi != nil {
+		hasil.Keterangan += fmt.Sprintf(" (posisi %d dari %d)", *hasil.Posisi, len(list))
+	}
+	return nil
+}
+
+// jadwalUlang adalah versi HasilReschedule untuk event WebSocket.
+func jadwalUlang(h *models.HasilReschedule) *ws.JadwalUlang {
+	return &ws.JadwalUlang{Mode: h.Mode, Posisi: h.Posisi, KembaliAt: h.KembaliAt, Keterangan: h.Keterangan}
+}
+
+// GetKebijakan mengembalikan kebijakan reschedule semua poli aktif, atau satu poli jika
+// idPoli tidak nil. Poli tanpa kebijakan sendiri ditampilkan dengan kebijakan bawaan.
+func (s *RescheduleService) GetKebijakan(idPoli *int) ([]models.KebijakanReschedule, error) {
+	query := "SELECT id_poli, nama_poli FROM Poliklinik WHERE id_status = 1"
+	params := []interface{}{}
+	if idPoli != nil {
+		query = "SELECT id_poli, nama_poli FROM Poliklinik WHERE id_poli = ?"
+		params = append(params, *idPoli)
+	}
+	rows, err := s.DB.Query(query+" ORDER BY nama_poli", params...)
+	if err != nil {
+		return nil, fmt.Errorf("gagal mengambil poliklinik: %v", err)
+	}
+	type poli struct {
+		id   int
+		nama string
+	}
+	polis := []poli{}
+	for rows.Next() {
+		var p poli
+		if err := rows.Scan(&p.id, &p.nama); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("gagal membaca poliklinik: %v", err)
+		}
+		polis = append(polis, p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if idPoli != nil && len(polis) == 0 {
+		return nil, fmt.Errorf("poliklinik dengan id %d tidak ditemukan", *idPoli)
+	}
+
+	list := make([]models.KebijakanReschedule, 0, len(polis))
+	for _, p := range polis {
+		k, err := kebijakanReschedule(s.DB, p.id)
+		if err != nil {
+			return nil, err
+		}
+		k.NamaPoli = p.nama
+		list = append(list, k)
+	}
+	return list, nil
+}
+
+// SetKebijakan menyimpan kebijakan reschedule poli. Antrian yang sudah menunggu jam kembali
+// (mode Jam) tetap memakai kembali_at yang sudah dijanjikan.
+func (s *RescheduleService) SetKebijakan(k models.KebijakanReschedule, idManagement int) error {
+	switch k.Mode {
+	case RescheduleSisip, RescheduleAkhir:
+		k.JamKembali = nil
+	case RescheduleJam:
+		if k.JamKembali == nil {
+			return fmt.Errorf("%w: jam_kembali wajib diisi untuk mode Jam", ErrKebijakanRescheduleTidakValid)
+		}
+		if _, err := jamKembaliHariIni(time.Now(), *k.JamKembali); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("%w: mode harus Sisip, Akhir, atau Jam", ErrKebijakanRescheduleTidakValid)
+	}
+	if k.SisipSetelah < 0 {
+		return fmt.Errorf("%w: sisip_setelah tidak boleh negatif", ErrKebijakanRescheduleTidakValid)
+	}
+	if k.MaksTunda != nil && *k.MaksTunda < 0 {
+		return fmt.Errorf("%w: maks_tunda tidak boleh negatif", ErrKebijakanRescheduleTidakValid)
+	}
+
+	var ada int
+	if err := s.DB.QueryRow("SELECT COUNT(*) FROM Poliklinik WHERE id_poli = ?", k.IDPoli).Scan(&ada); err != nil {
+		return fmt.Errorf("gagal memeriksa poliklinik: %v", err)
+	}
+	if ada == 0 {
+		return fmt.Errorf("poliklinik dengan id %d tidak ditemukan", k.IDPoli)
+	}
+	var updatedBy sql.NullInt64
+	if idManagement > 0 {
+		updatedBy = sql.NullInt64{Int64: int64(idManagement), Valid: true}
+	}
+	if _, err := s.DB.Exec(`
+		INSERT INTO Kebijakan_Reschedule (id_poli, mode, sisip_setelah, jam_kembali, maks_tunda, updated_by, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, NOW())
+		ON DUPLICATE KEY UPDATE
+		  mode = VALUES(mode), sisip_setelah = VALUES(sisip_setelah), jam_kembali = VALUES(jam_kembali),
+		  maks_tunda = VALUES(maks_tunda), updated_by = VALUES(updated_by), updated_at = NOW()`,
+		k.IDPoli, k.Mode, k.SisipSetelah, k.JamKembali, k.MaksTunda, updatedBy,
+	); err != nil {
+		return fmt.Errorf("gagal menyimpan kebijakan reschedule: %v", err)
+	}
+	return nil
+}
+
+// KembalikanTerjadwal memasukkan kembali antrian Ditunda hari ini yang jam kembalinya (mode Jam)
+// sudah lewat ke depan antrian, urut kembali_at lalu nomor_antrian.
+func (s *RescheduleService) KembalikanTerjadwal() (int, error) {
+	rows, err := s.DB.Query(`
+		SELECT id_antrian, id_poli
+		FROM Antrian
+		WHERE id_status = ? AND kembali_at IS NOT NULL AND kembali_at <= NOW()
+		  AND DATE(created_at) = CURDATE()
+		ORDER BY kembali_at, nomor_antrian`, antrian.StatusDitunda)
+	if err != nil {
+		return 0, fmt.Errorf("gagal mengambil antrian terjadwal: %v", err)
+	}
+	type terjadwal struct{ idAntrian, idPoli int }
+	list := []terjadwal{}
+	for rows.Next() {
+		var t terjadwal
+		if err := rows.Scan(&t.idAntrian, &t.idPoli); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("gagal membaca antrian terjadwal: %v", err)
+		}
+		list = append(list, t)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	total := 0
+	for _, t := range list {
+		hasil, err := s.kembalikan(t.idAntrian, t.idPoli)
+		if err != nil {
+			return total, fmt.Errorf("antrian %d: %w", t.idAntrian, err)
+		}
+		if hasil != nil {
+			total++
+		}
+	}
+	return total, nil
+}
+
+// kembalikan memproses satu antrian terjadwal; nil jika antrian sudah berubah sejak dipilih.
+func (s *RescheduleService) kembalikan(idAntrian, idPoli int) (*models.HasilReschedule, error) {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("gagal memulai transaksi: %v", err)
+	}
+	defer tx.Rollback()
+
+	status, err := antrian.Lock(tx, idAntrian)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		jumlahTunda int
+		kembaliAt   sql.NullTime
+	)
+	if err := tx.QueryRow(`SELECT jumlah_tunda, kembali_at FROM Antrian WHERE id_antrian = ?`, idAntrian).
+		Scan(&jumlahTunda, &kembaliAt); err != nil {
+		return nil, fmt.Errorf("gagal mengambil antrian: %v", err)
+	}
+	if status != antrian.StatusDitunda || !kembaliAt.Valid || kembaliAt.Time.After(time.Now()) {
+		return nil, nil
+	}
+
+	hasil := &models.HasilReschedule{IDAntrian: idAntrian, Mode: RescheduleJam, JumlahTunda: jumlahTunda}
+	alasan := "kembali ke antrian sesuai jadwal " + kembaliAt.Time.Format("15:04")
+	if err := masukkanKembali(tx, hasil, idPoli, 0, actorSistem, alasan); err != nil {
+		return nil, err
+	}
+	hasil.Keterangan = fmt.Sprintf("Kembali sesuai jadwal pukul %s. %s", kembaliAt.Time.Format("15:04"), hasil.Keterangan)
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("gagal commit transaksi: %v", err)
+	}
+	antrian.PublishJadwalUlang(s.DB, ws.EventAntrianRescheduled, idAntrian, antrian.StatusDitunda,
+		antrian.StatusMenunggu, actorSistem, alasan, jadwalUlang(hasil))
+	return hasil, nil
+}
+
+// RunKembaliTerjadwal menjalankan KembalikanTerjadwal setiap interval sampai ctx dibatalkan.
+func (s *RescheduleService) RunKembaliTerjadwal(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if n, err := s.KembalikanTerjadwal(); err != nil {
+			slog.Error("Gagal mengembalikan antrian terjadwal", "reason", err)
+		} else if n > 0 {
+			slog.Info("Antrian ditunda kembali sesuai jadwal", "jumlah", n)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
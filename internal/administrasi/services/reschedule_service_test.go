@@ -0,0 +1,35 @@
+package services
+
+import "testing"
+
+func TestPilihSlot(t *testing.T) {
+	biasa := []menunggu{{1, 0}, {2, 0}, {3, 0}, {4, 0}}
+	// Pasien triase (skor 100) dipanggil lebih dulu walaupun priority_order-nya besar.
+	triase := []menunggu{{7, 100}, {1, 0}, {2, 0}, {3, 0}}
+
+	tests := []struct {
+		nama    string
+		list    []menunggu
+		skor    int64
+		setelah int
+		want    int64
+	}{
+		{"antrian kosong", nil, 0, 2, 1},
+		{"paling belakang", biasa, 0, -1, 5},
+		{"paling depan", biasa, 0, 0, 0},
+		{"setelah 2 pasien", biasa, 0, 2, 3},
+		{"setelah lebih dari jumlah antrian", biasa, 0, 10, 5},
+		{"pasien triase terhitung di depan", triase, 0, 1, 0},
+		{"setelah triase dan 1 pasien", triase, 0, 2, 2},
+		{"kurang dari pasien triase", triase, 0, 0, 0},
+		{"paling belakang dengan triase", triase, 0, -1, 8},
+		{"skor tertinggi tanpa kelompok setara", biasa, 100, 0, 0},
+		{"skor tertinggi setelah N", biasa, 100, 2, 5},
+		{"skor sama dengan triase", triase, 100, 1, 8},
+	}
+	for _, tt := range tests {
+		if got := pilihSlot(tt.list, tt.skor, tt.setelah); got != tt.want {
+			t.Errorf("%s: pilihSlot(skor %d, setelah %d) = %d, want %d", tt.nama, tt.skor, tt.setelah, got, tt.want)
+		}
+	}
+}
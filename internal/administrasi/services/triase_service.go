@@ -0,0 +1,209 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/c14220110/poliklinik-backend/internal/administrasi/models"
+	"github.com/c14220110/poliklinik-backend/internal/common/antrian"
+	"github.com/c14220110/poliklinik-backend/ws"
+)
+
+var (
+	ErrTriaseTidakDitemukan = errors.New("kategori triase tidak ditemukan")
+	ErrTriaseTidakValid     = errors.New("data triase tidak valid")
+	ErrTriasePoliTidakSama  = errors.New("antrian bukan milik poliklinik pada token")
+)
+
+type TriaseService struct {
+	DB *sql.DB
+}
+
+func NewTriaseService(db *sql.DB) *TriaseService {
+	return &TriaseService{DB: db}
+}
+
+// ListTriase mengembalikan kategori triase, bobot tertinggi lebih dulu. hanyaAktif dipakai
+// untuk pilihan di pendaftaran & screening.
+func (s *TriaseService) ListTriase(hanyaAktif bool) ([]models.KategoriTriase, error) {
+	query := "SELECT id_triase, kode, nama, bobot, maks_tunggu_menit, aktif, created_at, updated_at FROM Kategori_Triase"
+	if hanyaAktif {
+		query += " WHERE aktif = 1"
+	}
+	rows, err := s.DB.Query(query + " ORDER BY bobot DESC, maks_tunggu_menit ASC, nama")
+	if err != nil {
+		return nil, fmt.Errorf("gagal mengambil kategori triase: %v", err)
+	}
+	defer rows.Close()
+
+	list := []models.KategoriTriase{}
+	for rows.Next() {
+		var k models.KategoriTriase
+		if err := rows.Scan(&k.IDTriase, &k.Kode, &k.Nama, &k.Bobot, &k.MaksTungguMenit, &k.Aktif,
+			&k.CreatedAt, &k.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("gagal membaca kategori triase: %v", err)
+		}
+		list = append(list, k)
+	}
+	return list, rows.Err()
+}
+
+func validasiTriase(k models.KategoriTriase) error {
+	if strings.TrimSpace(k.Kode) == "" || strings.TrimSpace(k.Nama) == "" {
+		return fmt.Errorf("%w: kode dan nama harus diisi", ErrTriaseTidakValid)
+	}
+	if k.Bobot < 0 {
+		return fmt.Errorf("%w: bobot tidak boleh negatif", ErrTriaseTidakValid)
+	}
+	if k.MaksTungguMenit <= 0 {
+		return fmt.Errorf("%w: maks_tunggu_menit harus lebih dari 0", ErrTriaseTidakValid)
+	}
+	return nil
+}
+
+// TambahTriase membuat kategori triase baru (aktif).
+func (s *TriaseService) TambahTriase(k models.KategoriTriase) (int, error) {
+	if err := validasiTriase(k); err != nil {
+		return 0, err
+	}
+	kode := strings.ToUpper(strings.TrimSpace(k.Kode))
+	res, err := s.DB.Exec(`
+		INSERT INTO Kategori_Triase (kode, nama, bobot, maks_tunggu_menit, aktif, created_at, updated_at)
+		VALUES (?, ?, ?, ?, 1, NOW(), NOW())`,
+		kode, strings.TrimSpace(k.Nama), k.Bobot, k.MaksTungguMenit)
+	if err != nil {
+		if strings.Contains(err.Error(), "Duplicate entry") {
+			return 0, fmt.Errorf("%w: kode %s sudah dipakai", ErrTriaseTidakValid, kode)
+		}
+		return 0, fmt.Errorf("gagal menambah kategori triase: %v", err)
+	}
+	id, err := res.LastInsertId()
+	return int(id), err
+}
+
+// UpdateTriase mengubah kategori triase dan (jika aktif tidak nil) status aktifnya. Antrian yang
+// sudah ditriase tetap memakai bobot & target tunggu saat triase ditetapkan.
+func (s *TriaseService) UpdateTriase(idTriase int, k models.KategoriTriase, aktif *bool) error {
+	if err := validasiTriase(k); err != nil {
+		return err
+	}
+	kode := strings.ToUpper(strings.TrimSpace(k.Kode))
+	res, err := s.DB.Exec(`
+		UPDATE Kategori_Triase
+		SET kode = ?, nama = ?, bobot = ?, maks_tunggu_menit = ?, aktif = IFNULL(?, aktif), updated_at = NOW()
+		WHERE id_triase = ?`,
+		kode, strings.TrimSpace(k.Nama), k.Bobot, k.MaksTungguMenit, aktif, idTriase)
+	if err != nil {
+		if strings.Contains(err.Error(), "Duplicate entry") {
+			return fmt.Errorf("%w: kode %s sudah dipakai", ErrTriaseTidakValid, kode)
+		}
+		return fmt.Errorf("gagal mengupdate kategori triase: %v", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		var ada int
+		if err := s.DB.QueryRow("SELECT COUNT(*) FROM Kategori_Triase WHERE id_triase = ?", idTriase).Scan(&ada); err == nil && ada == 0 {
+			return ErrTriaseTidakDitemukan
+		}
+	}
+	return nil
+}
+
+// TetapkanTriase dipakai suster untuk menetapkan (atau menghapus, idTriase 0) kategori triase
+// antrian di poli pada token. Hanya antrian yang belum masuk konsultasi yang bisa diubah;
+// urutan panggilan langsung mengikuti bobot baru.
+func (s *TriaseService) TetapkanTriase(idAntrian, idTriase, idPoli int, actor antrian.Actor) (*models.TriaseAntrian, error) {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("gagal memulai transaksi: %v", err)
+	}
+	defer tx.Rollback()
+
+	status, err := antrian.Lock(tx, idAntrian)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		antrianPoli int
+		hariIni     bool
+	)
+	if err := tx.QueryRow(`SELECT id_poli, DATE(created_at) = CURDATE() FROM Antrian WHERE id_antrian = ?`,
+		idAntrian).Scan(&antrianPoli, &hariIni); err != nil {
+		return nil, fmt.Errorf("gagal mengambil antrian: %v", err)
+	}
+	if antrianPoli != idPoli {
+		return nil, ErrTriasePoliTidakSama
+	}
+	switch status {
+	case antrian.StatusMenunggu, antrian.StatusDitunda, antrian.StatusScreening, antrian.StatusPraKonsultasi:
+	default:
+		return nil, fmt.Errorf("%w: triase tidak bisa diubah untuk antrian berstatus %s",
+			ErrTriaseTidakValid, antrian.NamaStatus(status))
+	}
+	if !hariIni {
+		return nil, fmt.Errorf("%w: hanya antrian hari ini yang bisa ditriase", ErrTriaseTidakValid)
+	}
+
+	hasil, err := tetapkanTriase(tx, int64(idAntrian), idTriase, actor.IDKaryawan)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("gagal commit transaksi: %v", err)
+	}
+
+	alasan := "triase dihapus"
+	if hasil.NamaTriase != nil {
+		alasan = "triase: " + *hasil.NamaTriase
+	}
+	antrian.PublishEvent(s.DB, ws.EventAntrianStatusChanged, idAntrian, status, status, actor, alasan)
+	return hasil, nil
+}
+
+// tetapkanTriase menyalin bobot & target tunggu kategori triase ke baris Antrian di dalam tx.
+// idTriase 0 menghapus triase. Kategori yang tidak aktif ditolak.
+func tetapkanTriase(tx *sql.Tx, idAntrian int64, idTriase int, idKaryawan int) (*models.TriaseAntrian, error) {
+	hasil := &models.TriaseAntrian{IDAntrian: int(idAntrian)}
+	var oleh sql.NullInt64
+	if idKaryawan > 0 {
+		oleh = sql.NullInt64{Int64: int64(idKaryawan), Valid: true}
+	}
+
+	if idTriase == 0 {
+		if _, err := tx.Exec(`
+			UPDATE Antrian
+			SET id_triase = NULL, bobot_triase = NULL, maks_tunggu_triase = NULL, triase_oleh = ?, triase_at = NOW()
+			WHERE id_antrian = ?`, oleh, idAntrian); err != nil {
+			return nil, fmt.Errorf("gagal menghapus triase antrian: %v", err)
+		}
+		return hasil, nil
+	}
+
+	var (
+		nama       string
+		bobot      int
+		maksTunggu int
+		aktif      bool
+	)
+	err := tx.QueryRow(`SELECT nama, bobot, maks_tunggu_menit, aktif FROM Kategori_Triase WHERE id_triase = ?`,
+		idTriase).Scan(&nama, &bobot, &maksTunggu, &aktif)
+	if err == sql.ErrNoRows {
+		return nil, ErrTriaseTidakDitemukan
+	}
+	if err != nil {
+		return nil, fmt.Errorf("gagal mengambil kategori triase: %v", err)
+	}
+	if !aktif {
+		return nil, fmt.Errorf("%w: kategori triase %s tidak aktif", ErrTriaseTidakValid, nama)
+	}
+	if _, err := tx.Exec(`
+		UPDATE Antrian
+		SET id_triase = ?, bobot_triase = ?, maks_tunggu_triase = ?, triase_oleh = ?, triase_at = NOW()
+		WHERE id_antrian = ?`, idTriase, bobot, maksTunggu, oleh, idAntrian); err != nil {
+		return nil, fmt.Errorf("gagal menyimpan triase antrian: %v", err)
+	}
+	hasil.IDTriase, hasil.NamaTriase = &idTriase, &nama
+	hasil.Bobot, hasil.MaksTungguMenit = bobot, &maksTunggu
+	return hasil, nil
+}
@@ -0,0 +1,110 @@
+package antrian
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrTidakAdaAntrian     = errors.New("tidak ada antrian")
+	ErrAntrianSudahDiambil = errors.New("antrian sudah diambil petugas lain, silakan panggil ulang")
+)
+
+const (
+	// kandidatPanggilan adalah jumlah antrian terdepan yang dicoba per putaran; cukup besar
+	// agar petugas yang memanggil bersamaan masing-masing mendapat baris berbeda.
+	kandidatPanggilan = 20
+	// putaranPanggilan membatasi berapa kali daftar kandidat dibaca ulang bila semua
+	// kandidat keburu diambil petugas lain.
+	putaranPanggilan = 5
+)
+
+// ClaimNext memanggil pasien berikutnya secara atomik: antrian hari ini dengan status `from`
+// di idPoli dibaca tanpa kunci dalam urutan UrutanPanggilan (skor triase, priority_order,
+// lalu nomor_antrian), kemudian kandidat dikunci satu per satu lewat primary key dan
+// dipindahkan ke status `to`. Kandidat yang sedang dikunci atau sudah berpindah status
+// dilewati, sehingga dua petugas yang menekan "panggil" bersamaan mendapat id_antrian
+// berbeda tanpa saling menunggu.
+func ClaimNext(db *sql.DB, idPoli, from, to int, actor Actor) (int, error) {
+	for putaran := 0; putaran < putaranPanggilan; putaran++ {
+		kandidat, err := kandidatClaim(db, idPoli, from)
+		if err != nil {
+			return 0, err
+		}
+		if len(kandidat) == 0 {
+			return 0, ErrTidakAdaAntrian
+		}
+		for _, idAntrian := range kandidat {
+			ok, err := claim(db, idAntrian, from, to, actor)
+			if err != nil {
+				return 0, err
+			}
+			if ok {
+				return idAntrian, nil
+			}
+		}
+	}
+	return 0, ErrAntrianSudahDiambil
+}
+
+// kandidatClaim membaca id antrian terdepan tanpa mengunci baris apa pun.
+func kandidatClaim(db *sql.DB, idPoli, from int) ([]int, error) {
+	rows, err := db.Query(`
+		SELECT id_antrian
+		FROM Antrian
+		WHERE id_poli = ? AND id_status = ? AND DATE(created_at) = CURDATE()
+		ORDER BY `+UrutanPanggilan("")+`
+		LIMIT ?`,
+		idPoli, from, kandidatPanggilan,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("gagal mengambil antrian berikutnya: %v", err)
+	}
+	defer rows.Close()
+
+	var kandidat []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("gagal membaca antrian berikutnya: %v", err)
+		}
+		kandidat = append(kandidat, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("gagal membaca antrian berikutnya: %v", err)
+	}
+	return kandidat, nil
+}
+
+// claim mengunci satu antrian lewat primary key dan memindahkannya ke status `to`.
+// Mengembalikan false bila baris sedang dikunci petugas lain atau statusnya sudah bukan `from`.
+func claim(db *sql.DB, idAntrian, from, to int, actor Actor) (bool, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return false, fmt.Errorf("gagal memulai transaksi: %v", err)
+	}
+	defer tx.Rollback()
+
+	var idStatus int
+	err = tx.QueryRow(`
+		SELECT id_status FROM Antrian
+		WHERE id_antrian = ? AND id_status = ?
+		FOR UPDATE SKIP LOCKED`,
+		idAntrian, from,
+	).Scan(&idStatus)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("gagal mengunci antrian: %v", err)
+	}
+
+	if _, err := Transition(tx, idAntrian, to, actor, ""); err != nil {
+		return false, err
+	}
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("gagal commit transaksi: %v", err)
+	}
+	return true, nil
+}
@@ -0,0 +1,118 @@
+package antrian
+
+import (
+	"errors"
+	"sync"
+	"testing"
+)
+
+func TestClaimNextBersamaan(t *testing.T) {
+	db := testDB(t)
+	idPoli, idPasien := testPoliPasien(t, db)
+
+	// ClaimNext mengambil semua antrian Menunggu di poli; jangan ganggu data lain.
+	var ada int
+	if err := db.QueryRow(`
+		SELECT COUNT(*) FROM Antrian
+		WHERE id_poli = ? AND id_status = ? AND DATE(created_at) = CURDATE()`,
+		idPoli, StatusMenunggu).Scan(&ada); err != nil {
+		t.Fatalf("gagal menghitung antrian: %v", err)
+	}
+	if ada > 0 {
+		t.Skipf("poli %d sudah punya %d antrian Menunggu hari ini", idPoli, ada)
+	}
+
+	const jumlahAntrian, jumlahPetugas = 10, 6
+	var dibuat []int64
+	t.Cleanup(func() { hapusAntrian(t, db, dibuat) })
+	for i := 0; i < jumlahAntrian; i++ {
+		tx, err := db.Begin()
+		if err != nil {
+			t.Fatal(err)
+		}
+		id, _, err := insertAntrian(tx, idPoli, idPasien)
+		if err != nil {
+			tx.Rollback()
+			t.Fatalf("gagal membuat antrian: %v", err)
+		}
+		if err := tx.Commit(); err != nil {
+			t.Fatal(err)
+		}
+		dibuat = append(dibuat, id)
+	}
+
+	var (
+		mu      sync.Mutex
+		diambil = map[int]int{} // id_antrian -> berapa kali diambil
+	)
+	// panggilBersamaan menjalankan semua petugas sekaligus; panggil dijalankan per petugas.
+	panggilBersamaan := func(panggil func(actor Actor)) {
+		var wg sync.WaitGroup
+		start := make(chan struct{})
+		for i := 0; i < jumlahPetugas; i++ {
+			wg.Add(1)
+			go func(petugas int) {
+				defer wg.Done()
+				<-start
+				panggil(Actor{IDKaryawan: petugas + 1, Role: "Test"})
+			}(i)
+		}
+		close(start)
+		wg.Wait()
+	}
+
+	// Putaran pertama: antrian masih lebih banyak dari petugas, jadi setiap petugas
+	// harus langsung mendapat antrian yang berbeda.
+	panggilBersamaan(func(actor Actor) {
+		id, err := ClaimNext(db, idPoli, StatusMenunggu, StatusScreening, actor)
+		if err != nil {
+			t.Errorf("putaran pertama petugas %d: %v", actor.IDKaryawan, err)
+			return
+		}
+		mu.Lock()
+		diambil[id]++
+		mu.Unlock()
+	})
+	if len(diambil) != jumlahPetugas {
+		t.Errorf("putaran pertama: %d antrian berbeda untuk %d petugas, want %d", len(diambil), jumlahPetugas, jumlahPetugas)
+	}
+
+	// Sisanya dipanggil sampai habis. ErrAntrianSudahDiambil selama masih ada antrian
+	// Menunggu berarti petugas ditolak padahal masih ada pasien yang bisa dipanggil.
+	panggilBersamaan(func(actor Actor) {
+		for {
+			id, err := ClaimNext(db, idPoli, StatusMenunggu, StatusScreening, actor)
+			if errors.Is(err, ErrTidakAdaAntrian) {
+				return
+			}
+			if err != nil {
+				t.Errorf("petugas %d: %v", actor.IDKaryawan, err)
+				return
+			}
+			mu.Lock()
+			diambil[id]++
+			mu.Unlock()
+		}
+	})
+
+	for _, id := range dibuat {
+		if n := diambil[int(id)]; n != 1 {
+			t.Errorf("antrian %d diambil %d kali, want 1", id, n)
+		}
+	}
+	if len(diambil) != jumlahAntrian {
+		t.Errorf("antrian yang diambil = %d, want %d", len(diambil), jumlahAntrian)
+	}
+
+	var screening int
+	if err := db.QueryRow(`
+		SELECT COUNT(*) FROM Antrian_Log
+		WHERE status_dari = ? AND status_ke = ? AND id_antrian IN (
+		  SELECT id_antrian FROM Antrian WHERE id_poli = ? AND DATE(created_at) = CURDATE() AND keluhan_utama = 'test')`,
+		StatusMenunggu, StatusScreening, idPoli).Scan(&screening); err != nil {
+		t.Fatalf("gagal menghitung Antrian_Log: %v", err)
+	}
+	if screening != jumlahAntrian {
+		t.Errorf("Antrian_Log Menunggu -> Screening = %d, want %d", screening, jumlahAntrian)
+	}
+}
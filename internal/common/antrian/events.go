@@ -0,0 +1,55 @@
+package antrian
+
+import (
+	"database/sql"
+	"log/slog"
+	"time"
+
+	"github.com/c14220110/poliklinik-backend/ws"
+)
+
+// PublishEvent mengirim event antrian ke hub WebSocket. Harus dipanggil SETELAH
+// transaksi commit agar client tidak menerima perubahan yang kemudian di-rollback.
+// from = 0 berarti antrian baru dibuat (tidak ada status asal).
+// Kegagalan hanya dicatat; event tidak boleh menggagalkan operasi yang sudah commit.
+func PublishEvent(db *sql.DB, eventType string, idAntrian, from, to int, actor Actor, alasan string) {
+	PublishJadwalUlang(db, eventType, idAntrian, from, to, actor, alasan, nil)
+}
+
+// PublishJadwalUlang sama dengan PublishEvent, ditambah penjelasan slot reschedule yang juga
+// dikirim ke layar display.
+func PublishJadwalUlang(db *sql.DB, eventType string, idAntrian, from, to int, actor Actor, alasan string, jadwal *ws.JadwalUlang) {
+	event := ws.AntrianEvent{
+		IDAntrian:   idAntrian,
+		IDStatus:    to,
+		Status:      NamaStatus(to),
+		Alasan:      alasan,
+		IDKaryawan:  actor.IDKaryawan,
+		Role:        actor.Role,
+		JadwalUlang: jadwal,
+		Timestamp:   time.Now(),
+	}
+	if from != 0 {
+		namaDari := NamaStatus(from)
+		event.IDStatusDari = &from
+		event.StatusDari = &namaDari
+	}
+
+	var priority sql.NullInt64
+	err := db.QueryRow(`
+		SELECT a.nomor_antrian, a.priority_order, a.id_poli, pl.nama_poli, a.id_pasien
+		FROM Antrian a
+		JOIN Poliklinik pl ON a.id_poli = pl.id_poli
+		WHERE a.id_antrian = ?`, idAntrian,
+	).Scan(&event.NomorAntrian, &priority, &event.IDPoli, &event.NamaPoli, &event.IDPasien)
+	if err != nil {
+		slog.Error("Gagal mengambil data event antrian", "id_antrian", idAntrian, "reason", err)
+		return
+	}
+	if priority.Valid {
+		order := int(priority.Int64)
+		event.PriorityOrder = &order
+	}
+
+	ws.HubInstance.PublishAntrianEvent(eventType, event)
+}
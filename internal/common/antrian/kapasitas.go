@@ -0,0 +1,275 @@
+package antrian
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Alasan poli tidak menerima pendaftaran; nilainya sama dengan Daftar_Tunggu.alasan.
+const (
+	AlasanLibur = "Libur"
+	AlasanJam   = "Jam"
+	AlasanKuota = "Kuota"
+)
+
+var (
+	ErrPoliLibur  = errors.New("poliklinik libur")
+	ErrPoliTutup  = errors.New("di luar jam operasional poliklinik")
+	ErrKuotaPenuh = errors.New("kuota pendaftaran poliklinik sudah penuh")
+)
+
+// SesiPoli adalah satu sesi buka poli (baris Jadwal_Poli) pada hari tertentu.
+// Kuota / Sisa nil berarti sesi hanya dibatasi kuota harian.
+type SesiPoli struct {
+	IDJadwal int    `json:"id_jadwal"`
+	IDShift  *int   `json:"id_shift"`
+	JamBuka  string `json:"jam_buka"`  // HH:MM
+	JamTutup string `json:"jam_tutup"` // HH:MM
+	Kuota    *int   `json:"kuota"`
+	Terpakai int    `json:"terpakai"`
+	Sisa     *int   `json:"sisa"`
+}
+
+// Kapasitas adalah kapasitas pendaftaran satu poli pada saat tertentu. Sisa adalah yang terkecil
+// dari sisa kuota harian dan sisa kuota sesi berjalan (nil = tanpa batas). Terjadwal false
+// berarti poli belum punya Jadwal_Poli dan dianggap buka sepanjang hari.
+type Kapasitas struct {
+	IDPoli          int        `json:"id_poli"`
+	Tanggal         string     `json:"tanggal"`
+	Libur           bool       `json:"libur"`
+	KeteranganLibur string     `json:"keterangan_libur,omitempty"`
+	Terjadwal       bool       `json:"terjadwal"`
+	Buka            bool       `json:"buka"`
+	Jadwal          []SesiPoli `json:"jadwal"`          // sesi hari ini
+	Sesi            *SesiPoli  `json:"sesi"`            // sesi yang sedang berjalan
+	SesiBerikutnya  *SesiPoli  `json:"sesi_berikutnya"` // sesi berikutnya hari ini
+	KuotaHarian     *int       `json:"kuota_harian"`
+	TerpakaiHarian  int        `json:"terpakai_harian"`
+	Sisa            *int       `json:"sisa"`
+	BisaDaftar      bool       `json:"bisa_daftar"`
+	BisaTunggu      bool       `json:"bisa_daftar_tunggu"` // boleh masuk Daftar_Tunggu
+	Alasan          string     `json:"alasan,omitempty"`   // Libur / Jam / Kuota
+	Keterangan      string     `json:"keterangan,omitempty"`
+}
+
+// Err mengembalikan ErrPoliLibur / ErrPoliTutup / ErrKuotaPenuh beserta keterangannya,
+// atau nil jika poli bisa menerima pendaftaran.
+func (k *Kapasitas) Err() error {
+	switch {
+	case k.BisaDaftar:
+		return nil
+	case k.Alasan == AlasanLibur:
+		return fmt.Errorf("%w: %s", ErrPoliLibur, k.Keterangan)
+	case k.Alasan == AlasanJam:
+		return fmt.Errorf("%w: %s", ErrPoliTutup, k.Keterangan)
+	default:
+		return fmt.Errorf("%w: %s", ErrKuotaPenuh, k.Keterangan)
+	}
+}
+
+// HariJadwal mengubah time.Weekday ke nomor hari Jadwal_Poli (1 = Senin ... 7 = Minggu).
+func HariJadwal(t time.Time) int {
+	if t.Weekday() == time.Sunday {
+		return 7
+	}
+	return int(t.Weekday())
+}
+
+// HitungKapasitas menghitung kapasitas pendaftaran idPoli pada waktu now tanpa mengunci apa pun.
+// Dipakai daftar poli dan tampilan; pendaftaran memakai CekKapasitas.
+func HitungKapasitas(q Queryer, idPoli int, now time.Time) (*Kapasitas, error) {
+	var kuota sql.NullInt64
+	err := q.QueryRow(`SELECT kuota_harian FROM Poliklinik WHERE id_poli = ?`, idPoli).Scan(&kuota)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("poliklinik %d tidak ditemukan", idPoli)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("gagal mengambil kuota poliklinik: %v", err)
+	}
+	return hitungKapasitas(q, idPoli, kuota, now)
+}
+
+// CekKapasitas mengunci baris Poliklinik sampai transaksi selesai (pendaftaran ke poli yang
+// sama berjalan berurutan sehingga kuota tidak terlampaui), lalu menghitung kapasitas.
+// Jika poli tidak bisa menerima pendaftaran, Kapasitas tetap dikembalikan bersama k.Err().
+func CekKapasitas(tx *sql.Tx, idPoli int, now time.Time) (*Kapasitas, error) {
+	var kuota sql.NullInt64
+	err := tx.QueryRow(`SELECT kuota_harian FROM Poliklinik WHERE id_poli = ? FOR UPDATE`, idPoli).Scan(&kuota)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("poliklinik %d tidak ditemukan", idPoli)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("gagal mengunci poliklinik: %v", err)
+	}
+	k, err := hitungKapasitas(tx, idPoli, kuota, now)
+	if err != nil {
+		return nil, err
+	}
+	return k, k.Err()
+}
+
+func hitungKapasitas(q Queryer, idPoli int, kuotaHarian sql.NullInt64, now time.Time) (*Kapasitas, error) {
+	tgl := now.Format("2006-01-02")
+	k := &Kapasitas{IDPoli: idPoli, Tanggal: tgl, Jadwal: []SesiPoli{}}
+
+	// 1. Libur khusus poli didahulukan dari libur seluruh klinik.
+	err := q.QueryRow(`
+		SELECT keterangan FROM Libur_Poli
+		WHERE tanggal = ? AND (id_poli = ? OR id_poli IS NULL)
+		ORDER BY id_poli IS NULL
+		LIMIT 1`, tgl, idPoli).Scan(&k.KeteranganLibur)
+	switch {
+	case err == nil:
+		k.Libur = true
+		k.Alasan = AlasanLibur
+		k.Keterangan = k.KeteranganLibur
+		return k, nil
+	case err != sql.ErrNoRows:
+		return nil, fmt.Errorf("gagal mengambil hari libur: %v", err)
+	}
+
+	// 2. Sesi hari ini
+	if err := q.QueryRow(`SELECT EXISTS(SELECT 1 FROM Jadwal_Poli WHERE id_poli = ?)`, idPoli).Scan(&k.Terjadwal); err != nil {
+		return nil, fmt.Errorf("gagal mengambil jadwal poliklinik: %v", err)
+	}
+	if k.Terjadwal {
+		if k.Jadwal, err = sesiHari(q, idPoli, HariJadwal(now)); err != nil {
+			return nil, err
+		}
+	}
+	jam := now.Format("15:04")
+	for i := range k.Jadwal {
+		s := &k.Jadwal[i]
+		if s.Terpakai, err = hitungPendaftaran(q, idPoli, tgl, s); err != nil {
+			return nil, err
+		}
+		if s.Kuota != nil {
+			sisa := max(*s.Kuota-s.Terpakai, 0)
+			s.Sisa = &sisa
+		}
+		switch {
+		case k.Sesi == nil && sesiBerjalan(*s, jam):
+			k.Sesi = s
+		case k.SesiBerikutnya == nil && s.JamBuka > jam:
+			k.SesiBerikutnya = s
+		}
+	}
+	k.Buka = !k.Terjadwal || k.Sesi != nil
+
+	// 3. Kuota harian dan kuota sesi
+	if k.TerpakaiHarian, err = hitungPendaftaran(q, idPoli, tgl, nil); err != nil {
+		return nil, err
+	}
+	if kuotaHarian.Valid {
+		kuota := int(kuotaHarian.Int64)
+		sisa := max(kuota-k.TerpakaiHarian, 0)
+		k.KuotaHarian, k.Sisa = &kuota, &sisa
+	}
+	if k.Sesi != nil && k.Sesi.Sisa != nil && (k.Sisa == nil || *k.Sesi.Sisa < *k.Sisa) {
+		k.Sisa = k.Sesi.Sisa
+	}
+
+	switch {
+	case !k.Buka:
+		k.Alasan = AlasanJam
+		k.Keterangan = keteranganTutup(k)
+		k.BisaTunggu = k.SesiBerikutnya != nil
+	case k.Sisa != nil && *k.Sisa == 0:
+		k.Alasan = AlasanKuota
+		if k.KuotaHarian != nil && k.TerpakaiHarian >= *k.KuotaHarian {
+			k.Keterangan = fmt.Sprintf("kuota harian %d pasien sudah terpakai", *k.KuotaHarian)
+		} else {
+			k.Keterangan = fmt.Sprintf("kuota sesi %s-%s (%d pasien) sudah terpakai",
+				k.Sesi.JamBuka, k.Sesi.JamTutup, *k.Sesi.Kuota)
+		}
+		k.BisaTunggu = true
+	default:
+		k.BisaDaftar = true
+	}
+	return k, nil
+}
+
+// sesiHari mengembalikan sesi Jadwal_Poli idPoli pada hari tersebut, urut jam buka.
+// Jam sesi yang kosong mengikuti Shift.
+func sesiHari(q Queryer, idPoli, hari int) ([]SesiPoli, error) {
+	rows, err := q.Query(`
+		SELECT jp.id_jadwal, jp.id_shift,
+		       TIME_FORMAT(COALESCE(jp.jam_buka, s.jam_mulai), '%H:%i') AS buka,
+		       TIME_FORMAT(COALESCE(jp.jam_tutup, s.jam_selesai), '%H:%i'),
+		       jp.kuota
+		FROM Jadwal_Poli jp
+		LEFT JOIN Shift s ON s.id_shift = jp.id_shift
+		WHERE jp.id_poli = ? AND jp.hari = ?
+		ORDER BY buka, jp.id_jadwal`, idPoli, hari)
+	if err != nil {
+		return nil, fmt.Errorf("gagal mengambil jadwal poliklinik: %v", err)
+	}
+	defer rows.Close()
+
+	list := []SesiPoli{}
+	for rows.Next() {
+		var (
+			s       SesiPoli
+			idShift sql.NullInt64
+			kuota   sql.NullInt64
+		)
+		if err := rows.Scan(&s.IDJadwal, &idShift, &s.JamBuka, &s.JamTutup, &kuota); err != nil {
+			return nil, fmt.Errorf("gagal membaca jadwal poliklinik: %v", err)
+		}
+		if idShift.Valid {
+			v := int(idShift.Int64)
+			s.IDShift = &v
+		}
+		if kuota.Valid {
+			v := int(kuota.Int64)
+			s.Kuota = &v
+		}
+		list = append(list, s)
+	}
+	return list, rows.Err()
+}
+
+// sesiBerjalan melaporkan apakah jam (HH:MM) berada di dalam sesi. Sesi tidak pernah
+// melewati tengah malam (ditolak saat jadwal disimpan).
+func sesiBerjalan(s SesiPoli, jam string) bool {
+	return jam >= s.JamBuka && jam < s.JamTutup
+}
+
+// hitungPendaftaran menghitung antrian pendaftaran (bukan check-in janji temu dan bukan rujukan
+// internal) yang tidak dibatalkan di idPoli pada tanggal tersebut, dibatasi jam sesi jika sesi
+// tidak nil.
+func hitungPendaftaran(q Queryer, idPoli int, tgl string, sesi *SesiPoli) (int, error) {
+	query := `
+		SELECT COUNT(*)
+		FROM Antrian a
+		WHERE a.id_poli = ? AND DATE(a.created_at) = ? AND a.id_status <> ?
+		  AND NOT EXISTS (SELECT 1 FROM Janji_Temu jt WHERE jt.id_antrian = a.id_antrian)
+		  AND NOT EXISTS (SELECT 1 FROM Kunjungan_Poli kp WHERE kp.id_antrian = a.id_antrian AND kp.id_antrian_asal IS NOT NULL)`
+	args := []interface{}{idPoli, tgl, StatusDibatalkan}
+	if sesi != nil {
+		query += ` AND TIME(a.created_at) >= ? AND TIME(a.created_at) < ?`
+		args = append(args, sesi.JamBuka, sesi.JamTutup)
+	}
+	var n int
+	if err := q.QueryRow(query, args...).Scan(&n); err != nil {
+		return 0, fmt.Errorf("gagal menghitung pendaftaran poliklinik: %v", err)
+	}
+	return n, nil
+}
+
+func keteranganTutup(k *Kapasitas) string {
+	if len(k.Jadwal) == 0 {
+		return "tidak ada jadwal praktik hari ini"
+	}
+	if k.SesiBerikutnya != nil {
+		return fmt.Sprintf("poli buka pukul %s", k.SesiBerikutnya.JamBuka)
+	}
+	jam := make([]string, len(k.Jadwal))
+	for i, s := range k.Jadwal {
+		jam[i] = s.JamBuka + "-" + s.JamTutup
+	}
+	return "jam operasional hari ini sudah selesai (" + strings.Join(jam, ", ") + ")"
+}
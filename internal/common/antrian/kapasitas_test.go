@@ -0,0 +1,80 @@
+package antrian
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHariJadwal(t *testing.T) {
+	// 2025-08-11 adalah Senin.
+	senin := time.Date(2025, 8, 11, 9, 0, 0, 0, time.UTC)
+	for i := 0; i < 7; i++ {
+		if got := HariJadwal(senin.AddDate(0, 0, i)); got != i+1 {
+			t.Errorf("HariJadwal(%s) = %d, want %d", senin.AddDate(0, 0, i).Weekday(), got, i+1)
+		}
+	}
+}
+
+func TestSesiBerjalan(t *testing.T) {
+	pagi := SesiPoli{JamBuka: "08:00", JamTutup: "12:00"}
+	tests := []struct {
+		jam  string
+		want bool
+	}{
+		{"07:59", false},
+		{"08:00", true},
+		{"10:30", true},
+		{"11:59", true},
+		{"12:00", false}, // jam tutup tidak termasuk
+		{"23:00", false},
+		{"00:30", false},
+	}
+	for _, tt := range tests {
+		if got := sesiBerjalan(pagi, tt.jam); got != tt.want {
+			t.Errorf("sesiBerjalan(08:00-12:00, %s) = %v, want %v", tt.jam, got, tt.want)
+		}
+	}
+}
+
+func TestKapasitasErr(t *testing.T) {
+	tests := []struct {
+		k    Kapasitas
+		want error
+	}{
+		{Kapasitas{BisaDaftar: true}, nil},
+		{Kapasitas{Alasan: AlasanLibur, Keterangan: "HUT RI"}, ErrPoliLibur},
+		{Kapasitas{Alasan: AlasanJam, Keterangan: "poli buka pukul 13:00"}, ErrPoliTutup},
+		{Kapasitas{Alasan: AlasanKuota, Keterangan: "kuota harian 40 pasien sudah terpakai"}, ErrKuotaPenuh},
+	}
+	for _, tt := range tests {
+		err := tt.k.Err()
+		if tt.want == nil {
+			if err != nil {
+				t.Errorf("Err() = %v, want nil", err)
+			}
+			continue
+		}
+		if !errors.Is(err, tt.want) || !strings.Contains(err.Error(), tt.k.Keterangan) {
+			t.Errorf("Err() = %v, want %v dengan keterangan %q", err, tt.want, tt.k.Keterangan)
+		}
+	}
+}
+
+func TestKeteranganTutup(t *testing.T) {
+	pagi := SesiPoli{JamBuka: "08:00", JamTutup: "12:00"}
+	sore := SesiPoli{JamBuka: "13:00", JamTutup: "16:00"}
+
+	if got := keteranganTutup(&Kapasitas{}); got != "tidak ada jadwal praktik hari ini" {
+		t.Errorf("tanpa jadwal: %q", got)
+	}
+	k := &Kapasitas{Jadwal: []SesiPoli{pagi, sore}, SesiBerikutnya: &sore}
+	if got := keteranganTutup(k); got != "poli buka pukul 13:00" {
+		t.Errorf("sebelum sesi sore: %q", got)
+	}
+	k.SesiBerikutnya = nil
+	if got, want := keteranganTutup(k), "jam operasional hari ini sudah selesai (08:00-12:00, 13:00-16:00)"; got != want {
+		t.Errorf("setelah sesi terakhir: %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,41 @@
+package antrian
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// AllocateNomor mengambil nomor antrian berikutnya untuk idPoli pada hari ini (CURDATE()).
+// Harus dipanggil di dalam transaksi yang juga meng-INSERT baris Antrian, sehingga
+// baris Counter_Antrian tetap terkunci (FOR UPDATE) sampai transaksi selesai, sama seperti
+// Counter_RM. Unique key uq_antrian_poli_tanggal_nomor menjadi pengaman terakhir.
+func AllocateNomor(tx *sql.Tx, idPoli int) (int64, error) {
+	// Pastikan baris counter hari ini ada. Saat pertama kali dibuat, mulai dari nomor
+	// terbesar yang sudah ada hari ini agar aman dipasang di tengah hari operasional.
+	_, err := tx.Exec(`
+		INSERT IGNORE INTO Counter_Antrian (id_poli, tanggal, count)
+		SELECT ?, CURDATE(), COALESCE(MAX(nomor_antrian), 0)
+		FROM Antrian
+		WHERE id_poli = ? AND DATE(created_at) = CURDATE()`,
+		idPoli, idPoli,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("gagal menyiapkan Counter_Antrian: %v", err)
+	}
+
+	var count int64
+	err = tx.QueryRow(`
+		SELECT count FROM Counter_Antrian
+		WHERE id_poli = ? AND tanggal = CURDATE()
+		FOR UPDATE`, idPoli,
+	).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("gagal mengunci Counter_Antrian: %v", err)
+	}
+
+	count++
+	if _, err = tx.Exec(`UPDATE Counter_Antrian SET count = ? WHERE id_poli = ? AND tanggal = CURDATE()`, count, idPoli); err != nil {
+		return 0, fmt.Errorf("gagal mengupdate Counter_Antrian: %v", err)
+	}
+	return count, nil
+}
@@ -0,0 +1,151 @@
+package antrian
+
+import (
+	"database/sql"
+	"errors"
+	"os"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// testDB membuka database uji dari TEST_DB_DSN (skema sudah dimigrasi), misalnya
+// user:pass@tcp(127.0.0.1:3306)/poliklinik_test?parseTime=true&loc=Asia%2FJakarta.
+// Test dilewati jika TEST_DB_DSN kosong.
+func testDB(t *testing.T) *sql.DB {
+	t.Helper()
+	dsn := os.Getenv("TEST_DB_DSN")
+	if dsn == "" {
+		t.Skip("TEST_DB_DSN tidak diset")
+	}
+	db, err := sql.Open("mysql", dsn)
+	if err != nil {
+		t.Fatalf("gagal membuka database uji: %v", err)
+	}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		t.Fatalf("gagal ping database uji: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// testPoliPasien mengambil satu poli dan satu pasien yang sudah ada di database uji.
+func testPoliPasien(t *testing.T, db *sql.DB) (idPoli int, idPasien int64) {
+	t.Helper()
+	if err := db.QueryRow("SELECT id_poli FROM Poliklinik ORDER BY id_poli LIMIT 1").Scan(&idPoli); err != nil {
+		t.Skipf("database uji belum punya Poliklinik: %v", err)
+	}
+	if err := db.QueryRow("SELECT id_pasien FROM Pasien ORDER BY id_pasien LIMIT 1").Scan(&idPasien); err != nil {
+		t.Skipf("database uji belum punya Pasien: %v", err)
+	}
+	return idPoli, idPasien
+}
+
+// insertAntrian membuat baris Antrian hari ini berstatus Menunggu dengan nomor dari AllocateNomor.
+func insertAntrian(tx *sql.Tx, idPoli int, idPasien int64) (idAntrian, nomor int64, err error) {
+	if nomor, err = AllocateNomor(tx, idPoli); err != nil {
+		return 0, 0, err
+	}
+	res, err := tx.Exec(`
+		INSERT INTO Antrian
+		  (id_pasien, id_poli, keluhan_utama, nomor_antrian,
+		   id_status, priority_order, created_at, nama_penanggung_jawab)
+		VALUES (?, ?, 'test', ?, ?, ?, NOW(), '')`,
+		idPasien, idPoli, nomor, StatusMenunggu, nomor)
+	if err != nil {
+		return 0, 0, err
+	}
+	idAntrian, err = res.LastInsertId()
+	return idAntrian, nomor, err
+}
+
+// hapusAntrian menghapus antrian buatan test beserta Antrian_Log-nya.
+func hapusAntrian(t *testing.T, db *sql.DB, ids []int64) {
+	t.Helper()
+	for _, id := range ids {
+		if _, err := db.Exec("DELETE FROM Antrian_Log WHERE id_antrian = ?", id); err != nil {
+			t.Errorf("gagal menghapus Antrian_Log %d: %v", id, err)
+		}
+		if _, err := db.Exec("DELETE FROM Antrian WHERE id_antrian = ?", id); err != nil {
+			t.Errorf("gagal menghapus Antrian %d: %v", id, err)
+		}
+	}
+}
+
+func TestAllocateNomorBersamaan(t *testing.T) {
+	db := testDB(t)
+	idPoli, idPasien := testPoliPasien(t, db)
+
+	const n = 20
+	var (
+		mu    sync.Mutex
+		ids   []int64
+		nomor []int64
+		wg    sync.WaitGroup
+		errs  = make(chan error, n)
+	)
+	t.Cleanup(func() { hapusAntrian(t, db, ids) })
+
+	start := make(chan struct{})
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			tx, err := db.Begin()
+			if err != nil {
+				errs <- err
+				return
+			}
+			defer tx.Rollback()
+			id, no, err := insertAntrian(tx, idPoli, idPasien)
+			if err != nil {
+				errs <- err
+				return
+			}
+			if err := tx.Commit(); err != nil {
+				errs <- err
+				return
+			}
+			mu.Lock()
+			ids = append(ids, id)
+			nomor = append(nomor, no)
+			mu.Unlock()
+		}()
+	}
+	close(start)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Errorf("alokasi gagal: %v", err)
+	}
+	if len(nomor) != n {
+		t.Fatalf("nomor teralokasi = %d, want %d", len(nomor), n)
+	}
+
+	sort.Slice(nomor, func(i, j int) bool { return nomor[i] < nomor[j] })
+	for i := 1; i < len(nomor); i++ {
+		if nomor[i] != nomor[0]+int64(i) {
+			t.Fatalf("nomor tidak berurutan / ganda: %v", nomor)
+		}
+	}
+
+	// Unique key uq_antrian_poli_tanggal_nomor menolak nomor ganda yang dimasukkan manual.
+	res, err := db.Exec(`
+		INSERT INTO Antrian
+		  (id_pasien, id_poli, keluhan_utama, nomor_antrian,
+		   id_status, priority_order, created_at, nama_penanggung_jawab)
+		VALUES (?, ?, 'test', ?, ?, ?, NOW(), '')`,
+		idPasien, idPoli, nomor[0], StatusMenunggu, nomor[0])
+	if err == nil {
+		id, _ := res.LastInsertId()
+		ids = append(ids, id)
+	}
+	var myErr *mysql.MySQLError
+	if !errors.As(err, &myErr) || myErr.Number != 1062 {
+		t.Fatalf("insert nomor ganda: err = %v, want duplicate key (1062)", err)
+	}
+}
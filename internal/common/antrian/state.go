@@ -0,0 +1,232 @@
+// Package antrian berisi state machine status Antrian. Semua perubahan id_status
+// pada tabel Antrian harus lewat Transition agar tervalidasi dan tercatat di Antrian_Log.
+package antrian
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/c14220110/poliklinik-backend/pkg/utils"
+)
+
+// ID status sesuai isi tabel Status_Antrian; migrasi 004 dan 014 gagal bila id Ditunda /
+// Tidak Hadir di database berbeda dengan konstanta ini.
+const (
+	StatusMenunggu      = 1
+	StatusDitunda       = 2
+	StatusScreening     = 3
+	StatusPraKonsultasi = 4
+	StatusKonsultasi    = 5
+	StatusPulang        = 6
+	StatusDibatalkan    = 7
+	StatusTidakHadir    = 8
+)
+
+var namaStatus = map[int]string{
+	StatusMenunggu:      "Menunggu",
+	StatusDitunda:       "Ditunda",
+	StatusScreening:     "Screening",
+	StatusPraKonsultasi: "Pra-Konsultasi",
+	StatusKonsultasi:    "Konsultasi",
+	StatusPulang:        "Pulang",
+	StatusDibatalkan:    "Dibatalkan",
+	StatusTidakHadir:    "Tidak Hadir",
+}
+
+// allowedTransitions: status asal -> status tujuan yang diizinkan.
+// Alur utama: Menunggu -> Screening -> Pra-Konsultasi -> Konsultasi -> Pulang.
+// Pasien yang belum masuk konsultasi boleh ditunda atau dibatalkan;
+// pasien yang ditunda dijadwalkan ulang kembali ke Menunggu. Penutupan harian menandai
+// pasien yang tidak pernah dipanggil (Menunggu / Ditunda) sebagai Tidak Hadir.
+var allowedTransitions = map[int][]int{
+	StatusMenunggu:      {StatusScreening, StatusDitunda, StatusDibatalkan, StatusTidakHadir},
+	StatusDitunda:       {StatusMenunggu, StatusDibatalkan, StatusTidakHadir},
+	StatusScreening:     {StatusPraKonsultasi, StatusDitunda, StatusDibatalkan},
+	StatusPraKonsultasi: {StatusKonsultasi, StatusDitunda, StatusDibatalkan},
+	StatusKonsultasi:    {StatusPulang},
+	StatusPulang:        {},
+	StatusDibatalkan:    {},
+	StatusTidakHadir:    {},
+}
+
+var (
+	ErrAntrianTidakDitemukan = errors.New("antrian tidak ditemukan")
+	ErrTransisiTidakValid    = errors.New("transisi status antrian tidak diizinkan")
+)
+
+// Actor adalah pengguna yang memicu perubahan status (diambil dari JWT).
+type Actor struct {
+	IDKaryawan int
+	Role       string
+}
+
+// ActorFromClaims membuat Actor dari claims JWT; claims nil menghasilkan actor sistem.
+func ActorFromClaims(claims *utils.Claims) Actor {
+	if claims == nil {
+		return Actor{Role: "Sistem"}
+	}
+	return Actor{IDKaryawan: claims.IDKaryawan, Role: claims.Role}
+}
+
+// NamaStatus mengembalikan nama status untuk id_status.
+func NamaStatus(idStatus int) string {
+	if nama, ok := namaStatus[idStatus]; ok {
+		return nama
+	}
+	return fmt.Sprintf("Status %d", idStatus)
+}
+
+// CanTransition melaporkan apakah perpindahan from -> to diizinkan.
+func CanTransition(from, to int) bool {
+	for _, next := range allowedTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// Lock mengunci baris antrian (SELECT ... FOR UPDATE) dan mengembalikan id_status saat ini.
+func Lock(tx *sql.Tx, idAntrian int) (int, error) {
+	var idStatus int
+	err := tx.QueryRow("SELECT id_status FROM Antrian WHERE id_antrian = ? FOR UPDATE", idAntrian).Scan(&idStatus)
+	if err == sql.ErrNoRows {
+		return 0, fmt.Errorf("%w: id %d", ErrAntrianTidakDitemukan, idAntrian)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("gagal mengunci antrian: %v", err)
+	}
+	return idStatus, nil
+}
+
+// PoliOf mengembalikan id_poli pemilik antrian.
+func PoliOf(db *sql.DB, idAntrian int) (int, error) {
+	var idPoli int
+	err := db.QueryRow("SELECT id_poli FROM Antrian WHERE id_antrian = ?", idAntrian).Scan(&idPoli)
+	if err == sql.ErrNoRows {
+		return 0, fmt.Errorf("%w: id %d", ErrAntrianTidakDitemukan, idAntrian)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("gagal mengambil poli antrian: %v", err)
+	}
+	return idPoli, nil
+}
+
+// Transition memindahkan antrian ke status `to` di dalam transaksi tx, lalu mencatatnya
+// ke Antrian_Log. Mengembalikan status asal.
+func Transition(tx *sql.Tx, idAntrian, to int, actor Actor, alasan string) (int, error) {
+	from, err := Lock(tx, idAntrian)
+	if err != nil {
+		return 0, err
+	}
+	if !CanTransition(from, to) {
+		return from, fmt.Errorf("%w: dari %s ke %s", ErrTransisiTidakValid, NamaStatus(from), NamaStatus(to))
+	}
+
+	if _, err := tx.Exec("UPDATE Antrian SET id_status = ? WHERE id_antrian = ?", to, idAntrian); err != nil {
+		return from, fmt.Errorf("gagal mengupdate status antrian: %v", err)
+	}
+	if err := insertLog(tx, idAntrian, sql.NullInt64{Int64: int64(from), Valid: true}, to, actor, alasan); err != nil {
+		return from, err
+	}
+	return from, nil
+}
+
+// TransitionDB sama dengan Transition tetapi membuka & meng-commit transaksinya sendiri.
+func TransitionDB(db *sql.DB, idAntrian, to int, actor Actor, alasan string) (int, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("gagal memulai transaksi: %v", err)
+	}
+	defer tx.Rollback()
+
+	from, err := Transition(tx, idAntrian, to, actor, alasan)
+	if err != nil {
+		return from, err
+	}
+	if err := tx.Commit(); err != nil {
+		return from, fmt.Errorf("gagal commit transaksi: %v", err)
+	}
+	return from, nil
+}
+
+// LogCreated mencatat antrian baru (status awal Menunggu) ke Antrian_Log.
+func LogCreated(tx *sql.Tx, idAntrian int64, actor Actor, alasan string) error {
+	return insertLog(tx, int(idAntrian), sql.NullInt64{}, StatusMenunggu, actor, alasan)
+}
+
+func insertLog(tx *sql.Tx, idAntrian int, from sql.NullInt64, to int, actor Actor, alasan string) error {
+	var idKaryawan sql.NullInt64
+	if actor.IDKaryawan > 0 {
+		idKaryawan = sql.NullInt64{Int64: int64(actor.IDKaryawan), Valid: true}
+	}
+	var reason sql.NullString
+	if alasan != "" {
+		reason = sql.NullString{String: alasan, Valid: true}
+	}
+
+	_, err := tx.Exec(`
+		INSERT INTO Antrian_Log (id_antrian, status_dari, status_ke, id_karyawan, role, alasan, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		idAntrian, from, to, idKaryawan, actor.Role, reason, time.Now(),
+	)
+	if err != nil {
+		return fmt.Errorf("gagal mencatat Antrian_Log: %v", err)
+	}
+	return nil
+}
+
+// LogEntry adalah satu baris riwayat perubahan status antrian.
+type LogEntry struct {
+	IDLog      int64     `json:"id_log"`
+	IDAntrian  int       `json:"id_antrian"`
+	StatusDari *string   `json:"status_dari"`
+	StatusKe   string    `json:"status_ke"`
+	IDKaryawan *int      `json:"id_karyawan"`
+	Role       string    `json:"role"`
+	Alasan     *string   `json:"alasan"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// GetLog mengembalikan riwayat status antrian, terlama lebih dulu.
+func GetLog(db *sql.DB, idAntrian int) ([]LogEntry, error) {
+	rows, err := db.Query(`
+		SELECT id_log, id_antrian, status_dari, status_ke, id_karyawan, role, alasan, created_at
+		FROM Antrian_Log
+		WHERE id_antrian = ?
+		ORDER BY created_at ASC, id_log ASC`, idAntrian)
+	if err != nil {
+		return nil, fmt.Errorf("gagal mengambil Antrian_Log: %v", err)
+	}
+	defer rows.Close()
+
+	list := []LogEntry{}
+	for rows.Next() {
+		var (
+			e          LogEntry
+			from       sql.NullInt64
+			to         int
+			idKaryawan sql.NullInt64
+			alasan     sql.NullString
+		)
+		if err := rows.Scan(&e.IDLog, &e.IDAntrian, &from, &to, &idKaryawan, &e.Role, &alasan, &e.CreatedAt); err != nil {
+			return nil, fmt.Errorf("gagal membaca Antrian_Log: %v", err)
+		}
+		if from.Valid {
+			nama := NamaStatus(int(from.Int64))
+			e.StatusDari = &nama
+		}
+		e.StatusKe = NamaStatus(to)
+		if idKaryawan.Valid {
+			id := int(idKaryawan.Int64)
+			e.IDKaryawan = &id
+		}
+		if alasan.Valid {
+			e.Alasan = &alasan.String
+		}
+		list = append(list, e)
+	}
+	return list, rows.Err()
+}
@@ -0,0 +1,80 @@
+package antrian
+
+import "testing"
+
+func TestCanTransition(t *testing.T) {
+	tests := []struct {
+		from, to int
+		want     bool
+	}{
+		// Alur utama
+		{StatusMenunggu, StatusScreening, true},
+		{StatusScreening, StatusPraKonsultasi, true},
+		{StatusPraKonsultasi, StatusKonsultasi, true},
+		{StatusKonsultasi, StatusPulang, true},
+
+		// Tunda, reschedule, batal, tidak hadir
+		{StatusMenunggu, StatusDitunda, true},
+		{StatusScreening, StatusDitunda, true},
+		{StatusPraKonsultasi, StatusDitunda, true},
+		{StatusDitunda, StatusMenunggu, true},
+		{StatusMenunggu, StatusDibatalkan, true},
+		{StatusDitunda, StatusDibatalkan, true},
+		{StatusPraKonsultasi, StatusDibatalkan, true},
+		{StatusMenunggu, StatusTidakHadir, true},
+		{StatusDitunda, StatusTidakHadir, true},
+
+		// Tidak boleh melompati atau mundur
+		{StatusMenunggu, StatusKonsultasi, false},
+		{StatusMenunggu, StatusPulang, false},
+		{StatusScreening, StatusMenunggu, false},
+		{StatusDitunda, StatusScreening, false},
+		{StatusKonsultasi, StatusDitunda, false},
+		{StatusKonsultasi, StatusDibatalkan, false},
+		{StatusScreening, StatusTidakHadir, false},
+		{StatusMenunggu, StatusMenunggu, false},
+
+		// Status akhir
+		{StatusPulang, StatusMenunggu, false},
+		{StatusDibatalkan, StatusMenunggu, false},
+		{StatusTidakHadir, StatusMenunggu, false},
+
+		// Status tidak dikenal
+		{0, StatusMenunggu, false},
+		{StatusMenunggu, 99, false},
+	}
+	for _, tt := range tests {
+		if got := CanTransition(tt.from, tt.to); got != tt.want {
+			t.Errorf("CanTransition(%s, %s) = %v, want %v", NamaStatus(tt.from), NamaStatus(tt.to), got, tt.want)
+		}
+	}
+}
+
+func TestAllowedTransitionsLengkap(t *testing.T) {
+	for id := range namaStatus {
+		if _, ok := allowedTransitions[id]; !ok {
+			t.Errorf("status %s tidak ada di allowedTransitions", NamaStatus(id))
+		}
+	}
+	for from, list := range allowedTransitions {
+		for _, to := range list {
+			if _, ok := namaStatus[to]; !ok {
+				t.Errorf("transisi %s -> %d menuju status tidak dikenal", NamaStatus(from), to)
+			}
+		}
+	}
+	for _, akhir := range []int{StatusPulang, StatusDibatalkan, StatusTidakHadir} {
+		if n := len(allowedTransitions[akhir]); n != 0 {
+			t.Errorf("status akhir %s punya %d transisi keluar", NamaStatus(akhir), n)
+		}
+	}
+}
+
+func TestNamaStatus(t *testing.T) {
+	if got := NamaStatus(StatusPraKonsultasi); got != "Pra-Konsultasi" {
+		t.Errorf("NamaStatus(StatusPraKonsultasi) = %q", got)
+	}
+	if got := NamaStatus(42); got != "Status 42" {
+		t.Errorf("NamaStatus(42) = %q", got)
+	}
+}
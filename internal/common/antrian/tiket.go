@@ -0,0 +1,70 @@
+package antrian
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// Kode tiket dicetak di struk pendaftaran dan dipakai pasien untuk melihat posisi antrian
+// serta check-in ulang. Alfabet tanpa karakter yang mirip (0/O, 1/I/L) agar mudah diketik.
+const (
+	kodeTiketAlfabet = "ABCDEFGHJKMNPQRSTUVWXYZ23456789"
+	kodeTiketPanjang = 6
+)
+
+var ErrKodeTiketTidakValid = errors.New("kode tiket tidak valid")
+
+// GenerateKodeTiket membuat kode tiket acak (crypto/rand) sepanjang kodeTiketPanjang.
+func GenerateKodeTiket() (string, error) {
+	var sb strings.Builder
+	max := big.NewInt(int64(len(kodeTiketAlfabet)))
+	for i := 0; i < kodeTiketPanjang; i++ {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", fmt.Errorf("gagal membuat kode tiket: %v", err)
+		}
+		sb.WriteByte(kodeTiketAlfabet[n.Int64()])
+	}
+	return sb.String(), nil
+}
+
+// NormalizeKodeTiket merapikan input pasien (spasi, huruf kecil) dan memvalidasi formatnya.
+func NormalizeKodeTiket(kode string) (string, error) {
+	kode = strings.ToUpper(strings.TrimSpace(kode))
+	if len(kode) != kodeTiketPanjang {
+		return "", ErrKodeTiketTidakValid
+	}
+	for _, r := range kode {
+		if !strings.ContainsRune(kodeTiketAlfabet, r) {
+			return "", ErrKodeTiketTidakValid
+		}
+	}
+	return kode, nil
+}
+
+// AssignKodeTiket memberi kode tiket unik ke antrian di dalam transaksi pendaftaran.
+// Bentrok dengan unique key uq_antrian_kode_tiket dicoba ulang dengan kode baru.
+func AssignKodeTiket(tx *sql.Tx, idAntrian int64) (string, error) {
+	for i := 0; i < 5; i++ {
+		kode, err := GenerateKodeTiket()
+		if err != nil {
+			return "", err
+		}
+		_, err = tx.Exec("UPDATE Antrian SET kode_tiket = ? WHERE id_antrian = ?", kode, idAntrian)
+		if err == nil {
+			return kode, nil
+		}
+		var mysqlErr *mysql.MySQLError
+		if errors.As(err, &mysqlErr) && mysqlErr.Number == 1062 {
+			continue
+		}
+		return "", fmt.Errorf("gagal menyimpan kode tiket: %v", err)
+	}
+	return "", fmt.Errorf("gagal membuat kode tiket unik")
+}
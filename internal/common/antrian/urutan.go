@@ -0,0 +1,81 @@
+package antrian
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/c14220110/poliklinik-backend/config"
+)
+
+// SkorTunggu ditambahkan ke skor antrian setiap kali target tunggu (maks_tunggu_triase, atau
+// TRIASE_MAKS_TUNGGU_UMUM untuk pasien tanpa triase) terlampaui. Nilainya di atas bobot triase
+// non-darurat, sehingga pasien yang sudah melewati targetnya menyusul kategori yang lebih tinggi.
+const SkorTunggu = 100
+
+// SkorPanggilan mengembalikan ekspresi skor antrian saat ini: bobot triase + SkorTunggu x
+// jumlah target tunggu yang terlampaui. Lama tunggu dihitung dari menunggu_sejak (diisi saat
+// reschedule), atau created_at. alias adalah alias tabel Antrian pada query ("" jika tanpa alias).
+func SkorPanggilan(alias string) string {
+	p := ""
+	if alias != "" {
+		p = alias + "."
+	}
+	maks := int(config.LoadConfig().TriaseMaksTungguUmum / time.Minute)
+	if maks <= 0 {
+		maks = 120
+	}
+	return fmt.Sprintf(`(IFNULL(%[1]sbobot_triase, 0)
+		  + %[2]d * FLOOR(TIMESTAMPDIFF(MINUTE, IFNULL(%[1]smenunggu_sejak, %[1]screated_at), NOW()) / IFNULL(%[1]smaks_tunggu_triase, %[3]d)))`,
+		p, SkorTunggu, maks)
+}
+
+// UrutanPanggilan mengembalikan ekspresi ORDER BY urutan panggilan antrian: SkorPanggilan
+// tertinggi lebih dulu, lalu priority_order dan nomor_antrian. Tanpa triase dan tanpa pasien
+// yang melewati target, urutannya sama dengan priority_order (janji temu, reschedule, rujukan
+// tetap berlaku). alias adalah alias tabel Antrian pada query ("" jika tanpa alias).
+func UrutanPanggilan(alias string) string {
+	p := ""
+	if alias != "" {
+		p = alias + "."
+	}
+	return fmt.Sprintf(`%[2]s DESC,
+		%[1]spriority_order IS NULL, %[1]spriority_order ASC, %[1]snomor_antrian ASC`, p, SkorPanggilan(alias))
+}
+
+// Urutan adalah satu antrian pada daftar panggilan.
+type Urutan struct {
+	IDAntrian    int
+	NomorAntrian int
+}
+
+// Queryer dipenuhi *sql.DB maupun *sql.Tx.
+type Queryer interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// DaftarPanggilan mengembalikan antrian hari ini di idPoli dengan status tertentu sesuai
+// urutan ClaimNext. Dipakai layar display, posisi tiket, dan reschedule agar sama dengan
+// panggilan petugas.
+func DaftarPanggilan(q Queryer, idPoli, status int) ([]Urutan, error) {
+	rows, err := q.Query(`
+		SELECT id_antrian, nomor_antrian
+		FROM Antrian
+		WHERE id_poli = ? AND id_status = ? AND DATE(created_at) = CURDATE()
+		ORDER BY `+UrutanPanggilan(""), idPoli, status)
+	if err != nil {
+		return nil, fmt.Errorf("gagal mengambil urutan antrian: %v", err)
+	}
+	defer rows.Close()
+
+	list := []Urutan{}
+	for rows.Next() {
+		var u Urutan
+		if err := rows.Scan(&u.IDAntrian, &u.NomorAntrian); err != nil {
+			return nil, fmt.Errorf("gagal membaca urutan antrian: %v", err)
+		}
+		list = append(list, u)
+	}
+	return list, rows.Err()
+}
@@ -0,0 +1,116 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/c14220110/poliklinik-backend/internal/common/middlewares"
+	"github.com/c14220110/poliklinik-backend/internal/common/services"
+	"github.com/c14220110/poliklinik-backend/pkg/utils"
+	"github.com/labstack/echo/v4"
+)
+
+type AuthController struct {
+	Service *services.SessionService
+}
+
+func NewAuthController(service *services.SessionService) *AuthController {
+	return &AuthController{Service: service}
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// RefreshHandler menukar refresh token dengan access token baru.
+// POST /api/auth/refresh
+func (ac *AuthController) RefreshHandler(c echo.Context) error {
+	var req RefreshRequest
+	if err := c.Bind(&req); err != nil || req.RefreshToken == "" {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"status":  http.StatusBadRequest,
+			"message": "refresh_token is required",
+			"data":    nil,
+		})
+	}
+
+	tokens, err := ac.Service.Refresh(req.RefreshToken)
+	if err != nil {
+		if err == services.ErrSesiTidakValid || err == services.ErrRefreshTokenReuse {
+			return c.JSON(http.StatusUnauthorized, map[string]interface{}{
+				"status":  http.StatusUnauthorized,
+				"message": err.Error(),
+				"data":    nil,
+			})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"status":  http.StatusInternalServerError,
+			"message": "Failed to refresh token: " + err.Error(),
+			"data":    nil,
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status":  http.StatusOK,
+		"message": "Token refreshed successfully",
+		"data":    tokens,
+	})
+}
+
+// LogoutHandler mencabut sesi milik token yang sedang dipakai.
+// POST /api/auth/logout
+func (ac *AuthController) LogoutHandler(c echo.Context) error {
+	claims, ok := c.Get(string(middlewares.ContextKeyClaims)).(*utils.Claims)
+	if !ok || claims == nil || claims.ID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]interface{}{
+			"status":  http.StatusUnauthorized,
+			"message": "Invalid or missing token claims",
+			"data":    nil,
+		})
+	}
+
+	if err := ac.Service.RevokeSession(claims.ID, "logout"); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"status":  http.StatusInternalServerError,
+			"message": "Failed to logout: " + err.Error(),
+			"data":    nil,
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status":  http.StatusOK,
+		"message": "Logout successful",
+		"data":    nil,
+	})
+}
+
+// RevokeKaryawanSessionsHandler mencabut semua sesi aktif milik satu karyawan.
+// PUT /api/management/karyawan/revoke-sessions?id_karyawan={id}
+func (ac *AuthController) RevokeKaryawanSessionsHandler(c echo.Context) error {
+	idKaryawan, err := strconv.Atoi(c.QueryParam("id_karyawan"))
+	if err != nil || idKaryawan <= 0 {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"status":  http.StatusBadRequest,
+			"message": "id_karyawan must be a positive number",
+			"data":    nil,
+		})
+	}
+
+	revoked, err := ac.Service.RevokeAllForKaryawan(idKaryawan, "dicabut oleh manajemen")
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"status":  http.StatusInternalServerError,
+			"message": "Failed to revoke sessions: " + err.Error(),
+			"data":    nil,
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status":  http.StatusOK,
+		"message": "Sessions revoked successfully",
+		"data": map[string]interface{}{
+			"id_karyawan":      idKaryawan,
+			"revoked_sessions": revoked,
+		},
+	})
+}
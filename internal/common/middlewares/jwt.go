@@ -16,6 +16,18 @@ const (
     ContextKeyClaims contextKey = "claims"
 )
 
+// SessionChecker memeriksa apakah sesi (jti pada token) masih aktif di server.
+type SessionChecker interface {
+    IsSessionActive(idSesi string, idKaryawan int, role string) (bool, error)
+}
+
+var sessionChecker SessionChecker
+
+// SetSessionChecker dipanggil sekali saat startup (routes.Init).
+func SetSessionChecker(checker SessionChecker) {
+    sessionChecker = checker
+}
+
 // JWTMiddleware untuk autentikasi JWT dengan Echo
 func JWTMiddleware() echo.MiddlewareFunc {
     return func(next echo.HandlerFunc) echo.HandlerFunc {
@@ -49,6 +61,32 @@ func JWTMiddleware() echo.MiddlewareFunc {
                 })
             }
 
+            // Token wajib terikat ke sesi yang belum dicabut & karyawan yang masih aktif
+            if sessionChecker != nil {
+                if claims.ID == "" {
+                    return c.JSON(http.StatusUnauthorized, map[string]interface{}{
+                        "status":  http.StatusUnauthorized,
+                        "message": "Token tidak memiliki sesi, silakan login ulang",
+                        "data":    nil,
+                    })
+                }
+                active, err := sessionChecker.IsSessionActive(claims.ID, claims.IDKaryawan, claims.Role)
+                if err != nil {
+                    return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+                        "status":  http.StatusInternalServerError,
+                        "message": "Gagal memeriksa sesi: " + err.Error(),
+                        "data":    nil,
+                    })
+                }
+                if !active {
+                    return c.JSON(http.StatusUnauthorized, map[string]interface{}{
+                        "status":  http.StatusUnauthorized,
+                        "message": "Sesi sudah berakhir atau dicabut, silakan login ulang",
+                        "data":    nil,
+                    })
+                }
+            }
+
             // Simpan claims ke context Echo
             c.Set(string(ContextKeyClaims), claims)
             return next(c)
@@ -56,6 +94,21 @@ func JWTMiddleware() echo.MiddlewareFunc {
     }
 }
 
+// TokenFromQuery menyalin ?token= ke header Authorization bila header kosong.
+// Dipakai untuk upgrade WebSocket karena browser tidak bisa mengirim header kustom.
+func TokenFromQuery() echo.MiddlewareFunc {
+    return func(next echo.HandlerFunc) echo.HandlerFunc {
+        return func(c echo.Context) error {
+            if c.Request().Header.Get("Authorization") == "" {
+                if token := c.QueryParam("token"); token != "" {
+                    c.Request().Header.Set("Authorization", "Bearer "+token)
+                }
+            }
+            return next(c)
+        }
+    }
+}
+
 // CORSMiddleware untuk mengatur CORS
 func CORSMiddleware() echo.MiddlewareFunc {
     return middleware.CORSWithConfig(middleware.CORSConfig{
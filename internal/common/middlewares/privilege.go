@@ -8,8 +8,8 @@ import (
 	"github.com/labstack/echo/v4"
 )
 
-// RequirePrivilege memeriksa apakah klaim JWT memiliki privilege yang dibutuhkan.
-func RequirePrivilege(requiredPriv int) echo.MiddlewareFunc {
+// RequirePrivilege memeriksa apakah klaim JWT memiliki salah satu privilege yang dibutuhkan.
+func RequirePrivilege(requiredPrivs ...int) echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
 			rawClaims := c.Get(string(ContextKeyClaims))
@@ -59,23 +59,30 @@ func RequirePrivilege(requiredPriv int) echo.MiddlewareFunc {
 
 			found := false
 			for _, item := range privSlice {
+				var have int
 				switch val := item.(type) {
 				case float64:
-					if int(val) == requiredPriv {
-						found = true
-						break
-					}
+					have = int(val)
 				case int:
-					if val == requiredPriv {
-						found = true
-						break
-					}
+					have = val
 				case string:
-					if n, err := strconv.Atoi(val); err == nil && n == requiredPriv {
+					n, err := strconv.Atoi(val)
+					if err != nil {
+						continue
+					}
+					have = n
+				default:
+					continue
+				}
+				for _, requiredPriv := range requiredPrivs {
+					if have == requiredPriv {
 						found = true
 						break
 					}
 				}
+				if found {
+					break
+				}
 			}
 
 			if !found {
@@ -90,3 +97,17 @@ func RequirePrivilege(requiredPriv int) echo.MiddlewareFunc {
 		}
 	}
 }
+
+// DenyAll menolak semua request. Dipakai untuk route yang belum terdaftar
+// di tabel kebijakan privilege sehingga tidak bisa diakses secara tidak sengaja.
+func DenyAll() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			return c.JSON(http.StatusForbidden, map[string]interface{}{
+				"status":  http.StatusForbidden,
+				"message": "Route belum memiliki kebijakan akses",
+				"data":    nil,
+			})
+		}
+	}
+}
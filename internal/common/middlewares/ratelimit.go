@@ -0,0 +1,40 @@
+package middlewares
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"golang.org/x/time/rate"
+)
+
+// RateLimit membatasi request per IP (limiter in-memory), dipakai untuk endpoint publik
+// seperti layar antrian. perSecond = rata-rata request per detik, burst = lonjakan maksimum.
+func RateLimit(perSecond float64, burst int) echo.MiddlewareFunc {
+	store := middleware.NewRateLimiterMemoryStoreWithConfig(middleware.RateLimiterMemoryStoreConfig{
+		Rate:      rate.Limit(perSecond),
+		Burst:     burst,
+		ExpiresIn: 3 * time.Minute,
+	})
+	return middleware.RateLimiterWithConfig(middleware.RateLimiterConfig{
+		Store: store,
+		IdentifierExtractor: func(c echo.Context) (string, error) {
+			return c.RealIP(), nil
+		},
+		ErrorHandler: func(c echo.Context, err error) error {
+			return c.JSON(http.StatusForbidden, map[string]interface{}{
+				"status":  http.StatusForbidden,
+				"message": "Tidak dapat mengidentifikasi client",
+				"data":    nil,
+			})
+		},
+		DenyHandler: func(c echo.Context, identifier string, err error) error {
+			return c.JSON(http.StatusTooManyRequests, map[string]interface{}{
+				"status":  http.StatusTooManyRequests,
+				"message": "Terlalu banyak request, coba lagi nanti",
+				"data":    nil,
+			})
+		},
+	})
+}
@@ -0,0 +1,51 @@
+package middlewares
+
+import (
+	"net/http"
+
+	"github.com/c14220110/poliklinik-backend/pkg/utils"
+	"github.com/labstack/echo/v4"
+)
+
+// Nilai Role yang ditulis ke JWT oleh masing-masing endpoint login.
+const (
+	RoleAdministrasi = "Administrasi"
+	RoleSuster       = "Suster"
+	RoleDokter       = "Dokter"
+	RoleManajemen    = "Manajemen"
+)
+
+// RequireRole memastikan token dibuat oleh salah satu alur login yang diizinkan.
+// Harus dipasang setelah JWTMiddleware.
+func RequireRole(roles ...string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			claims, ok := c.Get(string(ContextKeyClaims)).(*utils.Claims)
+			if !ok || claims == nil {
+				return c.JSON(http.StatusUnauthorized, map[string]interface{}{
+					"status":  http.StatusUnauthorized,
+					"message": "Missing or invalid JWT claims",
+					"data":    nil,
+				})
+			}
+
+			for _, role := range roles {
+				if claims.Role == role {
+					return next(c)
+				}
+			}
+
+			return c.JSON(http.StatusForbidden, map[string]interface{}{
+				"status":  http.StatusForbidden,
+				"message": "Token tidak berlaku untuk aplikasi ini",
+				"data":    nil,
+			})
+		}
+	}
+}
+
+// EnsureSamePoli mengembalikan true jika id_poli yang diminta sama dengan IDPoli pada token.
+// Token suster & dokter selalu terikat ke satu poli sejak login.
+func EnsureSamePoli(claims *utils.Claims, idPoli int) bool {
+	return claims != nil && claims.IDPoli != 0 && claims.IDPoli == idPoli
+}
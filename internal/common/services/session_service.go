@@ -0,0 +1,272 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/c14220110/poliklinik-backend/config"
+	"github.com/c14220110/poliklinik-backend/pkg/utils"
+)
+
+var (
+	ErrSesiTidakValid    = errors.New("sesi tidak valid atau sudah berakhir")
+	ErrRefreshTokenReuse = errors.New("refresh token sudah pernah dipakai, sesi dicabut")
+)
+
+// roleManajemen tidak tersimpan di tabel Karyawan sehingga pengecekan deleted_at dilewati.
+const roleManajemen = "Manajemen"
+
+// TokenPair adalah pasangan access token (JWT pendek) dan refresh token (opaque, berotasi).
+type TokenPair struct {
+	AccessToken  string    `json:"access_token"`
+	RefreshToken string    `json:"refresh_token"`
+	ExpiresAt    time.Time `json:"expires_at"`
+}
+
+type SessionService struct {
+	DB *sql.DB
+}
+
+func NewSessionService(db *sql.DB) *SessionService {
+	return &SessionService{DB: db}
+}
+
+// CreateSession menyimpan sesi baru di Sesi_Login lalu menerbitkan access & refresh token.
+// sessionExp adalah batas akhir sesi (misal akhir shift dokter/suster); access token
+// tidak pernah berlaku melewati batas ini.
+func (s *SessionService) CreateSession(claims utils.Claims, sessionExp time.Time) (*TokenPair, error) {
+	idSesi, err := randomHex(16)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := randomHex(32)
+	if err != nil {
+		return nil, err
+	}
+	privJSON, err := json.Marshal(claims.Privileges)
+	if err != nil {
+		return nil, fmt.Errorf("gagal encode privileges: %v", err)
+	}
+
+	_, err = s.DB.Exec(`
+		INSERT INTO Sesi_Login (
+			id_sesi, id_karyawan, role, id_role, id_poli, privileges, username, nama,
+			refresh_token_hash, expires_at, created_at, last_used_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, NOW(), NOW())`,
+		idSesi, claims.IDKaryawan, claims.Role, claims.IDRole, claims.IDPoli, string(privJSON),
+		claims.Username, claims.Nama, hashToken(secret), sessionExp,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("gagal menyimpan sesi: %v", err)
+	}
+
+	return issueTokens(claims, idSesi, secret, sessionExp)
+}
+
+// Refresh menukar refresh token dengan pasangan token baru (rotasi). Refresh token lama
+// langsung tidak berlaku; jika token lama dipakai lagi, seluruh sesi dicabut. Privilege dibaca
+// ulang dari database sehingga perubahan privilege karyawan berlaku paling lambat satu
+// masa berlaku access token.
+func (s *SessionService) Refresh(refreshToken string) (*TokenPair, error) {
+	idSesi, secret, ok := strings.Cut(refreshToken, ".")
+	if !ok || idSesi == "" || secret == "" {
+		return nil, ErrSesiTidakValid
+	}
+
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("gagal memulai transaksi: %v", err)
+	}
+	defer tx.Rollback()
+
+	var (
+		claims       utils.Claims
+		currentHash  string
+		previousHash sql.NullString
+		expiresAt    time.Time
+		revokedAt    sql.NullTime
+	)
+	err = tx.QueryRow(`
+		SELECT id_karyawan, role, id_role, id_poli, username, nama,
+		       refresh_token_hash, previous_token_hash, expires_at, revoked_at
+		FROM Sesi_Login
+		WHERE id_sesi = ?
+		FOR UPDATE`, idSesi).Scan(
+		&claims.IDKaryawan, &claims.Role, &claims.IDRole, &claims.IDPoli,
+		&claims.Username, &claims.Nama, &currentHash, &previousHash, &expiresAt, &revokedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, ErrSesiTidakValid
+	}
+	if err != nil {
+		return nil, fmt.Errorf("gagal mengambil sesi: %v", err)
+	}
+	if revokedAt.Valid || time.Now().After(expiresAt) {
+		return nil, ErrSesiTidakValid
+	}
+
+	presented := hashToken(secret)
+	if previousHash.Valid && tokenEqual(presented, previousHash.String) {
+		// Refresh token lama dipakai ulang: kemungkinan bocor, cabut sesi.
+		if _, err := tx.Exec(`UPDATE Sesi_Login SET revoked_at = NOW(), revoke_reason = 'refresh token reuse' WHERE id_sesi = ?`, idSesi); err != nil {
+			return nil, fmt.Errorf("gagal mencabut sesi: %v", err)
+		}
+		if err := tx.Commit(); err != nil {
+			return nil, fmt.Errorf("gagal commit transaksi: %v", err)
+		}
+		return nil, ErrRefreshTokenReuse
+	}
+	if !tokenEqual(presented, currentHash) {
+		return nil, ErrSesiTidakValid
+	}
+
+	if claims.Role != roleManajemen {
+		var aktif bool
+		err = tx.QueryRow(`SELECT deleted_at IS NULL FROM Karyawan WHERE id_karyawan = ?`, claims.IDKaryawan).Scan(&aktif)
+		if err != nil && err != sql.ErrNoRows {
+			return nil, fmt.Errorf("gagal memeriksa karyawan: %v", err)
+		}
+		if !aktif {
+			return nil, ErrSesiTidakValid
+		}
+	}
+
+	claims.Privileges, err = privilegeSaatIni(tx, claims)
+	if err != nil {
+		return nil, err
+	}
+	privJSON, err := json.Marshal(claims.Privileges)
+	if err != nil {
+		return nil, fmt.Errorf("gagal encode privileges: %v", err)
+	}
+
+	newSecret, err := randomHex(32)
+	if err != nil {
+		return nil, err
+	}
+	_, err = tx.Exec(`
+		UPDATE Sesi_Login
+		SET refresh_token_hash = ?, previous_token_hash = ?, privileges = ?, last_used_at = NOW()
+		WHERE id_sesi = ?`, hashToken(newSecret), currentHash, string(privJSON), idSesi)
+	if err != nil {
+		return nil, fmt.Errorf("gagal merotasi refresh token: %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("gagal commit transaksi: %v", err)
+	}
+
+	return issueTokens(claims, idSesi, newSecret, expiresAt)
+}
+
+// privilegeSaatIni membaca privilege terbaru pemilik sesi: manajemen mendapat seluruh
+// privilege (sama seperti saat login), karyawan dari Detail_Privilege_Karyawan.
+func privilegeSaatIni(tx *sql.Tx, claims utils.Claims) ([]int, error) {
+	query, args := "SELECT id_privilege FROM Privilege WHERE deleted_at IS NULL ORDER BY id_privilege", []interface{}{}
+	if claims.Role != roleManajemen {
+		query = "SELECT id_privilege FROM Detail_Privilege_Karyawan WHERE id_karyawan = ? ORDER BY id_privilege"
+		args = append(args, claims.IDKaryawan)
+	}
+	rows, err := tx.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("gagal mengambil privilege: %v", err)
+	}
+	defer rows.Close()
+
+	privileges := []int{}
+	for rows.Next() {
+		var priv int
+		if err := rows.Scan(&priv); err != nil {
+			return nil, fmt.Errorf("gagal membaca privilege: %v", err)
+		}
+		privileges = append(privileges, priv)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("gagal membaca privilege: %v", err)
+	}
+	return privileges, nil
+}
+
+// RevokeSession mencabut satu sesi (logout).
+func (s *SessionService) RevokeSession(idSesi, reason string) error {
+	_, err := s.DB.Exec(`
+		UPDATE Sesi_Login SET revoked_at = NOW(), revoke_reason = ?
+		WHERE id_sesi = ? AND revoked_at IS NULL`, reason, idSesi)
+	if err != nil {
+		return fmt.Errorf("gagal mencabut sesi: %v", err)
+	}
+	return nil
+}
+
+// RevokeAllForKaryawan mencabut semua sesi aktif milik karyawan dan mengembalikan jumlahnya.
+// Sesi manajemen tidak ikut dicabut karena id-nya berasal dari tabel Management.
+func (s *SessionService) RevokeAllForKaryawan(idKaryawan int, reason string) (int64, error) {
+	res, err := s.DB.Exec(`
+		UPDATE Sesi_Login SET revoked_at = NOW(), revoke_reason = ?
+		WHERE id_karyawan = ? AND role <> ? AND revoked_at IS NULL`, reason, idKaryawan, roleManajemen)
+	if err != nil {
+		return 0, fmt.Errorf("gagal mencabut sesi karyawan: %v", err)
+	}
+	return res.RowsAffected()
+}
+
+// IsSessionActive dipakai JWTMiddleware: sesi harus ada, belum dicabut, belum kedaluwarsa,
+// dan karyawannya belum di-soft-delete.
+func (s *SessionService) IsSessionActive(idSesi string, idKaryawan int, role string) (bool, error) {
+	var count int
+	err := s.DB.QueryRow(`
+		SELECT COUNT(*)
+		FROM Sesi_Login s
+		LEFT JOIN Karyawan k ON k.id_karyawan = s.id_karyawan
+		WHERE s.id_sesi = ?
+		  AND s.id_karyawan = ?
+		  AND s.role = ?
+		  AND s.revoked_at IS NULL
+		  AND s.expires_at > NOW()
+		  AND (s.role = ? OR (k.id_karyawan IS NOT NULL AND k.deleted_at IS NULL))`,
+		idSesi, idKaryawan, role, roleManajemen).Scan(&count)
+	if err != nil {
+		return false, fmt.Errorf("gagal memeriksa sesi: %v", err)
+	}
+	return count > 0, nil
+}
+
+func issueTokens(claims utils.Claims, idSesi, secret string, sessionExp time.Time) (*TokenPair, error) {
+	accessExp := time.Now().Add(config.LoadConfig().AccessTokenTTL)
+	if accessExp.After(sessionExp) {
+		accessExp = sessionExp
+	}
+	access, err := utils.SignClaims(claims, idSesi, accessExp)
+	if err != nil {
+		return nil, err
+	}
+	return &TokenPair{
+		AccessToken:  access,
+		RefreshToken: idSesi + "." + secret,
+		ExpiresAt:    accessExp,
+	}, nil
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("gagal membuat token acak: %v", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func hashToken(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+func tokenEqual(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
@@ -0,0 +1,120 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/c14220110/poliklinik-backend/internal/display/services"
+	"github.com/labstack/echo/v4"
+)
+
+type DisplayController struct {
+	Service *services.DisplayService
+}
+
+func NewDisplayController(service *services.DisplayService) *DisplayController {
+	return &DisplayController{Service: service}
+}
+
+// GetPapanAntrianHandler mengembalikan data layar antrian (publik, tanpa data pasien).
+// GET /api/display/antrian?id_poli={id} (id_poli opsional)
+func (dc *DisplayController) GetPapanAntrianHandler(c echo.Context) error {
+	idPoli := 0
+	if idPoliStr := c.QueryParam("id_poli"); idPoliStr != "" {
+		var err error
+		idPoli, err = strconv.Atoi(idPoliStr)
+		if err != nil || idPoli <= 0 {
+			return c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"status":  http.StatusBadRequest,
+				"message": "id_poli harus berupa angka",
+				"data":    nil,
+			})
+		}
+	}
+
+	papan, err := dc.Service.GetPapanAntrian(idPoli)
+	if err != nil {
+		if strings.Contains(err.Error(), "tidak ditemukan") {
+			return c.JSON(http.StatusNotFound, map[string]interface{}{
+				"status":  http.StatusNotFound,
+				"message": err.Error(),
+				"data":    nil,
+			})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"status":  http.StatusInternalServerError,
+			"message": "Gagal mengambil data layar antrian",
+			"data":    nil,
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status":  http.StatusOK,
+		"message": "Data layar antrian berhasil diambil",
+		"data":    papan,
+	})
+}
+
+// GetStatusTiketHandler menampilkan posisi antrian pasien berdasarkan kode tiket.
+// GET /api/display/tiket?kode={kode_tiket}
+func (dc *DisplayController) GetStatusTiketHandler(c echo.Context) error {
+	status, err := dc.Service.GetStatusTiket(c.QueryParam("kode"))
+	if err != nil {
+		return tiketError(c, err)
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status":  http.StatusOK,
+		"message": "Status tiket berhasil diambil",
+		"data":    status,
+	})
+}
+
+type CheckInRequest struct {
+	KodeTiket string `json:"kode_tiket"`
+}
+
+// CheckInTiketHandler memasukkan kembali pasien yang ditunda ke antrian.
+// POST /api/display/tiket/check-in
+func (dc *DisplayController) CheckInTiketHandler(c echo.Context) error {
+	var req CheckInRequest
+	if err := c.Bind(&req); err != nil || req.KodeTiket == "" {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"status":  http.StatusBadRequest,
+			"message": "kode_tiket wajib diisi",
+			"data":    nil,
+		})
+	}
+
+	status, err := dc.Service.CheckInTiket(req.KodeTiket)
+	if err != nil {
+		return tiketError(c, err)
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status":  http.StatusOK,
+		"message": "Check-in berhasil, Anda kembali masuk antrian",
+		"data":    status,
+	})
+}
+
+func tiketError(c echo.Context, err error) error {
+	switch err {
+	case services.ErrTiketTidakDitemukan:
+		return c.JSON(http.StatusNotFound, map[string]interface{}{
+			"status":  http.StatusNotFound,
+			"message": err.Error(),
+			"data":    nil,
+		})
+	case services.ErrTiketTidakDitunda:
+		return c.JSON(http.StatusConflict, map[string]interface{}{
+			"status":  http.StatusConflict,
+			"message": err.Error(),
+			"data":    nil,
+		})
+	}
+	return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+		"status":  http.StatusInternalServerError,
+		"message": "Gagal memproses tiket",
+		"data":    nil,
+	})
+}
@@ -0,0 +1,12 @@
+package models
+
+// PapanAntrian adalah data layar antrian satu poliklinik. Hanya berisi nomor antrian
+// dan nama poli — tidak boleh ada nama pasien, NIK, atau id_pasien karena endpoint ini publik.
+type PapanAntrian struct {
+	IDPoli           int    `json:"id_poli"`
+	NamaPoli         string `json:"nama_poli"`
+	SedangScreening  []int  `json:"sedang_screening"`
+	SedangKonsultasi []int  `json:"sedang_konsultasi"`
+	Berikutnya       []int  `json:"berikutnya"`
+	JumlahMenunggu   int    `json:"jumlah_menunggu"`
+}
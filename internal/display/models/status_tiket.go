@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// StatusTiket adalah posisi antrian yang dilihat pasien lewat kode tiket.
+// Seperti PapanAntrian, tidak memuat data pribadi pasien.
+type StatusTiket struct {
+	KodeTiket     string     `json:"kode_tiket"`
+	NomorAntrian  int        `json:"nomor_antrian"`
+	IDPoli        int        `json:"id_poli"`
+	NamaPoli      string     `json:"nama_poli"`
+	IDStatus      int        `json:"id_status"`
+	Status        string     `json:"status"`
+	JumlahDiDepan *int       `json:"jumlah_di_depan"` // hanya terisi saat status Menunggu
+	EstimasiMenit *int       `json:"estimasi_menit"`  // nil jika belum ada data historis
+	BisaCheckIn   bool       `json:"bisa_check_in"`   // true saat status Ditunda dan belum dijadwalkan kembali
+	KembaliAt     *time.Time `json:"kembali_at"`      // kebijakan Jam: waktu pasien masuk kembali ke antrian
+}
@@ -0,0 +1,97 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+
+	adminServices "github.com/c14220110/poliklinik-backend/internal/administrasi/services"
+	"github.com/c14220110/poliklinik-backend/internal/common/antrian"
+	"github.com/c14220110/poliklinik-backend/internal/display/models"
+	screeningServices "github.com/c14220110/poliklinik-backend/internal/screening/services"
+)
+
+// JumlahBerikutnya adalah banyaknya nomor "berikutnya" yang ditampilkan di layar.
+const JumlahBerikutnya = 5
+
+// DisplayService menyusun data layar antrian dari data antrian hari ini milik
+// PendaftaranService & AntrianService, lalu membuang semua data pribadi pasien.
+type DisplayService struct {
+	DB          *sql.DB
+	Pendaftaran *adminServices.PendaftaranService
+	Antrian     *screeningServices.AntrianService
+}
+
+func NewDisplayService(db *sql.DB, pendaftaran *adminServices.PendaftaranService, antrianService *screeningServices.AntrianService) *DisplayService {
+	return &DisplayService{DB: db, Pendaftaran: pendaftaran, Antrian: antrianService}
+}
+
+// GetPapanAntrian mengembalikan papan antrian semua poli aktif, atau satu poli jika idPoli > 0.
+func (s *DisplayService) GetPapanAntrian(idPoli int) ([]models.PapanAntrian, error) {
+	query := "SELECT id_poli, nama_poli FROM Poliklinik WHERE id_status = 1"
+	params := []interface{}{}
+	if idPoli > 0 {
+		query += " AND id_poli = ?"
+		params = append(params, idPoli)
+	}
+	query += " ORDER BY nama_poli ASC"
+
+	rows, err := s.DB.Query(query, params...)
+	if err != nil {
+		return nil, fmt.Errorf("gagal mengambil daftar poli: %v", err)
+	}
+	defer rows.Close()
+
+	papan := []models.PapanAntrian{}
+	index := map[int]int{}
+	for rows.Next() {
+		p := models.PapanAntrian{SedangScreening: []int{}, SedangKonsultasi: []int{}, Berikutnya: []int{}}
+		if err := rows.Scan(&p.IDPoli, &p.NamaPoli); err != nil {
+			return nil, fmt.Errorf("gagal membaca poli: %v", err)
+		}
+		index[p.IDPoli] = len(papan)
+		papan = append(papan, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if idPoli > 0 && len(papan) == 0 {
+		return nil, fmt.Errorf("poliklinik dengan id %d tidak ditemukan", idPoli)
+	}
+
+	// Antrian hari ini semua poli: dipakai untuk Konsultasi.
+	today, err := s.Pendaftaran.GetAntrianToday("")
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range today {
+		i, ok := index[a["id_poli"].(int)]
+		if !ok {
+			continue
+		}
+		if a["id_status"].(int) == antrian.StatusKonsultasi {
+			papan[i].SedangKonsultasi = append(papan[i].SedangKonsultasi, a["nomor_antrian"].(int))
+		}
+	}
+
+	for i := range papan {
+		// Pasien yang sedang di ruang screening, urut sesuai panggilan suster.
+		screening, err := s.Antrian.GetTodayScreeningAntrianByPoli(papan[i].IDPoli)
+		if err != nil {
+			return nil, err
+		}
+		for _, a := range screening {
+			papan[i].SedangScreening = append(papan[i].SedangScreening, a["nomor_antrian"].(int))
+		}
+
+		// Urutan panggilan sama dengan ClaimNext: skor triase, priority_order, nomor_antrian.
+		list, err := antrian.DaftarPanggilan(s.DB, papan[i].IDPoli, antrian.StatusMenunggu)
+		if err != nil {
+			return nil, err
+		}
+		papan[i].JumlahMenunggu = len(list)
+		for j := 0; j < len(list) && j < JumlahBerikutnya; j++ {
+			papan[i].Berikutnya = append(papan[i].Berikutnya, list[j].NomorAntrian)
+		}
+	}
+	return papan, nil
+}
@@ -0,0 +1,134 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/c14220110/poliklinik-backend/internal/common/antrian"
+	"github.com/c14220110/poliklinik-backend/internal/display/models"
+)
+
+var (
+	ErrTiketTidakDitemukan = errors.New("tiket tidak ditemukan atau sudah tidak berlaku")
+	ErrTiketTidakDitunda   = errors.New("check-in ulang hanya untuk antrian yang sedang ditunda")
+)
+
+// actorPasien dicatat di Antrian_Log untuk perubahan yang dilakukan pasien sendiri.
+var actorPasien = antrian.Actor{Role: "Pasien"}
+
+type tiket struct {
+	idAntrian int
+	priority  sql.NullInt64
+	status    models.StatusTiket
+}
+
+// findTiket mencari antrian HARI INI berdasarkan kode tiket; tiket hari sebelumnya tidak berlaku.
+func (s *DisplayService) findTiket(kode string) (*tiket, error) {
+	kode, err := antrian.NormalizeKodeTiket(kode)
+	if err != nil {
+		return nil, ErrTiketTidakDitemukan
+	}
+
+	t := &tiket{}
+	var kembaliAt sql.NullTime
+	err = s.DB.QueryRow(`
+		SELECT a.id_antrian, a.kode_tiket, a.nomor_antrian, a.priority_order,
+		       a.id_poli, pl.nama_poli, a.id_status, sa.status, a.kembali_at
+		FROM Antrian a
+		JOIN Poliklinik pl ON a.id_poli = pl.id_poli
+		JOIN Status_Antrian sa ON a.id_status = sa.id_status
+		WHERE a.kode_tiket = ? AND DATE(a.created_at) = CURDATE()`, kode,
+	).Scan(&t.idAntrian, &t.status.KodeTiket, &t.status.NomorAntrian, &t.priority,
+		&t.status.IDPoli, &t.status.NamaPoli, &t.status.IDStatus, &t.status.Status, &kembaliAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrTiketTidakDitemukan
+	}
+	if err != nil {
+		return nil, fmt.Errorf("gagal mengambil tiket: %v", err)
+	}
+	if kembaliAt.Valid {
+		t.status.KembaliAt = &kembaliAt.Time
+	}
+	return t, nil
+}
+
+// GetStatusTiket mengembalikan status antrian, jumlah pasien di depan (urutan sama dengan
+// ClaimNext: skor triase, priority_order, nomor_antrian) dan estimasi waktu tunggu.
+func (s *DisplayService) GetStatusTiket(kode string) (*models.StatusTiket, error) {
+	t, err := s.findTiket(kode)
+	if err != nil {
+		return nil, err
+	}
+	result := t.status
+	result.BisaCheckIn = result.IDStatus == antrian.StatusDitunda && result.KembaliAt == nil
+	if result.IDStatus != antrian.StatusMenunggu {
+		return &result, nil
+	}
+
+	// Posisi dihitung dari urutan panggilan yang sama dengan ClaimNext, karena skor triase
+	// berubah seiring waktu tunggu dan tidak bisa dibandingkan per kolom.
+	list, err := antrian.DaftarPanggilan(s.DB, result.IDPoli, antrian.StatusMenunggu)
+	if err != nil {
+		return nil, fmt.Errorf("gagal menghitung antrian di depan: %v", err)
+	}
+	didepan := 0
+	for didepan < len(list) && list[didepan].IDAntrian != t.idAntrian {
+		didepan++
+	}
+	result.JumlahDiDepan = &didepan
+
+	interval, err := s.rataRataIntervalPanggilan(result.IDPoli)
+	if err != nil {
+		return nil, err
+	}
+	if interval.Valid {
+		menit := int(math.Ceil(float64(didepan+1) * interval.Float64 / 60))
+		result.EstimasiMenit = &menit
+	}
+	return &result, nil
+}
+
+// rataRataIntervalPanggilan menghitung rata-rata jeda (detik) antar panggilan
+// Menunggu -> Screening di poli dalam 30 hari terakhir dari Antrian_Log. Jeda di atas
+// 2 jam (istirahat, pergantian shift) diabaikan. Estimasi = (di depan + 1) x interval.
+func (s *DisplayService) rataRataIntervalPanggilan(idPoli int) (sql.NullFloat64, error) {
+	var interval sql.NullFloat64
+	err := s.DB.QueryRow(`
+		SELECT AVG(jeda) FROM (
+			SELECT TIMESTAMPDIFF(SECOND,
+			         LAG(l.created_at) OVER (PARTITION BY DATE(l.created_at) ORDER BY l.created_at),
+			         l.created_at) AS jeda
+			FROM Antrian_Log l
+			JOIN Antrian a ON a.id_antrian = l.id_antrian
+			WHERE a.id_poli = ? AND l.status_dari = ? AND l.status_ke = ?
+			  AND l.created_at >= CURDATE() - INTERVAL 30 DAY
+		) t
+		WHERE jeda IS NOT NULL AND jeda <= 7200`,
+		idPoli, antrian.StatusMenunggu, antrian.StatusScreening,
+	).Scan(&interval)
+	if err != nil {
+		return interval, fmt.Errorf("gagal menghitung estimasi waktu tunggu: %v", err)
+	}
+	return interval, nil
+}
+
+// CheckInTiket mengembalikan pasien yang ditunda ke antrian memakai kebijakan reschedule poli,
+// sama dengan reschedule oleh administrasi (pada kebijakan Jam, pasien dijadwalkan kembali).
+func (s *DisplayService) CheckInTiket(kode string) (*models.StatusTiket, error) {
+	t, err := s.findTiket(kode)
+	if err != nil {
+		return nil, err
+	}
+	if t.status.IDStatus != antrian.StatusDitunda {
+		return nil, ErrTiketTidakDitunda
+	}
+	if _, err := s.Pendaftaran.RescheduleAntrianPriority(t.idAntrian, actorPasien, "check-in ulang dengan kode tiket"); err != nil {
+		if errors.Is(err, antrian.ErrTransisiTidakValid) {
+			return nil, ErrTiketTidakDitunda
+		}
+		return nil, err
+	}
+	return s.GetStatusTiket(t.status.KodeTiket)
+}
@@ -4,6 +4,7 @@ import (
 	"net/http"
 	"time"
 
+	commonServices "github.com/c14220110/poliklinik-backend/internal/common/services"
 	"github.com/c14220110/poliklinik-backend/internal/dokter/services"
 	"github.com/c14220110/poliklinik-backend/pkg/utils"
 	"github.com/labstack/echo/v4"
@@ -16,11 +17,12 @@ type LoginDokterRequest struct {
 }
 
 type DokterController struct {
-	Service *services.DokterService
+	Service  *services.DokterService
+	Sessions *commonServices.SessionService
 }
 
-func NewDokterController(service *services.DokterService) *DokterController {
-	return &DokterController{Service: service}
+func NewDokterController(service *services.DokterService, sessions *commonServices.SessionService) *DokterController {
+	return &DokterController{Service: service, Sessions: sessions}
 }
 
 func (dc *DokterController) LoginDokter(c echo.Context) error {
@@ -58,16 +60,15 @@ func (dc *DokterController) LoginDokter(c echo.Context) error {
 		expTime = time.Now().Add(1 * time.Hour)
 	}
 
-	token, err := utils.GenerateJWTToken(
-		dokter.ID_Dokter,
-		"Dokter",
-		dokter.ID_Role,
-		dokter.Privileges,
-		req.IDPoli,
-		dokter.Username,
-		dokter.Nama, // Menambahkan nama dokter ke token
-		expTime,
-	)
+	tokens, err := dc.Sessions.CreateSession(utils.Claims{
+		IDKaryawan: dokter.ID_Dokter,
+		Role:       "Dokter",
+		IDRole:     dokter.ID_Role,
+		Privileges: dokter.Privileges,
+		IDPoli:     req.IDPoli,
+		Username:   dokter.Username,
+		Nama:       dokter.Nama,
+	}, expTime)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
 			"status":  http.StatusInternalServerError,
@@ -80,12 +81,13 @@ func (dc *DokterController) LoginDokter(c echo.Context) error {
 		"status":  http.StatusOK,
 		"message": "Login successful",
 		"data": map[string]interface{}{
-			"id":       dokter.ID_Dokter,
-			"nama":     dokter.Nama,
-			"username": dokter.Username,
-			"role":     "Dokter",
-			"id_poli":  req.IDPoli,
-			"token":    token,
+			"id":            dokter.ID_Dokter,
+			"nama":          dokter.Nama,
+			"username":      dokter.Username,
+			"role":          "Dokter",
+			"id_poli":       req.IDPoli,
+			"token":         tokens.AccessToken,
+			"refresh_token": tokens.RefreshToken,
 			"shift": map[string]interface{}{
 				"id_shift_karyawan": shift.ID_Shift_Karyawan,
 				"jam_mulai":         shift.CustomJamMulai,
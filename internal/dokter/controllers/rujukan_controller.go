@@ -0,0 +1,120 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/c14220110/poliklinik-backend/internal/common/antrian"
+	"github.com/c14220110/poliklinik-backend/internal/common/middlewares"
+	"github.com/c14220110/poliklinik-backend/internal/dokter/models"
+	"github.com/c14220110/poliklinik-backend/internal/dokter/services"
+	"github.com/c14220110/poliklinik-backend/pkg/utils"
+)
+
+type RujukanController struct{ Service *services.RujukanService }
+
+func NewRujukanController(s *services.RujukanService) *RujukanController {
+	return &RujukanController{Service: s}
+}
+
+// BuatRujukanHandler merujuk pasien yang sedang konsultasi ke poli lain dalam kunjungan yang sama.
+// POST /api/dokter/rujukan?id_antrian={id}  body: {"id_poli_tujuan": 2, "alasan": "..."}
+func (rc *RujukanController) BuatRujukanHandler(c echo.Context) error {
+	idAntrian, err := strconv.Atoi(c.QueryParam("id_antrian"))
+	if err != nil || idAntrian <= 0 {
+		return c.JSON(http.StatusBadRequest, echo.Map{
+			"status":  http.StatusBadRequest,
+			"message": "id_antrian harus berupa angka",
+			"data":    nil,
+		})
+	}
+	var req models.RujukanRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{
+			"status":  http.StatusBadRequest,
+			"message": "Invalid request payload: " + err.Error(),
+			"data":    nil,
+		})
+	}
+	if req.IDPoliTujuan <= 0 {
+		return c.JSON(http.StatusBadRequest, echo.Map{
+			"status":  http.StatusBadRequest,
+			"message": "id_poli_tujuan wajib diisi",
+			"data":    nil,
+		})
+	}
+
+	claims, ok := c.Get(string(middlewares.ContextKeyClaims)).(*utils.Claims)
+	if !ok || claims == nil {
+		return c.JSON(http.StatusUnauthorized, echo.Map{
+			"status":  http.StatusUnauthorized,
+			"message": "Invalid or missing token claims",
+			"data":    nil,
+		})
+	}
+
+	result, err := rc.Service.BuatRujukan(idAntrian, claims.IDPoli, req, antrian.ActorFromClaims(claims))
+	if err != nil {
+		return rujukanError(c, err, "Gagal membuat rujukan internal")
+	}
+	return c.JSON(http.StatusCreated, echo.Map{
+		"status":  http.StatusCreated,
+		"message": "Pasien berhasil dirujuk, antrian di poli tujuan telah dibuat",
+		"data":    result,
+	})
+}
+
+// GetRujukanHandler menampilkan rujukan internal pada kunjungan.
+// GET /api/dokter/rujukan?id_kunjungan={id}
+func (rc *RujukanController) GetRujukanHandler(c echo.Context) error {
+	idKunjungan, err := strconv.Atoi(c.QueryParam("id_kunjungan"))
+	if err != nil || idKunjungan <= 0 {
+		return c.JSON(http.StatusBadRequest, echo.Map{
+			"status":  http.StatusBadRequest,
+			"message": "id_kunjungan harus berupa angka",
+			"data":    nil,
+		})
+	}
+
+	list, err := rc.Service.GetRujukanKunjungan(idKunjungan)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{
+			"status":  http.StatusInternalServerError,
+			"message": "Gagal mengambil rujukan internal",
+			"data":    nil,
+		})
+	}
+	return c.JSON(http.StatusOK, echo.Map{
+		"status":  http.StatusOK,
+		"message": "Rujukan internal berhasil diambil",
+		"data":    list,
+	})
+}
+
+func rujukanError(c echo.Context, err error, fallback string) error {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, services.ErrKunjunganNotFound), errors.Is(err, antrian.ErrAntrianTidakDitemukan),
+		strings.Contains(err.Error(), "tidak ditemukan"):
+		status = http.StatusNotFound
+	case errors.Is(err, services.ErrRujukanPoliTidakSama):
+		status = http.StatusForbidden
+	case errors.Is(err, services.ErrRujukanGanda):
+		status = http.StatusConflict
+	case errors.Is(err, services.ErrRujukanTidakValid):
+		status = http.StatusBadRequest
+	}
+	message := err.Error()
+	if status == http.StatusInternalServerError {
+		message = fallback
+	}
+	return c.JSON(status, echo.Map{
+		"status":  status,
+		"message": message,
+		"data":    nil,
+	})
+}
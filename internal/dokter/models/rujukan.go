@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+type RujukanRequest struct {
+	IDPoliTujuan int    `json:"id_poli_tujuan"`
+	Alasan       string `json:"alasan"`
+}
+
+// RujukanResult adalah antrian baru di poli tujuan hasil rujukan internal.
+type RujukanResult struct {
+	IDKunjungan   int    `json:"id_kunjungan"`
+	IDAntrian     int64  `json:"id_antrian"`
+	IDPoli        int    `json:"id_poli"`
+	NomorAntrian  int64  `json:"nomor_antrian"`
+	PriorityOrder int64  `json:"priority_order"`
+	KodeTiket     string `json:"kode_tiket"`
+}
+
+// Rujukan adalah satu baris rujukan internal (Kunjungan_Poli dengan id_antrian_asal).
+type Rujukan struct {
+	IDKunjungan   int        `json:"id_kunjungan"`
+	IDAntrian     int        `json:"id_antrian"`
+	IDAntrianAsal int        `json:"id_antrian_asal"`
+	PoliAsal      string     `json:"poli_asal"`
+	PoliTujuan    string     `json:"poli_tujuan"`
+	StatusAntrian string     `json:"status_antrian"`
+	Alasan        string     `json:"alasan"`
+	DirujukOleh   *string    `json:"dirujuk_oleh"` // nama dokter
+	DirujukAt     *time.Time `json:"dirujuk_at"`
+	IDAssessment  *int       `json:"id_assessment"`
+}
@@ -233,7 +233,7 @@ func (s *ResepService) GetRiwayatKunjunganByPasien(idPasien int) ([]models.Riway
             rk.id_assessment
         FROM Riwayat_Kunjungan rk
         JOIN Antrian a ON rk.id_antrian = a.id_antrian
-        JOIN Kunjungan_Poli kp ON rk.id_kunjungan = kp.id_kunjungan
+        JOIN Kunjungan_Poli kp ON rk.id_kunjungan = kp.id_kunjungan AND kp.id_antrian_asal IS NULL
         JOIN Poliklinik p ON kp.id_poli = p.id_poli
         LEFT JOIN Assessment ass ON rk.id_assessment = ass.id_assessment
         LEFT JOIN ICD10 icd10 ON ass.id_icd10 = icd10.id_icd10
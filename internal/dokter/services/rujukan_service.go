@@ -0,0 +1,221 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/c14220110/poliklinik-backend/internal/common/antrian"
+	"github.com/c14220110/poliklinik-backend/internal/dokter/models"
+	"github.com/c14220110/poliklinik-backend/ws"
+)
+
+var (
+	ErrRujukanTidakValid    = errors.New("rujukan tidak valid")
+	ErrRujukanGanda         = errors.New("pasien sudah dirujuk ke poli ini pada kunjungan yang sama")
+	ErrRujukanPoliTidakSama = errors.New("antrian bukan milik poliklinik pada token")
+)
+
+type RujukanService struct {
+	DB *sql.DB
+}
+
+func NewRujukanService(db *sql.DB) *RujukanService {
+	return &RujukanService{DB: db}
+}
+
+// BuatRujukan merujuk pasien yang sedang konsultasi ke poli lain dalam kunjungan yang sama:
+// antrian baru (Menunggu) dibuat di poli tujuan dengan slot prioritas dan ditautkan ke
+// kunjungan lewat Kunjungan_Poli. Tidak ada Billing baru; tagihan poli tujuan digabung ke
+// Billing kunjungan. idPoliDokter adalah poli pada token dokter.
+func (s *RujukanService) BuatRujukan(idAntrianAsal, idPoliDokter int, req models.RujukanRequest, actor antrian.Actor) (*models.RujukanResult, error) {
+	alasan := strings.TrimSpace(req.Alasan)
+	if alasan == "" {
+		return nil, fmt.Errorf("%w: alasan rujukan wajib diisi", ErrRujukanTidakValid)
+	}
+
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("gagal memulai transaksi: %v", err)
+	}
+	defer tx.Rollback()
+
+	// 1. Antrian asal: terkunci, milik poli dokter, sedang Konsultasi hari ini
+	status, err := antrian.Lock(tx, idAntrianAsal)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		idPasien, idPoliAsal int
+		keluhan, namaPJ      sql.NullString
+		hariIni              bool
+	)
+	if err = tx.QueryRow(`
+		SELECT id_pasien, id_poli, keluhan_utama, nama_penanggung_jawab, DATE(created_at) = CURDATE()
+		FROM Antrian WHERE id_antrian = ?`, idAntrianAsal,
+	).Scan(&idPasien, &idPoliAsal, &keluhan, &namaPJ, &hariIni); err != nil {
+		return nil, fmt.Errorf("gagal mengambil antrian asal: %v", err)
+	}
+	if idPoliAsal != idPoliDokter {
+		return nil, ErrRujukanPoliTidakSama
+	}
+	if status != antrian.StatusKonsultasi || !hariIni {
+		return nil, fmt.Errorf("%w: hanya pasien yang sedang konsultasi hari ini yang bisa dirujuk (status %s)",
+			ErrRujukanTidakValid, antrian.NamaStatus(status))
+	}
+
+	// 2. Kunjungan milik antrian asal (antrian pendaftaran atau antrian rujukan sebelumnya)
+	var idKunjungan int
+	err = tx.QueryRow(`
+		SELECT id_kunjungan FROM Riwayat_Kunjungan WHERE id_antrian = ?
+		UNION ALL
+		SELECT id_kunjungan FROM Kunjungan_Poli WHERE id_antrian = ? AND id_antrian_asal IS NOT NULL
+		LIMIT 1`, idAntrianAsal, idAntrianAsal,
+	).Scan(&idKunjungan)
+	if err == sql.ErrNoRows {
+		return nil, ErrKunjunganNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("gagal mengambil kunjungan: %v", err)
+	}
+
+	// 3. Poli tujuan aktif, berbeda dari poli asal, dan belum ada di kunjungan ini
+	if req.IDPoliTujuan == idPoliAsal {
+		return nil, fmt.Errorf("%w: poli tujuan sama dengan poli asal", ErrRujukanTidakValid)
+	}
+	var poliAktif int
+	if err = tx.QueryRow(`SELECT COUNT(*) FROM Poliklinik WHERE id_poli = ? AND id_status = 1`,
+		req.IDPoliTujuan).Scan(&poliAktif); err != nil {
+		return nil, fmt.Errorf("gagal memeriksa poli tujuan: %v", err)
+	}
+	if poliAktif == 0 {
+		return nil, fmt.Errorf("poli tujuan %d tidak ditemukan atau tidak aktif", req.IDPoliTujuan)
+	}
+	var sudahAda int
+	if err = tx.QueryRow(`SELECT COUNT(*) FROM Kunjungan_Poli WHERE id_kunjungan = ? AND id_poli = ?`,
+		idKunjungan, req.IDPoliTujuan).Scan(&sudahAda); err != nil {
+		return nil, fmt.Errorf("gagal memeriksa Kunjungan_Poli: %v", err)
+	}
+	if sudahAda > 0 {
+		return nil, ErrRujukanGanda
+	}
+
+	// 4. Nomor antrian & slot prioritas di poli tujuan
+	res := &models.RujukanResult{IDKunjungan: idKunjungan, IDPoli: req.IDPoliTujuan}
+	if res.NomorAntrian, err = antrian.AllocateNomor(tx, req.IDPoliTujuan); err != nil {
+		return nil, err
+	}
+	if res.PriorityOrder, err = slotPrioritas(tx, req.IDPoliTujuan, res.NomorAntrian); err != nil {
+		return nil, err
+	}
+
+	// 5. Antrian baru (Menunggu), log & kode tiket
+	keterangan := fmt.Sprintf("Rujukan internal dari antrian %d: %s", idAntrianAsal, alasan)
+	ins, err := tx.Exec(`
+		INSERT INTO Antrian
+		  (id_pasien, id_poli, keluhan_utama, nomor_antrian,
+		   id_status, priority_order, created_at, nama_penanggung_jawab)
+		VALUES (?, ?, ?, ?, ?, ?, NOW(), ?)`,
+		idPasien, req.IDPoliTujuan, keluhan, res.NomorAntrian,
+		antrian.StatusMenunggu, res.PriorityOrder, namaPJ,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("gagal membuat antrian rujukan: %v", err)
+	}
+	if res.IDAntrian, err = ins.LastInsertId(); err != nil {
+		return nil, fmt.Errorf("gagal mengambil id_antrian: %v", err)
+	}
+	if err = antrian.LogCreated(tx, res.IDAntrian, actor, keterangan); err != nil {
+		return nil, err
+	}
+	if res.KodeTiket, err = antrian.AssignKodeTiket(tx, res.IDAntrian); err != nil {
+		return nil, err
+	}
+
+	// 6. Tautkan ke kunjungan yang sama
+	var dirujukOleh sql.NullInt64
+	if actor.IDKaryawan > 0 {
+		dirujukOleh = sql.NullInt64{Int64: int64(actor.IDKaryawan), Valid: true}
+	}
+	if _, err = tx.Exec(`
+		INSERT INTO Kunjungan_Poli
+		  (id_poli, id_kunjungan, id_antrian, id_antrian_asal, alasan_rujukan, dirujuk_oleh, dirujuk_at)
+		VALUES (?, ?, ?, ?, ?, ?, NOW())`,
+		req.IDPoliTujuan, idKunjungan, res.IDAntrian, idAntrianAsal, alasan, dirujukOleh,
+	); err != nil {
+		return nil, fmt.Errorf("gagal menautkan rujukan ke kunjungan: %v", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("gagal commit transaksi: %v", err)
+	}
+	antrian.PublishEvent(s.DB, ws.EventAntrianCreated, int(res.IDAntrian), 0, antrian.StatusMenunggu, actor, keterangan)
+	return res, nil
+}
+
+// slotPrioritas menempatkan pasien rujukan tepat setelah pasien terdepan yang sedang Menunggu
+// di poli tujuan (priority_order sama, nomor_antrian lebih besar). Tanpa antrian Menunggu,
+// priority_order = nomor_antrian seperti pendaftaran biasa.
+func slotPrioritas(tx *sql.Tx, idPoli int, nomor int64) (int64, error) {
+	var minPriority sql.NullInt64
+	if err := tx.QueryRow(`
+		SELECT MIN(priority_order)
+		FROM Antrian
+		WHERE id_poli = ? AND id_status = ? AND DATE(created_at) = CURDATE()`,
+		idPoli, antrian.StatusMenunggu,
+	).Scan(&minPriority); err != nil {
+		return 0, fmt.Errorf("gagal menghitung slot prioritas: %v", err)
+	}
+	if minPriority.Valid && minPriority.Int64 < nomor {
+		return minPriority.Int64, nil
+	}
+	return nomor, nil
+}
+
+// GetRujukanKunjungan mengembalikan rujukan internal pada kunjungan, urut waktu rujukan.
+func (s *RujukanService) GetRujukanKunjungan(idKunjungan int) ([]models.Rujukan, error) {
+	rows, err := s.DB.Query(`
+		SELECT kp.id_kunjungan, kp.id_antrian, kp.id_antrian_asal,
+		       pa.nama_poli, pt.nama_poli, sa.status, IFNULL(kp.alasan_rujukan, ''),
+		       k.nama, kp.dirujuk_at, kp.id_assessment
+		FROM Kunjungan_Poli kp
+		JOIN Poliklinik pt ON kp.id_poli = pt.id_poli
+		JOIN Antrian a ON kp.id_antrian = a.id_antrian
+		JOIN Status_Antrian sa ON a.id_status = sa.id_status
+		JOIN Antrian asal ON kp.id_antrian_asal = asal.id_antrian
+		JOIN Poliklinik pa ON asal.id_poli = pa.id_poli
+		LEFT JOIN Karyawan k ON kp.dirujuk_oleh = k.id_karyawan
+		WHERE kp.id_kunjungan = ? AND kp.id_antrian_asal IS NOT NULL
+		ORDER BY kp.dirujuk_at, kp.id_antrian`, idKunjungan)
+	if err != nil {
+		return nil, fmt.Errorf("gagal mengambil rujukan: %v", err)
+	}
+	defer rows.Close()
+
+	list := []models.Rujukan{}
+	for rows.Next() {
+		var (
+			r            models.Rujukan
+			dirujukOleh  sql.NullString
+			dirujukAt    sql.NullTime
+			idAssessment sql.NullInt64
+		)
+		if err := rows.Scan(&r.IDKunjungan, &r.IDAntrian, &r.IDAntrianAsal, &r.PoliAsal, &r.PoliTujuan,
+			&r.StatusAntrian, &r.Alasan, &dirujukOleh, &dirujukAt, &idAssessment); err != nil {
+			return nil, fmt.Errorf("gagal membaca rujukan: %v", err)
+		}
+		if dirujukOleh.Valid {
+			r.DirujukOleh = &dirujukOleh.String
+		}
+		if dirujukAt.Valid {
+			r.DirujukAt = &dirujukAt.Time
+		}
+		if idAssessment.Valid {
+			id := int(idAssessment.Int64)
+			r.IDAssessment = &id
+		}
+		list = append(list, r)
+	}
+	return list, rows.Err()
+}
@@ -318,6 +318,13 @@ func (cc *CMSController) SaveAssessmentHandler(c echo.Context) error {
 		})
 	}
 	idKaryawan := claims.IDKaryawan
+	if !middlewares.EnsureSamePoli(claims, idPoli) {
+		return c.JSON(http.StatusForbidden, echo.Map{
+			"status":  http.StatusForbidden,
+			"message": "id_poli tidak sesuai dengan poli pada token",
+			"data":    nil,
+		})
+	}
 
 	/* ---------- payload ---------- */
 	var input models.AssessmentInput
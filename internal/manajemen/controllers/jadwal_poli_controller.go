@@ -0,0 +1,201 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/c14220110/poliklinik-backend/internal/common/middlewares"
+	"github.com/c14220110/poliklinik-backend/internal/manajemen/models"
+	"github.com/c14220110/poliklinik-backend/internal/manajemen/services"
+	"github.com/c14220110/poliklinik-backend/pkg/utils"
+	"github.com/labstack/echo/v4"
+)
+
+type JadwalPoliController struct {
+	Service *services.JadwalPoliService
+}
+
+func NewJadwalPoliController(service *services.JadwalPoliService) *JadwalPoliController {
+	return &JadwalPoliController{Service: service}
+}
+
+// GetJadwalHandler mengembalikan kuota harian dan jadwal mingguan poli.
+// GET /api/management/poliklinik/jadwal?id_poli={id}
+func (jc *JadwalPoliController) GetJadwalHandler(c echo.Context) error {
+	idPoli, err := strconv.Atoi(c.QueryParam("id_poli"))
+	if err != nil || idPoli <= 0 {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"status":  http.StatusBadRequest,
+			"message": "id_poli harus berupa angka",
+			"data":    nil,
+		})
+	}
+	jadwal, err := jc.Service.GetJadwal(idPoli)
+	if err != nil {
+		return jadwalPoliError(c, err, "Gagal mengambil jadwal poliklinik")
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status":  http.StatusOK,
+		"message": "Jadwal poliklinik berhasil diambil",
+		"data":    jadwal,
+	})
+}
+
+// SetJadwalHandler mengganti seluruh jadwal mingguan dan kuota harian poli.
+// PUT /api/management/poliklinik/jadwal?id_poli={id}
+// body: {"kuota_harian": 40, "jadwal": [{"hari": 1, "jam_buka": "08:00", "jam_tutup": "12:00", "kuota": 25}, {"hari": 1, "id_shift": 2}]}
+// kuota_harian null berarti tanpa batas; jadwal kosong berarti buka sepanjang hari.
+func (jc *JadwalPoliController) SetJadwalHandler(c echo.Context) error {
+	claims, ok := c.Get(string(middlewares.ContextKeyClaims)).(*utils.Claims)
+	if !ok || claims == nil {
+		return c.JSON(http.StatusUnauthorized, map[string]interface{}{
+			"status":  http.StatusUnauthorized,
+			"message": "Invalid or missing token claims",
+			"data":    nil,
+		})
+	}
+	idPoli, err := strconv.Atoi(c.QueryParam("id_poli"))
+	if err != nil || idPoli <= 0 {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"status":  http.StatusBadRequest,
+			"message": "id_poli harus berupa angka",
+			"data":    nil,
+		})
+	}
+	var req models.JadwalKuotaPoli
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"status":  http.StatusBadRequest,
+			"message": "Invalid request payload: " + err.Error(),
+			"data":    nil,
+		})
+	}
+	req.IDPoli = idPoli
+	if err := jc.Service.SetJadwal(req, claims.IDKaryawan); err != nil {
+		return jadwalPoliError(c, err, "Gagal menyimpan jadwal poliklinik")
+	}
+	jadwal, err := jc.Service.GetJadwal(idPoli)
+	if err != nil {
+		return jadwalPoliError(c, err, "Gagal mengambil jadwal poliklinik")
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status":  http.StatusOK,
+		"message": "Jadwal poliklinik berhasil disimpan",
+		"data":    jadwal,
+	})
+}
+
+// ListLiburHandler mengembalikan hari libur.
+// GET /api/management/poliklinik/libur?dari=YYYY-MM-DD&sampai=YYYY-MM-DD&id_poli={id}
+func (jc *JadwalPoliController) ListLiburHandler(c echo.Context) error {
+	dari, sampai := c.QueryParam("dari"), c.QueryParam("sampai")
+	for _, t := range []string{dari, sampai} {
+		if t == "" {
+			continue
+		}
+		if _, err := time.Parse("2006-01-02", t); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"status":  http.StatusBadRequest,
+				"message": "format tanggal tidak valid, gunakan YYYY-MM-DD",
+				"data":    nil,
+			})
+		}
+	}
+	idPoli := 0
+	if s := c.QueryParam("id_poli"); s != "" {
+		v, err := strconv.Atoi(s)
+		if err != nil || v <= 0 {
+			return c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"status":  http.StatusBadRequest,
+				"message": "id_poli harus berupa angka",
+				"data":    nil,
+			})
+		}
+		idPoli = v
+	}
+	list, err := jc.Service.ListLibur(dari, sampai, idPoli)
+	if err != nil {
+		return jadwalPoliError(c, err, "Gagal mengambil hari libur")
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status":  http.StatusOK,
+		"message": "Hari libur berhasil diambil",
+		"data":    list,
+	})
+}
+
+// TambahLiburHandler mencatat hari libur.
+// POST /api/management/poliklinik/libur
+// body: {"tanggal": "2025-08-17", "id_poli": null, "keterangan": "HUT RI"} (id_poli null = seluruh klinik)
+func (jc *JadwalPoliController) TambahLiburHandler(c echo.Context) error {
+	claims, ok := c.Get(string(middlewares.ContextKeyClaims)).(*utils.Claims)
+	if !ok || claims == nil {
+		return c.JSON(http.StatusUnauthorized, map[string]interface{}{
+			"status":  http.StatusUnauthorized,
+			"message": "Invalid or missing token claims",
+			"data":    nil,
+		})
+	}
+	var req models.LiburPoli
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"status":  http.StatusBadRequest,
+			"message": "Invalid request payload: " + err.Error(),
+			"data":    nil,
+		})
+	}
+	id, err := jc.Service.TambahLibur(req, claims.IDKaryawan)
+	if err != nil {
+		return jadwalPoliError(c, err, "Gagal menyimpan hari libur")
+	}
+	return c.JSON(http.StatusCreated, map[string]interface{}{
+		"status":  http.StatusCreated,
+		"message": "Hari libur berhasil ditambahkan",
+		"data":    map[string]interface{}{"id_libur": id},
+	})
+}
+
+// HapusLiburHandler menghapus hari libur.
+// PUT /api/management/poliklinik/libur/hapus?id_libur={id}
+func (jc *JadwalPoliController) HapusLiburHandler(c echo.Context) error {
+	idLibur, err := strconv.Atoi(c.QueryParam("id_libur"))
+	if err != nil || idLibur <= 0 {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"status":  http.StatusBadRequest,
+			"message": "id_libur harus berupa angka",
+			"data":    nil,
+		})
+	}
+	if err := jc.Service.HapusLibur(idLibur); err != nil {
+		return jadwalPoliError(c, err, "Gagal menghapus hari libur")
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status":  http.StatusOK,
+		"message": "Hari libur berhasil dihapus",
+		"data":    map[string]interface{}{"id_libur": idLibur},
+	})
+}
+
+func jadwalPoliError(c echo.Context, err error, fallback string) error {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, services.ErrJadwalPoliTidakValid), errors.Is(err, services.ErrLiburTidakValid):
+		status = http.StatusBadRequest
+	case errors.Is(err, services.ErrLiburGanda):
+		status = http.StatusConflict
+	case strings.Contains(err.Error(), "tidak ditemukan"):
+		status = http.StatusNotFound
+	}
+	message := err.Error()
+	if status == http.StatusInternalServerError {
+		message = fallback
+	}
+	return c.JSON(status, map[string]interface{}{
+		"status":  status,
+		"message": message,
+		"data":    nil,
+	})
+}
@@ -6,6 +6,7 @@ import (
 	"strconv"
 	"time"
 
+	"github.com/c14220110/poliklinik-backend/config"
 	"github.com/c14220110/poliklinik-backend/internal/common/middlewares"
 	"github.com/c14220110/poliklinik-backend/internal/manajemen/models"
 	"github.com/c14220110/poliklinik-backend/internal/manajemen/services"
@@ -67,6 +68,16 @@ func (kc *KaryawanController) AddKaryawan(c echo.Context) error {
 		})
 	}
 
+	// Validasi struktur NIK + cocokkan dengan tanggal lahir & jenis kelamin
+	peringatanNIK, err := utils.ValidateNIK(req.NIK, parsedDate, req.JenisKelamin, config.LoadConfig().NIKStrict)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"status":  http.StatusBadRequest,
+			"message": err.Error(),
+			"data":    nil,
+		})
+	}
+
 	// Buat objek Karyawan
 	karyawan := models.Karyawan{
 		NIK:          req.NIK,
@@ -119,7 +130,8 @@ func (kc *KaryawanController) AddKaryawan(c echo.Context) error {
 		"status":  http.StatusOK,
 		"message": "Karyawan added successfully",
 		"data": map[string]interface{}{
-			"id_karyawan": idKaryawan,
+			"id_karyawan":    idKaryawan,
+			"peringatan_nik": peringatanNIK,
 		},
 	})
 }
@@ -216,6 +228,16 @@ func (kc *KaryawanController) UpdateKaryawanHandler(c echo.Context) error {
 		})
 	}
 
+	// Validasi struktur NIK + cocokkan dengan tanggal lahir & jenis kelamin
+	peringatanNIK, err := utils.ValidateNIK(req.NIK, parsedDate, req.JenisKelamin, config.LoadConfig().NIKStrict)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"status":  http.StatusBadRequest,
+			"message": err.Error(),
+			"data":    nil,
+		})
+	}
+
 	// Buat objek Karyawan untuk update
 	karyawan := models.Karyawan{
 		IDKaryawan:   int64(idKaryawan),
@@ -284,7 +306,8 @@ func (kc *KaryawanController) UpdateKaryawanHandler(c echo.Context) error {
 		"status":  http.StatusOK,
 		"message": "Karyawan updated successfully",
 		"data": map[string]interface{}{
-			"id_karyawan": idUpdated,
+			"id_karyawan":    idUpdated,
+			"peringatan_nik": peringatanNIK,
 		},
 	})
 }
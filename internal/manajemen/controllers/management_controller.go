@@ -4,17 +4,20 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/c14220110/poliklinik-backend/config"
+	commonServices "github.com/c14220110/poliklinik-backend/internal/common/services"
 	"github.com/c14220110/poliklinik-backend/internal/manajemen/services"
 	"github.com/c14220110/poliklinik-backend/pkg/utils"
 	"github.com/labstack/echo/v4"
 )
 
 type ManagementController struct {
-	Service *services.ManagementService
+	Service  *services.ManagementService
+	Sessions *commonServices.SessionService
 }
 
-func NewManagementController(service *services.ManagementService) *ManagementController {
-	return &ManagementController{Service: service}
+func NewManagementController(service *services.ManagementService, sessions *commonServices.SessionService) *ManagementController {
+	return &ManagementController{Service: service, Sessions: sessions}
 }
 
 type LoginManagementRequest struct {
@@ -50,19 +53,26 @@ func (mc *ManagementController) Login(c echo.Context) error {
 		})
 	}
 
-	// Karena management tidak memiliki role/privilege, kita set idRole = 0 dan privileges sebagai slice kosong.
-	// Gunakan exp yang lama (misalnya, 999999 jam ke depan).
-	expTime := time.Now().Add(999999 * time.Hour)
-	token, err := utils.GenerateJWTToken(
-		m.ID_Management,
-		"Manajemen",
-		0,         // idRole tidak berlaku
-		[]int{},   // privileges kosong
-		0,         // idPoli tidak berlaku untuk management
-		m.Username,
-		m.Nama,    // Menambahkan nama management ke payload
-		expTime,
-	)
+	// Management tidak memiliki role, sehingga idRole = 0 dan diberikan seluruh privilege
+	// agar lolos pengecekan RequirePrivilege pada route management.
+	privileges, err := mc.Service.GetAllPrivilegeIDs()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"status":  http.StatusInternalServerError,
+			"message": "Failed to retrieve privileges: " + err.Error(),
+			"data":    nil,
+		})
+	}
+
+	// Sesi manajemen berlaku selama REFRESH_TOKEN_TTL; access token diperbarui lewat /api/auth/refresh
+	expTime := time.Now().Add(config.LoadConfig().RefreshTokenTTL)
+	tokens, err := mc.Sessions.CreateSession(utils.Claims{
+		IDKaryawan: m.ID_Management,
+		Role:       "Manajemen",
+		Privileges: privileges,
+		Username:   m.Username,
+		Nama:       m.Nama,
+	}, expTime)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
 			"status":  http.StatusInternalServerError,
@@ -75,10 +85,11 @@ func (mc *ManagementController) Login(c echo.Context) error {
 		"status":  http.StatusOK,
 		"message": "Login successful",
 		"data": map[string]interface{}{
-			"id":       m.ID_Management,
-			"nama":     m.Nama,
-			"username": m.Username,
-			"token":    token,
+			"id":            m.ID_Management,
+			"nama":          m.Nama,
+			"username":      m.Username,
+			"token":         tokens.AccessToken,
+			"refresh_token": tokens.RefreshToken,
 		},
 	})
 }
@@ -0,0 +1,107 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/c14220110/poliklinik-backend/internal/common/antrian"
+	"github.com/c14220110/poliklinik-backend/internal/common/middlewares"
+	"github.com/c14220110/poliklinik-backend/internal/manajemen/services"
+	"github.com/c14220110/poliklinik-backend/pkg/utils"
+	"github.com/labstack/echo/v4"
+)
+
+type PenutupanController struct {
+	Service *services.PenutupanService
+}
+
+func NewPenutupanController(service *services.PenutupanService) *PenutupanController {
+	return &PenutupanController{Service: service}
+}
+
+// TutupHarianHandler menjalankan penutupan antrian secara manual.
+// POST /api/management/penutupan-harian?tanggal=YYYY-MM-DD (default hari ini)
+func (pc *PenutupanController) TutupHarianHandler(c echo.Context) error {
+	claims, ok := c.Get(string(middlewares.ContextKeyClaims)).(*utils.Claims)
+	if !ok || claims == nil {
+		return c.JSON(http.StatusUnauthorized, map[string]interface{}{
+			"status":  http.StatusUnauthorized,
+			"message": "Invalid or missing token claims",
+			"data":    nil,
+		})
+	}
+
+	tanggal := time.Now()
+	if s := c.QueryParam("tanggal"); s != "" {
+		t, err := time.ParseInLocation("2006-01-02", s, time.Local)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"status":  http.StatusBadRequest,
+				"message": "tanggal harus berformat YYYY-MM-DD",
+				"data":    nil,
+			})
+		}
+		tanggal = t
+	}
+
+	hasil, err := pc.Service.TutupHarian(tanggal, antrian.ActorFromClaims(claims), services.PenutupanManual)
+	if err != nil {
+		status, message := http.StatusInternalServerError, "Gagal menjalankan penutupan harian"
+		if errors.Is(err, services.ErrPenutupanTanggal) {
+			status, message = http.StatusBadRequest, err.Error()
+		}
+		return c.JSON(status, map[string]interface{}{
+			"status":  status,
+			"message": message,
+			"data":    nil,
+		})
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status":  http.StatusOK,
+		"message": "Penutupan harian berhasil dijalankan",
+		"data":    hasil,
+	})
+}
+
+// GetPenutupanHandler menampilkan rekap penutupan harian per poli.
+// GET /api/management/penutupan-harian?tanggal=YYYY-MM-DD&id_poli={id}
+func (pc *PenutupanController) GetPenutupanHandler(c echo.Context) error {
+	tanggal := c.QueryParam("tanggal")
+	if tanggal != "" {
+		if _, err := time.Parse("2006-01-02", tanggal); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"status":  http.StatusBadRequest,
+				"message": "tanggal harus berformat YYYY-MM-DD",
+				"data":    nil,
+			})
+		}
+	}
+	var idPoli *int
+	if s := c.QueryParam("id_poli"); s != "" {
+		v, err := strconv.Atoi(s)
+		if err != nil || v <= 0 {
+			return c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"status":  http.StatusBadRequest,
+				"message": "id_poli harus berupa angka",
+				"data":    nil,
+			})
+		}
+		idPoli = &v
+	}
+
+	list, err := pc.Service.GetPenutupan(tanggal, idPoli)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"status":  http.StatusInternalServerError,
+			"message": "Gagal mengambil rekap penutupan harian",
+			"data":    nil,
+		})
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status":  http.StatusOK,
+		"message": "Rekap penutupan harian berhasil diambil",
+		"data":    list,
+	})
+}
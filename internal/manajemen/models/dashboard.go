@@ -4,6 +4,7 @@ type DashboardData struct {
 	PasienDibatalkan    int             `json:"pasien_dibatalkan"`
 	PasienKonsultasi    int             `json:"pasien_konsultasi"`
 	PasienMenunggu      int             `json:"pasien_menunggu"`
+	PasienTidakHadir    int             `json:"pasien_tidak_hadir"` // ditandai saat penutupan harian
 	TotalPasien         int             `json:"total_pasien"`
 
 	KaryawanAktif       int             `json:"karyawan_aktif"`
@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+// JadwalPoli adalah satu sesi buka mingguan poli. Jika IDShift diisi, jam yang kosong
+// mengikuti jam Shift.
+type JadwalPoli struct {
+	IDJadwal int     `json:"id_jadwal"`
+	Hari     int     `json:"hari"` // 1 = Senin ... 7 = Minggu
+	NamaHari string  `json:"nama_hari"`
+	IDShift  *int    `json:"id_shift"`
+	JamBuka  *string `json:"jam_buka"`  // HH:MM
+	JamTutup *string `json:"jam_tutup"` // HH:MM
+	Kuota    *int    `json:"kuota"`     // kuota sesi, nil = hanya kuota harian
+}
+
+// JadwalKuotaPoli adalah kuota harian dan jadwal mingguan satu poli. Jadwal kosong berarti
+// poli buka sepanjang hari; KuotaHarian nil berarti tanpa batas.
+type JadwalKuotaPoli struct {
+	IDPoli      int          `json:"id_poli"`
+	NamaPoli    string       `json:"nama_poli"`
+	KuotaHarian *int         `json:"kuota_harian"`
+	Jadwal      []JadwalPoli `json:"jadwal"`
+}
+
+// LiburPoli adalah hari libur satu poli, atau seluruh klinik jika IDPoli nil.
+type LiburPoli struct {
+	IDLibur    int       `json:"id_libur"`
+	Tanggal    string    `json:"tanggal"` // "2006-01-02"
+	IDPoli     *int      `json:"id_poli"`
+	NamaPoli   *string   `json:"nama_poli"`
+	Keterangan string    `json:"keterangan"`
+	CreatedAt  time.Time `json:"created_at"`
+}
@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// PenutupanHarian adalah rekap penutupan antrian satu poli pada satu tanggal.
+type PenutupanHarian struct {
+	IDPenutupan       int       `json:"id_penutupan"`
+	Tanggal           string    `json:"tanggal"` // "2006-01-02"
+	IDPoli            int       `json:"id_poli"`
+	NamaPoli          string    `json:"nama_poli"`
+	TotalAntrian      int       `json:"total_antrian"`
+	Pulang            int       `json:"pulang"`
+	Dibatalkan        int       `json:"dibatalkan"`
+	TidakHadir        int       `json:"tidak_hadir"`
+	MasihKonsultasi   int       `json:"masih_konsultasi"`
+	Ditutup           int       `json:"ditutup"`
+	BillingDibatalkan int       `json:"billing_dibatalkan"`
+	Sumber            string    `json:"sumber"` // Otomatis, Manual (penutupan terakhir)
+	DijalankanOleh    *int      `json:"dijalankan_oleh"`
+	Role              *string   `json:"role"`
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+}
+
+// HasilPenutupan merangkum satu kali penutupan harian.
+type HasilPenutupan struct {
+	Tanggal                 string            `json:"tanggal"`
+	Ditutup                 int               `json:"ditutup"`
+	BillingDibatalkan       int               `json:"billing_dibatalkan"`
+	DaftarTungguKedaluwarsa int               `json:"daftar_tunggu_kedaluwarsa"` // daftar tunggu yang tidak sempat didaftarkan
+	Rekap                   []PenutupanHarian `json:"rekap"`
+}
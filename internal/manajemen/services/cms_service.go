@@ -825,6 +825,24 @@ func (svc *CMSService) SaveAssessment(
 	idAssessment64, _ := res.LastInsertId()
 	idAssessment = int64(idAssessment64)
 
+/* ---------- 7a. Antrian rujukan internal ---------- */
+    // Assessment poli rujukan disimpan di Kunjungan_Poli; Riwayat_Kunjungan & Billing tetap
+    // milik antrian pendaftaran dan tagihannya digabung saat pembayaran.
+    rujukan, err := tx.Exec(`
+        UPDATE Kunjungan_Poli
+        SET id_assessment = ?
+        WHERE id_antrian = ? AND id_antrian_asal IS NOT NULL`,
+        idAssessment, idAntrian)
+    if err != nil {
+        return 0, err
+    }
+    if n, _ := rujukan.RowsAffected(); n > 0 {
+        if err = tx.Commit(); err != nil {
+            return 0, err
+        }
+        return idAssessment, nil
+    }
+
 /* ---------- 8. Update atau Insert Riwayat_Kunjungan ---------- */
     // Selalu update Riwayat_Kunjungan untuk id_antrian yang diberikan
     up, err := tx.Exec(`
@@ -846,7 +864,7 @@ func (svc *CMSService) SaveAssessment(
             SELECT rm.id_rm
             FROM Rekam_Medis rm
             JOIN Pasien p ON p.id_pasien = ?
-            WHERE rm.id_pasien = p.id_pasien
+            WHERE rm.id_pasien = p.id_pasien AND rm.digabung_dari IS NULL
             LIMIT 1`, idPasien).Scan(&idRM); err != nil {
             return 0, fmt.Errorf("pasien tidak memiliki rekam medis")
         }
@@ -5,6 +5,7 @@ import (
 	"log"
 	"time"
 
+	"github.com/c14220110/poliklinik-backend/internal/common/antrian"
 	"github.com/c14220110/poliklinik-backend/internal/manajemen/models"
 )
 
@@ -76,6 +77,13 @@ func (svc *DashboardService) GetDashboardData(idPoli *int, start, end time.Time)
 	}
 	d.PasienMenunggu = cnt
 
+	// 3b. Pasien Tidak Hadir (dari penutupan harian)
+	cnt, err = countQuery(ptrInt(antrian.StatusTidakHadir))
+	if err != nil {
+		return d, err
+	}
+	d.PasienTidakHadir = cnt
+
 	// 4. Total Pasien (all)
 	cnt, err = countQuery(nil)
 	if err != nil {
@@ -0,0 +1,294 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/c14220110/poliklinik-backend/internal/manajemen/models"
+)
+
+var (
+	ErrJadwalPoliTidakValid = errors.New("jadwal poliklinik tidak valid")
+	ErrLiburTidakValid      = errors.New("hari libur tidak valid")
+	ErrLiburGanda           = errors.New("hari libur pada tanggal tersebut sudah ada")
+)
+
+// namaHari mengikuti nomor hari Jadwal_Poli (1 = Senin ... 7 = Minggu).
+var namaHari = [...]string{"", "Senin", "Selasa", "Rabu", "Kamis", "Jumat", "Sabtu", "Minggu"}
+
+type JadwalPoliService struct {
+	DB *sql.DB
+}
+
+func NewJadwalPoliService(db *sql.DB) *JadwalPoliService {
+	return &JadwalPoliService{DB: db}
+}
+
+// GetJadwal mengembalikan kuota harian dan jadwal mingguan poli.
+func (s *JadwalPoliService) GetJadwal(idPoli int) (*models.JadwalKuotaPoli, error) {
+	j := &models.JadwalKuotaPoli{IDPoli: idPoli, Jadwal: []models.JadwalPoli{}}
+	var kuota sql.NullInt64
+	err := s.DB.QueryRow(`SELECT nama_poli, kuota_harian FROM Poliklinik WHERE id_poli = ?`, idPoli).Scan(&j.NamaPoli, &kuota)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("poliklinik dengan id %d tidak ditemukan", idPoli)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("gagal mengambil poliklinik: %v", err)
+	}
+	if kuota.Valid {
+		v := int(kuota.Int64)
+		j.KuotaHarian = &v
+	}
+
+	rows, err := s.DB.Query(`
+		SELECT id_jadwal, hari, id_shift, TIME_FORMAT(jam_buka, '%H:%i'), TIME_FORMAT(jam_tutup, '%H:%i'), kuota
+		FROM Jadwal_Poli
+		WHERE id_poli = ?
+		ORDER BY hari, COALESCE(jam_buka, '00:00'), id_jadwal`, idPoli)
+	if err != nil {
+		return nil, fmt.Errorf("gagal mengambil jadwal poliklinik: %v", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			jp                 models.JadwalPoli
+			idShift, kuotaSesi sql.NullInt64
+			buka, tutup        sql.NullString
+		)
+		if err := rows.Scan(&jp.IDJadwal, &jp.Hari, &idShift, &buka, &tutup, &kuotaSesi); err != nil {
+			return nil, fmt.Errorf("gagal membaca jadwal poliklinik: %v", err)
+		}
+		jp.NamaHari = namaHari[jp.Hari]
+		if idShift.Valid {
+			v := int(idShift.Int64)
+			jp.IDShift = &v
+		}
+		if kuotaSesi.Valid {
+			v := int(kuotaSesi.Int64)
+			jp.Kuota = &v
+		}
+		if buka.Valid {
+			jp.JamBuka = &buka.String
+		}
+		if tutup.Valid {
+			jp.JamTutup = &tutup.String
+		}
+		j.Jadwal = append(j.Jadwal, jp)
+	}
+	return j, rows.Err()
+}
+
+// validasiJadwal memeriksa satu sesi: hari 1-7, jam HH:MM (keduanya wajib tanpa shift),
+// jam tutup setelah jam buka, dan kuota tidak negatif. Jam yang mengikuti shift diperiksa
+// SetJadwal setelah shift-nya dibaca.
+func validasiJadwal(jp models.JadwalPoli) error {
+	if jp.Hari < 1 || jp.Hari > 7 {
+		return fmt.Errorf("%w: hari harus 1 (Senin) sampai 7 (Minggu)", ErrJadwalPoliTidakValid)
+	}
+	if jp.IDShift == nil && (jp.JamBuka == nil || jp.JamTutup == nil) {
+		return fmt.Errorf("%w: jam_buka dan jam_tutup wajib diisi jika id_shift kosong", ErrJadwalPoliTidakValid)
+	}
+	for _, jam := range []*string{jp.JamBuka, jp.JamTutup} {
+		if jam == nil {
+			continue
+		}
+		if _, err := time.Parse("15:04", *jam); err != nil {
+			return fmt.Errorf("%w: jam %q harus berformat HH:MM", ErrJadwalPoliTidakValid, *jam)
+		}
+	}
+	if jp.JamBuka != nil && jp.JamTutup != nil {
+		if err := cekJamSesi(*jp.JamBuka, *jp.JamTutup); err != nil {
+			return err
+		}
+	}
+	if jp.Kuota != nil && *jp.Kuota < 0 {
+		return fmt.Errorf("%w: kuota sesi tidak boleh negatif", ErrJadwalPoliTidakValid)
+	}
+	return nil
+}
+
+// cekJamSesi memastikan sesi selesai pada hari yang sama. Sesi yang melewati tengah malam
+// ditolak; pecah menjadi dua sesi di hari yang berurutan.
+func cekJamSesi(buka, tutup string) error {
+	b, err := time.Parse("15:04", buka)
+	if err != nil {
+		return fmt.Errorf("%w: jam %q harus berformat HH:MM", ErrJadwalPoliTidakValid, buka)
+	}
+	t, err := time.Parse("15:04", tutup)
+	if err != nil {
+		return fmt.Errorf("%w: jam %q harus berformat HH:MM", ErrJadwalPoliTidakValid, tutup)
+	}
+	if !t.After(b) {
+		return fmt.Errorf("%w: jam_tutup (%s) harus setelah jam_buka (%s); sesi tidak boleh melewati tengah malam",
+			ErrJadwalPoliTidakValid, tutup, buka)
+	}
+	return nil
+}
+
+// SetJadwal mengganti seluruh jadwal mingguan dan kuota harian poli. Jadwal kosong
+// mengembalikan poli ke buka sepanjang hari.
+func (s *JadwalPoliService) SetJadwal(j models.JadwalKuotaPoli, idManagement int) error {
+	if j.KuotaHarian != nil && *j.KuotaHarian < 0 {
+		return fmt.Errorf("%w: kuota_harian tidak boleh negatif", ErrJadwalPoliTidakValid)
+	}
+	for _, jp := range j.Jadwal {
+		if err := validasiJadwal(jp); err != nil {
+			return err
+		}
+	}
+
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return fmt.Errorf("gagal memulai transaksi: %v", err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(`UPDATE Poliklinik SET kuota_harian = ?, updated_at = NOW() WHERE id_poli = ?`, j.KuotaHarian, j.IDPoli)
+	if err != nil {
+		return fmt.Errorf("gagal menyimpan kuota harian: %v", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("poliklinik dengan id %d tidak ditemukan", j.IDPoli)
+	}
+	if _, err := tx.Exec(`DELETE FROM Jadwal_Poli WHERE id_poli = ?`, j.IDPoli); err != nil {
+		return fmt.Errorf("gagal menghapus jadwal lama: %v", err)
+	}
+	for _, jp := range j.Jadwal {
+		if jp.IDShift != nil {
+			// Jam yang kosong mengikuti shift; hasilnya juga tidak boleh melewati tengah malam.
+			var mulai, selesai string
+			err := tx.QueryRow(`
+				SELECT TIME_FORMAT(jam_mulai, '%H:%i'), TIME_FORMAT(jam_selesai, '%H:%i')
+				FROM Shift WHERE id_shift = ?`, *jp.IDShift).Scan(&mulai, &selesai)
+			if err == sql.ErrNoRows {
+				return fmt.Errorf("%w: shift %d tidak ada", ErrJadwalPoliTidakValid, *jp.IDShift)
+			}
+			if err != nil {
+				return fmt.Errorf("gagal mengambil shift: %v", err)
+			}
+			if jp.JamBuka != nil {
+				mulai = *jp.JamBuka
+			}
+			if jp.JamTutup != nil {
+				selesai = *jp.JamTutup
+			}
+			if err := cekJamSesi(mulai, selesai); err != nil {
+				return fmt.Errorf("%w (shift %d)", err, *jp.IDShift)
+			}
+		}
+		if _, err := tx.Exec(`
+			INSERT INTO Jadwal_Poli (id_poli, hari, id_shift, jam_buka, jam_tutup, kuota, updated_by)
+			VALUES (?, ?, ?, ?, ?, ?, ?)`,
+			j.IDPoli, jp.Hari, jp.IDShift, jp.JamBuka, jp.JamTutup, jp.Kuota, idManagement); err != nil {
+			return fmt.Errorf("gagal menyimpan jadwal poliklinik: %v", err)
+		}
+	}
+	if _, err := tx.Exec(`UPDATE Management_Poli SET updated_by = ? WHERE id_poli = ?`, idManagement, j.IDPoli); err != nil {
+		return fmt.Errorf("failed to update Management_Poli: %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("gagal commit transaksi: %v", err)
+	}
+	return nil
+}
+
+// ListLibur mengembalikan hari libur dari tanggal dari sampai sampai (YYYY-MM-DD, boleh kosong).
+// idPoli > 0 menampilkan libur poli tersebut dan libur seluruh klinik.
+func (s *JadwalPoliService) ListLibur(dari, sampai string, idPoli int) ([]models.LiburPoli, error) {
+	query := `
+		SELECT l.id_libur, DATE_FORMAT(l.tanggal, '%Y-%m-%d'), l.id_poli, p.nama_poli, l.keterangan, l.created_at
+		FROM Libur_Poli l
+		LEFT JOIN Poliklinik p ON p.id_poli = l.id_poli
+		WHERE 1=1`
+	args := []interface{}{}
+	if dari != "" {
+		query += " AND l.tanggal >= ?"
+		args = append(args, dari)
+	}
+	if sampai != "" {
+		query += " AND l.tanggal <= ?"
+		args = append(args, sampai)
+	}
+	if idPoli > 0 {
+		query += " AND (l.id_poli = ? OR l.id_poli IS NULL)"
+		args = append(args, idPoli)
+	}
+	query += " ORDER BY l.tanggal, l.id_poli IS NOT NULL, l.id_poli"
+
+	rows, err := s.DB.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("gagal mengambil hari libur: %v", err)
+	}
+	defer rows.Close()
+
+	list := []models.LiburPoli{}
+	for rows.Next() {
+		var (
+			l        models.LiburPoli
+			id       sql.NullInt64
+			namaPoli sql.NullString
+		)
+		if err := rows.Scan(&l.IDLibur, &l.Tanggal, &id, &namaPoli, &l.Keterangan, &l.CreatedAt); err != nil {
+			return nil, fmt.Errorf("gagal membaca hari libur: %v", err)
+		}
+		if id.Valid {
+			v := int(id.Int64)
+			l.IDPoli = &v
+		}
+		if namaPoli.Valid {
+			l.NamaPoli = &namaPoli.String
+		}
+		list = append(list, l)
+	}
+	return list, rows.Err()
+}
+
+// TambahLibur mencatat hari libur poli (atau seluruh klinik jika IDPoli nil).
+func (s *JadwalPoliService) TambahLibur(l models.LiburPoli, idManagement int) (int64, error) {
+	if _, err := time.Parse("2006-01-02", l.Tanggal); err != nil {
+		return 0, fmt.Errorf("%w: tanggal harus berformat YYYY-MM-DD", ErrLiburTidakValid)
+	}
+	l.Keterangan = strings.TrimSpace(l.Keterangan)
+	if l.Keterangan == "" {
+		return 0, fmt.Errorf("%w: keterangan wajib diisi", ErrLiburTidakValid)
+	}
+	if l.IDPoli != nil {
+		var ada bool
+		if err := s.DB.QueryRow(`SELECT EXISTS(SELECT 1 FROM Poliklinik WHERE id_poli = ?)`, *l.IDPoli).Scan(&ada); err != nil {
+			return 0, fmt.Errorf("gagal mengambil poliklinik: %v", err)
+		}
+		if !ada {
+			return 0, fmt.Errorf("poliklinik dengan id %d tidak ditemukan", *l.IDPoli)
+		}
+	}
+
+	var ada bool
+	if err := s.DB.QueryRow(`SELECT EXISTS(SELECT 1 FROM Libur_Poli WHERE tanggal = ? AND id_poli <=> ?)`,
+		l.Tanggal, l.IDPoli).Scan(&ada); err != nil {
+		return 0, fmt.Errorf("gagal memeriksa hari libur: %v", err)
+	}
+	if ada {
+		return 0, ErrLiburGanda
+	}
+	res, err := s.DB.Exec(`INSERT INTO Libur_Poli (tanggal, id_poli, keterangan, created_by) VALUES (?, ?, ?, ?)`,
+		l.Tanggal, l.IDPoli, l.Keterangan, idManagement)
+	if err != nil {
+		return 0, fmt.Errorf("gagal menyimpan hari libur: %v", err)
+	}
+	return res.LastInsertId()
+}
+
+// HapusLibur menghapus hari libur.
+func (s *JadwalPoliService) HapusLibur(idLibur int) error {
+	res, err := s.DB.Exec(`DELETE FROM Libur_Poli WHERE id_libur = ?`, idLibur)
+	if err != nil {
+		return fmt.Errorf("gagal menghapus hari libur: %v", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("hari libur dengan id %d tidak ditemukan", idLibur)
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/c14220110/poliklinik-backend/internal/manajemen/models"
+)
+
+func TestCekJamSesi(t *testing.T) {
+	tests := []struct {
+		buka, tutup string
+		ok          bool
+	}{
+		{"08:00", "12:00", true},
+		{"8:00", "12:00", true}, // dibandingkan sebagai jam, bukan teks
+		{"00:00", "23:59", true},
+		{"12:00", "12:00", false},
+		{"13:00", "12:00", false},
+		{"22:00", "06:00", false}, // melewati tengah malam
+		{"25:00", "26:00", false},
+		{"08.00", "12:00", false},
+	}
+	for _, tt := range tests {
+		err := cekJamSesi(tt.buka, tt.tutup)
+		if tt.ok && err != nil {
+			t.Errorf("cekJamSesi(%s, %s) = %v, want nil", tt.buka, tt.tutup, err)
+		}
+		if !tt.ok && !errors.Is(err, ErrJadwalPoliTidakValid) {
+			t.Errorf("cekJamSesi(%s, %s) = %v, want ErrJadwalPoliTidakValid", tt.buka, tt.tutup, err)
+		}
+	}
+}
+
+func TestValidasiJadwal(t *testing.T) {
+	jam := func(s string) *string { return &s }
+	angka := func(n int) *int { return &n }
+
+	tests := []struct {
+		nama string
+		jp   models.JadwalPoli
+		ok   bool
+	}{
+		{"jam sendiri", models.JadwalPoli{Hari: 1, JamBuka: jam("08:00"), JamTutup: jam("12:00"), Kuota: angka(25)}, true},
+		{"ikut shift", models.JadwalPoli{Hari: 7, IDShift: angka(2)}, true},
+		{"shift dengan jam buka sendiri", models.JadwalPoli{Hari: 3, IDShift: angka(2), JamBuka: jam("09:00")}, true},
+		{"hari 0", models.JadwalPoli{Hari: 0, JamBuka: jam("08:00"), JamTutup: jam("12:00")}, false},
+		{"hari 8", models.JadwalPoli{Hari: 8, IDShift: angka(1)}, false},
+		{"jam tutup kosong tanpa shift", models.JadwalPoli{Hari: 1, JamBuka: jam("08:00")}, false},
+		{"format jam salah", models.JadwalPoli{Hari: 1, IDShift: angka(1), JamTutup: jam("jam 5")}, false},
+		{"jam sama", models.JadwalPoli{Hari: 1, JamBuka: jam("08:00"), JamTutup: jam("08:00")}, false},
+		{"melewati tengah malam", models.JadwalPoli{Hari: 5, JamBuka: jam("22:00"), JamTutup: jam("02:00")}, false},
+		{"kuota negatif", models.JadwalPoli{Hari: 1, IDShift: angka(1), Kuota: angka(-1)}, false},
+	}
+	for _, tt := range tests {
+		err := validasiJadwal(tt.jp)
+		if tt.ok && err != nil {
+			t.Errorf("%s: %v", tt.nama, err)
+		}
+		if !tt.ok && !errors.Is(err, ErrJadwalPoliTidakValid) {
+			t.Errorf("%s: err = %v, want ErrJadwalPoliTidakValid", tt.nama, err)
+		}
+	}
+}
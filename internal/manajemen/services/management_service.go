@@ -30,3 +30,23 @@ func (s *ManagementService) AuthenticateManagement(username, password string) (*
 	}
 	return &m, nil
 }
+
+// GetAllPrivilegeIDs mengambil seluruh id_privilege yang masih aktif.
+// Manajemen tidak terikat role sehingga diberikan semua privilege.
+func (s *ManagementService) GetAllPrivilegeIDs() ([]int, error) {
+	rows, err := s.DB.Query("SELECT id_privilege FROM Privilege WHERE deleted_at IS NULL ORDER BY id_privilege")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	privileges := []int{}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		privileges = append(privileges, id)
+	}
+	return privileges, rows.Err()
+}
@@ -0,0 +1,342 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/c14220110/poliklinik-backend/config"
+	"github.com/c14220110/poliklinik-backend/internal/common/antrian"
+	"github.com/c14220110/poliklinik-backend/internal/manajemen/models"
+	"github.com/c14220110/poliklinik-backend/ws"
+)
+
+// Sumber penutupan, sesuai ENUM Penutupan_Harian.sumber.
+const (
+	PenutupanOtomatis = "Otomatis"
+	PenutupanManual   = "Manual"
+)
+
+// penutupanLookback membatasi berapa hari ke belakang yang diperiksa job otomatis.
+const penutupanLookback = 30
+
+var ErrPenutupanTanggal = errors.New("tanggal penutupan tidak boleh di masa depan")
+
+type PenutupanService struct {
+	DB *sql.DB
+}
+
+func NewPenutupanService(db *sql.DB) *PenutupanService {
+	return &PenutupanService{DB: db}
+}
+
+// antrianDitutup adalah antrian yang statusnya diubah oleh penutupan, untuk event setelah commit.
+type antrianDitutup struct {
+	id, from, to int
+}
+
+func hariIni(now time.Time) time.Time {
+	y, m, d := now.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, now.Location())
+}
+
+// TutupHarian menutup antrian pada tanggal tersebut: Menunggu / Ditunda menjadi Tidak Hadir,
+// Screening / Pra-Konsultasi menjadi Dibatalkan, dan billing yang belum dibayar ikut dibatalkan.
+// Antrian yang masih Konsultasi dibiarkan (dokter yang menyelesaikannya) dan hanya dilaporkan.
+// Daftar tunggu yang masih Menunggu ditandai Kedaluwarsa.
+// Rekap per poli disimpan di Penutupan_Harian; aman dijalankan berulang kali.
+func (s *PenutupanService) TutupHarian(tanggal time.Time, actor antrian.Actor, sumber string) (*models.HasilPenutupan, error) {
+	tanggal = hariIni(tanggal)
+	if tanggal.After(hariIni(time.Now())) {
+		return nil, ErrPenutupanTanggal
+	}
+	tgl := tanggal.Format("2006-01-02")
+
+	alasan := "Penutupan harian otomatis"
+	if sumber == PenutupanManual {
+		alasan = "Penutupan harian oleh manajemen"
+	}
+
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("gagal memulai transaksi: %v", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(`
+		SELECT id_antrian, id_poli, id_status
+		FROM Antrian
+		WHERE DATE(created_at) = ? AND id_status IN (?, ?, ?, ?)
+		ORDER BY id_antrian
+		FOR UPDATE`,
+		tgl, antrian.StatusMenunggu, antrian.StatusDitunda, antrian.StatusScreening, antrian.StatusPraKonsultasi,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("gagal mengambil antrian yang belum selesai: %v", err)
+	}
+	type terbuka struct{ id, idPoli, status int }
+	var daftar []terbuka
+	for rows.Next() {
+		var a terbuka
+		if err := rows.Scan(&a.id, &a.idPoli, &a.status); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("gagal membaca antrian: %v", err)
+		}
+		daftar = append(daftar, a)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("gagal membaca antrian: %v", err)
+	}
+
+	hasil := &models.HasilPenutupan{Tanggal: tgl, Rekap: []models.PenutupanHarian{}}
+	ditutupPoli := map[int]int{}
+	billingPoli := map[int]int{}
+	var ditutup []antrianDitutup
+	for _, a := range daftar {
+		to := antrian.StatusDibatalkan
+		if a.status == antrian.StatusMenunggu || a.status == antrian.StatusDitunda {
+			to = antrian.StatusTidakHadir
+		}
+		from, err := antrian.Transition(tx, a.id, to, actor, alasan)
+		if err != nil {
+			return nil, err
+		}
+
+		// Sama seperti BatalkanAntrian, tetapi hanya billing yang belum dibayar (id_status = 1).
+		res, err := tx.Exec(`
+			UPDATE Billing b
+			JOIN Riwayat_Kunjungan rk ON b.id_kunjungan = rk.id_kunjungan
+			SET b.id_status = 3
+			WHERE rk.id_antrian = ? AND b.id_status = 1`, a.id)
+		if err != nil {
+			return nil, fmt.Errorf("gagal mengupdate status billing: %v", err)
+		}
+		n, _ := res.RowsAffected()
+
+		ditutupPoli[a.idPoli]++
+		billingPoli[a.idPoli] += int(n)
+		hasil.Ditutup++
+		hasil.BillingDibatalkan += int(n)
+		ditutup = append(ditutup, antrianDitutup{id: a.id, from: from, to: to})
+	}
+
+	if err := simpanRekapPenutupan(tx, tgl, ditutupPoli, billingPoli, actor, sumber); err != nil {
+		return nil, err
+	}
+
+	// Pasien daftar tunggu yang tidak sempat didaftarkan.
+	res, err := tx.Exec(`UPDATE Daftar_Tunggu SET status = 'Kedaluwarsa' WHERE tanggal = ? AND status = 'Menunggu'`, tgl)
+	if err != nil {
+		return nil, fmt.Errorf("gagal menutup daftar tunggu: %v", err)
+	}
+	n, _ := res.RowsAffected()
+	hasil.DaftarTungguKedaluwarsa = int(n)
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("gagal commit transaksi: %v", err)
+	}
+
+	for _, a := range ditutup {
+		event := ws.EventAntrianStatusChanged
+		if a.to == antrian.StatusDibatalkan {
+			event = ws.EventAntrianCancelled
+		}
+		antrian.PublishEvent(s.DB, event, a.id, a.from, a.to, actor, alasan)
+	}
+
+	hasil.Rekap, err = s.GetPenutupan(tgl, nil)
+	if err != nil {
+		return nil, err
+	}
+	return hasil, nil
+}
+
+// simpanRekapPenutupan menghitung ulang jumlah status akhir per poli pada tanggal tersebut dan
+// menambahkan jumlah antrian / billing yang baru ditutup ke Penutupan_Harian.
+func simpanRekapPenutupan(tx *sql.Tx, tgl string, ditutupPoli, billingPoli map[int]int, actor antrian.Actor, sumber string) error {
+	rows, err := tx.Query(`
+		SELECT id_poli, COUNT(*),
+		       SUM(id_status = ?), SUM(id_status = ?), SUM(id_status = ?), SUM(id_status = ?)
+		FROM Antrian
+		WHERE DATE(created_at) = ?
+		GROUP BY id_poli`,
+		antrian.StatusPulang, antrian.StatusDibatalkan, antrian.StatusTidakHadir, antrian.StatusKonsultasi, tgl,
+	)
+	if err != nil {
+		return fmt.Errorf("gagal menghitung rekap penutupan: %v", err)
+	}
+	type rekap struct{ idPoli, total, pulang, dibatalkan, tidakHadir, konsultasi int }
+	var list []rekap
+	for rows.Next() {
+		var r rekap
+		if err := rows.Scan(&r.idPoli, &r.total, &r.pulang, &r.dibatalkan, &r.tidakHadir, &r.konsultasi); err != nil {
+			rows.Close()
+			return fmt.Errorf("gagal membaca rekap penutupan: %v", err)
+		}
+		list = append(list, r)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("gagal membaca rekap penutupan: %v", err)
+	}
+
+	var dijalankanOleh sql.NullInt64
+	if actor.IDKaryawan > 0 {
+		dijalankanOleh = sql.NullInt64{Int64: int64(actor.IDKaryawan), Valid: true}
+	}
+	for _, r := range list {
+		_, err := tx.Exec(`
+			INSERT INTO Penutupan_Harian
+				(tanggal, id_poli, total_antrian, pulang, dibatalkan, tidak_hadir, masih_konsultasi,
+				 ditutup, billing_dibatalkan, sumber, dijalankan_oleh, role)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			ON DUPLICATE KEY UPDATE
+				total_antrian      = VALUES(total_antrian),
+				pulang             = VALUES(pulang),
+				dibatalkan         = VALUES(dibatalkan),
+				tidak_hadir        = VALUES(tidak_hadir),
+				masih_konsultasi   = VALUES(masih_konsultasi),
+				ditutup            = ditutup + VALUES(ditutup),
+				billing_dibatalkan = billing_dibatalkan + VALUES(billing_dibatalkan),
+				sumber             = VALUES(sumber),
+				dijalankan_oleh    = VALUES(dijalankan_oleh),
+				role               = VALUES(role)`,
+			tgl, r.idPoli, r.total, r.pulang, r.dibatalkan, r.tidakHadir, r.konsultasi,
+			ditutupPoli[r.idPoli], billingPoli[r.idPoli], sumber, dijalankanOleh, actor.Role,
+		)
+		if err != nil {
+			return fmt.Errorf("gagal menyimpan Penutupan_Harian: %v", err)
+		}
+	}
+	return nil
+}
+
+// GetPenutupan mengembalikan rekap penutupan pada tanggal (kosong = semua tanggal, terbaru dulu),
+// opsional difilter per poli.
+func (s *PenutupanService) GetPenutupan(tanggal string, idPoli *int) ([]models.PenutupanHarian, error) {
+	query := `
+		SELECT ph.id_penutupan, DATE_FORMAT(ph.tanggal, '%Y-%m-%d'), ph.id_poli, pl.nama_poli,
+		       ph.total_antrian, ph.pulang, ph.dibatalkan, ph.tidak_hadir, ph.masih_konsultasi,
+		       ph.ditutup, ph.billing_dibatalkan, ph.sumber, ph.dijalankan_oleh, ph.role,
+		       ph.created_at, ph.updated_at
+		FROM Penutupan_Harian ph
+		JOIN Poliklinik pl ON ph.id_poli = pl.id_poli
+		WHERE 1 = 1`
+	var args []interface{}
+	if tanggal != "" {
+		query += " AND ph.tanggal = ?"
+		args = append(args, tanggal)
+	}
+	if idPoli != nil {
+		query += " AND ph.id_poli = ?"
+		args = append(args, *idPoli)
+	}
+	query += " ORDER BY ph.tanggal DESC, ph.id_poli ASC"
+
+	rows, err := s.DB.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("gagal mengambil Penutupan_Harian: %v", err)
+	}
+	defer rows.Close()
+
+	list := []models.PenutupanHarian{}
+	for rows.Next() {
+		var (
+			p              models.PenutupanHarian
+			dijalankanOleh sql.NullInt64
+			role           sql.NullString
+		)
+		if err := rows.Scan(&p.IDPenutupan, &p.Tanggal, &p.IDPoli, &p.NamaPoli,
+			&p.TotalAntrian, &p.Pulang, &p.Dibatalkan, &p.TidakHadir, &p.MasihKonsultasi,
+			&p.Ditutup, &p.BillingDibatalkan, &p.Sumber, &dijalankanOleh, &role,
+			&p.CreatedAt, &p.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("gagal membaca Penutupan_Harian: %v", err)
+		}
+		if dijalankanOleh.Valid {
+			id := int(dijalankanOleh.Int64)
+			p.DijalankanOleh = &id
+		}
+		if role.Valid {
+			p.Role = &role.String
+		}
+		list = append(list, p)
+	}
+	return list, rows.Err()
+}
+
+// tanggalBelumDitutup mengembalikan tanggal (sebelum batas) yang masih punya antrian belum selesai
+// atau belum punya rekap Penutupan_Harian untuk salah satu polinya.
+func (s *PenutupanService) tanggalBelumDitutup(dari, batas time.Time) ([]time.Time, error) {
+	rows, err := s.DB.Query(`
+		SELECT DISTINCT DATE_FORMAT(a.created_at, '%Y-%m-%d') AS tanggal
+		FROM Antrian a
+		LEFT JOIN Penutupan_Harian ph ON ph.tanggal = DATE(a.created_at) AND ph.id_poli = a.id_poli
+		WHERE a.created_at >= ? AND a.created_at < ?
+		  AND (a.id_status IN (?, ?, ?, ?) OR ph.id_penutupan IS NULL)
+		ORDER BY tanggal`,
+		dari, batas, antrian.StatusMenunggu, antrian.StatusDitunda, antrian.StatusScreening, antrian.StatusPraKonsultasi,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("gagal mencari tanggal yang belum ditutup: %v", err)
+	}
+	defer rows.Close()
+
+	var list []time.Time
+	for rows.Next() {
+		var tgl string
+		if err := rows.Scan(&tgl); err != nil {
+			return nil, fmt.Errorf("gagal membaca tanggal: %v", err)
+		}
+		t, err := time.ParseInLocation("2006-01-02", tgl, batas.Location())
+		if err != nil {
+			return nil, fmt.Errorf("tanggal tidak valid %q: %v", tgl, err)
+		}
+		list = append(list, t)
+	}
+	return list, rows.Err()
+}
+
+// TutupOtomatis menutup semua hari sebelumnya (sampai penutupanLookback hari) yang belum
+// ditutup, dan hari ini jika sudah melewati PENUTUPAN_HARIAN_JAM.
+func (s *PenutupanService) TutupOtomatis(now time.Time) (int, error) {
+	batas := hariIni(now)
+	if !now.Before(batas.Add(config.LoadConfig().PenutupanHarianJam)) {
+		batas = batas.AddDate(0, 0, 1)
+	}
+	tanggal, err := s.tanggalBelumDitutup(hariIni(now).AddDate(0, 0, -penutupanLookback), batas)
+	if err != nil {
+		return 0, err
+	}
+
+	total := 0
+	for _, t := range tanggal {
+		hasil, err := s.TutupHarian(t, antrian.Actor{Role: "Sistem"}, PenutupanOtomatis)
+		if err != nil {
+			return total, fmt.Errorf("penutupan %s: %w", t.Format("2006-01-02"), err)
+		}
+		total += hasil.Ditutup
+	}
+	return total, nil
+}
+
+// RunPenutupan menjalankan TutupOtomatis setiap interval sampai ctx dibatalkan.
+func (s *PenutupanService) RunPenutupan(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if n, err := s.TutupOtomatis(time.Now()); err != nil {
+			slog.Error("Gagal menjalankan penutupan antrian harian", "reason", err)
+		} else if n > 0 {
+			slog.Info("Penutupan antrian harian", "ditutup", n)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
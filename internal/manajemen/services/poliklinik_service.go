@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"strings"
 	"time"
+
+	"github.com/c14220110/poliklinik-backend/internal/common/antrian"
 )
 
 type PoliklinikService struct {
@@ -16,6 +18,7 @@ func NewPoliklinikService(db *sql.DB) *PoliklinikService {
 }
 
 // GetPoliklinikListFiltered mengambil daftar poliklinik dengan filter status (aktif / nonaktif / semua).
+// Poli aktif disertai kapasitas pendaftaran hari ini.
 func (ps *PoliklinikService) GetPoliklinikListFiltered(statusFilter string) ([]map[string]interface{}, error) {
 	baseQuery := `
 		SELECT
@@ -83,10 +86,34 @@ func (ps *PoliklinikService) GetPoliklinikListFiltered(statusFilter string) ([]m
 
 		list = append(list, record)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %v", err)
+	}
 
+	if err := ps.tambahKapasitas(list); err != nil {
+		return nil, err
+	}
 	return list, nil
 }
 
+// tambahKapasitas menambahkan "kapasitas" (jam operasional, libur, dan sisa kuota pendaftaran
+// saat ini) ke setiap poli pada list. Poli nonaktif mendapat nil.
+func (ps *PoliklinikService) tambahKapasitas(list []map[string]interface{}) error {
+	now := time.Now()
+	for _, record := range list {
+		if idStatus, ok := record["id_status"].(int); ok && idStatus != 1 {
+			record["kapasitas"] = nil
+			continue
+		}
+		kap, err := antrian.HitungKapasitas(ps.DB, record["id_poli"].(int), now)
+		if err != nil {
+			return err
+		}
+		record["kapasitas"] = kap
+	}
+	return nil
+}
+
 // SoftDeletePoliklinik melakukan soft delete dengan mengupdate kolom deleted_at,
 // mengubah id_status menjadi 0 (nonaktif) dan mencatat deleted_by di tabel Management_Poli.
 func (ps *PoliklinikService) SoftDeletePoliklinik(idPoli int, idManagement int) error {
@@ -277,6 +304,13 @@ func (ps *PoliklinikService) GetActivePoliklinikList() ([]map[string]interface{}
 		}
 		list = append(list, record)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %v", err)
+	}
+
+	if err := ps.tambahKapasitas(list); err != nil {
+		return nil, err
+	}
 	return list, nil
 }
 
@@ -2,7 +2,6 @@ package routes
 
 import (
 	"database/sql"
-	"net/http"
 
 	"github.com/labstack/echo/v4"
 
@@ -19,7 +18,13 @@ import (
 	dokterControllers "github.com/c14220110/poliklinik-backend/internal/dokter/controllers"
 	dokterServices "github.com/c14220110/poliklinik-backend/internal/dokter/services"
 
+	"github.com/c14220110/poliklinik-backend/internal/common/antrian"
+	commonControllers "github.com/c14220110/poliklinik-backend/internal/common/controllers"
 	"github.com/c14220110/poliklinik-backend/internal/common/middlewares"
+	commonServices "github.com/c14220110/poliklinik-backend/internal/common/services"
+
+	displayControllers "github.com/c14220110/poliklinik-backend/internal/display/controllers"
+	displayServices "github.com/c14220110/poliklinik-backend/internal/display/services"
 )
 
 func Init(e *echo.Echo, db *sql.DB) {
@@ -28,6 +33,17 @@ func Init(e *echo.Echo, db *sql.DB) {
 	adminService := adminServices.NewAdministrasiService(db)
 	pendaftaranService := adminServices.NewPendaftaranService(db)
 	billingService := adminServices.NewBillingService(db)
+	duplikatService := adminServices.NewDuplikatService(db)
+	pasienVersiService := adminServices.NewPasienVersiService(db)
+	penjaminService := adminServices.NewPenjaminService(db)
+	triaseService := adminServices.NewTriaseService(db)
+	rescheduleService := adminServices.NewRescheduleService(db)
+	persetujuanService := adminServices.NewPersetujuanService(db)
+	lampiranService := adminServices.NewLampiranService(db)
+	importPasienService := adminServices.NewImportPasienService(db)
+	hapusDataService := adminServices.NewHapusDataService(db)
+	janjiTemuService := adminServices.NewJanjiTemuService(db)
+	daftarTungguService := adminServices.NewDaftarTungguService(db)
 	// Untuk poliklinik, gunakan service dari manajemen
 	poliklinikService := manajemenServices.NewPoliklinikService(db)
 
@@ -38,6 +54,16 @@ func Init(e *echo.Echo, db *sql.DB) {
 	cmsService := manajemenServices.NewCMSService(db)
 	privilegeService := manajemenServices.NewPrivilegeService(db)
 	dashboardService := manajemenServices.NewDashboardService(db)
+	penutupanService := manajemenServices.NewPenutupanService(db)
+	jadwalPoliService := manajemenServices.NewJadwalPoliService(db)
+
+	// Sesi login (refresh token & pencabutan sesi) dipakai bersama oleh semua aplikasi
+	sessionService := commonServices.NewSessionService(db)
+	middlewares.SetSessionChecker(sessionService)
+	// Hub WebSocket perlu tahu poli sebuah antrian untuk otorisasi topik antrian:{id}
+	ws.HubInstance.SetAntrianPoliLookup(func(idAntrian int) (int, error) {
+		return antrian.PoliOf(db, idAntrian)
+	})
 
 	// Screening / Suster
 	screeningService := screeningServices.NewScreeningService(db)
@@ -47,14 +73,26 @@ func Init(e *echo.Echo, db *sql.DB) {
 	// Dokter
 	dokterService := dokterServices.NewDokterService(db)
 	resepService := dokterServices.NewResepService(db)
+	rujukanService := dokterServices.NewRujukanService(db)
 
 	// Inisialisasi controller
 	// Administrasi
-	adminController := adminControllers.NewAdministrasiController(adminService)
+	adminController := adminControllers.NewAdministrasiController(adminService, sessionService)
 	pasienController := adminControllers.NewPasienController(pendaftaranService)
 	billingController := adminControllers.NewBillingController(billingService)
+	duplikatController := adminControllers.NewDuplikatController(duplikatService)
+	pasienVersiController := adminControllers.NewPasienVersiController(pasienVersiService)
+	penjaminController := adminControllers.NewPenjaminController(penjaminService)
+	triaseController := adminControllers.NewTriaseController(triaseService)
+	rescheduleController := adminControllers.NewRescheduleController(rescheduleService)
+	persetujuanController := adminControllers.NewPersetujuanController(persetujuanService)
+	lampiranController := adminControllers.NewLampiranController(lampiranService)
+	importPasienController := adminControllers.NewImportPasienController(importPasienService)
+	hapusDataController := adminControllers.NewHapusDataController(hapusDataService)
+	janjiTemuController := adminControllers.NewJanjiTemuController(janjiTemuService)
+	daftarTungguController := adminControllers.NewDaftarTungguController(daftarTungguService)
 	// Management (poliklinik, karyawan, role, shift, CMS, privilege)
-	managementController := manajemenControllers.NewManagementController(managementService)
+	managementController := manajemenControllers.NewManagementController(managementService, sessionService)
 	karyawanController := manajemenControllers.NewKaryawanController(managementService)
 	roleController := manajemenControllers.NewRoleController(roleService)
 	shiftController := manajemenControllers.NewShiftController(shiftService)
@@ -62,92 +100,151 @@ func Init(e *echo.Echo, db *sql.DB) {
 	poliklinikController := manajemenControllers.NewPoliklinikController(poliklinikService)
 	privilegeController := manajemenControllers.NewPrivilegeController(privilegeService)
 	dashboardController := manajemenControllers.NewDashboardController(dashboardService)
+	penutupanController := manajemenControllers.NewPenutupanController(penutupanService)
+	jadwalPoliController := manajemenControllers.NewJadwalPoliController(jadwalPoliService)
 	// Screening / Suster
-	susterController := screeningControllers.NewSusterController(susterService)
+	susterController := screeningControllers.NewSusterController(susterService, sessionService)
 	screeningController := screeningControllers.NewScreeningController(screeningService)
 	antrianController := screeningControllers.NewAntrianController(antrianService)
 	// Dokter
-	dokterController := dokterControllers.NewDokterController(dokterService)
+	dokterController := dokterControllers.NewDokterController(dokterService, sessionService)
 	resepController := dokterControllers.NewResepController(resepService)
-
-	// Grup API utama
+	rujukanController := dokterControllers.NewRujukanController(rujukanService)
+	// Auth
+	authController := commonControllers.NewAuthController(sessionService)
+	// Layar antrian publik (dibangun di atas data antrian administrasi & screening)
+	displayService := displayServices.NewDisplayService(db, pendaftaranService, antrianService)
+	displayController := displayControllers.NewDisplayController(displayService)
+
+	// Grup API utama. Semua route didaftarkan lewat securedGroup sehingga
+	// JWT & privilege dipasang otomatis sesuai routePolicies (lihat policy.go).
 	api := e.Group("/api")
-	api.GET("/ws", ws.ServeWS(ws.HubInstance))
-	api.GET("/ws-test", func(c echo.Context) error {
-    ws.HubInstance.Broadcast <- []byte("Test broadcast message")
-    return c.JSON(http.StatusOK, map[string]interface{}{
-        "status":  http.StatusOK,
-        "message": "Broadcast message sent",
-    })
-})
 
+	// WebSocket: token boleh dikirim lewat ?token= karena browser tidak bisa set header saat upgrade
+	wsGroup := newSecuredGroup(api.Group("/ws", middlewares.TokenFromQuery()), "/api/ws")
+	wsGroup.GET("", ws.ServeWS(ws.HubInstance))
 
+	// Layar antrian di ruang tunggu: publik, tanpa data pasien, dibatasi per IP
+	display := newSecuredGroup(api.Group("/display", middlewares.RateLimit(2, 10)), "/api/display")
+	display.GET("/antrian", displayController.GetPapanAntrianHandler)
+	display.GET("/ws", ws.ServeDisplayWS(ws.HubInstance))
+	display.GET("/tiket", displayController.GetStatusTiketHandler)
+	display.POST("/tiket/check-in", displayController.CheckInTiketHandler)
 
+	// 0. Auth (refresh & logout untuk semua aplikasi)
+	auth := newSecuredGroup(api.Group("/auth"), "/api/auth")
+	auth.POST("/refresh", authController.RefreshHandler)
+	auth.POST("/logout", authController.LogoutHandler)
 
 	// 1. Administrasi (Aplikasi Pendaftaran & Administrasi)
-	administrasi := api.Group("/administrasi")
+	administrasi := newSecuredGroup(api.Group("/administrasi"), "/api/administrasi", middlewares.RoleAdministrasi)
 	administrasi.POST("/login", adminController.Login) // Tidak pakai JWT
-	administrasi.GET("/pasien", pasienController.GetAllPasienData, middlewares.JWTMiddleware())
-	administrasi.POST("/pasien/register", pasienController.RegisterPasien, middlewares.JWTMiddleware())
-	administrasi.PUT("/kunjungan", pasienController.UpdateKunjungan, middlewares.JWTMiddleware())
-	administrasi.PUT("/antrian/reschedule", pasienController.RescheduleAntrianHandler, middlewares.JWTMiddleware())
-	administrasi.PUT("/antrian/tunda", pasienController.TundaPasienHandler, middlewares.JWTMiddleware())
-	administrasi.GET("/antrian/today", pasienController.GetAntrianTodayHandler, middlewares.JWTMiddleware())
-	administrasi.GET("/status_antrian", pasienController.GetAllStatusAntrianHandler, middlewares.JWTMiddleware())
+	administrasi.GET("/pasien", pasienController.GetAllPasienData)
+	administrasi.GET("/pasien/cari", pasienController.SearchPasienHandler)
+	administrasi.GET("/pasien/duplikat", duplikatController.FindDuplicatesHandler)
+	administrasi.POST("/pasien/merge", duplikatController.AjukanMergeHandler)
+	administrasi.GET("/pasien/versi", pasienVersiController.GetVersiListHandler)
+	administrasi.GET("/penjamin", penjaminController.ListPenjaminAktifHandler)
+	administrasi.GET("/pasien/penjamin", penjaminController.GetKepesertaanHandler)
+	administrasi.POST("/pasien/penjamin", penjaminController.TambahKepesertaanHandler)
+	administrasi.PUT("/pasien/penjamin", penjaminController.UpdateKepesertaanHandler)
+	administrasi.GET("/triase", triaseController.ListTriaseAktifHandler)
+
+	// Persetujuan (informed consent) per kunjungan
+	administrasi.GET("/persetujuan/template", persetujuanController.ListTemplateAktifHandler)
+	administrasi.GET("/persetujuan", persetujuanController.GetPersetujuanKunjunganHandler)
+	administrasi.POST("/persetujuan", persetujuanController.CatatPersetujuanHandler)
+	administrasi.PUT("/persetujuan/cabut", persetujuanController.CabutPersetujuanHandler)
+	// Lampiran dokumen pasien (KTP, kartu penjamin, surat rujukan); diunduh lewat API, bukan /uploads
+	administrasi.POST("/lampiran", lampiranController.UploadLampiranHandler)
+	administrasi.GET("/lampiran", lampiranController.ListLampiranHandler)
+	administrasi.GET("/lampiran/unduh", lampiranController.UnduhLampiranHandler)
+	administrasi.PUT("/lampiran/hapus", lampiranController.HapusLampiranHandler)
+	// Permintaan hapus data (pseudonimisasi) dari pasien; diputuskan manajemen
+	administrasi.POST("/pasien/hapus-data", hapusDataController.AjukanPermintaanHandler)
+
+	// Janji temu (booking tanggal mendatang)
+	administrasi.POST("/janji-temu", janjiTemuController.BuatJanjiTemuHandler)
+	administrasi.GET("/janji-temu", janjiTemuController.ListJanjiTemuHandler)
+	administrasi.GET("/janji-temu/kuota", janjiTemuController.GetKuotaHandler)
+	administrasi.PUT("/janji-temu/batalkan", janjiTemuController.BatalkanJanjiTemuHandler)
+	administrasi.PUT("/janji-temu/check-in", janjiTemuController.CheckInJanjiTemuHandler)
+
+	// Daftar tunggu (pendaftaran saat kuota poli penuh / di luar jam operasional)
+	administrasi.GET("/daftar-tunggu", daftarTungguController.ListDaftarTungguHandler)
+	administrasi.PUT("/daftar-tunggu/proses", daftarTungguController.DaftarkanHandler)
+	administrasi.PUT("/daftar-tunggu/batal", daftarTungguController.BatalkanDaftarTungguHandler)
+	administrasi.POST("/pasien/register", pasienController.RegisterPasien)
+	administrasi.PUT("/kunjungan", pasienController.UpdateKunjungan)
+	administrasi.PUT("/antrian/reschedule", pasienController.RescheduleAntrianHandler)
+	administrasi.PUT("/antrian/tunda", pasienController.TundaPasienHandler)
+	administrasi.GET("/reschedule", rescheduleController.GetKebijakanHandler)
+	administrasi.GET("/antrian/today", pasienController.GetAntrianTodayHandler)
+	administrasi.GET("/status_antrian", pasienController.GetAllStatusAntrianHandler)
 	administrasi.GET("/poliklinik", poliklinikController.GetPoliklinikList)
-	administrasi.GET("/agama", pasienController.GetAgamaList, middlewares.JWTMiddleware())
+	administrasi.GET("/agama", pasienController.GetAgamaList)
 
-	administrasi.PUT("/antrian/batalkan", pasienController.BatalkanAntrianHandler, middlewares.JWTMiddleware())
-	administrasi.GET("/detail-antrian", pasienController.GetDetailAntrianHandler, middlewares.JWTMiddleware())
+	administrasi.PUT("/antrian/batalkan", pasienController.BatalkanAntrianHandler)
+	administrasi.GET("/detail-antrian", pasienController.GetDetailAntrianHandler)
+	administrasi.GET("/antrian/log", pasienController.GetAntrianLogHandler)
 
 
 
 
-	billing := administrasi.Group("/billing")
-	billing.GET("", billingController.ListBilling, middlewares.JWTMiddleware())
-	billing.GET("/detail", billingController.GetDetailBillingHandler, middlewares.JWTMiddleware())
-	billing.POST("/bayar", billingController.BayarTagihan, middlewares.JWTMiddleware())
+	billing := newSecuredGroup(administrasi.group.Group("/billing"), "/api/administrasi/billing", middlewares.RoleAdministrasi)
+	billing.GET("", billingController.ListBilling)
+	billing.GET("/detail", billingController.GetDetailBillingHandler)
+	billing.POST("/bayar", billingController.BayarTagihan)
 
 
 
 
 	// 2. Screening / Suster (Aplikasi Screening)
-	screening := api.Group("/screening")
+	screening := newSecuredGroup(api.Group("/screening"), "/api/screening", middlewares.RoleSuster)
 	screening.POST("/suster/login", susterController.LoginSuster) // Tidak pakai JWT
-	screening.POST("/input", screeningController.InputScreening, middlewares.JWTMiddleware())
-	screening.GET("", screeningController.GetScreeningByPasienHandler, middlewares.JWTMiddleware())
-	screening.GET("/antrian/terlama", antrianController.GetAntrianTerlamaHandler, middlewares.JWTMiddleware())
-	screening.PUT("/masukkan", antrianController.MasukkanPasienHandler, middlewares.JWTMiddleware())
+	screening.POST("/input", screeningController.InputScreening)
+	screening.GET("", screeningController.GetScreeningByPasienHandler)
+	screening.GET("/antrian/terlama", antrianController.GetAntrianTerlamaHandler)
+	screening.PUT("/masukkan", antrianController.MasukkanPasienHandler)
 	screening.GET("/poliklinik", poliklinikController.GetActivePoliklinikList)
 	screening.PUT("/alihkan-pasien", antrianController.AlihkanPasienHandler)
-	screening.GET("/antrian", antrianController.GetTodayScreeningAntrianHandler, middlewares.JWTMiddleware())
-	screening.GET("/detail-antrian", antrianController.GetDetailAntrianHandler, middlewares.JWTMiddleware())
-	screening.GET("/rincian/asesmen", cmsController.GetRincianAsesmenHandler, middlewares.JWTMiddleware()) 
+	screening.GET("/antrian", antrianController.GetTodayScreeningAntrianHandler)
+	screening.GET("/detail-antrian", antrianController.GetDetailAntrianHandler)
+	screening.GET("/triase", triaseController.ListTriaseAktifHandler)
+	screening.PUT("/antrian/triase", triaseController.TetapkanTriaseHandler)
+	screening.GET("/rincian/asesmen", cmsController.GetRincianAsesmenHandler) 
 
 
 
 	// 3. Dokter (Website untuk Dokter)
-	dokter := api.Group("/dokter")
+	dokter := newSecuredGroup(api.Group("/dokter"), "/api/dokter", middlewares.RoleDokter)
 	dokter.POST("/login", dokterController.LoginDokter) // Tidak pakai JWT
 	dokter.GET("/poliklinik", poliklinikController.GetActivePoliklinikList)
-	dokter.GET("/antrian/terlama", antrianController.GetAntrianTerlamaDokterHandler, middlewares.JWTMiddleware())
-	dokter.POST("/input-screening", screeningController.InputScreening, middlewares.JWTMiddleware())
-	dokter.GET("/screening", screeningController.GetScreeningByPasienHandler, middlewares.JWTMiddleware())
-	dokter.GET("/kunjungan", resepController.GetRiwayatKunjunganHandler, middlewares.JWTMiddleware())
-	dokter.PUT("/masukkan", antrianController.MasukkanPasienKeDokterHandler, middlewares.JWTMiddleware())
-	dokter.PUT("/pulangkan-pasien", antrianController.PulangkanPasienHandler, middlewares.JWTMiddleware())
-	dokter.POST("/assessment", cmsController.SaveAssessmentHandler, middlewares.JWTMiddleware())
-	dokter.POST("/resep", resepController.CreateResepHandler, middlewares.JWTMiddleware())
-	dokter.GET("/obat", resepController.GetObatList, middlewares.JWTMiddleware())
-	dokter.POST("/billing-assessment", billingController.InputBillingAssessment, middlewares.JWTMiddleware())
-	dokter.GET("/tindakan", resepController.GetICD9CMList, middlewares.JWTMiddleware())
-	dokter.GET("/diagnosa", resepController.GetICD10List, middlewares.JWTMiddleware())
-	dokter.GET("/detail-antrian", antrianController.GetDetailAntrianHandler, middlewares.JWTMiddleware())
-	dokter.GET("/assessment", cmsController.GetAssessmentDetail, middlewares.JWTMiddleware())
-	dokter.GET("/cms/detail", cmsController.GetCMSDetailByPoliHandler, middlewares.JWTMiddleware()) 
-	dokter.GET("/ruang", poliklinikController.GetRuangList, middlewares.JWTMiddleware()) 
-	dokter.GET("/resep", resepController.GetResepDetail, middlewares.JWTMiddleware())
-	dokter.GET("/pic", poliklinikController.GetPICList, middlewares.JWTMiddleware())
+	dokter.GET("/antrian/terlama", antrianController.GetAntrianTerlamaDokterHandler)
+	dokter.POST("/input-screening", screeningController.InputScreening)
+	dokter.GET("/screening", screeningController.GetScreeningByPasienHandler)
+	dokter.GET("/kunjungan", resepController.GetRiwayatKunjunganHandler)
+	dokter.PUT("/masukkan", antrianController.MasukkanPasienKeDokterHandler)
+	dokter.PUT("/pulangkan-pasien", antrianController.PulangkanPasienHandler)
+	dokter.POST("/assessment", cmsController.SaveAssessmentHandler)
+	dokter.POST("/resep", resepController.CreateResepHandler)
+	dokter.GET("/obat", resepController.GetObatList)
+	dokter.POST("/billing-assessment", billingController.InputBillingAssessment)
+	dokter.POST("/rujukan", rujukanController.BuatRujukanHandler)
+	dokter.GET("/rujukan", rujukanController.GetRujukanHandler)
+	dokter.GET("/persetujuan/template", persetujuanController.ListTemplateAktifHandler)
+	dokter.GET("/persetujuan", persetujuanController.GetPersetujuanKunjunganHandler)
+	dokter.POST("/persetujuan", persetujuanController.CatatPersetujuanHandler)
+	dokter.GET("/lampiran", lampiranController.ListLampiranHandler)
+	dokter.GET("/lampiran/unduh", lampiranController.UnduhLampiranHandler)
+	dokter.GET("/tindakan", resepController.GetICD9CMList)
+	dokter.GET("/diagnosa", resepController.GetICD10List)
+	dokter.GET("/detail-antrian", antrianController.GetDetailAntrianHandler)
+	dokter.GET("/assessment", cmsController.GetAssessmentDetail)
+	dokter.GET("/cms/detail", cmsController.GetCMSDetailByPoliHandler) 
+	dokter.GET("/ruang", poliklinikController.GetRuangList) 
+	dokter.GET("/resep", resepController.GetResepDetail)
+	dokter.GET("/pic", poliklinikController.GetPICList)
 
 
 
@@ -157,54 +254,103 @@ func Init(e *echo.Echo, db *sql.DB) {
 	// Tambahkan endpoint dokter lain sesuai kebutuhan
 
 	// 4. Management (Website untuk Manajemen)
-	management := api.Group("/management")
+	management := newSecuredGroup(api.Group("/management"), "/api/management", middlewares.RoleManajemen)
 	management.POST("/login", managementController.Login) // Tidak pakai JWT
 
-	management.GET("/dashboard", dashboardController.GetDashboard, middlewares.JWTMiddleware())
+	management.GET("/dashboard", dashboardController.GetDashboard)
+	management.GET("/antrian/log", pasienController.GetAntrianLogHandler)
+	// Penutupan antrian harian (otomatis lewat job, bisa dipicu manual)
+	management.POST("/penutupan-harian", penutupanController.TutupHarianHandler)
+	management.GET("/penutupan-harian", penutupanController.GetPenutupanHandler)
+	management.GET("/reschedule", rescheduleController.GetKebijakanHandler)
+	management.PUT("/reschedule", rescheduleController.SetKebijakanHandler)
 
 	// Manajemen Karyawan
-	management.POST("/karyawan", karyawanController.AddKaryawan, middlewares.JWTMiddleware()) 
-	management.GET("/karyawan", karyawanController.GetKaryawanListHandler, middlewares.JWTMiddleware())
-	management.PUT("/karyawan/update", karyawanController.UpdateKaryawanHandler, middlewares.JWTMiddleware())
-	management.PUT("/karyawan/delete", karyawanController.SoftDeleteKaryawanHandler, middlewares.JWTMiddleware())
-	management.POST("/karyawan/addRole", karyawanController.AddRoleHandler, middlewares.JWTMiddleware())
+	management.POST("/karyawan", karyawanController.AddKaryawan) 
+	management.GET("/karyawan", karyawanController.GetKaryawanListHandler)
+	management.PUT("/karyawan/update", karyawanController.UpdateKaryawanHandler)
+	management.PUT("/karyawan/revoke-sessions", authController.RevokeKaryawanSessionsHandler)
+	management.PUT("/karyawan/delete", karyawanController.SoftDeleteKaryawanHandler)
+	management.POST("/karyawan/addRole", karyawanController.AddRoleHandler)
 
 	// Manajemen Poliklinik
-	management.GET("/poliklinik", poliklinikController.GetPoliklinikList, middlewares.JWTMiddleware())
-	management.POST("/poliklinik/add", poliklinikController.AddPoliklinikHandler, middlewares.JWTMiddleware())
-	management.PUT("/poliklinik/update", poliklinikController.UpdatePoliklinikHandler, middlewares.JWTMiddleware())
-	management.PUT("/poliklinik/soft-delete", poliklinikController.SoftDeletePoliklinikHandler, middlewares.JWTMiddleware())
+	management.GET("/poliklinik", poliklinikController.GetPoliklinikList)
+	management.POST("/poliklinik/add", poliklinikController.AddPoliklinikHandler)
+	management.PUT("/poliklinik/update", poliklinikController.UpdatePoliklinikHandler)
+	management.PUT("/poliklinik/soft-delete", poliklinikController.SoftDeletePoliklinikHandler)
+	// Jam operasional, kuota pendaftaran harian, dan hari libur poli
+	management.GET("/poliklinik/jadwal", jadwalPoliController.GetJadwalHandler)
+	management.PUT("/poliklinik/jadwal", jadwalPoliController.SetJadwalHandler)
+	management.GET("/poliklinik/libur", jadwalPoliController.ListLiburHandler)
+	management.POST("/poliklinik/libur", jadwalPoliController.TambahLiburHandler)
+	management.PUT("/poliklinik/libur/hapus", jadwalPoliController.HapusLiburHandler)
+	management.GET("/janji-temu", janjiTemuController.ListJanjiTemuHandler)
+	management.GET("/janji-temu/kuota", janjiTemuController.GetKuotaHandler)
+	management.PUT("/janji-temu/kuota", janjiTemuController.SetKuotaHandler)
 
 
 	// Manajemen Role
-	management.POST("/role/add", roleController.AddRoleHandler, middlewares.JWTMiddleware())
-	management.PUT("/role/update", roleController.UpdateRoleHandler, middlewares.JWTMiddleware())
-	management.PUT("/role/nonaktifkan", roleController.SoftDeleteRoleHandler, middlewares.JWTMiddleware())
-	management.PUT("/role/aktifkan", roleController.ActivateRoleHandler, middlewares.JWTMiddleware())
-	management.GET("/role/list", roleController.GetRoleListHandler, middlewares.JWTMiddleware())
+	management.POST("/role/add", roleController.AddRoleHandler)
+	management.PUT("/role/update", roleController.UpdateRoleHandler)
+	management.PUT("/role/nonaktifkan", roleController.SoftDeleteRoleHandler)
+	management.PUT("/role/aktifkan", roleController.ActivateRoleHandler)
+	management.GET("/role/list", roleController.GetRoleListHandler)
 
 	// Manajemen Privilege
-	management.POST("/privilege/assign", karyawanController.AddPrivilegeHandler, middlewares.JWTMiddleware())
-	management.GET("/privilege", privilegeController.GetAllPrivilegesHandler, middlewares.JWTMiddleware())
-	management.POST("/privilege", privilegeController.CreatePrivilegeHandler, middlewares.JWTMiddleware())
+	management.POST("/privilege/assign", karyawanController.AddPrivilegeHandler)
+	management.GET("/privilege", privilegeController.GetAllPrivilegesHandler)
+	management.POST("/privilege", privilegeController.CreatePrivilegeHandler)
+	management.GET("/policy", GetPolicyHandler)
+	management.POST("/ws/publish", ws.PublishHandler(ws.HubInstance))
+
+	// Pasien ganda
+	management.GET("/pasien/duplikat", duplikatController.FindDuplicatePairsHandler)
+	management.GET("/pasien/merge", duplikatController.GetMergeListHandler)
+	management.PUT("/pasien/merge/setujui", duplikatController.SetujuiMergeHandler)
+	management.PUT("/pasien/merge/tolak", duplikatController.TolakMergeHandler)
+	management.PUT("/pasien/merge/batalkan", duplikatController.BatalkanMergeHandler)
+	management.GET("/pasien/versi", pasienVersiController.GetVersiListHandler)
+	management.GET("/pasien/versi/diff", pasienVersiController.GetDiffHandler)
+	management.PUT("/pasien/versi/restore", pasienVersiController.RestoreVersiHandler)
+	// Import pasien dari CSV (migrasi data klinik); default dry-run
+	management.POST("/pasien/import", importPasienController.ImportPasienHandler)
+	// Permintaan hapus data pasien: pseudonimisasi / vault (legal hold)
+	management.POST("/pasien/hapus-data", hapusDataController.AjukanPermintaanHandler)
+	management.GET("/pasien/hapus-data", hapusDataController.GetPermintaanListHandler)
+	management.PUT("/pasien/hapus-data/setujui", hapusDataController.SetujuiPermintaanHandler)
+	management.PUT("/pasien/hapus-data/tolak", hapusDataController.TolakPermintaanHandler)
+	management.PUT("/pasien/hapus-data/laksanakan", hapusDataController.LaksanakanPermintaanHandler)
+	management.PUT("/pasien/hapus-data/pulihkan", hapusDataController.PulihkanPermintaanHandler)
+	management.GET("/penjamin", penjaminController.ListPenjaminHandler)
+	management.POST("/penjamin", penjaminController.TambahPenjaminHandler)
+	management.PUT("/penjamin", penjaminController.UpdatePenjaminHandler)
+	management.PUT("/penjamin/aturan", penjaminController.SetAturanHandler)
+	management.GET("/penjamin/verifikasi", penjaminController.ListKepesertaanBelumVerifikasiHandler)
+	management.PUT("/penjamin/verifikasi", penjaminController.VerifikasiKepesertaanHandler)
+	management.GET("/triase", triaseController.ListTriaseHandler)
+	management.POST("/triase", triaseController.TambahTriaseHandler)
+	management.PUT("/triase", triaseController.UpdateTriaseHandler)
+	management.GET("/persetujuan/template", persetujuanController.ListTemplateHandler)
+	management.POST("/persetujuan/template", persetujuanController.TambahTemplateHandler)
+	management.PUT("/persetujuan/template", persetujuanController.UpdateTemplateHandler)
 
 	// Manajemen Shift & CMS
-	management.PUT("/shift/updateCustom", shiftController.UpdateCustomShiftHandler, middlewares.JWTMiddleware())
-	management.PUT("/shift/soft-delete", shiftController.SoftDeleteShiftHandler, middlewares.JWTMiddleware())
-	management.GET("/shift", shiftController.GetShiftPoliList, middlewares.JWTMiddleware())
-	management.GET("/cms/detail", cmsController.GetCMSDetailByPoliHandler, middlewares.JWTMiddleware()) 
-	management.GET("/cms", cmsController.GetCMSListByPoliHandler, middlewares.JWTMiddleware()) 
-	management.PUT("/cms/update", cmsController.UpdateCMSHandler, middlewares.JWTMiddleware()) 
-	management.PUT("/cms/activate", cmsController.ActivateCMSHandler, middlewares.JWTMiddleware()) 
-	management.PUT("/cms/deactivate", cmsController.DeactivateCMSHandler, middlewares.JWTMiddleware()) 
-	management.POST("/cms/create", cmsController.CreateCMSHandler, middlewares.JWTMiddleware()) 
-	management.PUT("/cms/move-to", cmsController.MoveCMS, middlewares.JWTMiddleware()) 
-
-
-	management.GET("/shift/karyawan", shiftController.GetKaryawanListHandler, middlewares.JWTMiddleware())
-	management.GET("/shift/karyawan-tanpa-shift", shiftController.GetKaryawanTanpaShiftHandler, middlewares.JWTMiddleware())
-	management.GET("/shift/jadwal", shiftController.GetJadwalShiftHandler, middlewares.JWTMiddleware())
-
-	management.POST("/shift/assign", shiftController.AssignShiftHandlerNew, middlewares.JWTMiddleware())
+	management.PUT("/shift/updateCustom", shiftController.UpdateCustomShiftHandler)
+	management.PUT("/shift/soft-delete", shiftController.SoftDeleteShiftHandler)
+	management.GET("/shift", shiftController.GetShiftPoliList)
+	management.GET("/cms/detail", cmsController.GetCMSDetailByPoliHandler) 
+	management.GET("/cms", cmsController.GetCMSListByPoliHandler) 
+	management.PUT("/cms/update", cmsController.UpdateCMSHandler) 
+	management.PUT("/cms/activate", cmsController.ActivateCMSHandler) 
+	management.PUT("/cms/deactivate", cmsController.DeactivateCMSHandler) 
+	management.POST("/cms/create", cmsController.CreateCMSHandler) 
+	management.PUT("/cms/move-to", cmsController.MoveCMS) 
+
+
+	management.GET("/shift/karyawan", shiftController.GetKaryawanListHandler)
+	management.GET("/shift/karyawan-tanpa-shift", shiftController.GetKaryawanTanpaShiftHandler)
+	management.GET("/shift/jadwal", shiftController.GetJadwalShiftHandler)
+
+	management.POST("/shift/assign", shiftController.AssignShiftHandlerNew)
 
 }
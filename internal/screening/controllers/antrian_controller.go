@@ -1,13 +1,15 @@
 package controllers
 
 import (
-	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 	"strings"
 
+	"github.com/c14220110/poliklinik-backend/internal/common/antrian"
+	"github.com/c14220110/poliklinik-backend/internal/common/middlewares"
 	"github.com/c14220110/poliklinik-backend/internal/screening/services"
-	"github.com/c14220110/poliklinik-backend/ws"
+	"github.com/c14220110/poliklinik-backend/pkg/utils"
 	"github.com/labstack/echo/v4"
 )
 
@@ -38,9 +40,26 @@ func (ac *AntrianController) MasukkanPasienHandler(c echo.Context) error {
         })
     }
 
+    // Token suster/dokter hanya boleh memanggil pasien di poli tempat ia login.
+    claims, _ := c.Get(string(middlewares.ContextKeyClaims)).(*utils.Claims)
+    if !middlewares.EnsureSamePoli(claims, idPoli) {
+        return c.JSON(http.StatusForbidden, map[string]interface{}{
+            "status":  http.StatusForbidden,
+            "message": "id_poli tidak sesuai dengan poli pada token",
+            "data":    nil,
+        })
+    }
+
     // Panggil service untuk mengubah status antrian dan mendapatkan detail data pasien.
-    result, err := ac.AntrianService.MasukkanPasien(idPoli)
+    result, err := ac.AntrianService.MasukkanPasien(idPoli, antrian.ActorFromClaims(claims))
     if err != nil {
+        if err == antrian.ErrAntrianSudahDiambil || errors.Is(err, antrian.ErrTransisiTidakValid) {
+            return c.JSON(http.StatusConflict, map[string]interface{}{
+                "status":  http.StatusConflict,
+                "message": err.Error(),
+                "data":    nil,
+            })
+        }
         if strings.Contains(err.Error(), "tidak ada pasien") {
             return c.JSON(http.StatusNotFound, map[string]interface{}{
                 "status":  http.StatusNotFound,
@@ -55,26 +74,6 @@ func (ac *AntrianController) MasukkanPasienHandler(c echo.Context) error {
         })
     }
 
-    // Siapkan payload broadcast dengan wrapper "type" & "data"
-    inner := map[string]interface{}{
-        "id_antrian": result["id_antrian"],
-        "status":     "Screening",
-    }
-    wrapper := map[string]interface{}{
-        "type": "antrian_update",
-        "data": inner,
-    }
-
-    msg, err := json.Marshal(wrapper)
-    if err != nil {
-        return c.JSON(http.StatusInternalServerError, map[string]interface{}{
-            "status":  http.StatusInternalServerError,
-            "message": "Failed to marshal broadcast message: " + err.Error(),
-            "data":    nil,
-        })
-    }
-    ws.HubInstance.Broadcast <- msg
-
     return c.JSON(http.StatusOK, map[string]interface{}{
         "status":  http.StatusOK,
         "message": "Pasien berhasil dimasukkan",
@@ -141,9 +140,26 @@ func (ac *AntrianController) MasukkanPasienKeDokterHandler(c echo.Context) error
         })
     }
 
+    // Token suster/dokter hanya boleh memanggil pasien di poli tempat ia login.
+    claims, _ := c.Get(string(middlewares.ContextKeyClaims)).(*utils.Claims)
+    if !middlewares.EnsureSamePoli(claims, idPoli) {
+        return c.JSON(http.StatusForbidden, map[string]interface{}{
+            "status":  http.StatusForbidden,
+            "message": "id_poli tidak sesuai dengan poli pada token",
+            "data":    nil,
+        })
+    }
+
     // Panggil service untuk mengubah status antrian dan mendapatkan detail data pasien.
-    result, err := ac.AntrianService.MasukkanPasienKeDokter(idPoli)
+    result, err := ac.AntrianService.MasukkanPasienKeDokter(idPoli, antrian.ActorFromClaims(claims))
     if err != nil {
+        if err == antrian.ErrAntrianSudahDiambil || errors.Is(err, antrian.ErrTransisiTidakValid) {
+            return c.JSON(http.StatusConflict, map[string]interface{}{
+                "status":  http.StatusConflict,
+                "message": err.Error(),
+                "data":    nil,
+            })
+        }
         if strings.Contains(err.Error(), "tidak ada pasien") {
             return c.JSON(http.StatusNotFound, map[string]interface{}{
                 "status":  http.StatusNotFound,
@@ -158,26 +174,6 @@ func (ac *AntrianController) MasukkanPasienKeDokterHandler(c echo.Context) error
         })
     }
 
-    // Siapkan payload broadcast dengan wrapper "type" & "data"
-    inner := map[string]interface{}{
-        "id_antrian": result["id_antrian"],
-        "status":     "Konsultasi",
-    }
-    wrapper := map[string]interface{}{
-        "type": "antrian_update",
-        "data": inner,
-    }
-
-    msg, err := json.Marshal(wrapper)
-    if err != nil {
-        return c.JSON(http.StatusInternalServerError, map[string]interface{}{
-            "status":  http.StatusInternalServerError,
-            "message": "Failed to marshal broadcast message: " + err.Error(),
-            "data":    nil,
-        })
-    }
-    ws.HubInstance.Broadcast <- msg
-
     return c.JSON(http.StatusOK, map[string]interface{}{
         "status":  http.StatusOK,
         "message": "Pasien berhasil dimasukkan",
@@ -207,8 +203,24 @@ func (ac *AntrianController) PulangkanPasienHandler(c echo.Context) error {
         })
     }
 
+    claims, ok := c.Get(string(middlewares.ContextKeyClaims)).(*utils.Claims)
+    if !ok || claims == nil {
+        return c.JSON(http.StatusUnauthorized, map[string]interface{}{
+            "status":  http.StatusUnauthorized,
+            "message": "Invalid or missing token claims",
+            "data":    nil,
+        })
+    }
+
     // Panggil service untuk memulangkan pasien
-    if err := ac.AntrianService.PulangkanPasien(idAntrian); err != nil {
+    if err := ac.AntrianService.PulangkanPasien(idAntrian, claims.IDPoli, antrian.ActorFromClaims(claims), c.QueryParam("alasan")); err != nil {
+        if err == services.ErrPoliTidakSesuai {
+            return c.JSON(http.StatusForbidden, map[string]interface{}{
+                "status":  http.StatusForbidden,
+                "message": err.Error(),
+                "data":    nil,
+            })
+        }
         if strings.Contains(err.Error(), "tidak ditemukan") {
             return c.JSON(http.StatusNotFound, map[string]interface{}{
                 "status":  http.StatusNotFound,
@@ -216,9 +228,9 @@ func (ac *AntrianController) PulangkanPasienHandler(c echo.Context) error {
                 "data":    nil,
             })
         }
-        if strings.Contains(err.Error(), "status antrian saat ini bukan Konsultasi") {
-            return c.JSON(http.StatusBadRequest, map[string]interface{}{
-                "status":  http.StatusBadRequest,
+        if errors.Is(err, antrian.ErrTransisiTidakValid) {
+            return c.JSON(http.StatusConflict, map[string]interface{}{
+                "status":  http.StatusConflict,
                 "message": err.Error(),
                 "data":    nil,
             })
@@ -230,24 +242,6 @@ func (ac *AntrianController) PulangkanPasienHandler(c echo.Context) error {
         })
     }
 
-    // Siapkan payload broadcast dengan wrapper "type" & "data"
-    inner := map[string]interface{}{
-        "id_antrian": idAntrian,
-        "status":     "Selesai",
-    }
-    wrapper := map[string]interface{}{
-        "type": "antrian_update",
-        "data": inner,
-    }
-
-    msg, err := json.Marshal(wrapper)
-    if err != nil {
-        // log error internal saja, tidak menggagalkan respons
-        // log.Println("Failed to marshal broadcast message:", err)
-    } else {
-        ws.HubInstance.Broadcast <- msg
-    }
-
     // Kembalikan respons sukses
     return c.JSON(http.StatusOK, map[string]interface{}{
         "status":  http.StatusOK,
@@ -278,34 +272,44 @@ func (ac *AntrianController) AlihkanPasienHandler(c echo.Context) error {
 		})
 	}
 
-	// Panggil service untuk mengubah status antrian menjadi 4.
-	if err := ac.AntrianService.AlihkanPasien(idAntrian); err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
-			"status":  http.StatusInternalServerError,
-			"message": "Gagal mengalihkan pasien: " + err.Error(),
+	claims, ok := c.Get(string(middlewares.ContextKeyClaims)).(*utils.Claims)
+	if !ok || claims == nil {
+		return c.JSON(http.StatusUnauthorized, map[string]interface{}{
+			"status":  http.StatusUnauthorized,
+			"message": "Invalid or missing token claims",
 			"data":    nil,
 		})
 	}
 
-	// Siapkan payload broadcast dengan wrapper "type" & "data"
-	inner := map[string]interface{}{
-		"id_antrian": idAntrian,
-		"status":     "Pra-Konsultasi",
-	}
-	wrapper := map[string]interface{}{
-		"type": "antrian_update",
-		"data": inner,
-	}
-
-	msg, err := json.Marshal(wrapper)
-	if err != nil {
+	// Panggil service untuk mengubah status antrian menjadi 4.
+	if err := ac.AntrianService.AlihkanPasien(idAntrian, claims.IDPoli, antrian.ActorFromClaims(claims), c.QueryParam("alasan")); err != nil {
+		if errors.Is(err, antrian.ErrTransisiTidakValid) {
+			return c.JSON(http.StatusConflict, map[string]interface{}{
+				"status":  http.StatusConflict,
+				"message": err.Error(),
+				"data":    nil,
+			})
+		}
+		if err == services.ErrPoliTidakSesuai {
+			return c.JSON(http.StatusForbidden, map[string]interface{}{
+				"status":  http.StatusForbidden,
+				"message": err.Error(),
+				"data":    nil,
+			})
+		}
+		if strings.Contains(err.Error(), "tidak ditemukan") {
+			return c.JSON(http.StatusNotFound, map[string]interface{}{
+				"status":  http.StatusNotFound,
+				"message": err.Error(),
+				"data":    nil,
+			})
+		}
 		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
 			"status":  http.StatusInternalServerError,
-			"message": "Gagal membuat pesan broadcast: " + err.Error(),
+			"message": "Gagal mengalihkan pasien: " + err.Error(),
 			"data":    nil,
 		})
 	}
-	ws.HubInstance.Broadcast <- msg
 
 	// Kembalikan response sukses
 	return c.JSON(http.StatusOK, map[string]interface{}{
@@ -414,7 +418,7 @@ func (ac *AntrianController) GetAntrianTerlamaDokterHandler(c echo.Context) erro
     }
 
     // Perbaiki: gunakan ac.AntrianService, bukan ac.Service
-    data, err := ac.AntrianService.GetAntrianTerlama(idPoli)
+    data, err := ac.AntrianService.GetAntrianTerlamaDokter(idPoli)
     if err != nil {
         return c.JSON(http.StatusNotFound, map[string]interface{}{
             "status":  http.StatusNotFound,
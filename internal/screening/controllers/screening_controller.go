@@ -1,15 +1,15 @@
 package controllers
 
 import (
-	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 
+	"github.com/c14220110/poliklinik-backend/internal/common/antrian"
 	"github.com/c14220110/poliklinik-backend/internal/common/middlewares"
 	"github.com/c14220110/poliklinik-backend/internal/screening/models"
 	"github.com/c14220110/poliklinik-backend/internal/screening/services"
 	"github.com/c14220110/poliklinik-backend/pkg/utils"
-	"github.com/c14220110/poliklinik-backend/ws"
 	"github.com/labstack/echo/v4"
 )
 
@@ -94,8 +94,22 @@ func (sc *ScreeningController) InputScreening(c echo.Context) error {
     }
 
     // Panggil service untuk menyimpan data
-    screeningID, err := sc.Service.InputScreening(screeningInput, idAntrian, operatorID)
+    screeningID, err := sc.Service.InputScreening(screeningInput, idAntrian, antrian.ActorFromClaims(claims), claims.IDPoli)
     if err != nil {
+        if errors.Is(err, antrian.ErrTransisiTidakValid) {
+            return c.JSON(http.StatusConflict, map[string]interface{}{
+                "status":  http.StatusConflict,
+                "message": err.Error(),
+                "data":    nil,
+            })
+        }
+        if err == services.ErrPoliTidakSesuai {
+            return c.JSON(http.StatusForbidden, map[string]interface{}{
+                "status":  http.StatusForbidden,
+                "message": err.Error(),
+                "data":    nil,
+            })
+        }
         return c.JSON(http.StatusInternalServerError, map[string]interface{}{
             "status":  http.StatusInternalServerError,
             "message": "Failed to input screening: " + err.Error(),
@@ -103,26 +117,6 @@ func (sc *ScreeningController) InputScreening(c echo.Context) error {
         })
     }
 
-    // Siapkan payload broadcast dengan wrapper "type" & "data"
-    inner := map[string]interface{}{
-        "id_antrian": idAntrian,
-        "status":     "Pra-Konsultasi",
-    }
-    wrapper := map[string]interface{}{
-        "type": "antrian_update",
-        "data": inner,
-    }
-
-    messageJSON, err := json.Marshal(wrapper)
-    if err != nil {
-        return c.JSON(http.StatusInternalServerError, map[string]interface{}{
-            "status":  http.StatusInternalServerError,
-            "message": "Failed to marshal broadcast message: " + err.Error(),
-            "data":    nil,
-        })
-    }
-    ws.HubInstance.Broadcast <- messageJSON
-
     // Kembalikan response sukses
     return c.JSON(http.StatusOK, map[string]interface{}{
         "status":  http.StatusOK,
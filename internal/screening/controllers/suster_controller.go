@@ -4,6 +4,7 @@ import (
 	"net/http"
 	"time"
 
+	commonServices "github.com/c14220110/poliklinik-backend/internal/common/services"
 	"github.com/c14220110/poliklinik-backend/internal/screening/services"
 	"github.com/c14220110/poliklinik-backend/pkg/utils"
 	"github.com/labstack/echo/v4"
@@ -16,11 +17,12 @@ type LoginSusterRequest struct {
 }
 
 type SusterController struct {
-	Service *services.SusterService
+	Service  *services.SusterService
+	Sessions *commonServices.SessionService
 }
 
-func NewSusterController(service *services.SusterService) *SusterController {
-	return &SusterController{Service: service}
+func NewSusterController(service *services.SusterService, sessions *commonServices.SessionService) *SusterController {
+	return &SusterController{Service: service, Sessions: sessions}
 }
 
 func (sc *SusterController) LoginSuster(c echo.Context) error {
@@ -58,17 +60,16 @@ func (sc *SusterController) LoginSuster(c echo.Context) error {
 		expTime = time.Now().Add(1 * time.Hour)
 	}
 
-	// Generate token JWT dengan memasukkan nama suster ke dalam payload.
-	token, err := utils.GenerateJWTToken(
-		suster.ID_Suster,
-		"Suster",
-		suster.ID_Role,
-		suster.Privileges,
-		req.IDPoli,
-		suster.Username,
-		suster.Nama, // Menyertakan nama suster
-		expTime,
-	)
+	// Buat sesi baru; access token berisi nama suster & berakhir paling lambat di akhir shift.
+	tokens, err := sc.Sessions.CreateSession(utils.Claims{
+		IDKaryawan: suster.ID_Suster,
+		Role:       "Suster",
+		IDRole:     suster.ID_Role,
+		Privileges: suster.Privileges,
+		IDPoli:     req.IDPoli,
+		Username:   suster.Username,
+		Nama:       suster.Nama,
+	}, expTime)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
 			"status":  http.StatusInternalServerError,
@@ -81,12 +82,13 @@ func (sc *SusterController) LoginSuster(c echo.Context) error {
 		"status":  http.StatusOK,
 		"message": "Login successful",
 		"data": map[string]interface{}{
-			"id":       suster.ID_Suster,
-			"nama":     suster.Nama,
-			"username": suster.Username,
-			"role":     "Suster",
-			"id_poli":  req.IDPoli,
-			"token":    token,
+			"id":            suster.ID_Suster,
+			"nama":          suster.Nama,
+			"username":      suster.Username,
+			"role":          "Suster",
+			"id_poli":       req.IDPoli,
+			"token":         tokens.AccessToken,
+			"refresh_token": tokens.RefreshToken,
 			"shift": map[string]interface{}{
 				"id_shift_karyawan": shift.ID_Shift_Karyawan,
 				"jam_mulai":         shift.CustomJamMulai,
@@ -5,8 +5,14 @@ import (
 	"errors"
 	"fmt"
 	"time"
+
+	"github.com/c14220110/poliklinik-backend/internal/common/antrian"
+	"github.com/c14220110/poliklinik-backend/ws"
 )
 
+// ErrPoliTidakSesuai dikembalikan jika antrian bukan milik poli yang ada di token.
+var ErrPoliTidakSesuai = errors.New("antrian bukan milik poliklinik pada token")
+
 type AntrianService struct {
 	DB *sql.DB
 }
@@ -15,41 +21,17 @@ func NewAntrianService(db *sql.DB) *AntrianService {
 	return &AntrianService{DB: db}
 }
 
-func (s *AntrianService) MasukkanPasien(idPoli int) (map[string]interface{}, error) {
-	// 1. Cari baris antrian teratas dengan id_status = 1 untuk id_poli yang diberikan dan untuk hari ini.
-	query := `
-		SELECT id_antrian 
-		FROM Antrian 
-		WHERE id_poli = ? AND id_status = 1 AND DATE(created_at) = CURDATE()
-		ORDER BY nomor_antrian ASC 
-		LIMIT 1
-	`
-	var idAntrian int
-	err := s.DB.QueryRow(query, idPoli).Scan(&idAntrian)
+func (s *AntrianService) MasukkanPasien(idPoli int, actor antrian.Actor) (map[string]interface{}, error) {
+	// 1-2. Klaim antrian Menunggu berikutnya (skor triase, priority_order, nomor_antrian) dan
+	// pindahkan ke Screening secara atomik.
+	idAntrian, err := antrian.ClaimNext(s.DB, idPoli, antrian.StatusMenunggu, antrian.StatusScreening, actor)
 	if err != nil {
-		if err == sql.ErrNoRows {
+		if err == antrian.ErrTidakAdaAntrian {
 			return nil, fmt.Errorf("tidak ada pasien dengan status 1 untuk poli dengan id %d pada hari ini", idPoli)
 		}
 		return nil, err
 	}
-
-	// 2. Update baris yang ditemukan, ubah id_status menjadi 3.
-	updateQuery := `
-		UPDATE Antrian 
-		SET id_status = 3 
-		WHERE id_antrian = ?
-	`
-	res, err := s.DB.Exec(updateQuery, idAntrian)
-	if err != nil {
-		return nil, err
-	}
-	affected, err := res.RowsAffected()
-	if err != nil {
-		return nil, err
-	}
-	if affected == 0 {
-		return nil, fmt.Errorf("gagal mengupdate antrian, baris tidak ditemukan")
-	}
+	antrian.PublishEvent(s.DB, ws.EventAntrianCalled, idAntrian, antrian.StatusMenunggu, antrian.StatusScreening, actor, "")
 
 	// 3. Ambil data detail pasien dan antrian.
 	// Query ini menggabungkan data dari tabel Pasien, Rekam_Medis, dan Antrian.
@@ -62,7 +44,7 @@ func (s *AntrianService) MasukkanPasien(idPoli int) (map[string]interface{}, err
 		       rm.id_rm, a.nomor_antrian
 		FROM Antrian a
 		JOIN Pasien p ON a.id_pasien = p.id_pasien
-		JOIN Rekam_Medis rm ON p.id_pasien = rm.id_pasien
+		JOIN Rekam_Medis rm ON p.id_pasien = rm.id_pasien AND rm.digabung_dari IS NULL
 		WHERE a.id_antrian = ?
 		ORDER BY rm.created_at DESC
 		LIMIT 1
@@ -123,7 +105,7 @@ func (s *AntrianService) GetAntrianTerlama(idPoli int) (map[string]interface{},
 		SELECT id_antrian, nomor_antrian 
 		FROM Antrian 
 		WHERE id_poli = ? AND id_status = 1 AND DATE(created_at) = CURDATE()
-		ORDER BY nomor_antrian ASC 
+		ORDER BY `+antrian.UrutanPanggilan("")+`
 		LIMIT 1
 	`
 	var idAntrian int
@@ -145,48 +127,24 @@ func (s *AntrianService) GetAntrianTerlama(idPoli int) (map[string]interface{},
 	return result, nil
 }
 
-func (s *AntrianService) MasukkanPasienKeDokter(idPoli int) (map[string]interface{}, error) {
-	// 1. Cari baris antrian teratas dengan id_status = 4 untuk id_poli yang diberikan dan untuk hari ini.
-	query := `
-		SELECT id_antrian 
-		FROM Antrian 
-		WHERE id_poli = ? AND id_status = 4 AND DATE(created_at) = CURDATE()
-		ORDER BY nomor_antrian ASC 
-		LIMIT 1
-	`
-	var idAntrian int
-	err := s.DB.QueryRow(query, idPoli).Scan(&idAntrian)
+func (s *AntrianService) MasukkanPasienKeDokter(idPoli int, actor antrian.Actor) (map[string]interface{}, error) {
+	// 1-2. Klaim antrian Pra-Konsultasi berikutnya (skor triase, priority_order, nomor_antrian) dan
+	// pindahkan ke Konsultasi secara atomik.
+	idAntrian, err := antrian.ClaimNext(s.DB, idPoli, antrian.StatusPraKonsultasi, antrian.StatusKonsultasi, actor)
 	if err != nil {
-		if err == sql.ErrNoRows {
+		if err == antrian.ErrTidakAdaAntrian {
 			return nil, fmt.Errorf("tidak ada pasien dengan status pra-konsul untuk poli dengan id %d pada hari ini", idPoli)
 		}
 		return nil, err
 	}
-
-	// 2. Update baris yang ditemukan, ubah id_status menjadi 5.
-	updateQuery := `
-		UPDATE Antrian 
-		SET id_status = 5 
-		WHERE id_antrian = ?
-	`
-	res, err := s.DB.Exec(updateQuery, idAntrian)
-	if err != nil {
-		return nil, err
-	}
-	affected, err := res.RowsAffected()
-	if err != nil {
-		return nil, err
-	}
-	if affected == 0 {
-		return nil, fmt.Errorf("gagal mengupdate antrian, baris tidak ditemukan")
-	}
+	antrian.PublishEvent(s.DB, ws.EventAntrianCalled, idAntrian, antrian.StatusPraKonsultasi, antrian.StatusKonsultasi, actor, "")
 
 	// 3. Ambil data tambahan: id_pasien, nama pasien, jenis_kelamin, id_rm, tanggal_lahir, dan nomor_antrian
 	queryDetails := `
 		SELECT p.id_pasien, p.nama, p.jenis_kelamin, rm.id_rm, p.tanggal_lahir, a.nomor_antrian
 		FROM Antrian a
 		JOIN Pasien p ON a.id_pasien = p.id_pasien
-		JOIN Rekam_Medis rm ON p.id_pasien = rm.id_pasien
+		JOIN Rekam_Medis rm ON p.id_pasien = rm.id_pasien AND rm.digabung_dari IS NULL
 		WHERE a.id_antrian = ?
 		ORDER BY rm.created_at DESC
 		LIMIT 1
@@ -227,50 +185,46 @@ func (s *AntrianService) MasukkanPasienKeDokter(idPoli int) (map[string]interfac
 	return result, nil
 }
 
-func (s *AntrianService) PulangkanPasien(idAntrian int) error {
-	// Periksa status saat ini
-	var currentStatus int
-	checkQuery := "SELECT id_status FROM Antrian WHERE id_antrian = ?"
-	err := s.DB.QueryRow(checkQuery, idAntrian).Scan(&currentStatus)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			return fmt.Errorf("antrian dengan id %d tidak ditemukan", idAntrian)
-		}
-		return fmt.Errorf("gagal memeriksa status antrian: %v", err)
+// PulangkanPasien memindahkan antrian dari Konsultasi ke Pulang.
+// Antrian harus milik idPoli (poli pada token).
+func (s *AntrianService) PulangkanPasien(idAntrian, idPoli int, actor antrian.Actor, alasan string) error {
+	if err := s.checkPoliAntrian(idAntrian, idPoli); err != nil {
+		return err
 	}
-	if currentStatus != 5 {
-		return fmt.Errorf("status antrian saat ini bukan Konsultasi (5), melainkan %d", currentStatus)
+	from, err := antrian.TransitionDB(s.DB, idAntrian, antrian.StatusPulang, actor, alasan)
+	if err != nil {
+		return err
 	}
+	antrian.PublishEvent(s.DB, ws.EventAntrianStatusChanged, idAntrian, from, antrian.StatusPulang, actor, alasan)
+	return nil
+}
 
-	// Update status ke 6 (Pulang)
-	updateQuery := "UPDATE Antrian SET id_status = ? WHERE id_antrian = ?"
-	result, err := s.DB.Exec(updateQuery, 6, idAntrian)
-	if err != nil {
-		return fmt.Errorf("gagal mengupdate antrian: %v", err)
+// AlihkanPasien memindahkan antrian dari Screening ke Pra-Konsultasi tanpa input screening.
+// Antrian harus milik idPoli (poli pada token).
+func (s *AntrianService) AlihkanPasien(idAntrian, idPoli int, actor antrian.Actor, alasan string) error {
+	if err := s.checkPoliAntrian(idAntrian, idPoli); err != nil {
+		return err
 	}
-	affected, err := result.RowsAffected()
+	from, err := antrian.TransitionDB(s.DB, idAntrian, antrian.StatusPraKonsultasi, actor, alasan)
 	if err != nil {
-		return fmt.Errorf("gagal memeriksa update antrian: %v", err)
-	}
-	if affected == 0 {
-		return fmt.Errorf("tidak ada baris yang terupdate, antrian dengan id %d mungkin tidak ada", idAntrian)
+		return err
 	}
+	antrian.PublishEvent(s.DB, ws.EventAntrianStatusChanged, idAntrian, from, antrian.StatusPraKonsultasi, actor, alasan)
 	return nil
 }
 
-// AlihkanPasien mengubah status antrian menjadi 4 untuk id_antrian yang diberikan.
-func (s *AntrianService) AlihkanPasien(idAntrian int) error {
-	updateQuery := "UPDATE Antrian SET id_status = ? WHERE id_antrian = ?"
-	result, err := s.DB.Exec(updateQuery, 4, idAntrian)
+// checkPoliAntrian memastikan antrian ada dan milik idPoli.
+func (s *AntrianService) checkPoliAntrian(idAntrian, idPoli int) error {
+	var antrianPoli int
+	err := s.DB.QueryRow("SELECT id_poli FROM Antrian WHERE id_antrian = ?", idAntrian).Scan(&antrianPoli)
 	if err != nil {
-		return fmt.Errorf("gagal mengupdate antrian: %v", err)
-	}
-	affected, err := result.RowsAffected()
-	if err != nil {
-		return fmt.Errorf("gagal memeriksa update antrian: %v", err)
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("antrian dengan id %d tidak ditemukan", idAntrian)
+		}
+		return fmt.Errorf("gagal memeriksa antrian: %v", err)
 	}
-	if affected == 0 {
-		return fmt.Errorf("tidak ada baris yang terupdate, antrian dengan id %d mungkin tidak ada", idAntrian)
+	if antrianPoli != idPoli {
+		return ErrPoliTidakSesuai
 	}
 	return nil
 }
@@ -283,6 +237,7 @@ func (s *AntrianService) GetTodayScreeningAntrianByPoli(idPoli int) ([]map[strin
     query := `
         SELECT
             a.id_antrian,
+            a.nomor_antrian,
             a.id_pasien,
             p.nama,
             a.priority_order
@@ -307,16 +262,17 @@ func (s *AntrianService) GetTodayScreeningAntrianByPoli(idPoli int) ([]map[strin
 
     for rows.Next() {
         var (
-            idAntrian, idPasien int
-            nama               string
-            priority           sql.NullInt64
+            idAntrian, nomorAntrian, idPasien int
+            nama                              string
+            priority                          sql.NullInt64
         )
-        if err := rows.Scan(&idAntrian, &idPasien, &nama, &priority); err != nil {
+        if err := rows.Scan(&idAntrian, &nomorAntrian, &idPasien, &nama, &priority); err != nil {
             return nil, fmt.Errorf("scan error: %v", err)
         }
 
         record := map[string]interface{}{
             "id_antrian":     idAntrian,
+            "nomor_antrian":  nomorAntrian,
             "id_pasien":      idPasien,
             "nama_pasien":    nama,
             "priority_order": nil,
@@ -347,7 +303,7 @@ func (s *AntrianService) GetDetailAntrianByID(idAntrian int) (map[string]interfa
 		       rm.id_rm, a.nomor_antrian
 		FROM   Antrian a
 		JOIN   Pasien  p  ON a.id_pasien = p.id_pasien
-		JOIN   Rekam_Medis rm ON p.id_pasien = rm.id_pasien
+		JOIN   Rekam_Medis rm ON p.id_pasien = rm.id_pasien AND rm.digabung_dari IS NULL
 		WHERE  a.id_antrian = ?
 		ORDER  BY rm.created_at DESC
 		LIMIT  1
@@ -419,13 +375,13 @@ func (s *AntrianService) GetDetailAntrianByID(idAntrian int) (map[string]interfa
 }
 
 
-// GetAntrianTerlama mengambil ID_Antrian dan Nomor_Antrian dari pasien dengan antrian paling lama (status = 1) pada hari ini
+// GetAntrianTerlamaDokter mengambil ID_Antrian dan Nomor_Antrian dari pasien berikutnya (status = 4) pada hari ini
 func (s *AntrianService) GetAntrianTerlamaDokter(idPoli int) (map[string]interface{}, error) {
 	query := `
 		SELECT id_antrian, nomor_antrian 
 		FROM Antrian 
 		WHERE id_poli = ? AND id_status = 4 AND DATE(created_at) = CURDATE()
-		ORDER BY nomor_antrian ASC 
+		ORDER BY `+antrian.UrutanPanggilan("")+`
 		LIMIT 1
 	`
 	var idAntrian int
@@ -5,7 +5,9 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/c14220110/poliklinik-backend/internal/common/antrian"
 	"github.com/c14220110/poliklinik-backend/internal/screening/models"
+	"github.com/c14220110/poliklinik-backend/ws"
 )
 
 type ScreeningService struct {
@@ -16,22 +18,28 @@ func NewScreeningService(db *sql.DB) *ScreeningService {
 	return &ScreeningService{DB: db}
 }
 
-func (s *ScreeningService) InputScreening(input models.ScreeningInput, idAntrian int, operatorID int) (int64, error) {
+// InputScreening menyimpan hasil screening untuk id_antrian. actor adalah operator dari token
+// dan idPoli poli pada token; antrian dari poli lain ditolak dengan ErrPoliTidakSesuai.
+func (s *ScreeningService) InputScreening(input models.ScreeningInput, idAntrian int, actor antrian.Actor, idPoli int) (int64, error) {
+	operatorID := actor.IDKaryawan
+
 	// Mulai transaksi
 	tx, err := s.DB.Begin()
 	if err != nil {
 		return 0, fmt.Errorf("gagal memulai transaksi: %v", err)
 	}
 
-	// Ambil id_pasien dari Antrian berdasarkan id_antrian di Riwayat_Kunjungan
-	var idPasien int
+	// Ambil id_pasien dari Antrian yang terdaftar di Riwayat_Kunjungan (antrian pendaftaran)
+	// atau Kunjungan_Poli (antrian rujukan internal)
+	var idPasien, antrianPoli int
 	queryGetPasien := `
-		SELECT A.id_pasien 
+		SELECT A.id_pasien, A.id_poli
 		FROM Antrian A
-		JOIN Riwayat_Kunjungan RK ON A.id_antrian = RK.id_antrian
-		WHERE RK.id_antrian = ?
+		WHERE A.id_antrian = ?
+		  AND (EXISTS (SELECT 1 FROM Riwayat_Kunjungan RK WHERE RK.id_antrian = A.id_antrian)
+		       OR EXISTS (SELECT 1 FROM Kunjungan_Poli KP WHERE KP.id_antrian = A.id_antrian))
 	`
-	err = tx.QueryRow(queryGetPasien, idAntrian).Scan(&idPasien)
+	err = tx.QueryRow(queryGetPasien, idAntrian).Scan(&idPasien, &antrianPoli)
 	if err != nil {
 		tx.Rollback()
 		if err == sql.ErrNoRows {
@@ -39,6 +47,10 @@ func (s *ScreeningService) InputScreening(input models.ScreeningInput, idAntrian
 		}
 		return 0, fmt.Errorf("gagal mengambil id_pasien: %v", err)
 	}
+	if antrianPoli != idPoli {
+		tx.Rollback()
+		return 0, ErrPoliTidakSesuai
+	}
 
 	// Buat objek Screening
 	screening := models.Screening{
@@ -116,31 +128,28 @@ func (s *ScreeningService) InputScreening(input models.ScreeningInput, idAntrian
 		return 0, fmt.Errorf("gagal update Riwayat_Kunjungan dengan ID screening")
 	}
 
-	// Update status Antrian menjadi 4 (misalnya Pra-Konsultasi)
-	updateAntrian := `
-		UPDATE Antrian 
-		SET id_status = 4 
-		WHERE id_antrian = ?
-	`
-	res, err = tx.Exec(updateAntrian, idAntrian)
+	// Pasien yang sedang Screening dipindahkan ke Pra-Konsultasi. Jika screening diinput
+	// ulang saat pasien sudah Pra-Konsultasi/Konsultasi (misalnya oleh dokter), status tidak berubah.
+	currentStatus, err := antrian.Lock(tx, idAntrian)
 	if err != nil {
 		tx.Rollback()
-		return 0, fmt.Errorf("gagal update status Antrian: %v", err)
+		return 0, err
 	}
-	affected, err = res.RowsAffected()
-	if err != nil {
-		tx.Rollback()
-		return 0, fmt.Errorf("gagal memeriksa affected rows Antrian: %v", err)
-	}
-	if affected == 0 {
-		tx.Rollback()
-		return 0, fmt.Errorf("gagal update status Antrian")
+	statusBerubah := currentStatus != antrian.StatusPraKonsultasi && currentStatus != antrian.StatusKonsultasi
+	if statusBerubah {
+		if _, err = antrian.Transition(tx, idAntrian, antrian.StatusPraKonsultasi, actor, "input screening"); err != nil {
+			tx.Rollback()
+			return 0, err
+		}
 	}
 
 	// Commit transaksi
 	if err = tx.Commit(); err != nil {
 		return 0, fmt.Errorf("gagal commit transaksi: %v", err)
 	}
+	if statusBerubah {
+		antrian.PublishEvent(s.DB, ws.EventAntrianStatusChanged, idAntrian, currentStatus, antrian.StatusPraKonsultasi, actor, "input screening")
+	}
 
 	return screeningID, nil
 }
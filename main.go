@@ -10,6 +10,8 @@ import (
 	"time"
 
 	"github.com/c14220110/poliklinik-backend/config"
+	adminServices "github.com/c14220110/poliklinik-backend/internal/administrasi/services"
+	manajemenServices "github.com/c14220110/poliklinik-backend/internal/manajemen/services"
 
 	"github.com/c14220110/poliklinik-backend/internal/routes"
 	"github.com/joho/godotenv"
@@ -50,6 +52,13 @@ func main() {
 
 	e.Static("/uploads", "uploads")
 
+	// Job latar belakang: janji temu yang tidak di-check-in sampai cutoff menjadi Kedaluwarsa,
+	// dan penutupan antrian harian (PENUTUPAN_HARIAN_JAM)
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	go adminServices.NewJanjiTemuService(db).RunExpiry(jobsCtx, time.Minute)
+	go manajemenServices.NewPenutupanService(db).RunPenutupan(jobsCtx, 5*time.Minute)
+	go adminServices.NewRescheduleService(db).RunKembaliTerjadwal(jobsCtx, time.Minute)
+
 
 	// Jalankan server di goroutine
 	go func() {
@@ -65,6 +74,7 @@ func main() {
 	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
 	<-quit
 	slog.Info("Received shutdown signal. Shutting down...")
+	stopJobs()
 
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
@@ -35,10 +35,22 @@ func GenerateJWTToken(idKaryawan int, role string, idRole int, privileges []int,
 		IDPoli:     idPoli,
 		Username:   username,
 		Nama:       nama,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(exp),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-		},
+	}
+	return SignClaims(claims, "", exp)
+}
+
+// SignClaims menandatangani claims dengan exp sesuai parameter. sessionID disimpan
+// di field jti (RegisteredClaims.ID) agar token bisa dicabut lewat tabel Sesi_Login.
+func SignClaims(claims Claims, sessionID string, exp time.Time) (string, error) {
+	jwtKey := []byte(os.Getenv("JWT_SECRET_KEY"))
+	if len(jwtKey) == 0 {
+		return "", fmt.Errorf("JWT secret key is missing")
+	}
+
+	claims.RegisteredClaims = jwt.RegisteredClaims{
+		ID:        sessionID,
+		ExpiresAt: jwt.NewNumericDate(exp),
+		IssuedAt:  jwt.NewNumericDate(time.Now()),
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
@@ -0,0 +1,134 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrNIKTidakValid dipakai untuk NIK yang strukturnya salah (panjang, digit, kode wilayah, tanggal).
+	ErrNIKTidakValid = errors.New("NIK tidak valid")
+	// ErrNIKTidakCocok dipakai pada mode strict jika tanggal lahir / jenis kelamin di NIK berbeda dengan data.
+	ErrNIKTidakCocok = errors.New("NIK tidak cocok dengan data")
+)
+
+// kodeProvinsi adalah dua digit pertama NIK yang terdaftar di Dukcapil.
+var kodeProvinsi = map[string]bool{
+	"11": true, "12": true, "13": true, "14": true, "15": true, "16": true, "17": true, "18": true, "19": true,
+	"21": true,
+	"31": true, "32": true, "33": true, "34": true, "35": true, "36": true,
+	"51": true, "52": true, "53": true,
+	"61": true, "62": true, "63": true, "64": true, "65": true,
+	"71": true, "72": true, "73": true, "74": true, "75": true, "76": true,
+	"81": true, "82": true,
+	"91": true, "92": true, "93": true, "94": true, "95": true, "96": true, "97": true,
+}
+
+// NIKInfo adalah data yang terkandung di NIK:
+// PP KK CC DDMMYY NNNN (provinsi, kabupaten/kota, kecamatan, tanggal lahir, nomor urut).
+// Tanggal lahir perempuan ditulis tanggal + 40.
+type NIKInfo struct {
+	KodeProvinsi  string
+	KodeKabupaten string
+	KodeKecamatan string
+	Hari          int
+	Bulan         int
+	Tahun2Digit   int
+	Perempuan     bool
+	NomorUrut     string
+}
+
+// ParseNIK memeriksa struktur NIK 16 digit dan mengurai isinya.
+func ParseNIK(nik string) (NIKInfo, error) {
+	var info NIKInfo
+	if len(nik) != 16 {
+		return info, fmt.Errorf("%w: harus 16 digit", ErrNIKTidakValid)
+	}
+	for _, r := range nik {
+		if r < '0' || r > '9' {
+			return info, fmt.Errorf("%w: hanya boleh berisi angka", ErrNIKTidakValid)
+		}
+	}
+
+	info.KodeProvinsi = nik[0:2]
+	info.KodeKabupaten = nik[2:4]
+	info.KodeKecamatan = nik[4:6]
+	info.NomorUrut = nik[12:16]
+	if info.KodeProvinsi == "00" || info.KodeKabupaten == "00" || info.KodeKecamatan == "00" {
+		return info, fmt.Errorf("%w: kode provinsi/kabupaten/kecamatan tidak boleh 00", ErrNIKTidakValid)
+	}
+	if info.NomorUrut == "0000" {
+		return info, fmt.Errorf("%w: nomor urut tidak boleh 0000", ErrNIKTidakValid)
+	}
+
+	info.Hari, _ = strconv.Atoi(nik[6:8])
+	info.Bulan, _ = strconv.Atoi(nik[8:10])
+	info.Tahun2Digit, _ = strconv.Atoi(nik[10:12])
+	if info.Hari > 40 {
+		info.Perempuan = true
+		info.Hari -= 40
+	}
+	if info.Bulan < 1 || info.Bulan > 12 || info.Hari < 1 || info.Hari > 31 {
+		return info, fmt.Errorf("%w: tanggal lahir di NIK tidak valid", ErrNIKTidakValid)
+	}
+	// Abad tidak tercantum di NIK; 29 Februari cukup valid di salah satu abad (2000 kabisat).
+	if !tanggalAda(1900+info.Tahun2Digit, info.Bulan, info.Hari) && !tanggalAda(2000+info.Tahun2Digit, info.Bulan, info.Hari) {
+		return info, fmt.Errorf("%w: tanggal lahir di NIK tidak valid", ErrNIKTidakValid)
+	}
+	return info, nil
+}
+
+func tanggalAda(tahun, bulan, hari int) bool {
+	t := time.Date(tahun, time.Month(bulan), hari, 0, 0, 0, 0, time.UTC)
+	return t.Day() == hari && int(t.Month()) == bulan
+}
+
+// jenisKelaminPerempuan mengenali penulisan jenis kelamin yang umum dipakai;
+// ok = false jika nilainya kosong atau tidak dikenali.
+func jenisKelaminPerempuan(jenisKelamin string) (perempuan, ok bool) {
+	switch strings.ToLower(strings.TrimSpace(jenisKelamin)) {
+	case "p", "perempuan", "wanita", "f", "female":
+		return true, true
+	case "l", "laki-laki", "laki laki", "lakilaki", "laki", "pria", "m", "male":
+		return false, true
+	}
+	return false, false
+}
+
+// ValidateNIK memeriksa struktur NIK lalu mencocokkan tanggal lahir dan jenis kelamin yang
+// tercantum di NIK dengan data. NIK yang strukturnya salah selalu ditolak (ErrNIKTidakValid).
+// Ketidakcocokan (dan kode provinsi yang tidak dikenal) dikembalikan sebagai peringatan pada
+// mode lenient, atau ditolak dengan ErrNIKTidakCocok pada mode strict.
+// tanggalLahir zero dan jenisKelamin kosong dilewati.
+func ValidateNIK(nik string, tanggalLahir time.Time, jenisKelamin string, strict bool) (peringatan []string, err error) {
+	info, err := ParseNIK(strings.TrimSpace(nik))
+	if err != nil {
+		return nil, err
+	}
+
+	peringatan = []string{}
+	if !kodeProvinsi[info.KodeProvinsi] {
+		peringatan = append(peringatan, fmt.Sprintf("kode provinsi %s di NIK tidak dikenal", info.KodeProvinsi))
+	}
+	if !tanggalLahir.IsZero() &&
+		(tanggalLahir.Day() != info.Hari || int(tanggalLahir.Month()) != info.Bulan || tanggalLahir.Year()%100 != info.Tahun2Digit) {
+		peringatan = append(peringatan, fmt.Sprintf(
+			"tanggal lahir di NIK (%02d-%02d-%02d) berbeda dengan tanggal_lahir (%s)",
+			info.Hari, info.Bulan, info.Tahun2Digit, tanggalLahir.Format("02-01-06")))
+	}
+	if perempuan, ok := jenisKelaminPerempuan(jenisKelamin); ok && perempuan != info.Perempuan {
+		diNIK := "laki-laki"
+		if info.Perempuan {
+			diNIK = "perempuan"
+		}
+		peringatan = append(peringatan, fmt.Sprintf("jenis kelamin di NIK (%s) berbeda dengan jenis_kelamin (%s)", diNIK, jenisKelamin))
+	}
+
+	if strict && len(peringatan) > 0 {
+		return nil, fmt.Errorf("%w: %s", ErrNIKTidakCocok, strings.Join(peringatan, "; "))
+	}
+	return peringatan, nil
+}
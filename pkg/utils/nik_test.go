@@ -0,0 +1,106 @@
+package utils
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseNIK(t *testing.T) {
+	info, err := ParseNIK("3171014501900001")
+	if err != nil {
+		t.Fatalf("ParseNIK: %v", err)
+	}
+	want := NIKInfo{
+		KodeProvinsi: "31", KodeKabupaten: "71", KodeKecamatan: "01",
+		Hari: 5, Bulan: 1, Tahun2Digit: 90, Perempuan: true, NomorUrut: "0001",
+	}
+	if info != want {
+		t.Errorf("ParseNIK = %+v, want %+v", info, want)
+	}
+
+	info, err = ParseNIK("3578021708450123")
+	if err != nil {
+		t.Fatalf("ParseNIK: %v", err)
+	}
+	if info.Perempuan || info.Hari != 17 || info.Bulan != 8 || info.Tahun2Digit != 45 {
+		t.Errorf("ParseNIK laki-laki = %+v", info)
+	}
+}
+
+func TestParseNIKTidakValid(t *testing.T) {
+	tests := []struct {
+		nama, nik string
+	}{
+		{"kosong", ""},
+		{"15 digit", "317101450190000"},
+		{"17 digit", "31710145019000011"},
+		{"huruf", "31710145019000A1"},
+		{"provinsi 00", "0071014501900001"},
+		{"kabupaten 00", "3100014501900001"},
+		{"kecamatan 00", "3171004501900001"},
+		{"nomor urut 0000", "3171014501900000"},
+		{"hari 00", "3171010001900001"},
+		{"hari 32", "3171013201900001"},
+		{"hari perempuan 72", "3171017201900001"},
+		{"bulan 00", "3171011500900001"},
+		{"bulan 13", "3171011513900001"},
+		{"31 April", "3171013104900001"},
+		{"30 Februari", "3171013002000001"},
+		{"29 Februari bukan kabisat", "3171012902010001"},
+	}
+	for _, tt := range tests {
+		if _, err := ParseNIK(tt.nik); !errors.Is(err, ErrNIKTidakValid) {
+			t.Errorf("%s: ParseNIK(%q) err = %v, want ErrNIKTidakValid", tt.nama, tt.nik, err)
+		}
+	}
+
+	// 29 Februari 00 valid karena 2000 kabisat; 29 Februari 04 valid di kedua abad.
+	for _, nik := range []string{"3171012902000001", "3171016902040001"} {
+		if _, err := ParseNIK(nik); err != nil {
+			t.Errorf("ParseNIK(%q): %v", nik, err)
+		}
+	}
+}
+
+func TestValidateNIK(t *testing.T) {
+	lahir := time.Date(1990, 1, 5, 0, 0, 0, 0, time.UTC)
+
+	// Cocok: tanpa peringatan di kedua mode.
+	for _, strict := range []bool{false, true} {
+		peringatan, err := ValidateNIK(" 3171014501900001 ", lahir, "Perempuan", strict)
+		if err != nil || len(peringatan) != 0 {
+			t.Errorf("strict=%v: peringatan = %v, err = %v", strict, peringatan, err)
+		}
+	}
+
+	// Tanggal lahir dan jenis kelamin berbeda: peringatan (lenient) atau ditolak (strict).
+	peringatan, err := ValidateNIK("3171014501900001", lahir.AddDate(0, 0, 1), "L", false)
+	if err != nil || len(peringatan) != 2 {
+		t.Errorf("lenient: peringatan = %v, err = %v", peringatan, err)
+	}
+	if _, err := ValidateNIK("3171014501900001", lahir.AddDate(0, 0, 1), "L", true); !errors.Is(err, ErrNIKTidakCocok) {
+		t.Errorf("strict: err = %v, want ErrNIKTidakCocok", err)
+	}
+
+	// Kode provinsi tidak terdaftar hanya peringatan pada mode lenient.
+	peringatan, err = ValidateNIK("9971014501900001", time.Time{}, "", false)
+	if err != nil || len(peringatan) != 1 {
+		t.Errorf("provinsi 99: peringatan = %v, err = %v", peringatan, err)
+	}
+	if _, err := ValidateNIK("9971014501900001", time.Time{}, "", true); !errors.Is(err, ErrNIKTidakCocok) {
+		t.Errorf("provinsi 99 strict: err = %v, want ErrNIKTidakCocok", err)
+	}
+
+	// Jenis kelamin yang tidak dikenali dilewati.
+	if peringatan, err := ValidateNIK("3171014501900001", time.Time{}, "-", true); err != nil || len(peringatan) != 0 {
+		t.Errorf("jenis kelamin tidak dikenal: peringatan = %v, err = %v", peringatan, err)
+	}
+
+	// Struktur salah selalu ditolak, termasuk provinsi 00.
+	for _, strict := range []bool{false, true} {
+		if _, err := ValidateNIK("0071014501900001", time.Time{}, "", strict); !errors.Is(err, ErrNIKTidakValid) {
+			t.Errorf("provinsi 00 strict=%v: err = %v, want ErrNIKTidakValid", strict, err)
+		}
+	}
+}
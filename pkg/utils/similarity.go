@@ -0,0 +1,97 @@
+package utils
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Levenshtein menghitung edit distance (sisip/hapus/ganti satu karakter) antara a dan b.
+func Levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	if len(ra) == 0 {
+		return len(rb)
+	}
+	if len(rb) == 0 {
+		return len(ra)
+	}
+
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
+
+// NormalizeText mengubah teks menjadi huruf kecil, hanya huruf/angka, dengan satu spasi antar kata.
+func NormalizeText(s string) string {
+	fields := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	return strings.Join(fields, " ")
+}
+
+// StringSimilarity mengembalikan 0..1 berdasarkan Levenshtein pada teks yang sudah dinormalisasi.
+func StringSimilarity(a, b string) float64 {
+	a, b = NormalizeText(a), NormalizeText(b)
+	if a == "" && b == "" {
+		return 0
+	}
+	maxLen := max(len([]rune(a)), len([]rune(b)))
+	return 1 - float64(Levenshtein(a, b))/float64(maxLen)
+}
+
+// TokenSimilarity mengembalikan kemiripan Jaccard (0..1) dari kata-kata a dan b,
+// cocok untuk alamat yang urutan/kelengkapannya sering berbeda.
+func TokenSimilarity(a, b string) float64 {
+	ta := strings.Fields(NormalizeText(a))
+	tb := strings.Fields(NormalizeText(b))
+	if len(ta) == 0 || len(tb) == 0 {
+		return 0
+	}
+	set := map[string]bool{}
+	for _, t := range ta {
+		set[t] = true
+	}
+	union := len(set)
+	inter := 0
+	seen := map[string]bool{}
+	for _, t := range tb {
+		if seen[t] {
+			continue
+		}
+		seen[t] = true
+		if set[t] {
+			inter++
+		} else {
+			union++
+		}
+	}
+	return float64(inter) / float64(union)
+}
+
+// NormalizePhone menyisakan digit dan menyeragamkan awalan +62/62 menjadi 0.
+func NormalizePhone(phone string) string {
+	var sb strings.Builder
+	for _, r := range phone {
+		if r >= '0' && r <= '9' {
+			sb.WriteRune(r)
+		}
+	}
+	digits := sb.String()
+	if strings.HasPrefix(digits, "62") {
+		digits = "0" + digits[2:]
+	}
+	return digits
+}
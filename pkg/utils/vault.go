@@ -0,0 +1,66 @@
+package utils
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrVaultKey dipakai jika kunci vault tidak dikonfigurasi atau panjangnya salah.
+	ErrVaultKey = errors.New("kunci vault tidak tersedia")
+	// ErrVaultRusak dipakai jika data vault tidak bisa didekripsi (kunci berbeda / data diubah).
+	ErrVaultRusak = errors.New("data vault tidak bisa dibuka")
+)
+
+// VaultKeyID adalah sidik kunci (16 karakter heksadesimal) yang disimpan bersama data vault
+// sehingga kunci yang salah terdeteksi sebelum dekripsi.
+func VaultKeyID(key []byte) string {
+	sum := sha256.Sum256(key)
+	return hex.EncodeToString(sum[:8])
+}
+
+func vaultAEAD(key []byte) (cipher.AEAD, error) {
+	if len(key) != 32 {
+		return nil, ErrVaultKey
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrVaultKey, err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// SealVault mengenkripsi plaintext dengan AES-256-GCM. aad (mis. id pasien & id permintaan)
+// ikut diautentikasi sehingga data vault tidak bisa dipindah ke baris lain.
+func SealVault(key, plaintext, aad []byte) (nonce, ciphertext []byte, err error) {
+	aead, err := vaultAEAD(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	nonce = make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, nil, fmt.Errorf("gagal membuat nonce: %v", err)
+	}
+	return nonce, aead.Seal(nil, nonce, plaintext, aad), nil
+}
+
+// OpenVault membalik SealVault.
+func OpenVault(key, nonce, ciphertext, aad []byte) ([]byte, error) {
+	aead, err := vaultAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(nonce) != aead.NonceSize() {
+		return nil, ErrVaultRusak
+	}
+	plaintext, err := aead.Open(nil, nonce, ciphertext, aad)
+	if err != nil {
+		return nil, ErrVaultRusak
+	}
+	return plaintext, nil
+}
@@ -0,0 +1,118 @@
+package ws
+
+import (
+	"encoding/json"
+	"log/slog"
+	"time"
+)
+
+// Jenis event antrian yang dikirim ke client. Semua event memakai wrapper
+// {"type": <jenis>, "data": AntrianEvent}.
+const (
+	EventAntrianCreated       = "antrian_created"        // pasien terdaftar & masuk antrian (Menunggu)
+	EventAntrianCalled        = "antrian_called"         // pasien dipanggil ke screening / ke dokter
+	EventAntrianStatusChanged = "antrian_status_changed" // perubahan status lain (screening selesai, ditunda, pulang)
+	EventAntrianCancelled     = "antrian_cancelled"      // antrian dibatalkan
+	EventAntrianRescheduled   = "antrian_rescheduled"    // pasien ditunda dijadwalkan ulang (lihat JadwalUlang)
+)
+
+// EventAntrianUpdate adalah event lama yang masih dipakai frontend administrasi & screening.
+// Tetap dikirim bersama setiap event di atas sampai semua client pindah ke event bertipe.
+const EventAntrianUpdate = "antrian_update"
+
+// JadwalUlang menjelaskan slot yang dipilih kebijakan reschedule poli. Tidak memuat data
+// pribadi pasien sehingga ikut dikirim ke layar display.
+type JadwalUlang struct {
+	Mode       string     `json:"mode"`       // Sisip, Akhir, Jam
+	Posisi     *int       `json:"posisi"`     // urutan panggilan (1 = berikutnya); nil jika belum masuk antrian
+	KembaliAt  *time.Time `json:"kembali_at"` // mode Jam: waktu pasien masuk kembali ke antrian
+	Keterangan string     `json:"keterangan"`
+}
+
+// AntrianEvent adalah payload event antrian. Tidak memuat nama pasien karena ikut dikirim ke
+// poli:{id_poli}; client yang perlu nama mengambilnya lewat id_pasien.
+type AntrianEvent struct {
+	IDAntrian     int          `json:"id_antrian"`
+	NomorAntrian  int          `json:"nomor_antrian"`
+	PriorityOrder *int         `json:"priority_order"`
+	IDPoli        int          `json:"id_poli"`
+	NamaPoli      string       `json:"nama_poli"`
+	IDPasien      int          `json:"id_pasien"`
+	IDStatusDari  *int         `json:"id_status_dari"`
+	StatusDari    *string      `json:"status_dari"`
+	IDStatus      int          `json:"id_status"`
+	Status        string       `json:"status"`
+	Alasan        string       `json:"alasan,omitempty"`
+	IDKaryawan    int          `json:"id_karyawan,omitempty"`
+	Role          string       `json:"role"`
+	JadwalUlang   *JadwalUlang `json:"jadwal_ulang,omitempty"`
+	Timestamp     time.Time    `json:"timestamp"`
+}
+
+// DisplayEvent adalah AntrianEvent tanpa data pasien maupun petugas, untuk topik display:*.
+type DisplayEvent struct {
+	NomorAntrian int          `json:"nomor_antrian"`
+	IDPoli       int          `json:"id_poli"`
+	NamaPoli     string       `json:"nama_poli"`
+	IDStatus     int          `json:"id_status"`
+	Status       string       `json:"status"`
+	JadwalUlang  *JadwalUlang `json:"jadwal_ulang,omitempty"`
+	Timestamp    time.Time    `json:"timestamp"`
+}
+
+// PublishAntrianEvent mengirim event ke antrian:{id}, poli:{id_poli} dan role:administrasi
+// (ditambah antrian_update lama ke topik yang sama), serta versi DisplayEvent ke
+// display:{id_poli} dan display:all.
+func (h *Hub) PublishAntrianEvent(eventType string, event AntrianEvent) {
+	msg, err := json.Marshal(map[string]interface{}{
+		"type": eventType,
+		"data": event,
+	})
+	if err != nil {
+		slog.Error("Gagal membuat event antrian", "type", eventType, "reason", err)
+		return
+	}
+	h.Publish(msg, AntrianTopics(event.IDPoli, event.IDAntrian)...)
+	h.publishAntrianUpdate(event)
+
+	// Versi publik untuk layar antrian: hanya nomor antrian, poli & status.
+	display, err := json.Marshal(map[string]interface{}{
+		"type": eventType,
+		"data": DisplayEvent{
+			NomorAntrian: event.NomorAntrian,
+			IDPoli:       event.IDPoli,
+			NamaPoli:     event.NamaPoli,
+			IDStatus:     event.IDStatus,
+			Status:       event.Status,
+			JadwalUlang:  event.JadwalUlang,
+			Timestamp:    event.Timestamp,
+		},
+	})
+	if err != nil {
+		slog.Error("Gagal membuat event display", "type", eventType, "reason", err)
+		return
+	}
+	h.Publish(display, TopicDisplay(event.IDPoli), TopicDisplayAll)
+}
+
+// publishAntrianUpdate mengirim antrian_update dengan field yang dipakai payload lama.
+func (h *Hub) publishAntrianUpdate(event AntrianEvent) {
+	msg, err := json.Marshal(map[string]interface{}{
+		"type": EventAntrianUpdate,
+		"data": map[string]interface{}{
+			"id_antrian":     event.IDAntrian,
+			"id_pasien":      event.IDPasien,
+			"id_poli":        event.IDPoli,
+			"id_status":      event.IDStatus,
+			"nama_poli":      event.NamaPoli,
+			"nomor_antrian":  event.NomorAntrian,
+			"priority_order": event.PriorityOrder,
+			"status":         event.Status,
+		},
+	})
+	if err != nil {
+		slog.Error("Gagal membuat event antrian", "type", EventAntrianUpdate, "reason", err)
+		return
+	}
+	h.Publish(msg, AntrianTopics(event.IDPoli, event.IDAntrian)...)
+}
@@ -1,8 +1,12 @@
 package ws
 
 import (
+	"encoding/json"
 	"net/http"
+	"strings"
 
+	"github.com/c14220110/poliklinik-backend/internal/common/middlewares"
+	"github.com/c14220110/poliklinik-backend/pkg/utils"
 	"github.com/gorilla/websocket"
 	"github.com/labstack/echo/v4"
 )
@@ -14,14 +18,27 @@ var upgrader = websocket.Upgrader{
 	},
 }
 
+// ServeWS meng-upgrade koneksi yang sudah lolos JWTMiddleware. Client otomatis
+// subscribe ke role:{role} (dan poli:{id_poli} untuk suster/dokter), lalu dapat
+// mengirim {"action":"subscribe"|"unsubscribe","topics":[...]}.
 func ServeWS(hub *Hub) echo.HandlerFunc {
 	return func(c echo.Context) error {
+		claims, ok := c.Get(string(middlewares.ContextKeyClaims)).(*utils.Claims)
+		if !ok || claims == nil {
+			return c.JSON(http.StatusUnauthorized, map[string]interface{}{
+				"status":  http.StatusUnauthorized,
+				"message": "Invalid or missing token claims",
+				"data":    nil,
+			})
+		}
+
 		conn, err := upgrader.Upgrade(c.Response(), c.Request(), nil)
 		if err != nil {
 			return err
 		}
-		client := &Client{Conn: conn, Send: make(chan []byte, 256)}
+		client := &Client{Conn: conn, Send: make(chan []byte, 256), Claims: claims}
 		hub.Register <- client
+		hub.subscribe(client, defaultTopics(claims), nil, false)
 
 		// Jalankan goroutine untuk membaca dan menulis pesan
 		go client.writePump()
@@ -30,18 +47,76 @@ func ServeWS(hub *Hub) echo.HandlerFunc {
 	}
 }
 
-// Implementasi sederhana fungsi readPump dan writePump
+// ServeDisplayWS adalah feed publik untuk layar antrian (tanpa JWT). Client hanya menerima
+// topik display:* — ?id_poli=1,2 untuk poli tertentu, atau display:all jika kosong.
+func ServeDisplayWS(hub *Hub) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		topics := []string{}
+		if idPoli := c.QueryParam("id_poli"); idPoli != "" {
+			for _, id := range strings.Split(idPoli, ",") {
+				topic := "display:" + strings.TrimSpace(id)
+				if !ValidTopic(topic) {
+					return c.JSON(http.StatusBadRequest, map[string]interface{}{
+						"status":  http.StatusBadRequest,
+						"message": "id_poli harus berupa angka",
+						"data":    nil,
+					})
+				}
+				topics = append(topics, topic)
+			}
+		} else {
+			topics = append(topics, TopicDisplayAll)
+		}
+
+		conn, err := upgrader.Upgrade(c.Response(), c.Request(), nil)
+		if err != nil {
+			return err
+		}
+		client := &Client{Conn: conn, Send: make(chan []byte, 256)}
+		hub.Register <- client
+		hub.subscribe(client, topics, nil, false)
+
+		go client.writePump()
+		go client.readPump(hub)
+		return nil
+	}
+}
+
+// clientRequest adalah pesan yang dikirim client melalui WebSocket.
+type clientRequest struct {
+	Action string   `json:"action"`
+	Topics []string `json:"topics"`
+}
+
 func (c *Client) readPump(hub *Hub) {
 	defer func() {
 		hub.Unregister <- c
 		c.Conn.Close()
 	}()
 	for {
-		_, _, err := c.Conn.ReadMessage()
+		_, raw, err := c.Conn.ReadMessage()
 		if err != nil {
 			break
 		}
-		// Boleh tambahkan logika jika client mengirim pesan
+
+		var req clientRequest
+		if err := json.Unmarshal(raw, &req); err != nil {
+			continue
+		}
+		switch req.Action {
+		case "subscribe":
+			var accepted, rejected []string
+			for _, topic := range req.Topics {
+				if hub.authorize(c.Claims, topic) {
+					accepted = append(accepted, topic)
+				} else {
+					rejected = append(rejected, topic)
+				}
+			}
+			hub.subscribe(c, accepted, rejected, false)
+		case "unsubscribe":
+			hub.subscribe(c, req.Topics, nil, true)
+		}
 	}
 }
 
@@ -53,4 +128,54 @@ func (c *Client) writePump() {
 		}
 	}
 	c.Conn.Close()
-}
\ No newline at end of file
+}
+
+// PublishRequest adalah body untuk mengirim pesan manual ke topik tertentu.
+type PublishRequest struct {
+	Topics []string    `json:"topics"`
+	Type   string      `json:"type"`
+	Data   interface{} `json:"data"`
+}
+
+// PublishHandler mengirim pesan ke topik tertentu (pengganti /ws-test).
+// POST /api/management/ws/publish
+func PublishHandler(hub *Hub) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		var req PublishRequest
+		if err := c.Bind(&req); err != nil || len(req.Topics) == 0 || req.Type == "" {
+			return c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"status":  http.StatusBadRequest,
+				"message": "topics and type are required",
+				"data":    nil,
+			})
+		}
+		for _, topic := range req.Topics {
+			if !ValidTopic(topic) {
+				return c.JSON(http.StatusBadRequest, map[string]interface{}{
+					"status":  http.StatusBadRequest,
+					"message": "Invalid topic: " + topic,
+					"data":    nil,
+				})
+			}
+		}
+
+		msg, err := json.Marshal(map[string]interface{}{
+			"type": req.Type,
+			"data": req.Data,
+		})
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+				"status":  http.StatusInternalServerError,
+				"message": "Failed to encode message: " + err.Error(),
+				"data":    nil,
+			})
+		}
+		hub.Publish(msg, req.Topics...)
+
+		return c.JSON(http.StatusOK, map[string]interface{}{
+			"status":  http.StatusOK,
+			"message": "Message published",
+			"data":    map[string]interface{}{"topics": req.Topics},
+		})
+	}
+}
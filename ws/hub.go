@@ -2,15 +2,17 @@ package ws
 
 //bertanggung jawab untuk:
 
-// Menyimpan koneksi client.
+// Menyimpan koneksi client beserta topik yang di-subscribe.
 
-// Menerima pesan dari API endpoint.
+// Menerima pesan dari API endpoint / service.
 
-// Melakukan broadcast pesan ke seluruh client yang terhubung.
+// Mengirim pesan hanya ke client yang subscribe ke topik pesan tersebut.
 
 import (
-	"fmt"
+	"encoding/json"
+	"log/slog"
 
+	"github.com/c14220110/poliklinik-backend/pkg/utils"
 	"github.com/gorilla/websocket"
 )
 
@@ -20,53 +22,146 @@ func init() {
 	go HubInstance.Run()
 }
 
-
-// Client mewakili koneksi WebSocket
+// Client mewakili satu koneksi WebSocket. Claims nil untuk koneksi publik layar antrian.
 type Client struct {
-	Conn *websocket.Conn
-	Send chan []byte
+	Conn   *websocket.Conn
+	Send   chan []byte
+	Claims *utils.Claims
+}
+
+// Message adalah pesan yang dikirim ke semua subscriber salah satu Topics.
+type Message struct {
+	Topics []string
+	Data   []byte
 }
 
-// Hub mengelola semua koneksi client
+// subscription adalah permintaan subscribe/unsubscribe yang sudah diotorisasi.
+type subscription struct {
+	client      *Client
+	topics      []string
+	unsubscribe bool
+	ack         []byte
+}
+
+// Hub mengelola semua koneksi client dan index topik -> client.
 type Hub struct {
 	Clients    map[*Client]bool
-	Broadcast  chan []byte
 	Register   chan *Client
 	Unregister chan *Client
+
+	messages      chan Message
+	subscriptions chan subscription
+	topics        map[string]map[*Client]bool
+	clientTopics  map[*Client]map[string]bool
+
+	// antrianPoli dipakai untuk mengotorisasi topik antrian:{id}.
+	antrianPoli func(idAntrian int) (int, error)
 }
 
 func NewHub() *Hub {
 	return &Hub{
-		Clients:    make(map[*Client]bool),
-		Broadcast:  make(chan []byte),
-		Register:   make(chan *Client),
-		Unregister: make(chan *Client),
+		Clients:       make(map[*Client]bool),
+		Register:      make(chan *Client),
+		Unregister:    make(chan *Client),
+		messages:      make(chan Message, 256),
+		subscriptions: make(chan subscription),
+		topics:        make(map[string]map[*Client]bool),
+		clientTopics:  make(map[*Client]map[string]bool),
 	}
 }
 
+// SetAntrianPoliLookup memasang fungsi pencarian id_poli dari id_antrian (diisi saat startup).
+func (h *Hub) SetAntrianPoliLookup(lookup func(idAntrian int) (int, error)) {
+	h.antrianPoli = lookup
+}
+
+// Publish mengirim data ke semua client yang subscribe ke salah satu topik.
+func (h *Hub) Publish(data []byte, topics ...string) {
+	if len(topics) == 0 {
+		return
+	}
+	h.messages <- Message{Topics: topics, Data: data}
+}
+
 func (h *Hub) Run() {
 	for {
 		select {
 		case client := <-h.Register:
 			h.Clients[client] = true
-			// Debug log
-			fmt.Println("Client registered")
+			h.clientTopics[client] = make(map[string]bool)
+			slog.Debug("WebSocket client registered", "clients", len(h.Clients))
 		case client := <-h.Unregister:
 			if _, ok := h.Clients[client]; ok {
-				delete(h.Clients, client)
-				close(client.Send)
-				fmt.Println("Client unregistered")
+				h.remove(client)
+				slog.Debug("WebSocket client unregistered", "clients", len(h.Clients))
+			}
+		case sub := <-h.subscriptions:
+			if _, ok := h.Clients[sub.client]; !ok {
+				continue
+			}
+			for _, topic := range sub.topics {
+				if sub.unsubscribe {
+					delete(h.topics[topic], sub.client)
+					delete(h.clientTopics[sub.client], topic)
+					continue
+				}
+				if h.topics[topic] == nil {
+					h.topics[topic] = make(map[*Client]bool)
+				}
+				h.topics[topic][sub.client] = true
+				h.clientTopics[sub.client][topic] = true
 			}
-		case message := <-h.Broadcast:
-			fmt.Println("Broadcasting message:", string(message))
-			for client := range h.Clients {
-				select {
-				case client.Send <- message:
-				default:
-					close(client.Send)
-					delete(h.Clients, client)
+			if sub.ack != nil {
+				h.send(sub.client, sub.ack)
+			}
+		case message := <-h.messages:
+			sent := make(map[*Client]bool)
+			for _, topic := range message.Topics {
+				for client := range h.topics[topic] {
+					if sent[client] {
+						continue
+					}
+					sent[client] = true
+					h.send(client, message.Data)
 				}
 			}
 		}
 	}
-}
\ No newline at end of file
+}
+
+// send mengirim tanpa blocking; client yang terlalu lambat diputus.
+func (h *Hub) send(client *Client, data []byte) {
+	select {
+	case client.Send <- data:
+	default:
+		h.remove(client)
+	}
+}
+
+func (h *Hub) remove(client *Client) {
+	for topic := range h.clientTopics[client] {
+		delete(h.topics[topic], client)
+		if len(h.topics[topic]) == 0 {
+			delete(h.topics, topic)
+		}
+	}
+	delete(h.clientTopics, client)
+	delete(h.Clients, client)
+	close(client.Send)
+}
+
+// subscribe dipanggil dari readPump setelah topik diotorisasi.
+func (h *Hub) subscribe(client *Client, accepted, rejected []string, unsubscribe bool) {
+	action := "subscribed"
+	if unsubscribe {
+		action = "unsubscribed"
+	}
+	ack, _ := json.Marshal(map[string]interface{}{
+		"type": action,
+		"data": map[string]interface{}{
+			"topics":   accepted,
+			"rejected": rejected,
+		},
+	})
+	h.subscriptions <- subscription{client: client, topics: accepted, unsubscribe: unsubscribe, ack: ack}
+}
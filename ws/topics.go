@@ -0,0 +1,129 @@
+package ws
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/c14220110/poliklinik-backend/internal/common/middlewares"
+	"github.com/c14220110/poliklinik-backend/pkg/utils"
+)
+
+// Format topik:
+//
+//	role:{administrasi|suster|dokter|manajemen}
+//	poli:{id_poli}
+//	antrian:{id_antrian}
+//	display:{id_poli|all}  (publik, hanya berisi nomor antrian & nama poli)
+const (
+	TopicRoleAdministrasi = "role:administrasi"
+	TopicDisplayAll       = "display:all"
+)
+
+func TopicRole(role string) string {
+	return "role:" + strings.ToLower(role)
+}
+
+func TopicPoli(idPoli int) string {
+	return fmt.Sprintf("poli:%d", idPoli)
+}
+
+func TopicDisplay(idPoli int) string {
+	return fmt.Sprintf("display:%d", idPoli)
+}
+
+func TopicAntrian(idAntrian int) string {
+	return fmt.Sprintf("antrian:%d", idAntrian)
+}
+
+// AntrianTopics adalah topik tujuan update antrian bila id_poli sudah diketahui.
+func AntrianTopics(idPoli, idAntrian int) []string {
+	return []string{TopicAntrian(idAntrian), TopicPoli(idPoli), TopicRoleAdministrasi}
+}
+
+// defaultTopics adalah topik yang otomatis di-subscribe saat client terhubung.
+func defaultTopics(claims *utils.Claims) []string {
+	topics := []string{TopicRole(claims.Role)}
+	if poliBound(claims) && claims.IDPoli > 0 {
+		topics = append(topics, TopicPoli(claims.IDPoli))
+	}
+	return topics
+}
+
+// poliBound: suster dan dokter hanya boleh melihat data poli tempat shift-nya.
+func poliBound(claims *utils.Claims) bool {
+	return claims.Role == middlewares.RoleSuster || claims.Role == middlewares.RoleDokter
+}
+
+// ValidTopic memeriksa format topik tanpa memeriksa hak akses.
+func ValidTopic(topic string) bool {
+	kind, value, ok := strings.Cut(topic, ":")
+	if !ok || value == "" {
+		return false
+	}
+	switch kind {
+	case "role":
+		switch value {
+		case "administrasi", "suster", "dokter", "manajemen":
+			return true
+		}
+	case "poli", "antrian":
+		id, err := strconv.Atoi(value)
+		return err == nil && id > 0
+	case "display":
+		if value == "all" {
+			return true
+		}
+		id, err := strconv.Atoi(value)
+		return err == nil && id > 0
+	}
+	return false
+}
+
+func isDisplayTopic(topic string) bool {
+	return strings.HasPrefix(topic, "display:")
+}
+
+// authorize memeriksa apakah pemilik claims boleh subscribe ke topik.
+// claims nil berarti koneksi publik (layar antrian).
+func (h *Hub) authorize(claims *utils.Claims, topic string) bool {
+	if !ValidTopic(topic) {
+		return false
+	}
+	// Topik display boleh untuk siapa saja, termasuk layar antrian tanpa login.
+	if isDisplayTopic(topic) {
+		return true
+	}
+	if claims == nil {
+		return false
+	}
+	if claims.Role == middlewares.RoleManajemen {
+		return true
+	}
+
+	kind, value, _ := strings.Cut(topic, ":")
+	switch kind {
+	case "role":
+		return value == strings.ToLower(claims.Role)
+	case "poli":
+		if !poliBound(claims) {
+			return true
+		}
+		idPoli, _ := strconv.Atoi(value)
+		return middlewares.EnsureSamePoli(claims, idPoli)
+	case "antrian":
+		if !poliBound(claims) {
+			return true
+		}
+		if h.antrianPoli == nil {
+			return false
+		}
+		idAntrian, _ := strconv.Atoi(value)
+		idPoli, err := h.antrianPoli(idAntrian)
+		if err != nil {
+			return false
+		}
+		return middlewares.EnsureSamePoli(claims, idPoli)
+	}
+	return false
+}